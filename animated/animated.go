@@ -0,0 +1,180 @@
+// Package animated renders small animated GIFs, such as countdown timers,
+// progress bars, and crossfade transitions, of the kind commonly embedded in
+// emails.
+package animated
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"time"
+
+	"image-resizer/imaging"
+
+	"golang.org/x/image/draw"
+)
+
+// Style controls the size and colors of generated animations.
+type Style struct {
+	Width, Height int
+	Background    color.Color
+	Foreground    color.Color
+	// TextScale magnifies the 7x13 bitmap font used for text
+	TextScale int
+}
+
+// DefaultStyle is black text or bars on white.
+var DefaultStyle = Style{Width: 240, Height: 60, Background: color.White, Foreground: color.Black, TextScale: 3}
+
+// Frame timing. GIF delays are in hundredths of a second.
+const (
+	countdownFrames = 60
+	countdownDelay  = 100
+	progressFrames  = 10
+	progressDelay   = 5
+)
+
+// Countdown renders a one-minute animation counting down to until, one frame
+// per second, in DD:HH:MM:SS form. Frames start at now truncated to the
+// minute, so every request within the same minute gets an identical GIF and
+// callers can cache on (until, now.Truncate(time.Minute)). The animation does
+// not loop; it stops early at zero.
+func Countdown(until, now time.Time, style Style) *gif.GIF {
+	start := now.Truncate(time.Minute)
+	anim := &gif.GIF{LoopCount: -1}
+	for i := 0; i < countdownFrames; i++ {
+		remaining := until.Sub(start.Add(time.Duration(i) * time.Second))
+		if remaining < 0 {
+			remaining = 0
+		}
+		anim.Image = append(anim.Image, textFrame(formatRemaining(remaining), style))
+		anim.Delay = append(anim.Delay, countdownDelay)
+		if remaining == 0 {
+			break
+		}
+	}
+	return anim
+}
+
+// formatRemaining formats d as DD:HH:MM:SS.
+func formatRemaining(d time.Duration) string {
+	s := int(d / time.Second)
+	return fmt.Sprintf("%02d:%02d:%02d:%02d", s/86400, s/3600%24, s/60%60, s%60)
+}
+
+// Progress renders a bar that fills from empty to percent (0-100) and then
+// holds. The animation does not loop.
+func Progress(percent float64, style Style) *gif.GIF {
+	percent = max(0, min(100, percent))
+	anim := &gif.GIF{LoopCount: -1}
+	for i := 1; i <= progressFrames; i++ {
+		frame := newFrame(style)
+		// Leave a one-pixel foreground outline with a gap around the bar
+		b := frame.Bounds()
+		draw.Draw(frame, b, image.NewUniform(style.Foreground), image.Point{}, draw.Src)
+		draw.Draw(frame, b.Inset(1), image.NewUniform(style.Background), image.Point{}, draw.Src)
+		inner := b.Inset(3)
+		inner.Max.X = inner.Min.X + int(float64(inner.Dx())*percent/100*float64(i)/progressFrames)
+		draw.Draw(frame, inner, image.NewUniform(style.Foreground), image.Point{}, draw.Src)
+
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, progressDelay)
+	}
+	return anim
+}
+
+// newFrame returns a two-color frame; index 0, the background, fills it.
+func newFrame(style Style) *image.Paletted {
+	return image.NewPaletted(image.Rect(0, 0, style.Width, style.Height), color.Palette{style.Background, style.Foreground})
+}
+
+// textFrame renders s centered on a frame, magnified by style.TextScale.
+func textFrame(s string, style Style) *image.Paletted {
+	frame := newFrame(style)
+	w, h := imaging.TextSize(s)
+	text := image.NewPaletted(image.Rect(0, 0, w, h), frame.Palette)
+	imaging.DrawText(text, image.Pt(0, imaging.TextFace.Metrics().Ascent.Ceil()), s, style.Foreground)
+
+	scale := max(style.TextScale, 1)
+	sw, sh := w*scale, h*scale
+	x, y := (style.Width-sw)/2, (style.Height-sh)/2
+	draw.NearestNeighbor.Scale(frame, image.Rect(x, y, x+sw, y+sh), text, text.Bounds(), draw.Src, nil)
+	return frame
+}
+
+// CrossfadeOptions control Crossfade. Delays are in hundredths of a second.
+type CrossfadeOptions struct {
+	Frames int  // Intermediate frames between the two images
+	Delay  int  // Delay of each intermediate frame
+	Hold   int  // Delay of the first and last images
+	Loop   bool // Fade back to the first image and repeat forever
+}
+
+// MaxCrossfadeFrames bounds CrossfadeOptions.Frames, since every
+// intermediate frame is a full-size image held until the GIF is encoded.
+const MaxCrossfadeFrames = 100
+
+// DefaultCrossfade is a half-second fade between images shown for a second.
+var DefaultCrossfade = CrossfadeOptions{Frames: 10, Delay: 5, Hold: 100}
+
+// Crossfade renders a transition from one image to another. to is scaled to
+// cover from's size and center-cropped, and transparency is flattened onto
+// white. Frames are dithered to the Plan 9 palette, as gif.Encode does by
+// default.
+func Crossfade(from, to image.Image, opts CrossfadeOptions) (*gif.GIF, error) {
+	if opts.Frames < 0 || opts.Delay < 0 || opts.Hold < 0 {
+		return nil, fmt.Errorf("crossfade frames and delays must not be negative")
+	}
+	if opts.Frames > MaxCrossfadeFrames {
+		return nil, fmt.Errorf("crossfade frames must be at most %d", MaxCrossfadeFrames)
+	}
+	a := imaging.ToNRGBA(imaging.Flatten(from, color.White))
+	b := a.Bounds()
+	if b.Empty() {
+		return nil, fmt.Errorf("crossfade of an empty image")
+	}
+	g := imaging.Geometry{Width: b.Dx(), Height: b.Dy(), Flag: imaging.GeometryFill}
+	w, h := g.Size(to.Bounds().Dx(), to.Bounds().Dy())
+	z := imaging.ToNRGBA(imaging.Extent(imaging.Resize(to, w, h), b.Dx(), b.Dy(), imaging.GravityCenter, image.Point{}, color.White))
+
+	anim := &gif.GIF{LoopCount: -1}
+	add := func(img image.Image, delay int) {
+		frame := image.NewPaletted(img.Bounds(), palette.Plan9)
+		draw.FloydSteinberg.Draw(frame, frame.Bounds(), img, img.Bounds().Min)
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, delay)
+	}
+
+	mixes := make([]*image.NRGBA, opts.Frames)
+	for i := range mixes {
+		mixes[i] = mix(a, z, float64(i+1)/float64(opts.Frames+1))
+	}
+	add(a, opts.Hold)
+	for _, m := range mixes {
+		add(m, opts.Delay)
+	}
+	add(z, opts.Hold)
+	if opts.Loop {
+		for i := len(mixes) - 1; i >= 0; i-- {
+			add(mixes[i], opts.Delay)
+		}
+		anim.LoopCount = 0
+	}
+	return anim, nil
+}
+
+// mix blends two opaque images of the same size, t of the way from a to b.
+func mix(a, b *image.NRGBA, t float64) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, a.Bounds().Dx(), a.Bounds().Dy()))
+	for y := 0; y < dst.Rect.Dy(); y++ {
+		ra := a.Pix[a.PixOffset(a.Rect.Min.X, a.Rect.Min.Y+y):][:dst.Rect.Dx()*4]
+		rb := b.Pix[b.PixOffset(b.Rect.Min.X, b.Rect.Min.Y+y):][:dst.Rect.Dx()*4]
+		rd := dst.Pix[y*dst.Stride:][:dst.Rect.Dx()*4]
+		for i := range rd {
+			rd[i] = uint8(float64(ra[i])*(1-t) + float64(rb[i])*t + 0.5)
+		}
+	}
+	return dst
+}
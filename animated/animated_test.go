@@ -0,0 +1,116 @@
+package animated
+
+import (
+	"bytes"
+	"image"
+	"image/gif"
+	"testing"
+	"time"
+)
+
+func TestCountdown(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 30, 0, time.UTC)
+	until := now.Add(26*time.Hour + 90*time.Second)
+
+	anim := Countdown(until, now, DefaultStyle)
+	if len(anim.Image) != countdownFrames || len(anim.Delay) != countdownFrames {
+		t.Fatalf("expected %d frames, got %d", countdownFrames, len(anim.Image))
+	}
+
+	// Requests within the same minute render the same animation
+	var a, b bytes.Buffer
+	if err := gif.EncodeAll(&a, anim); err != nil {
+		t.Fatal(err)
+	}
+	gif.EncodeAll(&b, Countdown(until, now.Add(20*time.Second), DefaultStyle))
+	if !bytes.Equal(a.Bytes(), b.Bytes()) {
+		t.Error("expected identical GIFs within a minute bucket")
+	}
+}
+
+func TestCountdown_StopsAtZero(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	anim := Countdown(now.Add(5*time.Second), now, DefaultStyle)
+	if len(anim.Image) != 6 {
+		t.Errorf("expected 6 frames (5..0), got %d", len(anim.Image))
+	}
+}
+
+func TestFormatRemaining(t *testing.T) {
+	if got := formatRemaining(50*time.Hour + 3*time.Minute + 4*time.Second); got != "02:02:03:04" {
+		t.Errorf("expected 02:02:03:04, got %s", got)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	anim := Progress(50, DefaultStyle)
+	if len(anim.Image) != progressFrames {
+		t.Fatalf("expected %d frames, got %d", progressFrames, len(anim.Image))
+	}
+
+	// The last frame is filled halfway across the bar
+	last := anim.Image[len(anim.Image)-1]
+	mid := DefaultStyle.Height / 2
+	if last.ColorIndexAt(10, mid) != 1 {
+		t.Error("expected the start of the bar to be filled")
+	}
+	if last.ColorIndexAt(DefaultStyle.Width*3/4, mid) != 0 {
+		t.Error("expected the end of the bar to be empty")
+	}
+}
+
+func TestCrossfade(t *testing.T) {
+	black := image.NewGray(image.Rect(0, 0, 40, 20))
+	// A larger image is scaled down to the first one's size
+	to := image.NewRGBA(image.Rect(0, 0, 80, 40))
+	for i := range to.Pix {
+		to.Pix[i] = 255
+	}
+
+	anim, err := Crossfade(black, to, CrossfadeOptions{Frames: 3, Delay: 5, Hold: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anim.Image) != 5 || anim.LoopCount != -1 {
+		t.Fatalf("expected 5 frames played once, got %d (loop %d)", len(anim.Image), anim.LoopCount)
+	}
+	if got := anim.Delay; got[0] != 100 || got[1] != 5 || got[4] != 100 {
+		t.Errorf("unexpected delays %v", got)
+	}
+	luma := func(i int) uint8 {
+		frame := anim.Image[i]
+		if frame.Bounds().Dx() != 40 || frame.Bounds().Dy() != 20 {
+			t.Fatalf("frame %d is %v", i, frame.Bounds())
+		}
+		var sum int
+		for _, idx := range frame.Pix {
+			r, _, _, _ := frame.Palette[idx].RGBA()
+			sum += int(r >> 8)
+		}
+		return uint8(sum / len(frame.Pix))
+	}
+	// Each frame is lighter than the one before
+	for i := 1; i < len(anim.Image); i++ {
+		if luma(i) <= luma(i-1) {
+			t.Errorf("frame %d (%d) is not lighter than frame %d (%d)", i, luma(i), i-1, luma(i-1))
+		}
+	}
+
+	looped, err := Crossfade(black, to, CrossfadeOptions{Frames: 3, Loop: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(looped.Image) != 8 || looped.LoopCount != 0 {
+		t.Errorf("expected 8 frames looping forever, got %d (loop %d)", len(looped.Image), looped.LoopCount)
+	}
+
+	if _, err := Crossfade(black, to, CrossfadeOptions{Frames: -1}); err == nil {
+		t.Error("expected error for negative frames")
+	}
+	if _, err := Crossfade(black, to, CrossfadeOptions{Frames: 1_000_000}); err == nil {
+		t.Error("expected error for more than MaxCrossfadeFrames frames")
+	}
+	if _, err := Crossfade(black, to, CrossfadeOptions{Frames: MaxCrossfadeFrames}); err != nil {
+		t.Errorf("expected MaxCrossfadeFrames frames to be allowed, got %v", err)
+	}
+}
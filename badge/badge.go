@@ -0,0 +1,102 @@
+// Package badge renders shields.io-style status badges ("build | passing")
+// as SVG or as raster images drawn with imaging.DrawText.
+package badge
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"strings"
+
+	"image-resizer/imaging"
+
+	"golang.org/x/image/draw"
+)
+
+// Badge layout in pixels.
+const (
+	height  = 20
+	padding = 6
+	// baseline is the text baseline, centering TextFace's 13px line in the badge
+	baseline = 14
+)
+
+// LabelColor is the background of the label (left) half of a badge.
+var LabelColor = color.NRGBA{0x55, 0x55, 0x55, 0xff}
+
+// namedColors are the shields.io color names accepted by ParseColor.
+var namedColors = map[string]color.NRGBA{
+	"brightgreen":   {0x44, 0xcc, 0x11, 0xff},
+	"green":         {0x97, 0xca, 0x00, 0xff},
+	"yellowgreen":   {0xa4, 0xa6, 0x1d, 0xff},
+	"yellow":        {0xdf, 0xb3, 0x17, 0xff},
+	"orange":        {0xfe, 0x7d, 0x37, 0xff},
+	"red":           {0xe0, 0x5d, 0x44, 0xff},
+	"blue":          {0x00, 0x7e, 0xc6, 0xff},
+	"lightgrey":     {0x9f, 0x9f, 0x9f, 0xff},
+	"lightgray":     {0x9f, 0x9f, 0x9f, 0xff},
+	"inactive":      {0x9f, 0x9f, 0x9f, 0xff},
+	"success":       {0x44, 0xcc, 0x11, 0xff},
+	"important":     {0xfe, 0x7d, 0x37, 0xff},
+	"critical":      {0xe0, 0x5d, 0x44, 0xff},
+	"informational": {0x00, 0x7e, 0xc6, 0xff},
+}
+
+// ParseColor accepts the shields.io color names ("brightgreen", "critical",
+// ...) as well as anything imaging.ParseColor accepts.
+func ParseColor(s string) (color.NRGBA, error) {
+	if c, ok := namedColors[strings.ToLower(strings.TrimSpace(s))]; ok {
+		return c, nil
+	}
+	return imaging.ParseColor(s)
+}
+
+// Badge is a two-part badge: a gray label followed by a colored value.
+type Badge struct {
+	Label string
+	Value string
+	Color color.Color
+}
+
+// widths returns the width of the label and value halves.
+func (b Badge) widths() (label, value int) {
+	lw, _ := imaging.TextSize(b.Label)
+	vw, _ := imaging.TextSize(b.Value)
+	return lw + 2*padding, vw + 2*padding
+}
+
+// Image draws the badge.
+func (b Badge) Image() image.Image {
+	lw, vw := b.widths()
+	img := image.NewNRGBA(image.Rect(0, 0, lw+vw, height))
+	draw.Draw(img, image.Rect(0, 0, lw, height), image.NewUniform(LabelColor), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(lw, 0, lw+vw, height), image.NewUniform(b.Color), image.Point{}, draw.Src)
+	imaging.DrawText(img, image.Pt(padding, baseline), b.Label, color.White)
+	imaging.DrawText(img, image.Pt(lw+padding, baseline), b.Value, color.White)
+	return img
+}
+
+// SVG returns the badge as an SVG document with the same layout as Image.
+func (b Badge) SVG() []byte {
+	lw, vw := b.widths()
+	label, value := html.EscapeString(b.Label), html.EscapeString(b.Value)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" role="img" aria-label="%s: %s">`, lw+vw, height, label, value)
+	fmt.Fprintf(&buf, `<title>%s: %s</title>`, label, value)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="%s"/>`, lw, height, hex(LabelColor))
+	fmt.Fprintf(&buf, `<rect x="%d" width="%d" height="%d" fill="%s"/>`, lw, vw, height, hex(b.Color))
+	fmt.Fprintf(&buf, `<g fill="#fff" font-family="monospace" font-size="11">`)
+	fmt.Fprintf(&buf, `<text x="%d" y="%d" textLength="%d">%s</text>`, padding, baseline, lw-2*padding, label)
+	fmt.Fprintf(&buf, `<text x="%d" y="%d" textLength="%d">%s</text>`, lw+padding, baseline, vw-2*padding, value)
+	buf.WriteString(`</g></svg>`)
+	return buf.Bytes()
+}
+
+// hex formats an opaque color as #rrggbb.
+func hex(c color.Color) string {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return fmt.Sprintf("#%02x%02x%02x", n.R, n.G, n.B)
+}
@@ -0,0 +1,46 @@
+package badge
+
+import (
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestParseColor(t *testing.T) {
+	c, err := ParseColor("brightgreen")
+	if err != nil || c != (color.NRGBA{0x44, 0xcc, 0x11, 0xff}) {
+		t.Errorf("expected shields brightgreen, got %v (err=%v)", c, err)
+	}
+	c, err = ParseColor("#123456")
+	if err != nil || c != (color.NRGBA{0x12, 0x34, 0x56, 0xff}) {
+		t.Errorf("expected hex fallback, got %v (err=%v)", c, err)
+	}
+	if _, err := ParseColor("notacolor"); err == nil {
+		t.Error("expected error for unknown color")
+	}
+}
+
+func TestBadge_Image(t *testing.T) {
+	b := Badge{Label: "build", Value: "ok", Color: color.NRGBA{0, 0, 255, 255}}
+	img := b.Image()
+
+	// "build" is 5*7+12 = 47px wide, "ok" 2*7+12 = 26px
+	if bounds := img.Bounds(); bounds.Dx() != 73 || bounds.Dy() != 20 {
+		t.Fatalf("expected 73x20, got %dx%d", bounds.Dx(), bounds.Dy())
+	}
+	if c := color.NRGBAModel.Convert(img.At(1, 1)); c != LabelColor {
+		t.Errorf("expected label background %v, got %v", LabelColor, c)
+	}
+	if c := color.NRGBAModel.Convert(img.At(72, 1)); c != (color.NRGBA{0, 0, 255, 255}) {
+		t.Errorf("expected value background, got %v", c)
+	}
+}
+
+func TestBadge_SVG(t *testing.T) {
+	svg := string(Badge{Label: "a<b", Value: "ok", Color: color.White}.SVG())
+	for _, want := range []string{`width="59"`, `a&lt;b`, `fill="#ffffff"`, `fill="#555555"`} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("expected SVG to contain %s:\n%s", want, svg)
+		}
+	}
+}
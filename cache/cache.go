@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// Store holds encoded results by key, such as processed responses and
+// temporary results awaiting download. Implementations decide where the
+// bytes live (process memory or local disk), so callers can pick one
+// without other code changes.
+type Store interface {
+	// Get returns the value stored under key. Expired values are misses.
+	Get(key string) ([]byte, bool)
+	// Set stores value under key, replacing any existing value. A ttl of
+	// zero or less keeps it until it is evicted or deleted.
+	Set(key string, value []byte, ttl time.Duration) error
+	// Delete removes the value stored under key, if any.
+	Delete(key string) error
+	// Stats returns a snapshot of the store counters, including the
+	// number and total size of stored values where the store tracks them.
+	Stats() Stats
+}
+
+var (
+	_ Store = (*Memory)(nil)
+	_ Store = (*Disk)(nil)
+)
+
+// Memory is a Store backed by an in-process LRU.
+type Memory struct {
+	lru *LRU[[]byte]
+}
+
+// NewMemory creates an in-memory store holding at most maxEntries values
+// totalling at most maxBytes. A limit of zero or less disables that bound.
+func NewMemory(maxEntries int, maxBytes int64) *Memory {
+	return &Memory{lru: NewLRU(maxEntries, maxBytes, func(b []byte) int64 { return int64(len(b)) })}
+}
+
+// Get returns the value stored under key.
+func (m *Memory) Get(key string) ([]byte, bool) { return m.lru.Get(key) }
+
+// Set stores value under key.
+func (m *Memory) Set(key string, value []byte, ttl time.Duration) error {
+	m.lru.AddTTL(key, value, ttl)
+	return nil
+}
+
+// Delete removes the value stored under key, if any.
+func (m *Memory) Delete(key string) error {
+	m.lru.Remove(key)
+	return nil
+}
+
+// Stats returns a snapshot of the store counters.
+func (m *Memory) Stats() Stats { return m.lru.Stats() }
+
+// entryMagic starts every value written by WithExpiry, so data in another
+// format is never mistaken for a live entry.
+const entryMagic = "meh1"
+
+// EntryOverhead is the number of bytes WithExpiry adds to a value.
+const EntryOverhead = len(entryMagic) + 8
+
+// WithExpiry prefixes value with its expiry time, for stores whose backend
+// has no TTL of its own. A ttl of zero or less never expires.
+func WithExpiry(value []byte, ttl time.Duration, now time.Time) []byte {
+	var expires int64
+	if ttl > 0 {
+		expires = now.Add(ttl).UnixNano()
+	}
+	out := make([]byte, 0, EntryOverhead+len(value))
+	out = append(out, entryMagic...)
+	out = binary.BigEndian.AppendUint64(out, uint64(expires))
+	return append(out, value...)
+}
+
+// StripExpiry returns the value stored by WithExpiry, or false if data is
+// not such an entry or has expired by now.
+func StripExpiry(data []byte, now time.Time) ([]byte, bool) {
+	if len(data) < EntryOverhead || string(data[:len(entryMagic)]) != entryMagic {
+		return nil, false
+	}
+	expires := int64(binary.BigEndian.Uint64(data[len(entryMagic):]))
+	if expires != 0 && now.UnixNano() >= expires {
+		return nil, false
+	}
+	return data[EntryOverhead:], true
+}
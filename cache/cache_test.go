@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemory(t *testing.T) {
+	var c Store = NewMemory(1, 0)
+	c.Set("a", []byte("1"), 0)
+	c.Set("b", []byte("2"), 0)
+
+	if _, ok := c.Get("a"); ok {
+		t.Error("expected a to be evicted")
+	}
+	if got, ok := c.Get("b"); !ok || string(got) != "2" {
+		t.Errorf("expected b, got %q (ok=%v)", got, ok)
+	}
+}
+
+func TestMemory_TTL(t *testing.T) {
+	c := NewMemory(0, 0)
+	c.Set("a", []byte("1"), time.Nanosecond)
+	c.Set("b", []byte("22"), time.Hour)
+	time.Sleep(time.Millisecond)
+
+	if _, ok := c.Get("a"); ok {
+		t.Error("expected a to have expired")
+	}
+	if _, ok := c.Get("b"); !ok {
+		t.Error("expected b to remain")
+	}
+	if s := c.Stats(); s.Entries != 1 || s.Size != 2 || s.Misses != 1 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	data := WithExpiry([]byte("v"), time.Minute, now)
+	if len(data) != 1+EntryOverhead {
+		t.Errorf("expected %d bytes, got %d", 1+EntryOverhead, len(data))
+	}
+	if v, ok := StripExpiry(data, now.Add(59*time.Second)); !ok || string(v) != "v" {
+		t.Errorf("expected a live entry, got %q (ok=%v)", v, ok)
+	}
+	if _, ok := StripExpiry(data, now.Add(time.Minute)); ok {
+		t.Error("expected the entry to have expired")
+	}
+	if _, ok := StripExpiry(WithExpiry(nil, 0, now), now.Add(1000*time.Hour)); !ok {
+		t.Error("expected an entry without a TTL to never expire")
+	}
+	if _, ok := StripExpiry([]byte("raw value from elsewhere"), now); ok {
+		t.Error("expected data without the entry header to be rejected")
+	}
+}
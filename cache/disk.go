@@ -0,0 +1,204 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tempPrefix marks in-progress writes so they are never served or counted.
+const tempPrefix = ".tmp-"
+
+// Disk is a persistent Store keeping each value as a file in a directory,
+// prefixed with its expiry (see WithExpiry). Writes are atomic (temp file +
+// rename) and the least recently used files are evicted once the values
+// exceed the size bound. It is safe for concurrent use within a process.
+type Disk struct {
+	mu      sync.Mutex
+	dir     string
+	maxSize int64
+	size    int64
+	hits    int64
+	misses  int64
+}
+
+// NewDisk opens (creating if needed) a disk cache rooted at dir holding at
+// most maxSize bytes. A maxSize of zero or less disables eviction.
+// Leftover temp files from interrupted writes are removed.
+func NewDisk(dir string, maxSize int64) (*Disk, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	d := &Disk{dir: dir, maxSize: maxSize}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		if strings.HasPrefix(e.Name(), tempPrefix) {
+			os.Remove(filepath.Join(dir, e.Name()))
+			continue
+		}
+		if info, err := e.Info(); err == nil {
+			d.size += valueSize(info)
+		}
+	}
+	return d, nil
+}
+
+// valueSize is the size of the value stored in a cache file.
+func valueSize(info fs.FileInfo) int64 {
+	return max(info.Size()-int64(EntryOverhead), 0)
+}
+
+// path maps a key onto a file name that is safe regardless of key contents.
+func (d *Disk) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(d.dir, hex.EncodeToString(sum[:]))
+}
+
+// Get returns the value stored under key and marks it as recently used.
+func (d *Disk) Get(key string) ([]byte, bool) {
+	p := d.path(key)
+	data, err := os.ReadFile(p)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err != nil {
+		d.misses++
+		return nil, false
+	}
+	now := time.Now()
+	value, ok := StripExpiry(data, now)
+	if !ok {
+		// Expired, or written in an older format
+		d.misses++
+		if os.Remove(p) == nil {
+			d.size -= max(int64(len(data)-EntryOverhead), 0)
+		}
+		return nil, false
+	}
+	d.hits++
+	os.Chtimes(p, now, now)
+	return value, true
+}
+
+// Set stores value under key, replacing any existing value, then evicts
+// least recently used files until the cache fits its size bound.
+// Values larger than the size bound are not stored.
+func (d *Disk) Set(key string, value []byte, ttl time.Duration) error {
+	if d.maxSize > 0 && int64(len(value)) > d.maxSize {
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(d.dir, tempPrefix+"*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(WithExpiry(value, ttl, time.Now())); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	p := d.path(key)
+	if info, err := os.Stat(p); err == nil {
+		d.size -= valueSize(info)
+	}
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	d.size += int64(len(value))
+
+	return d.evict()
+}
+
+// Delete removes the value stored under key, if any.
+func (d *Disk) Delete(key string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	p := d.path(key)
+	info, err := os.Stat(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil {
+		return err
+	}
+	d.size -= valueSize(info)
+	return nil
+}
+
+// Stats returns a snapshot of the cache counters.
+func (d *Disk) Stats() Stats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entries, _ := os.ReadDir(d.dir)
+	n := 0
+	for _, e := range entries {
+		if !e.IsDir() && !strings.HasPrefix(e.Name(), tempPrefix) {
+			n++
+		}
+	}
+	return Stats{Hits: d.hits, Misses: d.misses, Entries: n, Size: d.size}
+}
+
+// evict removes the least recently used files until the cache fits.
+// d.mu must be held.
+func (d *Disk) evict() error {
+	if d.maxSize <= 0 || d.size <= d.maxSize {
+		return nil
+	}
+
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return err
+	}
+	files := make([]fs.FileInfo, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), tempPrefix) {
+			continue
+		}
+		if info, err := e.Info(); err == nil {
+			files = append(files, info)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().Before(files[j].ModTime())
+	})
+
+	for _, f := range files {
+		if d.size <= d.maxSize {
+			break
+		}
+		if err := os.Remove(filepath.Join(d.dir, f.Name())); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		d.size -= valueSize(f)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDisk_GetSet(t *testing.T) {
+	d, err := NewDisk(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := d.Get("a"); ok {
+		t.Fatal("expected miss on empty cache")
+	}
+	if err := d.Set("a", []byte("one"), 0); err != nil {
+		t.Fatal(err)
+	}
+	got, ok := d.Get("a")
+	if !ok || string(got) != "one" {
+		t.Fatalf("expected hit with %q, got %q (ok=%v)", "one", got, ok)
+	}
+
+	s := d.Stats()
+	if s.Hits != 1 || s.Misses != 1 || s.Entries != 1 || s.Size != 3 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
+
+func TestDisk_Persists(t *testing.T) {
+	dir := t.TempDir()
+	d, err := NewDisk(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Set("a", []byte("persisted"), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate an interrupted write left behind by a previous process
+	if err := os.WriteFile(filepath.Join(dir, tempPrefix+"junk"), []byte("partial"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := NewDisk(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := reopened.Get("a"); !ok || string(got) != "persisted" {
+		t.Errorf("expected value to survive reopen, got %q (ok=%v)", got, ok)
+	}
+	if s := reopened.Stats(); s.Entries != 1 || s.Size != 9 {
+		t.Errorf("expected 1 entry of size 9 after cleanup, got %+v", s)
+	}
+}
+
+func TestDisk_EvictsLeastRecentlyUsed(t *testing.T) {
+	d, err := NewDisk(t.TempDir(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old := time.Now().Add(-time.Hour)
+	d.Set("a", make([]byte, 4), 0)
+	os.Chtimes(d.path("a"), old, old)
+	d.Set("b", make([]byte, 4), 0)
+	os.Chtimes(d.path("b"), old.Add(time.Minute), old.Add(time.Minute))
+	d.Get("a") // a is now most recently used
+	d.Set("c", make([]byte, 4), 0)
+
+	if _, ok := d.Get("b"); ok {
+		t.Error("expected b to be evicted")
+	}
+	if _, ok := d.Get("a"); !ok {
+		t.Error("expected a to remain")
+	}
+	if s := d.Stats(); s.Size != 8 {
+		t.Errorf("expected size 8, got %d", s.Size)
+	}
+}
+
+func TestDisk_Delete(t *testing.T) {
+	d, err := NewDisk(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Set("a", []byte("12345"), 0)
+	d.Set("a", []byte("12"), 0)
+	if s := d.Stats(); s.Size != 2 {
+		t.Errorf("expected replaced value size 2, got %d", s.Size)
+	}
+
+	if err := d.Delete("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Delete("missing"); err != nil {
+		t.Errorf("expected deleting a missing key to succeed, got %v", err)
+	}
+	if s := d.Stats(); s.Entries != 0 || s.Size != 0 {
+		t.Errorf("expected empty cache, got %+v", s)
+	}
+}
+
+func TestDisk_TTL(t *testing.T) {
+	d, err := NewDisk(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Set("a", []byte("123"), time.Nanosecond)
+	d.Set("b", []byte("4"), time.Hour)
+	time.Sleep(time.Millisecond)
+
+	if _, ok := d.Get("a"); ok {
+		t.Error("expected a to have expired")
+	}
+	if _, ok := d.Get("b"); !ok {
+		t.Error("expected b to remain")
+	}
+	if s := d.Stats(); s.Entries != 1 || s.Size != 1 {
+		t.Errorf("expected the expired file to be removed, got %+v", s)
+	}
+}
@@ -0,0 +1,154 @@
+// Package cache provides caches for processed image results so repeated
+// transformations of the same source can skip decode, resize, and encode.
+package cache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// Key returns a cache key for the given source bytes and a canonical
+// string describing the transformation options.
+func Key(src []byte, opts string) string {
+	h := sha256.New()
+	h.Write(src)
+	h.Write([]byte{0})
+	h.Write([]byte(opts))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Stats reports cache usage counters.
+type Stats struct {
+	Hits    int64
+	Misses  int64
+	Entries int
+	Size    int64
+}
+
+// LRU is a least-recently-used cache bounded by entry count and total size.
+// It is safe for concurrent use.
+type LRU[V any] struct {
+	mu         sync.Mutex
+	maxEntries int
+	maxSize    int64
+	sizeOf     func(V) int64
+	ll         *list.List
+	items      map[string]*list.Element
+	size       int64
+	hits       int64
+	misses     int64
+}
+
+type lruEntry[V any] struct {
+	key     string
+	value   V
+	size    int64
+	expires time.Time // Zero for entries without a TTL
+}
+
+// NewLRU creates an LRU cache holding at most maxEntries values whose
+// combined size, as reported by sizeOf, does not exceed maxSize.
+// A limit of zero or less disables that bound.
+func NewLRU[V any](maxEntries int, maxSize int64, sizeOf func(V) int64) *LRU[V] {
+	return &LRU[V]{
+		maxEntries: maxEntries,
+		maxSize:    maxSize,
+		sizeOf:     sizeOf,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+	}
+}
+
+// Get returns the value stored under key and marks it as recently used.
+func (c *LRU[V]) Get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*lruEntry[V])
+		if e.expires.IsZero() || time.Now().Before(e.expires) {
+			c.ll.MoveToFront(el)
+			c.hits++
+			return e.value, true
+		}
+		c.removeElement(el)
+	}
+	c.misses++
+	var zero V
+	return zero, false
+}
+
+// Add stores value under key, evicting least recently used entries as
+// needed. Values larger than the size bound are not stored.
+func (c *LRU[V]) Add(key string, value V) {
+	c.AddTTL(key, value, 0)
+}
+
+// AddTTL is Add for a value that expires after ttl. A ttl of zero or less
+// never expires.
+func (c *LRU[V]) AddTTL(key string, value V, ttl time.Duration) {
+	var expires time.Time
+	if ttl > 0 {
+		expires = time.Now().Add(ttl)
+	}
+	var size int64
+	if c.sizeOf != nil {
+		size = c.sizeOf(value)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.maxSize > 0 && size > c.maxSize {
+		return
+	}
+
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*lruEntry[V])
+		c.size += size - e.size
+		e.value = value
+		e.size = size
+		e.expires = expires
+		c.ll.MoveToFront(el)
+	} else {
+		c.items[key] = c.ll.PushFront(&lruEntry[V]{key: key, value: value, size: size, expires: expires})
+		c.size += size
+	}
+
+	for c.ll.Len() > 0 && (c.maxEntries > 0 && c.ll.Len() > c.maxEntries || c.maxSize > 0 && c.size > c.maxSize) {
+		c.removeElement(c.ll.Back())
+	}
+}
+
+// Remove deletes the value stored under key, if any.
+func (c *LRU[V]) Remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.removeElement(el)
+	}
+}
+
+// Stats returns a snapshot of the cache counters.
+func (c *LRU[V]) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return Stats{
+		Hits:    c.hits,
+		Misses:  c.misses,
+		Entries: c.ll.Len(),
+		Size:    c.size,
+	}
+}
+
+func (c *LRU[V]) removeElement(el *list.Element) {
+	e := el.Value.(*lruEntry[V])
+	c.ll.Remove(el)
+	delete(c.items, e.key)
+	c.size -= e.size
+}
@@ -0,0 +1,91 @@
+package cache
+
+import "testing"
+
+func byteLen(b []byte) int64 { return int64(len(b)) }
+
+func TestKey(t *testing.T) {
+	a := Key([]byte("image"), "w=100")
+	if a != Key([]byte("image"), "w=100") {
+		t.Error("expected identical inputs to produce identical keys")
+	}
+	if a == Key([]byte("image"), "w=200") {
+		t.Error("expected different options to produce different keys")
+	}
+	if a == Key([]byte("other"), "w=100") {
+		t.Error("expected different sources to produce different keys")
+	}
+}
+
+func TestLRU_GetAdd(t *testing.T) {
+	c := NewLRU[[]byte](0, 0, byteLen)
+
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("expected miss on empty cache")
+	}
+	c.Add("a", []byte("one"))
+	got, ok := c.Get("a")
+	if !ok || string(got) != "one" {
+		t.Fatalf("expected hit with %q, got %q (ok=%v)", "one", got, ok)
+	}
+
+	s := c.Stats()
+	if s.Hits != 1 || s.Misses != 1 {
+		t.Errorf("expected 1 hit and 1 miss, got %d hits and %d misses", s.Hits, s.Misses)
+	}
+	if s.Entries != 1 || s.Size != 3 {
+		t.Errorf("expected 1 entry of size 3, got %d entries of size %d", s.Entries, s.Size)
+	}
+}
+
+func TestLRU_EvictsByEntries(t *testing.T) {
+	c := NewLRU[[]byte](2, 0, byteLen)
+	c.Add("a", []byte("1"))
+	c.Add("b", []byte("2"))
+	c.Get("a") // a is now most recently used
+	c.Add("c", []byte("3"))
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("expected b to be evicted")
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Error("expected a to remain")
+	}
+	if _, ok := c.Get("c"); !ok {
+		t.Error("expected c to remain")
+	}
+}
+
+func TestLRU_EvictsBySize(t *testing.T) {
+	c := NewLRU[[]byte](0, 10, byteLen)
+	c.Add("a", make([]byte, 4))
+	c.Add("b", make([]byte, 4))
+	c.Add("c", make([]byte, 4))
+
+	if _, ok := c.Get("a"); ok {
+		t.Error("expected a to be evicted")
+	}
+	if s := c.Stats(); s.Size != 8 {
+		t.Errorf("expected size 8, got %d", s.Size)
+	}
+
+	// Values larger than the bound are never stored
+	c.Add("huge", make([]byte, 11))
+	if _, ok := c.Get("huge"); ok {
+		t.Error("expected oversized value to be rejected")
+	}
+}
+
+func TestLRU_Replace(t *testing.T) {
+	c := NewLRU[[]byte](0, 0, byteLen)
+	c.Add("a", []byte("12345"))
+	c.Add("a", []byte("12"))
+
+	if s := c.Stats(); s.Entries != 1 || s.Size != 2 {
+		t.Errorf("expected 1 entry of size 2, got %d entries of size %d", s.Entries, s.Size)
+	}
+	c.Remove("a")
+	if s := c.Stats(); s.Entries != 0 || s.Size != 0 {
+		t.Errorf("expected empty cache, got %d entries of size %d", s.Entries, s.Size)
+	}
+}
@@ -4,66 +4,579 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
 	"image"
-	"image/jpeg"
-	"image/png"
+	"image/color"
+	"image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"slices"
 	"syscall/js"
+	"time"
 
+	"image-resizer/animated"
+	"image-resizer/cache"
+	"image-resizer/errcode"
 	"image-resizer/imaging"
+	"image-resizer/marketplace"
+	"image-resizer/presets"
 
-	"golang.org/x/image/draw"
 	_ "golang.org/x/image/webp"
 )
 
+// result is an encoded output image along with its metadata.
+type result struct {
+	data         []byte
+	mimeType     string
+	width        int
+	height       int
+	quality      int
+	formatReason string
+	warning      string
+}
+
+// resultHeader is a result's metadata as stored ahead of its data in the
+// results cache.
+type resultHeader struct {
+	MimeType     string `json:"mimeType"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	Quality      int    `json:"quality"`
+	FormatReason string `json:"formatReason,omitempty"`
+	Warning      string `json:"warning,omitempty"`
+}
+
+// marshal encodes r for a cache.Store: the length of its JSON header, the
+// header, then the image data.
+func (r result) marshal() []byte {
+	header, _ := json.Marshal(resultHeader{r.mimeType, r.width, r.height, r.quality, r.formatReason, r.warning})
+	out := binary.BigEndian.AppendUint32(make([]byte, 0, 4+len(header)+len(r.data)), uint32(len(header)))
+	out = append(out, header...)
+	return append(out, r.data...)
+}
+
+// unmarshalResult decodes a result written by marshal. The data shares
+// b's memory.
+func unmarshalResult(b []byte) (result, bool) {
+	if len(b) < 4 {
+		return result{}, false
+	}
+	n := int(binary.BigEndian.Uint32(b))
+	if len(b)-4 < n {
+		return result{}, false
+	}
+	var h resultHeader
+	if err := json.Unmarshal(b[4:4+n], &h); err != nil {
+		return result{}, false
+	}
+	return result{b[4+n:], h.MimeType, h.Width, h.Height, h.Quality, h.FormatReason, h.Warning}, true
+}
+
+// Default bounds for the result and decoded-image caches; adjustable from
+// JavaScript via configureCache.
+const (
+	defaultCacheEntries = 32
+	defaultCacheBytes   = 64 << 20
+	defaultDecodedMP    = 50
+)
+
+var (
+	results = newResultCache(defaultCacheEntries, defaultCacheBytes)
+	decoded = newDecodedCache(defaultDecodedMP)
+	named   = presets.NewStore(nil)
+
+	// maxPixels rejects images whose header declares more pixels, before decoding
+	maxPixels = imaging.DefaultMaxPixels
+)
+
+// inputFormats are the formats whose decoders are registered by the imports above.
+var inputFormats = []string{"jpeg", "png", "gif", "webp"}
+
+// newResultCache holds encoded results in memory. Any cache.Store will do,
+// as results are stored marshaled.
+func newResultCache(maxEntries int, maxBytes int64) cache.Store {
+	return cache.NewMemory(maxEntries, maxBytes)
+}
+
+// newDecodedCache caches decoded source images, bounded by total megapixels,
+// so re-processing the same upload with different options skips decoding.
+// It is a typed LRU rather than a cache.Store, which holds bytes: decoded
+// images are kept in their own color model (paletted, YCbCr, ...) and
+// shared without copying.
+func newDecodedCache(maxMegapixels int) *cache.LRU[image.Image] {
+	return cache.NewLRU(0, int64(maxMegapixels)*1_000_000, func(img image.Image) int64 {
+		b := img.Bounds()
+		return int64(b.Dx()) * int64(b.Dy())
+	})
+}
+
+// decodeImage decodes data, reusing a previously decoded image of the same
+// bytes. Cached images are shared, so callers must not modify them in place.
+func decodeImage(data []byte) (image.Image, error) {
+	key := cache.Key(data, "")
+	if img, ok := decoded.Get(key); ok {
+		// The limit may have been lowered since the image was decoded
+		if b := img.Bounds(); int64(b.Dx())*int64(b.Dy()) > int64(maxPixels) {
+			return nil, &imaging.TooLargeError{Width: b.Dx(), Height: b.Dy(), MaxPixels: maxPixels}
+		}
+		return img, nil
+	}
+	img, _, err := imaging.Decode(data, maxPixels)
+	if err != nil {
+		return nil, err
+	}
+	decoded.Add(key, img)
+	return img, nil
+}
+
+// exports are the functions registered for JavaScript, by name.
+var exports = map[string]func(js.Value, []js.Value) interface{}{
+	"processImage":     processImage,
+	"processImageData": processImageData,
+	"processImages":    processImages,
+	"inspectImage":     inspectImage,
+	"trimRect":         trimRect,
+	"configureCache":   configureCache,
+	"cacheStats":       cacheStats,
+	"setPresets":       setPresets,
+	"validateImage":    validateImage,
+	"crossfadeImages":  crossfadeImages,
+	"compositeImages":  compositeImages,
+	"configureLimits":  configureLimits,
+	"configurePolicy":  configurePolicy,
+	"capabilities":     capabilities,
+}
+
 func main() {
-	// Register functions for JavaScript to call
-	js.Global().Set("processImage", js.FuncOf(processImage))
+	// Register functions for JavaScript to call. The global scope is the
+	// window on a page and the worker's self in a Web Worker (web/meh-worker.js),
+	// which reads mehExports to know what it can forward.
+	names := make([]interface{}, 0, len(exports))
+	for name, fn := range exports {
+		js.Global().Set(name, js.FuncOf(fn))
+		names = append(names, name)
+	}
+	js.Global().Set("mehExports", names)
 
 	// Keep the program running
 	select {}
 }
 
 // processImage is called from JavaScript with image data and options
-// Args: imageData (Uint8Array), width (int), height (int), trim (bool), format (string), quality (int), transparentBg (bool)
+// Args: imageData (Uint8Array, or raw pixels as {data, width, height, stride?, format?} such as
+// canvas ImageData; format is "rgba" (default) or "gray"), options (object)
+// Options: width (int), height (int), trim (bool, or fuzz percent), format (string), quality (int),
+// transparentBg (bool, or a replacement color), geometry (string), autoOrient (bool),
+// maxBytes (int), downscale (bool), ops (string), preset (string), matte (string), timings (bool),
+// progress (function), cropX, cropY, cropW, cropH (int), feather (number),
+// keyColor (string), trimEdges (string), trimMargin (int), pad (int or string), padColor (string),
+// radius (number), shape (string), mask (Uint8Array), grayscale, sepia, invert (bool),
+// brightness, contrast, gamma, hue, saturation, vibrance, blur (number), redact, redactMode (string),
+// autoContrast (bool, or clip percent), equalize (bool), wb (string), temp, tint (number),
+// levels, curve (string)
+// A crop region, given in pixels of the upright image, is cut out before trimming.
+// redact lists regions of the upright image as "x,y,w,h;x,y,w,h" to pixelate, or blur with
+// redactMode "blur", before cropping, as for faces and license plates.
+// feather fades the edge left by transparentBg to transparent over that many pixels.
+// trimEdges limits trim to some edges ("top,bottom"), and trimMargin keeps that many pixels of border.
+// pad adds margins of padColor (default transparent) after trimming and background removal.
+// radius rounds the corners and shape ("circle" or "ellipse") cuts the output to that outline after
+// the final resize; a circle is cut from the centered square, so the output is square.
+// wb ("auto", "grayworld", or "whitepatch") removes a color cast and temp and tint (-100 to 100) warm
+// or cool the result after the final resize, then autoContrast stretches levels and equalize applies
+// adaptive histogram equalization, levels ("black,white[,gamma]") sets black and white points and
+// curve ("in:out;in:out;...") maps every channel through a tone curve,
+// then brightness and contrast (-100 to 100) and gamma (1 for none) correct exposure,
+// then hue (degrees), saturation, and vibrance (-100 to 100) adjust colors,
+// and blur applies a Gaussian blur of that sigma in output pixels (e.g. for placeholders),
+// before grayscale, sepia, and invert apply those tonal filters in that order.
+// mask is a second image whose brightness, scaled to the output size, becomes its alpha (imaging.ApplyMask).
+// keyColor removes that color everywhere in the image, as for green screens (imaging.ChromaKey).
+// progress is called synchronously with (stage, percent) as each stage starts and with ("done", 100)
+// at the end; the page only repaints in between when processImage runs in a Web Worker.
+// Options are validated by parseOptions; unknown names and wrongly typed values are errors.
+// The options from width through timings may instead be passed positionally in the order above,
+// as older callers do; the rest are only accepted in the options object.
+// A non-empty ImageMagick-style geometry (e.g. "300x200^", "50%") takes precedence over width and height.
+// autoOrient defaults to imaging.DefaultAutoOrient, matching the CLI. Passing "document" instead of a bool
+// also detects the text orientation of scans that have no EXIF orientation (imaging.DocumentOrientation).
+// ops is an imaging.Pipeline expression (e.g. "trim:fuzz=5|rotate:90|grayscale") run after trim and
+// background removal, before the final resize.
+// A preset (see setPresets) runs its operations first and supplies format, quality, and maxBytes where the caller leaves them unset.
+// format "smart" picks the output format from the image content and explains why in formatReason.
+// Transparent images encoded as JPEG are flattened onto matte (default white) and a warning is returned.
+// With timings set, the result lists the milliseconds spent in each stage, including each pipeline op.
+// A positive maxBytes searches for the highest quality that fits the budget (shrinking the image
+// if downscale is set) and reports the chosen quality in the result.
 // Returns: processed image as Uint8Array
 func processImage(this js.Value, args []js.Value) interface{} {
-	if len(args) < 6 {
-		return map[string]interface{}{"error": "missing arguments"}
+	if len(args) < 2 {
+		return errorResult(errcode.New(errcode.InvalidArgument, "missing arguments"))
 	}
 
-	// Get image data from JavaScript Uint8Array
-	jsData := args[0]
-	length := jsData.Get("length").Int()
-	imageData := make([]byte, length)
-	js.CopyBytesToGo(imageData, jsData)
+	src, err := readSource(args[0])
+	if err != nil {
+		return errorResult(errcode.Wrap(errcode.InvalidArgument, "", err))
+	}
 
-	width := args[1].Int()
-	height := args[2].Int()
-	trim := args[3].Bool()
-	format := args[4].String()
-	quality := args[5].Int()
-	if quality <= 0 || quality > 100 {
-		quality = 90
+	// Options come as one object, or as the legacy positional arguments
+	var values map[string]js.Value
+	if args[1].Type() == js.TypeObject {
+		values, err = optionsFromObject(args[1])
+	} else if len(args) < 6 {
+		return errorResult(errcode.New(errcode.InvalidArgument, "missing arguments"))
+	} else {
+		values = optionsFromArgs(args[1:])
 	}
-	transparentBg := false
-	if len(args) >= 7 {
-		transparentBg = args[6].Bool()
+	if err != nil {
+		return errorResult(errcode.Wrap(errcode.InvalidArgument, "", err))
 	}
+	opts, err := parseOptions(values)
+	if err != nil {
+		return errorResult(errcode.Wrap(errcode.InvalidArgument, "", err))
+	}
+	return process(src, opts)
+}
 
-	// Decode the image
-	img, _, err := image.Decode(bytes.NewReader(imageData))
+// processImageData is processImage for pixels already decoded by the
+// browser, such as a canvas ImageData or an ImageBitmap drawn to a canvas,
+// which skips decoding entirely.
+// Args: imageData ({data, width, height, stride?, format?}), width (int), height (int),
+// options (object, optional; as for processImage, with width and height given separately)
+func processImageData(this js.Value, args []js.Value) interface{} {
+	if len(args) < 3 {
+		return errorResult(errcode.New(errcode.InvalidArgument, "missing arguments"))
+	}
+	if args[0].Get("width").Type() != js.TypeNumber {
+		return errorResult(errcode.New(errcode.InvalidArgument, "imageData must be {data, width, height}"))
+	}
+	src, err := readSource(args[0])
+	if err != nil {
+		return errorResult(errcode.Wrap(errcode.InvalidArgument, "", err))
+	}
+	values := map[string]js.Value{}
+	if len(args) >= 4 && args[3].Type() == js.TypeObject {
+		if values, err = optionsFromObject(args[3]); err != nil {
+			return errorResult(errcode.Wrap(errcode.InvalidArgument, "", err))
+		}
+	}
+	values["width"], values["height"] = args[1], args[2]
+	opts, err := parseOptions(values)
+	if err != nil {
+		return errorResult(errcode.Wrap(errcode.InvalidArgument, "", err))
+	}
+	return process(src, opts)
+}
+
+// processImages processes several images with the same options, such as a
+// gallery upload, yielding to the browser between images so the page stays
+// responsive.
+// Args: inputs (array of Uint8Array or raw pixel objects, as processImage's imageData),
+// options (object, as for processImage)
+// Returns: a Promise of an array with one processImage result (or {error}) per input, in order,
+// or of a single {error} when the arguments or options are invalid
+func processImages(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 || args[0].Type() != js.TypeObject || args[1].Type() != js.TypeObject {
+		return resolved(errorResult(errcode.New(errcode.InvalidArgument, "expected an array of images and an options object")))
+	}
+	values, err := optionsFromObject(args[1])
+	if err != nil {
+		return resolved(errorResult(errcode.Wrap(errcode.InvalidArgument, "", err)))
+	}
+	opts, err := parseOptions(values)
+	if err != nil {
+		return resolved(errorResult(errcode.Wrap(errcode.InvalidArgument, "", err)))
+	}
+
+	inputs := args[0]
+	var handler js.Func
+	handler = js.FuncOf(func(this js.Value, promise []js.Value) interface{} {
+		handler.Release()
+		resolve := promise[0]
+		go func() {
+			out := make([]interface{}, inputs.Length())
+			for i := range out {
+				if i > 0 {
+					yield()
+				}
+				src, err := readSource(inputs.Index(i))
+				if err != nil {
+					out[i] = errorResult(errcode.Wrap(errcode.InvalidArgument, "", err))
+					continue
+				}
+				out[i] = process(src, opts)
+			}
+			resolve.Invoke(out)
+		}()
+		return nil
+	})
+	return js.Global().Get("Promise").New(handler)
+}
+
+// resolved returns a Promise already resolved with v, for asynchronous
+// functions that fail before starting any work.
+func resolved(v interface{}) js.Value {
+	return js.Global().Get("Promise").Call("resolve", v)
+}
+
+// yield lets the browser's event loop run (rendering, input, timers) before
+// returning. It must be called from a goroutine, not a JavaScript callback.
+func yield() {
+	done := make(chan struct{})
+	var resume js.Func
+	resume = js.FuncOf(func(js.Value, []js.Value) interface{} {
+		resume.Release()
+		close(done)
+		return nil
+	})
+	js.Global().Call("setTimeout", resume, 0)
+	<-done
+}
+
+// inspectImage describes an image from its header, without decoding the
+// pixels, so the page can show details and check limits before processing.
+// Args: imageData (Uint8Array)
+// Returns: {width, height, format, orientation, colorModel}, where width and
+// height are as displayed once the EXIF orientation is applied, as in `meh info`
+func inspectImage(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return errorResult(errcode.New(errcode.InvalidArgument, "missing arguments"))
+	}
+	data := make([]byte, args[0].Get("length").Int())
+	js.CopyBytesToGo(data, args[0])
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return errorResult(errcode.Wrap(errcode.DecodeFailed, "failed to read image header", err))
+	}
+	orientation := imaging.Orientation(data)
+	w, h := cfg.Width, cfg.Height
+	// Orientations 5-8 swap the axes
+	if orientation >= 5 {
+		w, h = h, w
+	}
+	return map[string]interface{}{
+		"width":       w,
+		"height":      h,
+		"format":      format,
+		"orientation": orientation,
+		"colorModel":  colorModelName(cfg.ColorModel),
+	}
+}
+
+// trimRect finds the content imaging.TrimFuzz would keep without producing
+// an image, for tools that store crop coordinates rather than new files.
+// Args: imageData (Uint8Array), fuzz (number, optional)
+// Returns: {x, y, width, height, imageWidth, imageHeight} in pixels of the
+// upright image, matching the cropX/cropY/cropW/cropH options
+func trimRect(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return errorResult(errcode.New(errcode.InvalidArgument, "missing arguments"))
+	}
+	var fuzz float64
+	if len(args) > 1 && args[1].Type() == js.TypeNumber {
+		fuzz = args[1].Float()
+	}
+	if fuzz < 0 || fuzz > 100 {
+		return errorResult(errcode.New(errcode.InvalidArgument, fmt.Sprintf("invalid trim fuzz %g", fuzz)))
+	}
+	if err := imaging.CurrentPolicy().CheckOp("trim"); err != nil {
+		return errorResult(errcode.Wrap(errcode.InvalidArgument, "", err))
+	}
+	data := make([]byte, args[0].Get("length").Int())
+	js.CopyBytesToGo(data, args[0])
+	img, err := decodeImage(data)
+	if err != nil {
+		return errorResult(errcode.Wrap(errcode.DecodeFailed, "failed to decode image", err))
+	}
+	img = imaging.ApplyOrientation(img, imaging.Orientation(data))
+
+	b := img.Bounds()
+	r := imaging.TrimRectFuzz(img, fuzz).Sub(b.Min)
+	return map[string]interface{}{
+		"x":           r.Min.X,
+		"y":           r.Min.Y,
+		"width":       r.Dx(),
+		"height":      r.Dy(),
+		"imageWidth":  b.Dx(),
+		"imageHeight": b.Dy(),
+	}
+}
+
+// colorModelName names the standard library color models.
+func colorModelName(m color.Model) string {
+	switch m {
+	case color.RGBAModel:
+		return "rgba"
+	case color.RGBA64Model:
+		return "rgba64"
+	case color.NRGBAModel:
+		return "nrgba"
+	case color.NRGBA64Model:
+		return "nrgba64"
+	case color.GrayModel:
+		return "gray"
+	case color.Gray16Model:
+		return "gray16"
+	case color.YCbCrModel:
+		return "ycbcr"
+	case color.CMYKModel:
+		return "cmyk"
+	}
+	if _, ok := m.(color.Palette); ok {
+		return "paletted"
+	}
+	return "unknown"
+}
+
+// imageSource is an input image as received from JavaScript.
+type imageSource struct {
+	data []byte      // Encoded file, or the raw pixel buffer
+	raw  image.Image // Wraps data for raw pixels; nil for encoded files
+	desc string      // Distinguishes raw layouts in cache keys
+}
+
+// readSource copies an image from JavaScript: an encoded file as a
+// Uint8Array, or an ImageData-like object holding raw pixels.
+func readSource(v js.Value) (imageSource, error) {
+	jsData := v
+	var pixels js.Value
+	if v.Get("width").Type() == js.TypeNumber {
+		pixels, jsData = v, v.Get("data")
+	}
+	src := imageSource{data: make([]byte, jsData.Get("length").Int()), desc: "encoded"}
+	js.CopyBytesToGo(src.data, jsData)
+	if pixels.IsUndefined() {
+		return src, nil
+	}
+
+	// Raw pixels are wrapped rather than decoded
+	format := ""
+	if f := pixels.Get("format"); f.Type() == js.TypeString {
+		format = f.String()
+	}
+	pixelFormat, err := imaging.ParsePixelFormat(format)
 	if err != nil {
-		return map[string]interface{}{"error": "failed to decode image: " + err.Error()}
+		return src, err
+	}
+	stride := 0
+	if s := pixels.Get("stride"); s.Type() == js.TypeNumber {
+		stride = s.Int()
+	}
+	src.raw, err = imaging.FromPixels(src.data, pixels.Get("width").Int(), pixels.Get("height").Int(), stride, pixelFormat)
+	if err != nil {
+		return src, fmt.Errorf("invalid pixels: %w", err)
+	}
+	src.desc = fmt.Sprintf("raw %s %v stride=%d", format, src.raw.Bounds(), stride)
+	return src, nil
+}
+
+// process runs processImage's stages on src and returns the result object.
+func process(src imageSource, opts processOptions) interface{} {
+	var sw *stopwatch
+	if opts.timings {
+		sw = newStopwatch()
+	}
+
+	// Serve repeated transformations of the same image from the cache, as
+	// long as the image is still within the pixel limit
+	key := cache.Key(src.data, fmt.Sprintf("%s pixels=%d", opts.cacheKey(src.desc), maxPixels))
+	if b, ok := results.Get(key); ok {
+		if r, ok := unmarshalResult(b); ok {
+			opts.report("done", 100)
+			sw.lap("cache")
+			return sw.attach(r.toJS())
+		}
+	}
+
+	// Decode the image
+	opts.report("decode", 0)
+	img := src.raw
+	var err error
+	if img == nil {
+		if img, err = decodeImage(src.data); err != nil {
+			return errorResult(errcode.Wrap(errcode.DecodeFailed, "failed to decode image", err))
+		}
+	}
+	sw.lap("decode")
+
+	// Rotate upright according to EXIF orientation, falling back to the text
+	// orientation of scanned documents
+	if opts.autoOrient {
+		opts.report("orient", 20)
+		orientation := imaging.OrientNormal
+		if src.raw == nil {
+			orientation = imaging.Orientation(src.data)
+		}
+		if orientation == imaging.OrientNormal && opts.documentOrient {
+			orientation = imaging.DocumentOrientation(img)
+		}
+		img = imaging.ApplyOrientation(img, orientation)
+		sw.lap("orient")
+	}
+
+	// Obscure regions of the upright image before anything moves them
+	if len(opts.redact) > 0 {
+		origin := img.Bounds().Min
+		regions := make([]image.Rectangle, len(opts.redact))
+		for i, r := range opts.redact {
+			regions[i] = r.Add(origin)
+		}
+		img = imaging.RedactRegions(img, regions, imaging.RedactOptions{Mode: opts.redactMode})
+		sw.lap("redact")
+	}
+
+	// Crop to the region chosen on the upright image
+	if !opts.crop.Empty() {
+		b := img.Bounds()
+		r := opts.crop.Add(b.Min).Intersect(b)
+		if r.Empty() {
+			return errorResult(errcode.New(errcode.InvalidArgument, fmt.Sprintf("crop region %v is outside the %dx%d image", opts.crop, b.Dx(), b.Dy())))
+		}
+		img = imaging.Crop(img, r)
+		sw.lap("crop")
 	}
 
 	// Apply trim if requested
-	if trim {
-		img = imaging.Trim(img)
+	if opts.trim {
+		opts.report("trim", 25)
+		img = imaging.TrimSides(img, opts.trimFuzz, opts.trimSides, opts.trimMargin)
+		sw.lap("trim")
+	}
+
+	// Make background transparent if requested, or replace it with a color
+	if opts.transparentBg {
+		opts.report("removebg", 30)
+		img, err = imaging.RemoveBackgroundWith(context.Background(), img, imaging.BackgroundOptions{Feather: opts.feather})
+		if err != nil {
+			return errorResult(errcode.Wrap(errcode.ProcessingFailed, "failed to remove background", err))
+		}
+		if opts.replaceBg != nil {
+			img = imaging.Flatten(img, opts.replaceBg)
+		}
+		sw.lap("removebg")
+	}
+
+	// Key out a green screen or other backdrop color
+	if opts.keyColor != nil {
+		opts.report("chromakey", 35)
+		img = imaging.ChromaKey(img, opts.keyColor, imaging.DefaultKeyTolerance, imaging.DefaultKeySoftness)
+		sw.lap("chromakey")
 	}
 
-	// Make background transparent if requested
-	if transparentBg {
-		img = imaging.RemoveBackground(img)
+	// Add margins around the trimmed image
+	if opts.pad != (imaging.Insets{}) {
+		img = imaging.Pad(img, opts.pad, opts.padColor)
+		sw.lap("pad")
+	}
+
+	// Run the requested operations
+	opts.report("ops", 40)
+	img, err = opts.pipeline.ApplyTimed(img, sw.step)
+	if err != nil {
+		return errorResult(errcode.Wrap(errcode.ProcessingFailed, "failed to process image", err))
 	}
 
 	// Calculate new dimensions
@@ -71,11 +584,13 @@ func processImage(this js.Value, args []js.Value) interface{} {
 	origWidth := origBounds.Dx()
 	origHeight := origBounds.Dy()
 
-	newWidth := width
-	newHeight := height
+	newWidth := opts.width
+	newHeight := opts.height
 
 	// Maintain aspect ratio if only one dimension is provided
-	if newWidth > 0 && newHeight == 0 {
+	if opts.geometry != nil {
+		newWidth, newHeight = opts.geometry.Size(origWidth, origHeight)
+	} else if newWidth > 0 && newHeight == 0 {
 		newHeight = int(float64(origHeight) * float64(newWidth) / float64(origWidth))
 	} else if newHeight > 0 && newWidth == 0 {
 		newWidth = int(float64(origWidth) * float64(newHeight) / float64(origHeight))
@@ -84,50 +599,535 @@ func processImage(this js.Value, args []js.Value) interface{} {
 		newHeight = origHeight
 	}
 
-	// Resize the image
-	dst := image.NewRGBA(image.Rect(0, 0, newWidth, newHeight))
-	draw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Over, nil)
+	if err := opts.policy.CheckScale("resize", origBounds.Size(), image.Pt(newWidth, newHeight)); err != nil {
+		return errorResult(errcode.Wrap(errcode.Disabled, "", err))
+	}
+
+	// Resize the image; paletted sources stay paletted so PNG output keeps its palette
+	opts.report("resize", 60)
+	dst := imaging.Resize(img, newWidth, newHeight)
+	sw.lap("resize")
 
-	// Encode the result
+	// Tonal filters run at the output size, where there are fewest pixels;
+	// automatic corrections come before manual ones, and color before levels
+	if opts.whiteBalance || opts.temp != 0 || opts.tint != 0 {
+		if opts.whiteBalance {
+			dst = imaging.AutoWhiteBalance(dst, opts.wbMethod)
+		}
+		if opts.temp != 0 || opts.tint != 0 {
+			dst = imaging.AdjustWhiteBalance(dst, opts.temp, opts.tint)
+		}
+		sw.lap("wb")
+	}
+	if opts.autoContrast {
+		dst = imaging.AutoContrast(dst, opts.contrastClip)
+		sw.lap("autocontrast")
+	}
+	if opts.equalize {
+		dst = imaging.Equalize(dst, imaging.DefaultEqualizeClip, imaging.DefaultEqualizeTiles)
+		sw.lap("equalize")
+	}
+	if !opts.levels.IsZero() {
+		dst = imaging.Levels(dst, opts.levels)
+		sw.lap("levels")
+	}
+	if len(opts.curve) > 0 {
+		dst = imaging.ApplyCurves(dst, imaging.Curves{All: opts.curve})
+		sw.lap("curves")
+	}
+	if !opts.adjust.IsZero() {
+		dst = imaging.Adjust(dst, opts.adjust)
+		sw.lap("adjust")
+	}
+	if !opts.hsl.IsZero() {
+		dst = imaging.AdjustHSL(dst, opts.hsl)
+		sw.lap("hsl")
+	}
+	if opts.blur > 0 {
+		dst = imaging.Blur(dst, opts.blur)
+		sw.lap("blur")
+	}
+	if opts.grayscale {
+		dst = imaging.Grayscale(dst)
+	}
+	if opts.sepia {
+		dst = imaging.Sepia(dst)
+	}
+	if opts.invert {
+		dst = imaging.Invert(dst)
+	}
+	if opts.grayscale || opts.sepia || opts.invert {
+		sw.lap("tone")
+	}
+
+	// Round the corners or cut to a shape at the output size, as for avatars
+	if opts.radius > 0 {
+		dst = imaging.RoundCorners(dst, opts.radius)
+		sw.lap("round")
+	}
+	if opts.shape != imaging.ShapeNone {
+		dst = imaging.MaskShape(dst, opts.shape)
+		newWidth, newHeight = dst.Bounds().Dx(), dst.Bounds().Dy()
+		sw.lap("shape")
+	}
+	if opts.mask != nil {
+		mask, err := decodeImage(opts.mask)
+		if err != nil {
+			return errorResult(errcode.Wrap(errcode.DecodeFailed, "failed to decode mask", err))
+		}
+		dst = imaging.ApplyMask(dst, mask)
+		sw.lap("mask")
+	}
+
+	// Let the image content decide the format
+	formatReason := ""
+	if opts.format == "smart" {
+		choice := imaging.ChooseFormat(dst)
+		opts.format, opts.quality, formatReason = choice.Format, choice.Quality, choice.Reason
+		if choice.Palette != nil {
+			dst = imaging.ToPaletted(dst, choice.Palette)
+		}
+		sw.lap("smart")
+	}
+
+	// JPEG has no alpha channel, so composite onto the matte rather than losing transparency silently
+	warning := ""
+	if (opts.format == "jpeg" || opts.format == "jpg") && !imaging.Opaque(dst) {
+		dst = imaging.Flatten(dst, opts.matte)
+		warning = fmt.Sprintf("transparency was flattened onto #%02x%02x%02x for JPEG output", opts.matte.R, opts.matte.G, opts.matte.B)
+		sw.lap("flatten")
+	}
+
+	// Encode the result, fitting it to the byte budget if one was given
+	opts.report("encode", 75)
+	var r result
+	if opts.maxBytes > 0 {
+		enc, err := imaging.EncodeMaxBytes(dst, opts.format, opts.maxBytes, opts.downscale)
+		if err != nil {
+			return errorResult(errcode.Wrap(errcode.EncodeFailed, "failed to encode image", err))
+		}
+		r = result{data: enc.Data, mimeType: enc.MimeType, width: enc.Width, height: enc.Height, quality: enc.Quality}
+	} else {
+		var buf bytes.Buffer
+		mimeType, err := imaging.Encode(&buf, dst, opts.format, opts.quality)
+		if err != nil {
+			return errorResult(errcode.Wrap(errcode.EncodeFailed, "failed to encode image", err))
+		}
+		r = result{data: buf.Bytes(), mimeType: mimeType, width: newWidth, height: newHeight, quality: opts.quality}
+	}
+	sw.lap("encode")
+	r.formatReason = formatReason
+	r.warning = warning
+	results.Set(key, r.marshal(), 0)
+	opts.report("done", 100)
+	return sw.attach(r.toJS())
+}
+
+// stopwatch records the time spent in each processing stage, in order. A nil
+// stopwatch records nothing, so stages can be timed unconditionally.
+type stopwatch struct {
+	stages []interface{}
+	last   time.Time
+}
+
+func newStopwatch() *stopwatch {
+	return &stopwatch{last: time.Now()}
+}
+
+// lap records the time since the previous lap as stage.
+func (s *stopwatch) lap(stage string) {
+	if s == nil {
+		return
+	}
+	now := time.Now()
+	s.add(stage, now.Sub(s.last))
+	s.last = now
+}
+
+// step records a pipeline step; it has the signature Pipeline.ApplyTimed expects.
+func (s *stopwatch) step(step imaging.Step, d time.Duration) {
+	if s == nil {
+		return
+	}
+	s.add("op:"+step.Name, d)
+	s.last = time.Now()
+}
+
+func (s *stopwatch) add(stage string, d time.Duration) {
+	s.stages = append(s.stages, map[string]interface{}{"stage": stage, "ms": float64(d.Microseconds()) / 1000})
+}
+
+// attach adds the recorded timings to a processImage result.
+func (s *stopwatch) attach(out map[string]interface{}) map[string]interface{} {
+	if s != nil {
+		out["timings"] = s.stages
+	}
+	return out
+}
+
+// errorResult is the object returned to JavaScript for a failed call,
+// {error: {code, message}}, with the codes defined in package errcode.
+func errorResult(err *errcode.Error) map[string]interface{} {
+	return map[string]interface{}{"error": map[string]interface{}{"code": string(err.Code), "message": err.Message}}
+}
+
+// toJS converts a result into the object returned to JavaScript.
+func (r result) toJS() map[string]interface{} {
+	// Create Uint8Array to return to JavaScript
+	jsResult := js.Global().Get("Uint8Array").New(len(r.data))
+	js.CopyBytesToJS(jsResult, r.data)
+
+	out := map[string]interface{}{
+		"data":     jsResult,
+		"mimeType": r.mimeType,
+		"width":    r.width,
+		"height":   r.height,
+		"size":     len(r.data),
+		"quality":  r.quality,
+	}
+	if r.formatReason != "" {
+		out["formatReason"] = r.formatReason
+	}
+	if r.warning != "" {
+		out["warning"] = r.warning
+	}
+	return out
+}
+
+// setPresets replaces the named presets available to processImage.
+// Args: presets (string, JSON object mapping names to options)
+// Returns: the preset names, or an error
+func setPresets(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return errorResult(errcode.New(errcode.InvalidArgument, "missing arguments"))
+	}
+	p, err := presets.Parse([]byte(args[0].String()))
+	if err != nil {
+		return errorResult(errcode.Wrap(errcode.InvalidArgument, "", err))
+	}
+	named.Replace(p)
+
+	names := named.Names()
+	out := make([]interface{}, len(names))
+	for i, name := range names {
+		out[i] = name
+	}
+	return map[string]interface{}{"names": out}
+}
+
+// validateImage checks an image against a marketplace's image rules.
+// Args: imageData (Uint8Array), rules (string: "amazon", "ebay", or "etsy"), fix (bool, optional)
+// Returns: {pass, reasons}; with fix set and a failing image, also fixed: the
+// image run through the rules' fix pipeline as JPEG, with its own pass and reasons
+func validateImage(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 {
+		return errorResult(errcode.New(errcode.InvalidArgument, "missing arguments"))
+	}
+	imageData := make([]byte, args[0].Get("length").Int())
+	js.CopyBytesToGo(imageData, args[0])
+	rules, err := marketplace.Lookup(args[1].String())
+	if err != nil {
+		return errorResult(errcode.Wrap(errcode.InvalidArgument, "", err))
+	}
+
+	img, err := decodeImage(imageData)
+	if err != nil {
+		return errorResult(errcode.Wrap(errcode.DecodeFailed, "failed to decode image", err))
+	}
+	img = imaging.ApplyOrientation(img, imaging.Orientation(imageData))
+
+	res := marketplace.Check(img, rules)
+	out := validationToJS(res)
+	if res.Pass || len(args) < 3 || !args[2].Truthy() {
+		return out
+	}
+
+	fixed, err := marketplace.Fix(img, rules)
+	if err != nil {
+		return errorResult(errcode.Wrap(errcode.ProcessingFailed, "", err))
+	}
 	var buf bytes.Buffer
-	var mimeType string
-
-	switch format {
-	case "jpeg":
-		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: quality})
-		mimeType = "image/jpeg"
-	default:
-		// Map quality to PNG compression level (inverted: 1-25 = BestCompression, 76-100 = NoCompression)
-		// This makes "higher = faster/larger" consistent with JPEG's "higher = better/larger"
-		var compression png.CompressionLevel
-		if quality <= 25 {
-			compression = png.BestCompression
-		} else if quality <= 50 {
-			compression = png.DefaultCompression
-		} else if quality <= 75 {
-			compression = png.BestSpeed
-		} else {
-			compression = png.NoCompression
+	mimeType, err := imaging.Encode(&buf, fixed, "jpeg", imaging.DefaultQuality)
+	if err != nil {
+		return errorResult(errcode.Wrap(errcode.EncodeFailed, "failed to encode image", err))
+	}
+	b := fixed.Bounds()
+	fixedOut := result{data: buf.Bytes(), mimeType: mimeType, width: b.Dx(), height: b.Dy(), quality: imaging.DefaultQuality}.toJS()
+	for k, v := range validationToJS(marketplace.Check(fixed, rules)) {
+		fixedOut[k] = v
+	}
+	out["fixed"] = fixedOut
+	return out
+}
+
+// crossfadeImages renders an animated GIF fading from one image to another,
+// at the first image's size.
+// Args: from (Uint8Array), to (Uint8Array), frames (int, optional, up to
+// animated.MaxCrossfadeFrames), delay (int, optional), hold (int, optional), loop (bool, optional).
+// Delays are in hundredths of a second; zero or missing uses the defaults.
+func crossfadeImages(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 {
+		return errorResult(errcode.New(errcode.InvalidArgument, "missing arguments"))
+	}
+	if err := imaging.CurrentPolicy().CheckOp("crossfade"); err != nil {
+		return errorResult(errcode.Wrap(errcode.Disabled, "", err))
+	}
+	var imgs [2]image.Image
+	for i := range imgs {
+		data := make([]byte, args[i].Get("length").Int())
+		js.CopyBytesToGo(data, args[i])
+		img, err := decodeImage(data)
+		if err != nil {
+			return errorResult(errcode.Wrap(errcode.DecodeFailed, "failed to decode image", err))
+		}
+		imgs[i] = imaging.ApplyOrientation(img, imaging.Orientation(data))
+	}
+
+	opts := animated.DefaultCrossfade
+	for i, v := range []*int{&opts.Frames, &opts.Delay, &opts.Hold} {
+		if len(args) > 2+i && args[2+i].Truthy() {
+			*v = args[2+i].Int()
 		}
-		encoder := &png.Encoder{CompressionLevel: compression}
-		err = encoder.Encode(&buf, dst)
-		mimeType = "image/png"
 	}
+	opts.Loop = len(args) > 5 && args[5].Truthy()
 
+	anim, err := animated.Crossfade(imgs[0], imgs[1], opts)
 	if err != nil {
-		return map[string]interface{}{"error": "failed to encode image: " + err.Error()}
+		return errorResult(errcode.Wrap(errcode.ProcessingFailed, "", err))
 	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		return errorResult(errcode.Wrap(errcode.EncodeFailed, "failed to encode animation", err))
+	}
+	b := anim.Image[0].Bounds()
+	return result{data: buf.Bytes(), mimeType: "image/gif", width: b.Dx(), height: b.Dy()}.toJS()
+}
 
-	// Create Uint8Array to return to JavaScript
-	result := buf.Bytes()
-	jsResult := js.Global().Get("Uint8Array").New(len(result))
-	js.CopyBytesToJS(jsResult, result)
+// compositeImages layers several images into one, as for banners and thumbnails.
+// Args: layers (array of {data (Uint8Array), x, y (int), scale (number, default 1),
+// opacity (number 0-1, default 1), blend (string: "normal", "multiply", "screen",
+// "overlay", "darken", or "lighten")}, bottom first), options (object, optional:
+// width, height (int; default the first layer's size), background (color, default
+// transparent), format (string, default "png"), quality (int))
+// Returns: the same result object as processImage
+func compositeImages(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || args[0].Type() != js.TypeObject || args[0].Length() == 0 {
+		return errorResult(errcode.New(errcode.InvalidArgument, "expected an array of layers"))
+	}
+	if err := imaging.CurrentPolicy().CheckOp("composite"); err != nil {
+		return errorResult(errcode.Wrap(errcode.Disabled, "", err))
+	}
+	num := func(v js.Value, name string, def float64) (float64, error) {
+		f := v.Get(name)
+		if f.IsUndefined() || f.IsNull() {
+			return def, nil
+		}
+		if f.Type() != js.TypeNumber {
+			return 0, fmt.Errorf("%s must be a number, got %s", name, f.Type())
+		}
+		return f.Float(), nil
+	}
+
+	layers := make([]imaging.Layer, args[0].Length())
+	for i := range layers {
+		v := args[0].Index(i)
+		data := v.Get("data")
+		if !data.InstanceOf(js.Global().Get("Uint8Array")) {
+			return errorResult(errcode.New(errcode.InvalidArgument, fmt.Sprintf("layer %d: data must be a Uint8Array", i)))
+		}
+		buf := make([]byte, data.Length())
+		js.CopyBytesToGo(buf, data)
+		img, err := decodeImage(buf)
+		if err != nil {
+			return errorResult(errcode.Wrap(errcode.DecodeFailed, fmt.Sprintf("layer %d: failed to decode image", i), err))
+		}
+		l := imaging.Layer{Image: imaging.ApplyOrientation(img, imaging.Orientation(buf))}
+		var x, y float64
+		for _, f := range []struct {
+			name string
+			dst  *float64
+			def  float64
+		}{{"x", &x, 0}, {"y", &y, 0}, {"scale", &l.Scale, 1}, {"opacity", &l.Opacity, 1}} {
+			if *f.dst, err = num(v, f.name, f.def); err != nil {
+				return errorResult(errcode.New(errcode.InvalidArgument, fmt.Sprintf("layer %d: %v", i, err)))
+			}
+		}
+		if l.Scale <= 0 || l.Opacity < 0 || l.Opacity > 1 {
+			return errorResult(errcode.New(errcode.InvalidArgument, fmt.Sprintf("layer %d: scale must be positive and opacity between 0 and 1", i)))
+		}
+		l.Position = image.Pt(int(x), int(y))
+		if b := v.Get("blend"); b.Type() == js.TypeString {
+			if l.Blend, err = imaging.ParseBlendMode(b.String()); err != nil {
+				return errorResult(errcode.New(errcode.InvalidArgument, fmt.Sprintf("layer %d: %v", i, err)))
+			}
+		}
+		layers[i] = l
+	}
+
+	opts := js.Undefined()
+	if len(args) > 1 && args[1].Type() == js.TypeObject {
+		opts = args[1]
+	}
+	get := func(name string) js.Value {
+		if opts.IsUndefined() {
+			return js.Undefined()
+		}
+		return opts.Get(name)
+	}
+	width, height := 0, 0
+	if v := get("width"); v.Type() == js.TypeNumber {
+		width = v.Int()
+	}
+	if v := get("height"); v.Type() == js.TypeNumber {
+		height = v.Int()
+	}
+	if width < 0 || height < 0 || int64(width)*int64(height) > int64(maxPixels) {
+		return errorResult(errcode.New(errcode.InvalidArgument, fmt.Sprintf("invalid canvas size %dx%d", width, height)))
+	}
+	var bg color.Color = color.Transparent
+	if v := get("background"); v.Type() == js.TypeString {
+		c, err := imaging.ParseColor(v.String())
+		if err != nil {
+			return errorResult(errcode.Wrap(errcode.InvalidArgument, "invalid background", err))
+		}
+		bg = c
+	}
+	format, quality := "png", imaging.DefaultQuality
+	if v := get("format"); v.Type() == js.TypeString {
+		format = v.String()
+	}
+	if v := get("quality"); v.Type() == js.TypeNumber && v.Int() > 0 && v.Int() <= 100 {
+		quality = v.Int()
+	}
+
+	dst := imaging.Composite(width, height, bg, layers)
+	var buf bytes.Buffer
+	mimeType, err := imaging.Encode(&buf, dst, format, quality)
+	if err != nil {
+		return errorResult(errcode.Wrap(errcode.EncodeFailed, "failed to encode image", err))
+	}
+	b := dst.Bounds()
+	return result{data: buf.Bytes(), mimeType: mimeType, width: b.Dx(), height: b.Dy(), quality: quality}.toJS()
+}
 
+// validationToJS converts a marketplace check into {pass, reasons}.
+func validationToJS(res marketplace.Result) map[string]interface{} {
+	reasons := make([]interface{}, len(res.Reasons))
+	for i, r := range res.Reasons {
+		reasons[i] = r
+	}
+	return map[string]interface{}{"pass": res.Pass, "reasons": reasons}
+}
+
+// configureCache replaces the caches with ones using new bounds.
+// Args: maxEntries (int), maxBytes (int), decodedMegapixels (int, optional); zero disables a bound.
+func configureCache(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 {
+		return errorResult(errcode.New(errcode.InvalidArgument, "missing arguments"))
+	}
+	results = newResultCache(args[0].Int(), int64(args[1].Int()))
+	if len(args) >= 3 {
+		decoded = newDecodedCache(args[2].Int())
+	}
+	return nil
+}
+
+// configureLimits sets the largest image processImage will decode.
+// Args: maxMegapixels (number; 0 restores the default)
+func configureLimits(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return errorResult(errcode.New(errcode.InvalidArgument, "missing arguments"))
+	}
+	maxPixels = int(args[0].Float() * 1_000_000)
+	if maxPixels <= 0 {
+		maxPixels = imaging.DefaultMaxPixels
+	}
+	return nil
+}
+
+// configurePolicy restricts the operations every call may use (see imaging.Policy);
+// forbidden ones fail with the DISABLED error code.
+// Args: policy ({disable: [names], maxUpscale: number}; missing fields lift that restriction)
+func configurePolicy(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || args[0].Type() != js.TypeObject {
+		return errorResult(errcode.New(errcode.InvalidArgument, "expected a policy object"))
+	}
+	var p imaging.Policy
+	if d := args[0].Get("disable"); !d.IsUndefined() && !d.IsNull() {
+		if !js.Global().Get("Array").Call("isArray", d).Bool() {
+			return errorResult(errcode.New(errcode.InvalidArgument, "disable must be an array of operation names"))
+		}
+		known := imaging.OpNames()
+		for i := 0; i < d.Length(); i++ {
+			name := d.Index(i).String()
+			if !slices.Contains(known, name) {
+				return errorResult(errcode.New(errcode.InvalidArgument, fmt.Sprintf("unknown operation %q", name)))
+			}
+			p.Disabled = append(p.Disabled, name)
+		}
+	}
+	if m := args[0].Get("maxUpscale"); m.Type() == js.TypeNumber {
+		p.MaxUpscale = m.Float()
+	}
+	imaging.SetPolicy(p)
+	return nil
+}
+
+// policyOps names the features outside the pipeline that configurePolicy can
+// also disable: the mask option and the compositeImages and crossfadeImages
+// exports. Document orientation is covered by the autorotate op.
+var policyOps = []string{"mask", "composite", "crossfade"}
+
+// capabilities describes what this build and its current configuration
+// support, so pages can adapt instead of hard-coding assumptions.
+// Returns: {inputFormats, outputFormats, operations, disabled, filters, presets,
+// limits: {maxPixels, maxUpscale}}, where operations lists the pipeline operations and
+// policyOps, excluding those the policy disables
+func capabilities(this js.Value, args []js.Value) interface{} {
+	policy := imaging.CurrentPolicy()
+	var ops, disabled []interface{}
+	for _, name := range append(imaging.OpNames(), policyOps...) {
+		if policy.CheckOp(name) != nil {
+			disabled = append(disabled, name)
+		} else {
+			ops = append(ops, name)
+		}
+	}
+	list := func(names []string) []interface{} {
+		out := make([]interface{}, len(names))
+		for i, name := range names {
+			out[i] = name
+		}
+		return out
+	}
 	return map[string]interface{}{
-		"data":     jsResult,
-		"mimeType": mimeType,
-		"width":    newWidth,
-		"height":   newHeight,
-		"size":     len(result),
+		"inputFormats":  list(inputFormats),
+		"outputFormats": append(list(imaging.OutputFormats), "smart"),
+		"operations":    ops,
+		"disabled":      disabled,
+		"filters":       []interface{}{"catmullrom", "nearest", "pixel"},
+		"presets":       list(named.Names()),
+		"limits": map[string]interface{}{
+			"maxPixels":  maxPixels,
+			"maxUpscale": policy.MaxUpscale,
+		},
+	}
+}
+
+// cacheStats returns the cache hit/miss counters and current usage.
+func cacheStats(this js.Value, args []js.Value) interface{} {
+	s := results.Stats()
+	d := decoded.Stats()
+	return map[string]interface{}{
+		"hits":    s.Hits,
+		"misses":  s.Misses,
+		"entries": s.Entries,
+		"bytes":   s.Size,
+		"decoded": map[string]interface{}{
+			"hits":    d.Hits,
+			"misses":  d.Misses,
+			"entries": d.Entries,
+			"pixels":  d.Size,
+		},
 	}
 }
@@ -0,0 +1,169 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"syscall/js"
+	"testing"
+
+	"image-resizer/imaging"
+	"image-resizer/presets"
+)
+
+func TestResultMarshal(t *testing.T) {
+	r := result{data: []byte{1, 2, 3}, mimeType: "image/png", width: 4, height: 5, quality: 80, warning: "flattened"}
+	got, ok := unmarshalResult(r.marshal())
+	if !ok {
+		t.Fatal("expected the marshaled result to decode")
+	}
+	if !bytes.Equal(got.data, r.data) || got.mimeType != r.mimeType || got.width != 4 || got.height != 5 ||
+		got.quality != 80 || got.formatReason != "" || got.warning != "flattened" {
+		t.Errorf("got %+v, want %+v", got, r)
+	}
+	for _, b := range [][]byte{nil, {0, 0, 0, 9, '{'}, {0, 0, 0, 1, 'x'}} {
+		if _, ok := unmarshalResult(b); ok {
+			t.Errorf("unmarshalResult(%v) expected failure", b)
+		}
+	}
+}
+
+// testSource returns a small encoded image.
+func testSource(t *testing.T) imageSource {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 10, 10))); err != nil {
+		t.Fatal(err)
+	}
+	return imageSource{data: buf.Bytes(), desc: "encoded"}
+}
+
+// testOptions parses options given as Go values.
+func testOptions(t *testing.T, values map[string]interface{}) processOptions {
+	jsValues := make(map[string]js.Value, len(values))
+	for k, v := range values {
+		jsValues[k] = js.ValueOf(v)
+	}
+	opts, err := parseOptions(jsValues)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return opts
+}
+
+// errorCode returns the code of an errorResult, or "" for a success.
+func errorCode(out interface{}) string {
+	if e, ok := out.(map[string]interface{})["error"]; ok {
+		return e.(map[string]interface{})["code"].(string)
+	}
+	return ""
+}
+
+func TestProcess_PolicyNotCached(t *testing.T) {
+	src := testSource(t)
+	upscale := map[string]interface{}{"width": 40}
+	defer imaging.SetPolicy(imaging.CurrentPolicy())
+
+	if code := errorCode(process(src, testOptions(t, upscale))); code != "" {
+		t.Fatalf("expected the first upscale to succeed, got %s", code)
+	}
+	// A stricter policy applies to a transformation that's already cached
+	imaging.SetPolicy(imaging.Policy{MaxUpscale: 2})
+	if code := errorCode(process(src, testOptions(t, upscale))); code != "DISABLED" {
+		t.Errorf("expected DISABLED after tightening the policy, got %q", code)
+	}
+}
+
+func TestPolicy_OutsidePipeline(t *testing.T) {
+	defer imaging.SetPolicy(imaging.CurrentPolicy())
+	imaging.SetPolicy(imaging.Policy{Disabled: []string{"mask", "autorotate", "composite", "crossfade"}})
+
+	mask := js.Global().Get("Uint8Array").New(1)
+	for _, values := range []map[string]js.Value{
+		{"mask": mask},
+		{"autoOrient": js.ValueOf("document")},
+	} {
+		if _, err := parseOptions(values); err == nil {
+			t.Errorf("parseOptions(%v) expected a disabled error", values)
+		}
+	}
+	if _, err := parseOptions(map[string]js.Value{"autoOrient": js.ValueOf("exif")}); err != nil {
+		t.Errorf("expected EXIF orientation to stay allowed, got %v", err)
+	}
+
+	data := js.Global().Get("Uint8Array").New(len(testSource(t).data))
+	js.CopyBytesToJS(data, testSource(t).data)
+	layers := js.ValueOf([]interface{}{map[string]interface{}{"data": data}})
+	if code := errorCode(compositeImages(js.Undefined(), []js.Value{layers})); code != "DISABLED" {
+		t.Errorf("compositeImages: expected DISABLED, got %q", code)
+	}
+	if code := errorCode(crossfadeImages(js.Undefined(), []js.Value{data, data})); code != "DISABLED" {
+		t.Errorf("crossfadeImages: expected DISABLED, got %q", code)
+	}
+}
+
+func TestProcess_LimitNotCached(t *testing.T) {
+	src := testSource(t)
+	defer func(n int) { maxPixels = n }(maxPixels)
+
+	if code := errorCode(process(src, testOptions(t, nil))); code != "" {
+		t.Fatalf("expected the first call to succeed, got %s", code)
+	}
+	// A lower limit applies to an image that's already decoded and cached
+	maxPixels = 50
+	if code := errorCode(process(src, testOptions(t, nil))); code != "TOO_LARGE" {
+		t.Errorf("expected TOO_LARGE after lowering the limit, got %q", code)
+	}
+	if _, err := decodeImage(src.data); err == nil {
+		t.Error("expected decodeImage to reject the cached image")
+	}
+}
+
+func TestOptionsFromArgs_Frozen(t *testing.T) {
+	args := make([]js.Value, len(positionalOptions)+1)
+	for i := range args {
+		args[i] = js.ValueOf(i)
+	}
+	values := optionsFromArgs(args)
+	if len(values) != len(positionalOptions) || values["timings"].Int() != len(positionalOptions)-1 {
+		t.Errorf("expected only the %d legacy positions, got %v", len(positionalOptions), values)
+	}
+
+	obj := js.ValueOf(map[string]interface{}{"width": 10, "blur": 2})
+	if values, err := optionsFromObject(obj); err != nil || len(values) != 2 {
+		t.Errorf("expected positional and object-only names by name, got %v, %v", values, err)
+	}
+	if _, err := optionsFromObject(js.ValueOf(map[string]interface{}{"blurr": 2})); err == nil {
+		t.Error("expected an unknown option to be rejected")
+	}
+}
+
+func TestProcessImages_InvalidPromise(t *testing.T) {
+	bad := [][]js.Value{
+		nil,
+		{js.ValueOf([]interface{}{}), js.ValueOf(map[string]interface{}{"blurr": 2})},
+		{js.ValueOf([]interface{}{}), js.ValueOf(map[string]interface{}{"width": "wide"})},
+	}
+	for _, args := range bad {
+		out, ok := processImages(js.Undefined(), args).(js.Value)
+		if !ok || !out.InstanceOf(js.Global().Get("Promise")) {
+			t.Errorf("processImages(%v): expected a Promise, got %v", args, out)
+		}
+	}
+}
+
+func TestParseOptions_PresetDefaults(t *testing.T) {
+	defer named.Replace(nil)
+	named.Replace(map[string]presets.Preset{"thumbnail": {Format: "jpeg", Quality: 50, MaxBytes: 1000}})
+
+	opts := testOptions(t, map[string]interface{}{"preset": "thumbnail"})
+	if opts.format != "jpeg" || opts.quality != 50 || opts.maxBytes != 1000 {
+		t.Errorf("expected the preset's settings, got format %q quality %d maxBytes %d", opts.format, opts.quality, opts.maxBytes)
+	}
+	// Explicit options win over the preset
+	opts = testOptions(t, map[string]interface{}{"preset": "thumbnail", "format": "png", "quality": 90, "maxBytes": 5000})
+	if opts.format != "png" || opts.quality != 90 || opts.maxBytes != 5000 {
+		t.Errorf("expected the explicit settings, got format %q quality %d maxBytes %d", opts.format, opts.quality, opts.maxBytes)
+	}
+}
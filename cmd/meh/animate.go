@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"image"
+	"image/gif"
+	"io"
+	"time"
+
+	"image-resizer/animated"
+	"image-resizer/imaging"
+)
+
+// runAnimate implements `meh animate`:
+//
+//	meh animate countdown -until 2026-12-31T00:00:00Z [style flags] output.gif
+//	meh animate progress -percent 40 [style flags] output.gif
+//	meh animate crossfade [-frames N] [-delay cs] [-hold cs] [-loop] [-size WxH] from to output.gif
+//
+// A crossfade is the size of from, or -size (covered and center-cropped).
+// Delays are in hundredths of a second.
+func runAnimate(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("expected countdown, progress, or crossfade")
+	}
+	kind := args[0]
+
+	fs := flag.NewFlagSet("animate "+kind, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	size := fs.String("size", "", "frame size as WxH")
+	bg := fs.String("background", "white", "background color")
+	fg := fs.String("color", "black", "text and bar color")
+	until := fs.String("until", "", "countdown target (RFC 3339)")
+	percent := fs.Float64("percent", 0, "progress bar fill (0-100)")
+	frames := fs.Int("frames", animated.DefaultCrossfade.Frames, "crossfade frames between the images")
+	delay := fs.Int("delay", animated.DefaultCrossfade.Delay, "crossfade frame delay")
+	hold := fs.Int("hold", animated.DefaultCrossfade.Hold, "delay on the first and last images")
+	loop := fs.Bool("loop", false, "fade back and repeat forever")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	files := 1
+	if kind == "crossfade" {
+		files = 3
+	}
+	if fs.NArg() != files {
+		return fmt.Errorf("expected %d file argument(s), got %d", files, fs.NArg())
+	}
+	output := fs.Arg(files - 1)
+
+	style := animated.DefaultStyle
+	if *size != "" {
+		g, err := imaging.ParseGeometry(*size)
+		if err != nil || g.Width <= 0 || g.Height <= 0 {
+			return fmt.Errorf("invalid -size %q", *size)
+		}
+		style.Width, style.Height = g.Width, g.Height
+	}
+	var err error
+	if style.Background, err = imaging.ParseColor(*bg); err != nil {
+		return err
+	}
+	if style.Foreground, err = imaging.ParseColor(*fg); err != nil {
+		return err
+	}
+
+	var anim *gif.GIF
+	switch kind {
+	case "countdown":
+		t, err := time.Parse(time.RFC3339, *until)
+		if err != nil {
+			return fmt.Errorf("invalid -until %q", *until)
+		}
+		anim = animated.Countdown(t, time.Now(), style)
+	case "progress":
+		anim = animated.Progress(*percent, style)
+	case "crossfade":
+		from, err := decodeFile(fs.Arg(0), imaging.DefaultAutoOrient, 0)
+		if err != nil {
+			return err
+		}
+		to, err := decodeFile(fs.Arg(1), imaging.DefaultAutoOrient, 0)
+		if err != nil {
+			return err
+		}
+		if *size != "" {
+			g := imaging.Geometry{Width: style.Width, Height: style.Height, Flag: imaging.GeometryFill}
+			w, h := g.Size(from.Bounds().Dx(), from.Bounds().Dy())
+			from = imaging.Extent(imaging.Resize(from, w, h), style.Width, style.Height, imaging.GravityCenter, image.Point{}, style.Background)
+		}
+		opts := animated.CrossfadeOptions{Frames: *frames, Delay: *delay, Hold: *hold, Loop: *loop}
+		if anim, err = animated.Crossfade(from, to, opts); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown animation %q, expected countdown, progress, or crossfade", kind)
+	}
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		return fmt.Errorf("failed to encode animation: %w", err)
+	}
+	return writeFile(output, buf.Bytes())
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"image"
+	"image/gif"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRunAnimate(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.gif")
+
+	until := time.Now().Add(time.Hour).Format(time.RFC3339)
+	if err := runAnimate([]string{"countdown", "-until", until, "-size", "200x40", out}); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	anim, err := gif.DecodeAll(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anim.Image) != 60 || anim.Config.Width != 200 || anim.Config.Height != 40 {
+		t.Errorf("expected 60 200x40 frames, got %d %dx%d", len(anim.Image), anim.Config.Width, anim.Config.Height)
+	}
+
+	if err := runAnimate([]string{"progress", "-percent", "40", out}); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	from, to := filepath.Join(dir, "from.png"), filepath.Join(dir, "to.png")
+	for _, path := range []string{from, to} {
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		png.Encode(f, image.NewGray(image.Rect(0, 0, 64, 48)))
+		f.Close()
+	}
+	if err := runAnimate([]string{"crossfade", "-frames", "4", "-size", "32x32", from, to, out}); err != nil {
+		t.Fatal(err)
+	}
+	f, err = os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	anim, err = gif.DecodeAll(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anim.Image) != 6 || anim.Config.Width != 32 || anim.Config.Height != 32 {
+		t.Errorf("expected 6 32x32 frames, got %d %dx%d", len(anim.Image), anim.Config.Width, anim.Config.Height)
+	}
+
+	for _, args := range [][]string{
+		{"countdown", "-until", "tomorrow", out},
+		{"spinner", out},
+		{"progress", "-color", "nope", out},
+		{"crossfade", from, out},
+		{"crossfade", from, filepath.Join(dir, "missing.png"), out},
+	} {
+		if err := runAnimate(args); err == nil {
+			t.Errorf("expected error for %v", args)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"image-resizer/badge"
+)
+
+// runBadge implements `meh badge`:
+//
+//	meh badge [-label text] [-value text] [-color color] output.{svg,png,gif,jpg}
+func runBadge(args []string) error {
+	fs := flag.NewFlagSet("badge", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	label := fs.String("label", "", "left-hand text")
+	value := fs.String("value", "", "right-hand text")
+	colorName := fs.String("color", "lightgrey", "value background (shields.io name or hex)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("expected one output file, got %d", fs.NArg())
+	}
+	output := fs.Arg(0)
+
+	c, err := badge.ParseColor(*colorName)
+	if err != nil {
+		return err
+	}
+	b := badge.Badge{Label: *label, Value: *value, Color: c}
+
+	if strings.EqualFold(filepath.Ext(output), ".svg") {
+		return writeFile(output, b.SVG())
+	}
+	return encodeFile(output, b.Image(), 0)
+}
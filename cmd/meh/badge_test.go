@@ -0,0 +1,46 @@
+package main
+
+import (
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunBadge(t *testing.T) {
+	dir := t.TempDir()
+
+	svg := filepath.Join(dir, "badge.svg")
+	if err := runBadge([]string{"-label", "build", "-value", "passing", "-color", "green", svg}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(svg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "<svg") || !strings.Contains(string(data), "passing") {
+		t.Errorf("expected an SVG badge, got %s", data)
+	}
+
+	out := filepath.Join(dir, "badge.png")
+	if err := runBadge([]string{"-label", "build", "-value", "passing", out}); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dy() != 20 {
+		t.Errorf("expected a 20px tall badge, got %v", img.Bounds())
+	}
+
+	if err := runBadge([]string{"-color", "nope", out}); err == nil {
+		t.Error("expected error for unknown color")
+	}
+}
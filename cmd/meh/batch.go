@@ -0,0 +1,292 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"image-resizer/imaging"
+)
+
+// batchInputExts are the file extensions meh batch decodes; other files are
+// skipped as non-images.
+var batchInputExts = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".webp": true,
+}
+
+// batchOptions are the parsed `meh batch` flags applied to every file.
+type batchOptions struct {
+	crop      image.Rectangle // Applied to every file when not empty
+	geometry  *imaging.Geometry
+	pipeline  *imaging.Pipeline
+	format    string // Output format; empty keeps each input's own format
+	quality   int
+	skipBlank bool
+	threshold float64
+	dryRun    bool
+}
+
+// batchSummary counts the outcome of a batch run.
+type batchSummary struct {
+	mu        sync.Mutex
+	processed int
+	nonImages int
+	blank     []string
+	failed    []string
+}
+
+// runBatch implements `meh batch`:
+//
+//	meh batch -in dir -out dir [-recursive] [-w width] [-h height] [-g geometry]
+//	    [-ops pipeline] [-format format] [-quality N] [-workers N]
+//	    [-skip-blank] [-blank-threshold score]
+//	    [-crop-from union|intersection|reference] [-fuzz percent] [-dry-run]
+//
+// Every image under -in is processed by -workers goroutines and written to the
+// same relative path under -out, keeping its format unless -format is given.
+// Non-images are skipped, and with -skip-blank so are blank scans
+// (imaging.IsBlank). -crop-from crops every image to the same rectangle, so
+// animation frames and screenshot sequences stay aligned: the trimmed area of
+// a reference image, or the union or intersection of every
+// image's trimmed area. -dry-run prints each output's path, format, dimensions,
+// and size instead of writing it. A summary goes to standard error; any failed file makes
+// the command fail after the rest have been processed.
+func runBatch(args []string) error {
+	flags := flag.NewFlagSet("batch", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	in := flags.String("in", "", "input directory")
+	out := flags.String("out", "", "output directory")
+	recursive := flags.Bool("recursive", false, "include subdirectories")
+	width := flags.Int("w", 0, "width in pixels")
+	flags.IntVar(width, "width", 0, "width in pixels")
+	height := flags.Int("h", 0, "height in pixels")
+	flags.IntVar(height, "height", 0, "height in pixels")
+	geometry := flags.String("g", "", "ImageMagick geometry")
+	ops := flags.String("ops", "", "imaging pipeline run before resizing")
+	format := flags.String("format", "", "output format (png, jpeg, gif)")
+	quality := flags.Int("quality", 0, "JPEG quality (1-100)")
+	workers := flags.Int("workers", runtime.NumCPU(), "files processed in parallel")
+	skipBlank := flags.Bool("skip-blank", false, "don't write blank images")
+	threshold := flags.Float64("blank-threshold", imaging.DefaultBlankThreshold, "blank score below which an image is blank")
+	cropFrom := flags.String("crop-from", "", "crop every image like a reference image, or to the union or intersection of their trims")
+	fuzz := flags.Float64("fuzz", 0, "border color tolerance in percent for -crop-from")
+	dryRun := flags.Bool("dry-run", false, "report the outputs without writing them")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" || *out == "" {
+		return fmt.Errorf("requires -in and -out directories")
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments %q", flags.Args())
+	}
+	if *workers < 1 {
+		return fmt.Errorf("-workers must be at least 1")
+	}
+	if *quality < 0 || *quality > 100 {
+		return fmt.Errorf("invalid -quality %d", *quality)
+	}
+	if *fuzz < 0 || *fuzz > 100 {
+		return fmt.Errorf("-fuzz must be between 0 and 100")
+	}
+
+	opts := batchOptions{quality: *quality, skipBlank: *skipBlank, threshold: *threshold, dryRun: *dryRun}
+	var err error
+	if opts.geometry, err = sizeGeometry(*width, *height, *geometry); err != nil {
+		return err
+	}
+	if opts.pipeline, err = imaging.ParsePipeline(*ops); err != nil {
+		return err
+	}
+	if *format != "" {
+		if opts.format, err = imaging.FormatFromPath("." + *format); err != nil {
+			return err
+		}
+	}
+
+	summary := &batchSummary{}
+	var rels []string
+	err = filepath.WalkDir(*in, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			// Don't descend into the output when it lives under the input
+			if path != *in && (!*recursive || filepath.Clean(path) == filepath.Clean(*out)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !batchInputExts[strings.ToLower(filepath.Ext(path))] {
+			summary.nonImages++
+			return nil
+		}
+		rel, err := filepath.Rel(*in, path)
+		if err != nil {
+			return err
+		}
+		rels = append(rels, rel)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if *cropFrom != "" {
+		if opts.crop, err = seriesCrop(*in, rels, *cropFrom, *fuzz); err != nil {
+			return err
+		}
+	}
+
+	paths := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < *workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for rel := range paths {
+				summary.record(rel, processBatchFile(*in, *out, rel, &opts))
+			}
+		}()
+	}
+	for _, rel := range rels {
+		paths <- rel
+	}
+	close(paths)
+	wg.Wait()
+
+	summary.report(os.Stderr)
+	if len(summary.failed) > 0 {
+		return fmt.Errorf("%d file(s) failed", len(summary.failed))
+	}
+	return nil
+}
+
+// errBlank marks a file skipped by -skip-blank.
+var errBlank = errors.New("blank")
+
+// processBatchFile converts the file at rel under inDir into outDir.
+func processBatchFile(inDir, outDir, rel string, opts *batchOptions) error {
+	img, err := decodeFile(filepath.Join(inDir, rel), imaging.DefaultAutoOrient, 0)
+	if err != nil {
+		return err
+	}
+	if opts.skipBlank && imaging.IsBlank(img, opts.threshold) {
+		return errBlank
+	}
+	if !opts.crop.Empty() {
+		img = imaging.Crop(img, opts.crop.Add(img.Bounds().Min))
+	}
+	if img, err = opts.pipeline.Apply(img); err != nil {
+		return err
+	}
+	if opts.geometry != nil {
+		w, h := opts.geometry.Size(img.Bounds().Dx(), img.Bounds().Dy())
+		img = imaging.Resize(img, w, h)
+	}
+
+	output := filepath.Join(outDir, rel)
+	format, err := imaging.FormatFromPath(output)
+	if opts.format != "" || err != nil {
+		// Switch the extension to the requested format, or to PNG for inputs
+		// such as WebP that can be read but not written
+		if format = opts.format; format == "" {
+			format = "png"
+		}
+		ext := "." + format
+		if format == "jpeg" {
+			ext = ".jpg"
+		}
+		output = strings.TrimSuffix(output, filepath.Ext(output)) + ext
+	}
+	if opts.dryRun {
+		format, data, err := encodeForPath(output, img, opts.quality)
+		if err != nil {
+			return err
+		}
+		reportDryRun(os.Stdout, output, format, img, len(data))
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(output), 0o755); err != nil {
+		return err
+	}
+	return encodeFile(output, img, opts.quality)
+}
+
+// seriesCrop returns the rectangle, relative to each image's origin, that
+// -crop-from crops every file in rels to. mode is "union", "intersection", or
+// the path of a reference image.
+func seriesCrop(inDir string, rels []string, mode string, fuzz float64) (image.Rectangle, error) {
+	trimRect := func(path string) (image.Rectangle, error) {
+		img, err := decodeFile(path, imaging.DefaultAutoOrient, 0)
+		if err != nil {
+			return image.Rectangle{}, err
+		}
+		return imaging.TrimFuzz(img, fuzz).Bounds().Sub(img.Bounds().Min), nil
+	}
+
+	if mode != "union" && mode != "intersection" {
+		return trimRect(mode)
+	}
+	var crop image.Rectangle
+	for i, rel := range rels {
+		r, err := trimRect(filepath.Join(inDir, rel))
+		if err != nil {
+			return image.Rectangle{}, err
+		}
+		switch {
+		case i == 0:
+			crop = r
+		case mode == "union":
+			crop = crop.Union(r)
+		default:
+			crop = crop.Intersect(r)
+		}
+	}
+	if crop.Empty() {
+		return crop, fmt.Errorf("-crop-from %s: the trimmed areas don't overlap", mode)
+	}
+	return crop, nil
+}
+
+// record counts the outcome of one file.
+func (s *batchSummary) record(rel string, err error) {
+	switch {
+	case errors.Is(err, errBlank):
+		s.add(&s.blank, rel)
+	case err != nil:
+		s.add(&s.failed, fmt.Sprintf("%s: %v", rel, err))
+	default:
+		s.mu.Lock()
+		s.processed++
+		s.mu.Unlock()
+	}
+}
+
+func (s *batchSummary) add(list *[]string, path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	*list = append(*list, path)
+}
+
+// report writes the summary, listing blank and failed files in path order.
+func (s *batchSummary) report(w io.Writer) {
+	sort.Strings(s.blank)
+	sort.Strings(s.failed)
+	for _, path := range s.blank {
+		fmt.Fprintf(w, "meh: skipped blank %s\n", path)
+	}
+	for _, failure := range s.failed {
+		fmt.Fprintf(w, "meh: failed %s\n", failure)
+	}
+	fmt.Fprintf(w, "meh: processed %d, skipped %d blank and %d non-image, failed %d\n",
+		s.processed, len(s.blank), s.nonImages, len(s.failed))
+}
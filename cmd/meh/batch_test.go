@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunBatch(t *testing.T) {
+	in, out := t.TempDir(), t.TempDir()
+	writePNG := func(rel string, img image.Image) {
+		path := filepath.Join(in, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		png.Encode(f, img)
+		f.Close()
+	}
+
+	// Half black, half white, so it isn't blank
+	photo := image.NewGray(image.Rect(0, 0, 80, 40))
+	for y := 0; y < 40; y++ {
+		for x := 40; x < 80; x++ {
+			photo.SetGray(x, y, color.Gray{255})
+		}
+	}
+	writePNG("a.png", photo)
+	writePNG("sub/b.png", photo)
+	writePNG("sub/deeper/c.png", photo)
+	writePNG("empty.png", image.NewGray(image.Rect(0, 0, 80, 40)))
+	if err := os.WriteFile(filepath.Join(in, "notes.txt"), []byte("not an image"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	args := []string{"-in", in, "-out", out, "-recursive", "-width", "40", "-workers", "3", "-skip-blank"}
+	if err := runBatch(args); err != nil {
+		t.Fatal(err)
+	}
+	for _, rel := range []string{"a.png", "sub/b.png", "sub/deeper/c.png"} {
+		if w := pngWidth(t, filepath.Join(out, rel)); w != 40 {
+			t.Errorf("%s: expected width 40, got %d", rel, w)
+		}
+	}
+	for _, rel := range []string{"empty.png", "notes.txt"} {
+		if _, err := os.Stat(filepath.Join(out, rel)); !os.IsNotExist(err) {
+			t.Errorf("%s: expected to be skipped, got %v", rel, err)
+		}
+	}
+
+	// Without -recursive only the top level is processed, here as JPEG
+	flat := t.TempDir()
+	if err := runBatch([]string{"-in", in, "-out", flat, "-format", "jpg"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(flat, "a.jpg")); err != nil {
+		t.Errorf("expected a.jpg: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(flat, "sub")); !os.IsNotExist(err) {
+		t.Errorf("expected subdirectories to be skipped, got %v", err)
+	}
+
+	// A corrupt image fails the batch without stopping the rest
+	if err := os.WriteFile(filepath.Join(in, "broken.png"), []byte("garbage"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := runBatch([]string{"-in", in, "-out", t.TempDir()}); err == nil {
+		t.Error("expected error for a corrupt image")
+	}
+
+	for _, args := range [][]string{
+		{"-in", in},
+		{"-in", in, "-out", out, "-workers", "0"},
+		{"-in", in, "-out", out, "-format", "bmp"},
+		{"-in", in, "-out", out, "-ops", "explode"},
+		{"-in", in, "-out", out, "extra"},
+	} {
+		if err := runBatch(args); err == nil {
+			t.Errorf("runBatch(%q) expected error", args)
+		}
+	}
+}
+
+func TestRunBatch_CropFrom(t *testing.T) {
+	in := t.TempDir()
+	// Frames with a white background and a black square moving to the right
+	for i, x := range []int{10, 20, 30} {
+		frame := image.NewGray(image.Rect(0, 0, 100, 50))
+		for j := range frame.Pix {
+			frame.Pix[j] = 255
+		}
+		for y := 10; y < 30; y++ {
+			for dx := 0; dx < 20; dx++ {
+				frame.SetGray(x+dx, y, color.Gray{0})
+			}
+		}
+		f, err := os.Create(filepath.Join(in, fmt.Sprintf("frame%d.png", i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		png.Encode(f, frame)
+		f.Close()
+	}
+
+	for _, tt := range []struct {
+		from  string
+		width int
+	}{
+		{"union", 40},
+		{"intersection", 0},
+		{filepath.Join(in, "frame1.png"), 20},
+	} {
+		out := t.TempDir()
+		err := runBatch([]string{"-in", in, "-out", out, "-crop-from", tt.from})
+		if tt.width == 0 {
+			if err == nil {
+				t.Errorf("-crop-from %s: expected error for frames that don't overlap", tt.from)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 3; i++ {
+			if w := pngWidth(t, filepath.Join(out, fmt.Sprintf("frame%d.png", i))); w != tt.width {
+				t.Errorf("-crop-from %s: frame%d: expected width %d, got %d", tt.from, i, tt.width, w)
+			}
+		}
+	}
+}
@@ -0,0 +1,516 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"image-resizer/cache"
+	"image-resizer/imaging"
+
+	_ "golang.org/x/image/webp"
+)
+
+// convertOp is a single image operation from the command line, applied in order.
+type convertOp func(img image.Image, s *convertSettings) (image.Image, error)
+
+// convertSettings are the ImageMagick settings that apply to the whole run
+// rather than to a single position in the operation sequence.
+type convertSettings struct {
+	quality    int
+	background color.Color
+	gravity    imaging.Gravity
+	filter     imaging.Filter
+	autoOrient bool
+	maxBytes   int         // From -define jpeg:extent
+	maxPixels  int         // From -limit area
+	size       image.Point // From -size, for raw pixel input
+}
+
+// convertCacheSize bounds the -cache-dir directory.
+const convertCacheSize = 512 << 20
+
+// convertJob is a parsed `meh convert` invocation.
+type convertJob struct {
+	input    string
+	rawInput string // "rgba" or "gray" for raw pixel input, from an input prefix
+	output   string
+	format   string
+	dryRun   bool   // Report the output instead of writing it
+	cacheDir string // Reuse outputs stored here for the same input and options
+	options  []string
+	ops      []convertOp
+	settings convertSettings
+}
+
+// cacheKey describes everything about the job but its files that affects
+// the output: the options in order, the raw input layout, and the format.
+func (j *convertJob) cacheKey() string {
+	return fmt.Sprintf("convert %q raw=%s format=%s", j.options, j.rawInput, j.format)
+}
+
+// parseConvertArgs parses a practical subset of ImageMagick convert syntax:
+//
+//	meh convert input [-resize geom] [-trim] [-quality N] [-strip]
+//	    [-rotate degrees] [-background color] [-flatten]
+//	    [-gravity type] [-extent geom] [-filter point|pixel]
+//	    [-auto-orient|+auto-orient] [-define jpeg:extent=size]
+//	    [-limit area pixels] [-size WxH [-depth 8]] [-dry-run]
+//	    [-cache-dir dir] [format:]output
+//
+// Raw pixel input is read from "rgba:file" or "gray:file" with -size, as in
+// ImageMagick.
+//
+// Unlike ImageMagick, images are auto-oriented from EXIF by default
+// (imaging.DefaultAutoOrient) so results match the wasm build; pass
+// +auto-orient to keep the stored pixel orientation. Transparent images
+// written as JPEG are flattened onto the -background color (white by default).
+// -dry-run (or --dry-run) prints what would be written instead of writing it.
+// -cache-dir keeps outputs in a directory, keyed by the input's contents and
+// the options, so converting the same input the same way again skips the work.
+func parseConvertArgs(args []string) (*convertJob, error) {
+	job := &convertJob{settings: convertSettings{background: color.White, autoOrient: imaging.DefaultAutoOrient}}
+
+	var files []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "+auto-orient" {
+			job.settings.autoOrient = false
+			job.options = append(job.options, arg)
+			continue
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			files = append(files, arg)
+			continue
+		}
+		start := i
+
+		value := func() (string, error) {
+			if i+1 >= len(args) {
+				return "", fmt.Errorf("%s requires an argument", arg)
+			}
+			i++
+			return args[i], nil
+		}
+
+		switch arg {
+		case "-resize":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			g, err := imaging.ParseGeometry(v)
+			if err != nil {
+				return nil, err
+			}
+			job.ops = append(job.ops, func(img image.Image, s *convertSettings) (image.Image, error) {
+				w, h := g.Size(img.Bounds().Dx(), img.Bounds().Dy())
+				return imaging.ResizeFilter(img, w, h, s.filter), nil
+			})
+		case "-trim":
+			job.ops = append(job.ops, func(img image.Image, _ *convertSettings) (image.Image, error) {
+				return imaging.Trim(img), nil
+			})
+		case "-rotate":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			// ImageMagick allows a trailing > or < to rotate only landscape/portrait images
+			var cond byte
+			if strings.HasSuffix(v, ">") || strings.HasSuffix(v, "<") {
+				cond, v = v[len(v)-1], v[:len(v)-1]
+			}
+			degrees, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid -rotate %q", args[i])
+			}
+			job.ops = append(job.ops, func(img image.Image, s *convertSettings) (image.Image, error) {
+				b := img.Bounds()
+				if cond == '>' && b.Dx() <= b.Dy() || cond == '<' && b.Dx() >= b.Dy() {
+					return img, nil
+				}
+				return imaging.Rotate(img, degrees, s.background), nil
+			})
+		case "-flatten":
+			job.ops = append(job.ops, func(img image.Image, s *convertSettings) (image.Image, error) {
+				return imaging.Flatten(img, s.background), nil
+			})
+		case "-gravity":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			g, err := imaging.ParseGravity(v)
+			if err != nil {
+				return nil, err
+			}
+			job.settings.gravity = g
+		case "-filter":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			f, err := imaging.ParseFilter(v)
+			if err != nil {
+				return nil, err
+			}
+			job.settings.filter = f
+		case "-extent":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			g, err := imaging.ParseGeometry(v)
+			if err != nil || g.Width <= 0 || g.Height <= 0 || g.Percent {
+				return nil, fmt.Errorf("invalid -extent %q", v)
+			}
+			job.ops = append(job.ops, func(img image.Image, s *convertSettings) (image.Image, error) {
+				return imaging.Extent(img, g.Width, g.Height, s.gravity, image.Pt(g.X, g.Y), s.background), nil
+			})
+		case "-quality":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			q, err := strconv.Atoi(v)
+			if err != nil || q < 1 || q > 100 {
+				return nil, fmt.Errorf("invalid -quality %q", v)
+			}
+			job.settings.quality = q
+		case "-background":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			c, err := imaging.ParseColor(v)
+			if err != nil {
+				return nil, err
+			}
+			job.settings.background = c
+		case "-define":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			name, size, _ := strings.Cut(v, "=")
+			if name != "jpeg:extent" {
+				return nil, fmt.Errorf("unsupported -define %q", v)
+			}
+			n, err := parseByteSize(size)
+			if err != nil {
+				return nil, err
+			}
+			job.settings.maxBytes = n
+		case "-limit":
+			resource, err := value()
+			if err != nil {
+				return nil, err
+			}
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			if resource != "area" {
+				return nil, fmt.Errorf("unsupported -limit %s", resource)
+			}
+			n, err := parsePixelCount(v)
+			if err != nil {
+				return nil, err
+			}
+			job.settings.maxPixels = n
+		case "-size":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			g, err := imaging.ParseGeometry(v)
+			if err != nil || g.Width <= 0 || g.Height <= 0 {
+				return nil, fmt.Errorf("invalid -size %q", v)
+			}
+			job.settings.size = image.Pt(g.Width, g.Height)
+		case "-depth":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			if v != "8" {
+				return nil, fmt.Errorf("unsupported -depth %s, only 8 is supported", v)
+			}
+		case "-auto-orient":
+			job.settings.autoOrient = true
+		case "-dry-run", "--dry-run":
+			job.dryRun = true
+			continue
+		case "-cache-dir":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			job.cacheDir = v
+			continue
+		case "-strip":
+			// Output is always re-encoded without metadata, so there is nothing to strip.
+		default:
+			return nil, fmt.Errorf("unsupported option %s", arg)
+		}
+		job.options = append(job.options, args[start:i+1]...)
+	}
+
+	if len(files) != 2 {
+		return nil, fmt.Errorf("expected an input and an output file, got %d file arguments", len(files))
+	}
+	job.input, job.output = files[0], files[1]
+
+	if f, path, ok := strings.Cut(job.input, ":"); ok && len(f) > 1 {
+		if _, err := imaging.ParsePixelFormat(f); err != nil {
+			return nil, fmt.Errorf("unsupported input format %q", f)
+		}
+		if job.settings.size == (image.Point{}) {
+			return nil, fmt.Errorf("%s: input requires -size", f)
+		}
+		job.rawInput, job.input = strings.ToLower(f), path
+	}
+
+	// An explicit "format:" prefix wins over the file extension, as in ImageMagick
+	if f, path, ok := strings.Cut(job.output, ":"); ok && len(f) > 1 {
+		job.format, job.output = strings.ToLower(f), path
+	} else if job.output == "-" {
+		job.format = "png"
+	} else {
+		format, err := imaging.FormatFromPath(job.output)
+		if err != nil {
+			return nil, err
+		}
+		job.format = format
+	}
+	if job.format == "jpg" {
+		job.format = "jpeg"
+	}
+	if !slices.Contains(imaging.OutputFormats, job.format) {
+		return nil, fmt.Errorf("unsupported output format %q (expected one of %s)", job.format, strings.Join(imaging.OutputFormats, ", "))
+	}
+	return job, nil
+}
+
+// runConvert implements `meh convert`.
+func runConvert(args []string) error {
+	job, err := parseConvertArgs(args)
+	if err != nil {
+		return err
+	}
+	src, err := readFile(job.input)
+	if err != nil {
+		return err
+	}
+
+	// A dry run reports on the image, so it always does the work
+	var store cache.Store
+	var key string
+	if job.cacheDir != "" && !job.dryRun {
+		if store, err = cache.NewDisk(job.cacheDir, convertCacheSize); err != nil {
+			return fmt.Errorf("cache: %w", err)
+		}
+		key = cache.Key(src, job.cacheKey())
+		if data, ok := store.Get(key); ok {
+			return writeFile(job.output, data)
+		}
+	}
+
+	var img image.Image
+	if job.rawInput != "" {
+		img, err = rawImage(src, job.input, job.rawInput, job.settings.size)
+	} else {
+		img, err = decodeImage(src, job.input, job.settings.autoOrient, job.settings.maxPixels)
+	}
+	if err != nil {
+		return err
+	}
+	for _, op := range job.ops {
+		if img, err = op(img, &job.settings); err != nil {
+			return err
+		}
+	}
+
+	if job.format == "jpeg" && !imaging.Opaque(img) {
+		fmt.Fprintln(os.Stderr, "meh: warning: JPEG has no alpha channel; flattening transparency onto the background color")
+		img = imaging.Flatten(img, job.settings.background)
+	}
+
+	var data []byte
+	if job.settings.maxBytes > 0 && job.format == "jpeg" {
+		enc, err := imaging.EncodeMaxBytes(img, job.format, job.settings.maxBytes, false)
+		if err != nil {
+			return fmt.Errorf("failed to encode image: %w", err)
+		}
+		data = enc.Data
+	} else {
+		var buf bytes.Buffer
+		if _, err := imaging.Encode(&buf, img, job.format, job.settings.quality); err != nil {
+			return fmt.Errorf("failed to encode image: %w", err)
+		}
+		data = buf.Bytes()
+	}
+	if job.dryRun {
+		reportDryRun(os.Stdout, job.output, job.format, img, len(data))
+		return nil
+	}
+	if store != nil {
+		if err := store.Set(key, data, 0); err != nil {
+			fmt.Fprintf(os.Stderr, "meh: warning: not cached: %v\n", err)
+		}
+	}
+	return writeFile(job.output, data)
+}
+
+// parseByteSize parses sizes such as "150000", "200kb", or "1.5MB" (binary units).
+func parseByteSize(s string) (int, error) {
+	v := strings.ToLower(strings.TrimSpace(s))
+	mult := 1.0
+	for _, unit := range []struct {
+		suffix string
+		mult   float64
+	}{{"kb", 1 << 10}, {"mb", 1 << 20}, {"k", 1 << 10}, {"m", 1 << 20}, {"b", 1}} {
+		if strings.HasSuffix(v, unit.suffix) {
+			v, mult = strings.TrimSuffix(v, unit.suffix), unit.mult
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int(n * mult), nil
+}
+
+// parsePixelCount parses pixel counts such as "50000000", "50MP", or "1.5GP"
+// (decimal units, as ImageMagick uses for -limit area).
+func parsePixelCount(s string) (int, error) {
+	v := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(s)), "P")
+	mult := 1.0
+	switch {
+	case strings.HasSuffix(v, "K"):
+		mult = 1e3
+	case strings.HasSuffix(v, "M"):
+		mult = 1e6
+	case strings.HasSuffix(v, "G"):
+		mult = 1e9
+	}
+	if mult != 1 {
+		v = v[:len(v)-1]
+	}
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid pixel count %q", s)
+	}
+	return int(n * mult), nil
+}
+
+// decodeFile decodes the image at path, or standard input for "-",
+// optionally rotating it upright according to its EXIF orientation. Images
+// over maxPixels (imaging.DefaultMaxPixels if 0) are rejected before decoding.
+func decodeFile(path string, autoOrient bool, maxPixels int) (image.Image, error) {
+	data, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return decodeImage(data, path, autoOrient, maxPixels)
+}
+
+// decodeImage is decodeFile for data already read from path.
+func decodeImage(data []byte, path string, autoOrient bool, maxPixels int) (image.Image, error) {
+	img, _, err := imaging.Decode(data, maxPixels)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	if autoOrient {
+		img = imaging.ApplyOrientation(img, imaging.Orientation(data))
+	}
+	return img, nil
+}
+
+// rawImage wraps packed raw pixels read from path, of the given format
+// ("rgba" or "gray") and size.
+func rawImage(data []byte, path, format string, size image.Point) (image.Image, error) {
+	pixelFormat, err := imaging.ParsePixelFormat(format)
+	if err != nil {
+		return nil, err
+	}
+	img, err := imaging.FromPixels(data, size.X, size.Y, 0, pixelFormat)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return img, nil
+}
+
+// readFile reads path, or standard input for "-".
+func readFile(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
+// encodeFile encodes img in the format named by path's extension (PNG for
+// standard output) and writes it to path.
+func encodeFile(path string, img image.Image, quality int) error {
+	_, data, err := encodeForPath(path, img, quality)
+	if err != nil {
+		return err
+	}
+	return writeFile(path, data)
+}
+
+// encodeForPath encodes img as encodeFile would for path, returning the
+// format and the encoded bytes.
+func encodeForPath(path string, img image.Image, quality int) (string, []byte, error) {
+	format := "png"
+	if path != "-" {
+		var err error
+		if format, err = imaging.FormatFromPath(path); err != nil {
+			return "", nil, err
+		}
+	}
+	var buf bytes.Buffer
+	if _, err := imaging.Encode(&buf, img, format, quality); err != nil {
+		return "", nil, fmt.Errorf("failed to encode %s: %w", path, err)
+	}
+	return format, buf.Bytes(), nil
+}
+
+// reportDryRun prints, for -dry-run, what writing img to path would produce.
+// The size is exact: the image has already been encoded in memory.
+func reportDryRun(w io.Writer, path, format string, img image.Image, size int) {
+	b := img.Bounds()
+	fmt.Fprintf(w, "%s: %s %dx%d, %s (dry run, not written)\n", path, format, b.Dx(), b.Dy(), formatBytes(size))
+}
+
+// formatBytes formats n in binary units, such as "512 B" or "1.5 KB".
+func formatBytes(n int) string {
+	switch {
+	case n < 1<<10:
+		return fmt.Sprintf("%d B", n)
+	case n < 1<<20:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	}
+}
+
+// writeFile writes data to path, or standard output for "-".
+func writeFile(path string, data []byte) error {
+	if path == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
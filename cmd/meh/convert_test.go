@@ -0,0 +1,395 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"image-resizer/cache"
+	"image-resizer/imaging"
+	"image-resizer/imaging/imagingtest"
+)
+
+func TestParseConvertArgs(t *testing.T) {
+	job, err := parseConvertArgs([]string{"in.png", "-trim", "-resize", "50%", "-quality", "80", "-background", "#000", "-rotate", "90>", "-strip", "out.jpg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.input != "in.png" || job.output != "out.jpg" || job.format != "jpeg" {
+		t.Errorf("unexpected files/format: %+v", job)
+	}
+	if len(job.ops) != 3 {
+		t.Errorf("expected 3 operations, got %d", len(job.ops))
+	}
+	if job.settings.quality != 80 || job.settings.background != (color.NRGBA{0, 0, 0, 255}) {
+		t.Errorf("unexpected settings: %+v", job.settings)
+	}
+
+	job, err = parseConvertArgs([]string{"in.png", "png:out.dat"})
+	if err != nil || job.format != "png" || job.output != "out.dat" {
+		t.Errorf("expected format prefix to select png, got %+v (err=%v)", job, err)
+	}
+}
+
+func TestParseConvertArgs_Errors(t *testing.T) {
+	tests := [][]string{
+		{"in.png"},
+		{"in.png", "out.png", "extra.png"},
+		{"in.png", "-resize"},
+		{"in.png", "-resize", "bad", "out.png"},
+		{"in.png", "-quality", "0", "out.png"},
+		{"in.png", "-rotate", "", "out.png"},
+		{"in.png", "-sepia-tone", "80%", "out.png"},
+		{"in.png", "out.bmp"},
+		{"in.png", "webpp:out"},
+		{"in.png", "bmp:out.png"},
+		{"in.png", "-limit", "memory", "1GB", "out.png"},
+		{"in.png", "-limit", "area", "out.png"},
+		{"in.png", "-gravity", "up", "out.png"},
+		{"in.png", "-extent", "50%", "out.png"},
+	}
+	for _, args := range tests {
+		if _, err := parseConvertArgs(args); err == nil {
+			t.Errorf("parseConvertArgs(%q) expected error", args)
+		}
+	}
+}
+
+func TestRunConvert(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.png")
+	out := filepath.Join(dir, "out.png")
+
+	// 20x10 white image with a 4x2 red block
+	img := image.NewRGBA(image.Rect(0, 0, 20, 10))
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 20; x++ {
+			img.Set(x, y, color.White)
+		}
+	}
+	for y := 4; y < 6; y++ {
+		for x := 8; x < 12; x++ {
+			img.Set(x, y, color.RGBA{255, 0, 0, 255})
+		}
+	}
+	f, err := os.Create(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	png.Encode(f, img)
+	f.Close()
+
+	if err := runConvert([]string{in, "-trim", "-resize", "200%", "-rotate", "90", out}); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err = os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	result, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := result.Bounds(); b.Dx() != 4 || b.Dy() != 8 {
+		t.Errorf("expected 4x8 after trim, 2x resize and rotation, got %dx%d", b.Dx(), b.Dy())
+	}
+}
+
+func TestRunConvert_AutoOrientConformance(t *testing.T) {
+	dir := t.TempDir()
+	for _, tc := range imagingtest.OrientationCases {
+		in := filepath.Join(dir, fmt.Sprintf("in%d.jpg", tc.Orientation))
+		out := filepath.Join(dir, fmt.Sprintf("out%d.png", tc.Orientation))
+		if err := os.WriteFile(in, imagingtest.OrientedJPEG(tc.Orientation), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := runConvert([]string{in, out}); err != nil {
+			t.Fatal(err)
+		}
+
+		f, err := os.Open(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := png.Decode(f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b := result.Bounds()
+		if b.Dx() != tc.Width || b.Dy() != tc.Height {
+			t.Errorf("orientation %d: expected %dx%d, got %dx%d", tc.Orientation, tc.Width, tc.Height, b.Dx(), b.Dy())
+		}
+		if got := imagingtest.MarkerCorner(result); got != tc.Marker {
+			t.Errorf("orientation %d: expected marker at %s, got %q", tc.Orientation, tc.Marker, got)
+		}
+	}
+
+	// +auto-orient keeps the stored orientation
+	in := filepath.Join(dir, "in6.jpg")
+	out := filepath.Join(dir, "raw.png")
+	if err := runConvert([]string{in, "+auto-orient", out}); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	result, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := result.Bounds(); b.Dx() != 40 || b.Dy() != 20 {
+		t.Errorf("expected unrotated 40x20 with +auto-orient, got %dx%d", b.Dx(), b.Dy())
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	for in, want := range map[string]int{"1500": 1500, "200kb": 200 << 10, "1.5MB": 3 << 19, "64k": 64 << 10} {
+		if got, err := parseByteSize(in); err != nil || got != want {
+			t.Errorf("parseByteSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "kb", "-5", "lots"} {
+		if _, err := parseByteSize(in); err == nil {
+			t.Errorf("parseByteSize(%q) expected error", in)
+		}
+	}
+}
+
+func TestParsePixelCount(t *testing.T) {
+	for in, want := range map[string]int{"5000": 5000, "50MP": 50_000_000, "1.5GP": 1_500_000_000, "10k": 10_000} {
+		if got, err := parsePixelCount(in); err != nil || got != want {
+			t.Errorf("parsePixelCount(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "MP", "-1", "many"} {
+		if _, err := parsePixelCount(in); err == nil {
+			t.Errorf("parsePixelCount(%q) expected error", in)
+		}
+	}
+}
+
+func TestRunConvert_LimitArea(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.png")
+	f, err := os.Create(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	png.Encode(f, image.NewGray(image.Rect(0, 0, 100, 100)))
+	f.Close()
+
+	out := filepath.Join(dir, "out.png")
+	if err := runConvert([]string{in, "-limit", "area", "10k", out}); err != nil {
+		t.Errorf("expected 10000 pixels to be within the limit, got %v", err)
+	}
+	var tooLarge *imaging.TooLargeError
+	if err := runConvert([]string{in, "-limit", "area", "9999", out}); !errors.As(err, &tooLarge) {
+		t.Errorf("expected TooLargeError, got %v", err)
+	}
+}
+
+func TestRunConvert_JPEGExtent(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.png")
+	out := filepath.Join(dir, "out.jpg")
+
+	img := image.NewRGBA(image.Rect(0, 0, 200, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 200; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * y), uint8(x + y), uint8(x ^ y), 255})
+		}
+	}
+	f, err := os.Create(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	png.Encode(f, img)
+	f.Close()
+
+	if err := runConvert([]string{in, "-define", "jpeg:extent=6kb", out}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() > 6<<10 {
+		t.Errorf("expected at most %d bytes, got %d", 6<<10, info.Size())
+	}
+}
+
+func TestRunConvert_JPEGMatte(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.png")
+	out := filepath.Join(dir, "out.jpg")
+
+	f, err := os.Create(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	png.Encode(f, image.NewNRGBA(image.Rect(0, 0, 8, 8))) // Fully transparent
+	f.Close()
+
+	if err := runConvert([]string{in, "-background", "#0000ff", out}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := color.RGBAModel.Convert(result.At(4, 4)).(color.RGBA)
+	if c.B < 240 || c.R > 15 || c.G > 15 {
+		t.Errorf("expected transparent pixels flattened onto blue, got %v", c)
+	}
+}
+
+func TestRunConvert_RawInput(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.raw")
+	// 2x1 gray: black, white
+	if err := os.WriteFile(in, []byte{0, 255}, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "out.png")
+	if err := runConvert([]string{"-size", "2x1", "-depth", "8", "gray:" + in, out}); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := color.GrayModel.Convert(img.At(1, 0)).(color.Gray); img.Bounds().Dx() != 2 || c.Y != 255 {
+		t.Errorf("expected 2px wide image ending in white, got %v %v", img.Bounds(), c)
+	}
+
+	for _, args := range [][]string{
+		{"gray:" + in, out},
+		{"-size", "2x1", "cmyk:" + in, out},
+		{"-size", "2x1", "-depth", "16", "gray:" + in, out},
+	} {
+		if err := runConvert(args); err == nil {
+			t.Errorf("expected error for %v", args)
+		}
+	}
+	// The buffer is too short for 2x2
+	if err := runConvert([]string{"-size", "2x2", "gray:" + in, out}); err == nil {
+		t.Error("expected error for a short buffer")
+	}
+}
+
+func TestRunConvert_Extent(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.png")
+	out := filepath.Join(dir, "out.png")
+
+	img := image.NewNRGBA(image.Rect(0, 0, 40, 20))
+	for i := 0; i < len(img.Pix); i += 4 {
+		copy(img.Pix[i:], []uint8{0, 0, 0, 255})
+	}
+	f, err := os.Create(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	png.Encode(f, img)
+	f.Close()
+
+	// The ImageMagick marketplace recipe: fit, then pad onto a white square
+	args := []string{in, "-resize", "85x85", "-background", "white", "-gravity", "north", "-extent", "100x100", out}
+	if err := runConvert(args); err != nil {
+		t.Fatal(err)
+	}
+	f, err = os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	result, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := result.Bounds(); b != image.Rect(0, 0, 100, 100) {
+		t.Fatalf("expected 100x100, got %v", b)
+	}
+	if r, _, _, _ := result.At(50, 10).RGBA(); r != 0 {
+		t.Errorf("expected the image anchored at the top, got %v", result.At(50, 10))
+	}
+	if r, _, _, _ := result.At(50, 90).RGBA(); r != 0xffff {
+		t.Errorf("expected white padding below, got %v", result.At(50, 90))
+	}
+}
+
+func TestRunConvert_CacheDir(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.png")
+	out := filepath.Join(dir, "out.png")
+	cacheDir := filepath.Join(dir, "cache")
+
+	f, err := os.Create(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	png.Encode(f, image.NewNRGBA(image.Rect(0, 0, 20, 10)))
+	f.Close()
+
+	args := []string{in, "-resize", "50%", "-cache-dir", cacheDir, out}
+	if err := runConvert(args); err != nil {
+		t.Fatal(err)
+	}
+	first, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Replace the cached output with a marker to see that the next run uses it
+	store, err := cache.NewDisk(cacheDir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, _ := os.ReadFile(in)
+	job, err := parseConvertArgs(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := cache.Key(src, job.cacheKey())
+	if got, ok := store.Get(key); !ok || !bytes.Equal(got, first) {
+		t.Fatal("expected the output to be cached")
+	}
+	store.Set(key, []byte("cached"), 0)
+	if err := runConvert(args); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(out); string(got) != "cached" {
+		t.Errorf("expected the cached output, got %d bytes", len(got))
+	}
+
+	// Only options that change the output change the key
+	other, _ := parseConvertArgs([]string{in, "-resize", "25%", "-cache-dir", cacheDir, out})
+	elsewhere, _ := parseConvertArgs([]string{in, "-cache-dir", "/tmp/x", "-resize", "50%", filepath.Join(dir, "b.png")})
+	if other.cacheKey() == job.cacheKey() {
+		t.Error("expected different options to key differently")
+	}
+	if elsewhere.cacheKey() != job.cacheKey() {
+		t.Errorf("expected the output path and cache dir not to matter: %q != %q", elsewhere.cacheKey(), job.cacheKey())
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"sort"
+	"strings"
+
+	"image-resizer/imaging"
+	"image-resizer/imaging/gen"
+)
+
+// generateOptions are the flags shared by all `meh generate` kinds.
+type generateOptions struct {
+	width, height int
+	colors        []color.Color
+	direction     gen.Direction
+	cell          int // Checker, stripe, or noise feature size
+	octaves       int
+	seed          int64
+	steps         int // Gray ramp bands, or Siemens star spokes
+}
+
+// generators are the image kinds `meh generate` can produce.
+var generators = map[string]func(o generateOptions) (image.Image, error){
+	"swatch": func(o generateOptions) (image.Image, error) {
+		return gen.Swatch(o.colors, o.width, o.height, o.direction), nil
+	},
+	"gradient": func(o generateOptions) (image.Image, error) {
+		return gen.Gradient(o.colors, o.width, o.height, o.direction), nil
+	},
+	"checkerboard": func(o generateOptions) (image.Image, error) {
+		if len(o.colors) < 2 {
+			return nil, fmt.Errorf("checkerboard needs two colors")
+		}
+		return gen.Checkerboard(o.width, o.height, o.cell, o.colors[0], o.colors[1]), nil
+	},
+	"stripes": func(o generateOptions) (image.Image, error) {
+		return gen.Stripes(o.colors, o.width, o.height, o.cell, o.direction), nil
+	},
+	"noise": func(o generateOptions) (image.Image, error) {
+		return gen.Noise(o.width, o.height, float64(o.cell), o.octaves, o.seed), nil
+	},
+	"smpte": func(o generateOptions) (image.Image, error) {
+		return gen.SMPTEBars(o.width, o.height), nil
+	},
+	"ramp": func(o generateOptions) (image.Image, error) {
+		return gen.GrayRamp(o.width, o.height, o.steps), nil
+	},
+	"gamma": func(o generateOptions) (image.Image, error) {
+		return gen.GammaChart(o.width, o.height), nil
+	},
+	"star": func(o generateOptions) (image.Image, error) {
+		return gen.SiemensStar(o.width, o.height, o.steps), nil
+	},
+	"colorchecker": func(o generateOptions) (image.Image, error) {
+		return gen.ColorChecker(o.width, o.height), nil
+	},
+}
+
+// generatorNames returns the generator kinds, sorted.
+func generatorNames() string {
+	names := make([]string, 0, len(generators))
+	for name := range generators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// runGenerate implements `meh generate`:
+//
+//	meh generate swatch -colors ff0000,00ff00 [-direction vertical] [-size 600x100] output
+//	meh generate gradient -colors ff0000,00ff00 [-direction vertical] [-size 600x100] output
+//	meh generate checkerboard|stripes [-colors a,b,...] [-cell 32] [-direction vertical] [-size WxH] output
+//	meh generate noise [-cell 32] [-octaves 4] [-seed N] [-size WxH] output
+//	meh generate ramp|star [-steps N] [-size WxH] output
+//	meh generate smpte|gamma|colorchecker [-size WxH] output
+func runGenerate(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("expected a kind (%s)", generatorNames())
+	}
+	kind := args[0]
+	build, ok := generators[kind]
+	if !ok {
+		return fmt.Errorf("unknown kind %q (expected %s)", kind, generatorNames())
+	}
+
+	var o generateOptions
+	fs := flag.NewFlagSet("generate "+kind, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	size := fs.String("size", "256x256", "image size as WxH")
+	colors := fs.String("colors", "black,white", "comma-separated colors")
+	direction := fs.String("direction", "horizontal", "horizontal or vertical")
+	fs.IntVar(&o.cell, "cell", 32, "checker, stripe, or noise feature size in pixels")
+	fs.IntVar(&o.octaves, "octaves", 4, "noise detail levels")
+	fs.Int64Var(&o.seed, "seed", 1, "noise seed; the same seed gives the same image")
+	fs.IntVar(&o.steps, "steps", 0, "gray ramp bands (0 for continuous) or star spokes")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("expected one output file, got %d", fs.NArg())
+	}
+	output := fs.Arg(0)
+
+	g, err := imaging.ParseGeometry(*size)
+	if err != nil || g.Width <= 0 || g.Height <= 0 {
+		return fmt.Errorf("invalid -size %q", *size)
+	}
+	o.width, o.height = g.Width, g.Height
+	if o.colors, err = gen.ParseColors(*colors); err != nil {
+		return err
+	}
+	if o.direction, err = gen.ParseDirection(*direction); err != nil {
+		return err
+	}
+
+	img, err := build(o)
+	if err != nil {
+		return err
+	}
+	return encodeFile(output, img, 0)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunGenerate(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.png")
+	if err := runGenerate([]string{"swatch", "-colors", "ff0000,0000ff", "-size", "20x4", out}); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 20 || b.Dy() != 4 {
+		t.Errorf("expected 20x4, got %dx%d", b.Dx(), b.Dy())
+	}
+	if c := color.NRGBAModel.Convert(img.At(19, 0)); c != (color.NRGBA{0, 0, 255, 255}) {
+		t.Errorf("expected blue right band, got %v", c)
+	}
+
+	for _, kind := range []string{"gradient", "checkerboard", "stripes", "noise", "smpte", "ramp", "gamma", "star", "colorchecker"} {
+		if err := runGenerate([]string{kind, "-size", "16x16", "-cell", "4", out}); err != nil {
+			t.Errorf("%s: %v", kind, err)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"plasma", out},
+		{"checkerboard", "-colors", "red", out},
+		{"gradient", "-size", "0x0", out},
+		{"gradient", "-direction", "diagonal", out},
+		{"gradient", "out.bmp"},
+	} {
+		if err := runGenerate(args); err == nil {
+			t.Errorf("expected error for %v", args)
+		}
+	}
+}
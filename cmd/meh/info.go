@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"image-resizer/imaging"
+)
+
+// imageInfo describes one file for `meh info`.
+type imageInfo struct {
+	Path        string `json:"path"`
+	Format      string `json:"format"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	Bytes       int    `json:"bytes"`
+	Orientation int    `json:"orientation"`
+	Alpha       bool   `json:"alpha"`
+}
+
+// runInfo implements `meh info`:
+//
+//	meh info [-json] files...
+//
+// Flags may also follow the files, as in `meh info photo.jpg --json`.
+func runInfo(args []string) error {
+	return info(args, os.Stdout)
+}
+
+// info writes a line per file to w, or with -json an array of imageInfo.
+// Width and height are as displayed, after the EXIF orientation is applied.
+func info(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("info", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	asJSON := fs.Bool("json", false, "print JSON")
+	files, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("expected at least one file")
+	}
+
+	infos := make([]imageInfo, 0, len(files))
+	for _, path := range files {
+		data, err := readFile(path)
+		if err != nil {
+			return err
+		}
+		img, format, err := imaging.Decode(data, 0)
+		if err != nil {
+			return fmt.Errorf("failed to decode %s: %w", path, err)
+		}
+		b := img.Bounds()
+		i := imageInfo{
+			Path:        path,
+			Format:      format,
+			Width:       b.Dx(),
+			Height:      b.Dy(),
+			Bytes:       len(data),
+			Orientation: imaging.Orientation(data),
+			Alpha:       !imaging.Opaque(img),
+		}
+		// Orientations 5-8 swap the axes
+		if i.Orientation >= 5 {
+			i.Width, i.Height = i.Height, i.Width
+		}
+		infos = append(infos, i)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(infos)
+	}
+	for _, i := range infos {
+		alpha := "opaque"
+		if i.Alpha {
+			alpha = "alpha"
+		}
+		fmt.Fprintf(w, "%s: %s %dx%d, %s, orientation %d, %s\n", i.Path, i.Format, i.Width, i.Height, formatBytes(i.Bytes), i.Orientation, alpha)
+	}
+	return nil
+}
+
+// parseInterspersed parses args with fs, allowing flags after positional
+// arguments, and returns the positional arguments. Arguments after "--" are
+// all positional.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		if n := len(args) - len(rest); n > 0 && args[n-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
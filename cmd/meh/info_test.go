@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInfo(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "in.png")
+	img := image.NewNRGBA(image.Rect(0, 0, 30, 20))
+	img.SetNRGBA(0, 0, color.NRGBA{255, 0, 0, 128})
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	png.Encode(f, img)
+	f.Close()
+
+	var out bytes.Buffer
+	if err := info([]string{path, "--json"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	var infos []imageInfo
+	if err := json.Unmarshal(out.Bytes(), &infos); err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 1 {
+		t.Fatalf("expected one result, got %d", len(infos))
+	}
+	if i := infos[0]; i.Format != "png" || i.Width != 30 || i.Height != 20 || !i.Alpha || i.Orientation != 1 || i.Bytes == 0 {
+		t.Errorf("unexpected info %+v", i)
+	}
+
+	out.Reset()
+	if err := info([]string{path}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "png 30x20") {
+		t.Errorf("unexpected output %q", out.String())
+	}
+
+	for _, args := range [][]string{nil, {"-json"}, {filepath.Join(dir, "missing.png")}, {path, "-bogus"}} {
+		if err := info(args, &out); err == nil {
+			t.Errorf("info(%q) expected error", args)
+		}
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.png")
+	f, err := os.Create(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	png.Encode(f, image.NewGray(image.Rect(0, 0, 100, 50)))
+	f.Close()
+
+	out := filepath.Join(dir, "out.jpg")
+	if err := runResize([]string{"-w", "40", "-dry-run", in, out}); err != nil {
+		t.Fatal(err)
+	}
+	if err := runConvert([]string{in, "-resize", "50%", "--dry-run", out}); err != nil {
+		t.Fatal(err)
+	}
+	if err := runBatch([]string{"-in", dir, "-out", filepath.Join(dir, "out"), "-dry-run"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{out, filepath.Join(dir, "out")} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s: expected nothing written, got %v", path, err)
+		}
+	}
+}
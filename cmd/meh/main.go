@@ -0,0 +1,93 @@
+// Command meh runs the imaging pipeline on local files.
+//
+// Usage:
+//
+//	meh convert input [options...] output
+//	meh info [-json] files...
+//	meh pipe [-ops pipeline] [-format format] < input > output
+//	meh resize [-w width] [-h height] [-g geometry] input output
+//	meh trim [-fuzz percent] [-edges top,bottom] [-margin N] [-o dir] [-inspect] files...
+//	meh batch -in dir -out dir [-recursive] [-width N] [options...]
+//	meh passport -spec us -crown X,Y -chin X,Y [-sheet output] input output
+//	meh print [-dpi N] [-size WxH] [-bleed mm] [-marks] [-cmyk] input output.{tiff,pdf}
+//	meh validate -rules amazon|ebay|etsy [-fix dir] files...
+//	meh manifest [-workers N] [-o results.json] manifest.json
+//	meh badge -label text -value text [-color color] output
+//	meh animate countdown|progress [options...] output.gif
+//	meh generate kind [options...] output
+//	meh split [-trim] input left-output right-output
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+const usage = `Usage: meh <command> [arguments]
+
+Commands:
+  convert   ImageMagick-compatible conversion (meh convert in.png -resize 50% out.jpg)
+  info      Format, dimensions, size, and orientation of images (meh info photo.jpg --json)
+  pipe      Filter standard input to standard output (cat in.jpg | meh pipe -ops 'trim|resize:w=200' > out.png)
+  resize    Resize one image (meh resize -w 300 in.png out.jpg)
+  trim      Trim borders in place (meh trim -fuzz 5 *.png), or print the content boxes with -inspect
+  batch     Process a directory in parallel (meh batch -in photos -out thumbs -recursive -width 400)
+  passport  Passport/ID photo from crown and chin positions (meh passport -spec us -crown 410,220 -chin 410,760 in.jpg out.jpg)
+  print     Print-ready TIFF/PDF with bleed and crop marks (meh print -size 148x105 -bleed 3 -marks in.jpg out.pdf)
+  validate  Check marketplace image rules (meh validate -rules amazon -fix fixed/ *.jpg)
+  manifest  Run the renditions listed in a JSON manifest (meh manifest -o results.json images.json)
+  badge     Status badge as SVG or PNG (meh badge -label build -value passing -color green out.svg)
+  animate   Countdown or progress GIF (meh animate countdown -until 2026-12-31T00:00:00Z out.gif)
+  split     Split a double-page scan at its gutter (meh split -trim spread.jpg left.png right.png)
+  generate  Swatches, gradients, patterns, noise, and test charts (meh generate smpte -size 1280x720 out.png)
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "convert":
+		err = runConvert(os.Args[2:])
+	case "info":
+		err = runInfo(os.Args[2:])
+	case "pipe":
+		err = runPipe(os.Args[2:])
+	case "resize":
+		err = runResize(os.Args[2:])
+	case "trim":
+		err = runTrim(os.Args[2:])
+	case "batch":
+		err = runBatch(os.Args[2:])
+	case "passport":
+		err = runPassport(os.Args[2:])
+	case "print":
+		err = runPrint(os.Args[2:])
+	case "validate":
+		err = runValidate(os.Args[2:])
+	case "manifest":
+		err = runManifest(os.Args[2:])
+	case "badge":
+		err = runBadge(os.Args[2:])
+	case "animate":
+		err = runAnimate(os.Args[2:])
+	case "generate":
+		err = runGenerate(os.Args[2:])
+	case "split":
+		err = runSplit(os.Args[2:])
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "meh: unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "meh %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
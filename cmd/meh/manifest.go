@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"image-resizer/imaging"
+	"image-resizer/presets"
+)
+
+// manifest is a `meh manifest` job file. Relative paths are resolved against
+// the manifest's directory.
+//
+//	{
+//	  "presets": {"thumb": {"geometry": "150x150^", "format": "jpeg"}},
+//	  "jobs": [
+//	    {"input": "src/hero.jpg", "outputs": [
+//	      {"path": "dist/hero-800.jpg", "geometry": "800x", "quality": 80},
+//	      {"path": "dist/hero-thumb.jpg", "preset": "thumb"}
+//	    ]}
+//	  ]
+//	}
+type manifest struct {
+	Presets map[string]presets.Preset `json:"presets"`
+	Jobs    []manifestJob             `json:"jobs"`
+}
+
+// manifestJob is one input and the renditions made from it.
+type manifestJob struct {
+	Input   string           `json:"input"`
+	Outputs []manifestOutput `json:"outputs"`
+}
+
+// manifestOutput is one rendition: either a named preset, or inline preset
+// fields. Without a format, the path's extension decides.
+type manifestOutput struct {
+	Path       string `json:"path"`
+	PresetName string `json:"preset,omitempty"`
+	presets.Preset
+}
+
+// manifestResult is reported for every output in the results JSON.
+type manifestResult struct {
+	Input   string `json:"input"`
+	Output  string `json:"output"`
+	Format  string `json:"format,omitempty"`
+	Width   int    `json:"width,omitempty"`
+	Height  int    `json:"height,omitempty"`
+	Bytes   int    `json:"bytes,omitempty"`
+	Quality int    `json:"quality,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// runManifest implements `meh manifest`:
+//
+//	meh manifest [-workers N] [-o results.json] manifest.json
+//
+// Inputs are processed by -workers goroutines, each decoded once for all of
+// its outputs. The results, one per output in manifest order, are written as
+// JSON to -o or standard output; any failed output makes the command fail
+// after the rest have been written.
+func runManifest(args []string) error {
+	fs := flag.NewFlagSet("manifest", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	workers := fs.Int("workers", runtime.NumCPU(), "inputs processed in parallel")
+	resultsPath := fs.String("o", "-", "write the results JSON here")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("expected one manifest file, got %d", fs.NArg())
+	}
+	if *workers < 1 {
+		return fmt.Errorf("-workers must be at least 1")
+	}
+
+	m, err := loadManifest(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	base := filepath.Dir(fs.Arg(0))
+	resolve := func(path string) string {
+		if filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(base, path)
+	}
+
+	results := make([][]manifestResult, len(m.Jobs))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < *workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				results[j] = runManifestJob(m, m.Jobs[j], resolve)
+			}
+		}()
+	}
+	for j := range m.Jobs {
+		jobs <- j
+	}
+	close(jobs)
+	wg.Wait()
+
+	out := struct {
+		Results []manifestResult `json:"results"`
+		Failed  int              `json:"failed"`
+	}{Results: []manifestResult{}}
+	for _, rs := range results {
+		for _, r := range rs {
+			if r.Error != "" {
+				out.Failed++
+			}
+			out.Results = append(out.Results, r)
+		}
+	}
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFile(*resultsPath, append(data, '\n')); err != nil {
+		return err
+	}
+	if out.Failed > 0 {
+		return fmt.Errorf("%d of %d output(s) failed", out.Failed, len(out.Results))
+	}
+	return nil
+}
+
+// loadManifest reads and validates a manifest, so mistakes are reported
+// before any work is done.
+func loadManifest(path string) (*manifest, error) {
+	data, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	for name, p := range m.Presets {
+		if err := p.Validate(); err != nil {
+			return nil, fmt.Errorf("preset %q: %w", name, err)
+		}
+	}
+	for i, job := range m.Jobs {
+		if job.Input == "" || len(job.Outputs) == 0 {
+			return nil, fmt.Errorf("job %d: requires an input and at least one output", i)
+		}
+		for _, o := range job.Outputs {
+			if o.Path == "" {
+				return nil, fmt.Errorf("job %d: output without a path", i)
+			}
+			if _, err := m.preset(o); err != nil {
+				return nil, fmt.Errorf("%s: %w", o.Path, err)
+			}
+		}
+	}
+	return &m, nil
+}
+
+// preset returns the options for o: its named preset or its inline fields,
+// with the format taken from the path when unset.
+func (m *manifest) preset(o manifestOutput) (presets.Preset, error) {
+	p := o.Preset
+	if o.PresetName != "" {
+		named, ok := m.Presets[o.PresetName]
+		if !ok {
+			return p, fmt.Errorf("unknown preset %q", o.PresetName)
+		}
+		p = named
+	}
+	if p.Format == "" {
+		format, err := imaging.FormatFromPath(o.Path)
+		if err != nil {
+			return p, err
+		}
+		p.Format = format
+	}
+	if p.Format == "jpg" {
+		p.Format = "jpeg"
+	}
+	return p, p.Validate()
+}
+
+// runManifestJob decodes one input and writes each of its outputs.
+func runManifestJob(m *manifest, job manifestJob, resolve func(string) string) []manifestResult {
+	results := make([]manifestResult, len(job.Outputs))
+	img, err := decodeFile(resolve(job.Input), imaging.DefaultAutoOrient, 0)
+	for i, o := range job.Outputs {
+		r := &results[i]
+		*r = manifestResult{Input: job.Input, Output: o.Path}
+		if err != nil {
+			r.Error = err.Error()
+			continue
+		}
+		// Validated by loadManifest
+		p, _ := m.preset(o)
+		if err := renderPreset(img, p, resolve(o.Path), r); err != nil {
+			r.Error = err.Error()
+		}
+	}
+	return results
+}
+
+// renderPreset runs p on img and writes the result to path, recording what
+// was written in r.
+func renderPreset(img image.Image, p presets.Preset, path string, r *manifestResult) error {
+	pipeline, err := p.Pipeline()
+	if err != nil {
+		return err
+	}
+	if img, err = pipeline.Apply(img); err != nil {
+		return err
+	}
+
+	format, quality := p.Format, p.Quality
+	if format == "smart" {
+		choice := imaging.ChooseFormat(img)
+		format = choice.Format
+		if quality == 0 {
+			quality = choice.Quality
+		}
+		if choice.Palette != nil {
+			img = imaging.ToPaletted(img, choice.Palette)
+		}
+	}
+
+	var data []byte
+	if p.MaxBytes > 0 {
+		enc, err := imaging.EncodeMaxBytes(img, format, p.MaxBytes, false)
+		if err != nil {
+			return err
+		}
+		data, quality = enc.Data, enc.Quality
+	} else {
+		var buf bytes.Buffer
+		if _, err := imaging.Encode(&buf, img, format, quality); err != nil {
+			return err
+		}
+		data = buf.Bytes()
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if err := writeFile(path, data); err != nil {
+		return err
+	}
+	b := img.Bounds()
+	r.Format, r.Width, r.Height, r.Bytes = format, b.Dx(), b.Dy(), len(data)
+	if format == "jpeg" {
+		if quality <= 0 || quality > 100 {
+			quality = imaging.DefaultQuality
+		}
+		r.Quality = quality
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunManifest(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "src"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.png", "b.png"} {
+		f, err := os.Create(filepath.Join(dir, "src", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		png.Encode(f, image.NewGray(image.Rect(0, 0, 200, 100)))
+		f.Close()
+	}
+
+	manifestPath := filepath.Join(dir, "manifest.json")
+	writeManifest := func(s string) {
+		if err := os.WriteFile(manifestPath, []byte(s), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeManifest(`{
+		"presets": {"thumb": {"geometry": "50x50^", "format": "jpeg", "quality": 70}},
+		"jobs": [
+			{"input": "src/a.png", "outputs": [
+				{"path": "dist/a-100.png", "geometry": "100x"},
+				{"path": "dist/a-thumb.jpg", "preset": "thumb"}
+			]},
+			{"input": "src/b.png", "outputs": [{"path": "dist/b.jpg", "geometry": "40x"}]},
+			{"input": "src/missing.png", "outputs": [{"path": "dist/missing.png"}]}
+		]
+	}`)
+
+	resultsPath := filepath.Join(dir, "results.json")
+	if err := runManifest([]string{"-workers", "2", "-o", resultsPath, manifestPath}); err == nil {
+		t.Error("expected error for the missing input")
+	}
+
+	data, err := os.ReadFile(resultsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out struct {
+		Results []manifestResult
+		Failed  int
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Results) != 4 || out.Failed != 1 {
+		t.Fatalf("expected 4 results with 1 failure, got %+v", out)
+	}
+	want := []manifestResult{
+		{Output: "dist/a-100.png", Format: "png", Width: 100, Height: 50},
+		{Output: "dist/a-thumb.jpg", Format: "jpeg", Width: 100, Height: 50, Quality: 70},
+		{Output: "dist/b.jpg", Format: "jpeg", Width: 40, Height: 20, Quality: 90},
+	}
+	for i, w := range want {
+		r := out.Results[i]
+		if r.Output != w.Output || r.Format != w.Format || r.Width != w.Width || r.Height != w.Height || r.Quality != w.Quality || r.Bytes == 0 {
+			t.Errorf("result %d: expected %+v, got %+v", i, w, r)
+		}
+	}
+	if r := out.Results[3]; r.Error == "" {
+		t.Errorf("expected an error for the missing input, got %+v", r)
+	}
+	if w := pngWidth(t, filepath.Join(dir, "dist", "a-100.png")); w != 100 {
+		t.Errorf("expected a 100px output, got %d", w)
+	}
+
+	// Mistakes are reported before anything is processed
+	for _, bad := range []string{
+		`not json`,
+		`{"jobs": [{"input": "src/a.png"}]}`,
+		`{"jobs": [{"input": "src/a.png", "outputs": [{"path": "x.png", "preset": "nope"}]}]}`,
+		`{"jobs": [{"input": "src/a.png", "outputs": [{"path": "x.bmp"}]}]}`,
+		`{"jobs": [{"input": "src/a.png", "outputs": [{"path": "x.png", "ops": "explode"}]}]}`,
+	} {
+		writeManifest(bad)
+		if err := runManifest([]string{"-o", resultsPath, manifestPath}); err == nil {
+			t.Errorf("expected error for manifest %s", bad)
+		}
+	}
+}
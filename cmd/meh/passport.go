@@ -0,0 +1,101 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"image-resizer/imaging"
+	"image-resizer/passport"
+	"image-resizer/prepress"
+)
+
+// runPassport implements `meh passport`:
+//
+//	meh passport -spec us|uk|eu|canada -crown X,Y -chin X,Y [-dpi N]
+//	    [-sheet output] [-paper WxH] input output
+//
+// The crown and chin are pixel positions in the input; faces are not detected
+// automatically. Background problems are reported on standard error. -sheet
+// also writes a print sheet of copies on -paper (millimeters, 6x4 inch by default).
+func runPassport(args []string) error {
+	fs := flag.NewFlagSet("passport", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	specName := fs.String("spec", "", "photo specification")
+	crown := fs.String("crown", "", "top of the head, as X,Y in input pixels")
+	chin := fs.String("chin", "", "bottom of the chin, as X,Y in input pixels")
+	dpi := fs.Float64("dpi", prepress.DefaultDPI, "output resolution")
+	sheetPath := fs.String("sheet", "", "also write a print sheet here")
+	paper := fs.String("paper", "152x102", "print sheet size in millimeters")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("expected an input and an output file, got %d file arguments", fs.NArg())
+	}
+	spec, err := passport.Lookup(*specName)
+	if err != nil {
+		return err
+	}
+	var face passport.Face
+	if face.Crown, err = parsePoint(*crown); err != nil {
+		return fmt.Errorf("-crown: %w", err)
+	}
+	if face.Chin, err = parsePoint(*chin); err != nil {
+		return fmt.Errorf("-chin: %w", err)
+	}
+	if *dpi <= 0 {
+		return fmt.Errorf("-dpi must be positive")
+	}
+	var paperSize [2]float64
+	if *sheetPath != "" {
+		w, h, ok := strings.Cut(strings.TrimSuffix(strings.ToLower(*paper), "mm"), "x")
+		if ok {
+			if paperSize[0], err = parseMillimeters(w); err == nil {
+				paperSize[1], err = parseMillimeters(h)
+			}
+		}
+		if !ok || err != nil {
+			return fmt.Errorf("invalid -paper %q, want WxH in millimeters", *paper)
+		}
+	}
+
+	img, err := decodeFile(fs.Arg(0), imaging.DefaultAutoOrient, 0)
+	if err != nil {
+		return err
+	}
+	photo, err := passport.Crop(img, face, spec, *dpi)
+	if err != nil {
+		return err
+	}
+	for _, reason := range passport.CheckBackground(photo) {
+		fmt.Fprintf(os.Stderr, "meh: warning: %s\n", reason)
+	}
+	if err := encodeFile(fs.Arg(1), photo, 0); err != nil {
+		return err
+	}
+
+	if *sheetPath == "" {
+		return nil
+	}
+	sheet, err := passport.Sheet(photo, paperSize[0], paperSize[1], *dpi)
+	if err != nil {
+		return err
+	}
+	return encodeFile(*sheetPath, sheet, 0)
+}
+
+// parsePoint parses "X,Y".
+func parsePoint(s string) (image.Point, error) {
+	xs, ys, ok := strings.Cut(s, ",")
+	x, errX := strconv.Atoi(strings.TrimSpace(xs))
+	y, errY := strconv.Atoi(strings.TrimSpace(ys))
+	if !ok || errX != nil || errY != nil {
+		return image.Point{}, fmt.Errorf("invalid point %q, want X,Y", s)
+	}
+	return image.Pt(x, y), nil
+}
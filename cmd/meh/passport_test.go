@@ -0,0 +1,48 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunPassport(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.png")
+	img := image.NewNRGBA(image.Rect(0, 0, 300, 400))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(110, 80, 190, 220), image.NewUniform(color.NRGBA{60, 40, 30, 255}), image.Point{}, draw.Src)
+	f, err := os.Create(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	png.Encode(f, img)
+	f.Close()
+
+	out, sheet := filepath.Join(dir, "photo.png"), filepath.Join(dir, "sheet.png")
+	args := []string{"-spec", "uk", "-crown", "150,80", "-chin", "150,220", "-dpi", "254", "-sheet", sheet, in, out}
+	if err := runPassport(args); err != nil {
+		t.Fatal(err)
+	}
+	if w := pngWidth(t, out); w != 350 {
+		t.Errorf("expected a 35mm (350px) photo, got %d", w)
+	}
+	if w := pngWidth(t, sheet); w != 1520 {
+		t.Errorf("expected a 152mm (1520px) sheet, got %d", w)
+	}
+
+	for _, args := range [][]string{
+		{"-spec", "mars", "-crown", "150,80", "-chin", "150,220", in, out},
+		{"-spec", "uk", "-crown", "150", "-chin", "150,220", in, out},
+		{"-spec", "uk", "-crown", "150,80", "-chin", "150,220", "-sheet", sheet, "-paper", "10", in, out},
+		{"-spec", "uk", "-crown", "150,80", "-chin", "150,220", in},
+	} {
+		if err := runPassport(args); err == nil {
+			t.Errorf("runPassport(%q) expected error", args)
+		}
+	}
+}
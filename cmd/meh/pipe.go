@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"image-resizer/imaging"
+)
+
+// runPipe implements `meh pipe`:
+//
+//	meh pipe [-ops pipeline] [-format format] [-quality N] < input > output
+//
+// It reads an image from standard input, runs an imaging.Pipeline expression
+// such as "trim|resize:w=200" on it, and writes the result to standard output
+// (PNG unless -format says otherwise), so meh composes with shell pipelines.
+// Output is written as it is encoded; if encoding fails partway, what was
+// already written is left incomplete and the command fails.
+func runPipe(args []string) error {
+	return pipe(args, os.Stdin, os.Stdout)
+}
+
+// pipe is runPipe with its input and output as parameters, for tests.
+func pipe(args []string, r io.Reader, w io.Writer) error {
+	fs := flag.NewFlagSet("pipe", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	ops := fs.String("ops", "", "imaging pipeline, e.g. trim|resize:w=200")
+	format := fs.String("format", "png", "output format (png, jpeg, gif, rgba, npy, csv)")
+	quality := fs.Int("quality", 0, "JPEG quality (1-100)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments %q; input is read from standard input", fs.Args())
+	}
+	outFormat, err := imaging.FormatFromPath("." + *format)
+	if err != nil {
+		return err
+	}
+	if *quality < 0 || *quality > 100 {
+		return fmt.Errorf("invalid -quality %d", *quality)
+	}
+	p, err := imaging.ParsePipeline(*ops)
+	if err != nil {
+		return err
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	img, _, err := imaging.Decode(data, 0)
+	if err != nil {
+		return fmt.Errorf("failed to decode standard input: %w", err)
+	}
+	if imaging.DefaultAutoOrient {
+		img = imaging.ApplyOrientation(img, imaging.Orientation(data))
+	}
+	if img, err = p.Apply(img); err != nil {
+		return err
+	}
+
+	// Stream the encoder's output rather than buffering the whole file, so the
+	// next command in the pipeline starts receiving bytes while encoding runs
+	bw := bufio.NewWriter(w)
+	if _, err := imaging.Encode(bw, img, outFormat, *quality); err != nil {
+		return fmt.Errorf("failed to encode image: %w", err)
+	}
+	return bw.Flush()
+}
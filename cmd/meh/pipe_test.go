@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestPipe(t *testing.T) {
+	// White 40x40 with a 10x10 black square
+	img := image.NewGray(image.Rect(0, 0, 40, 40))
+	for i := range img.Pix {
+		img.Pix[i] = 255
+	}
+	for y := 10; y < 20; y++ {
+		for x := 10; x < 20; x++ {
+			img.SetGray(x, y, color.Gray{0})
+		}
+	}
+	var in bytes.Buffer
+	png.Encode(&in, img)
+
+	var out bytes.Buffer
+	if err := pipe([]string{"-ops", "trim|resize:w=5"}, bytes.NewReader(in.Bytes()), &out); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := png.DecodeConfig(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Width != 5 || cfg.Height != 5 {
+		t.Errorf("expected 5x5, got %dx%d", cfg.Width, cfg.Height)
+	}
+
+	out.Reset()
+	if err := pipe([]string{"-format", "jpg"}, bytes.NewReader(in.Bytes()), &out); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jpeg.DecodeConfig(&out); err != nil {
+		t.Errorf("expected JPEG output: %v", err)
+	}
+
+	for _, args := range [][]string{
+		{"-ops", "explode"},
+		{"-format", "bmp"},
+		{"in.png"},
+	} {
+		if err := pipe(args, bytes.NewReader(in.Bytes()), &out); err == nil {
+			t.Errorf("pipe(%q) expected error", args)
+		}
+	}
+	if err := pipe(nil, strings.NewReader("not an image"), &out); err == nil {
+		t.Error("expected error for invalid input")
+	}
+}
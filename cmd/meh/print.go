@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"image-resizer/imaging"
+	"image-resizer/prepress"
+)
+
+// runPrint implements `meh print`:
+//
+//	meh print [-dpi N] [-size WxH] [-bleed mm] [-marks] [-cmyk] input output.{tiff,pdf}
+//
+// It lays the image out for a print shop with prepress.Prepare: -size is the
+// trim size in millimeters (the image is scaled to cover it), -bleed extends
+// the image past the trim edge, and -marks adds crop marks. The output TIFF or
+// PDF records -dpi.
+func runPrint(args []string) error {
+	fs := flag.NewFlagSet("print", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	dpi := fs.Float64("dpi", prepress.DefaultDPI, "output resolution")
+	size := fs.String("size", "", "trim size in millimeters, e.g. 148x105")
+	bleed := fs.Float64("bleed", 0, "bleed in millimeters")
+	marks := fs.Bool("marks", false, "add crop marks")
+	cmyk := fs.Bool("cmyk", false, "convert to CMYK (no ICC profile)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("expected an input and an output file, got %d file arguments", fs.NArg())
+	}
+	if *dpi <= 0 {
+		return fmt.Errorf("-dpi must be positive")
+	}
+	output := fs.Arg(1)
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(output)), ".")
+	if format != "tiff" && format != "tif" && format != "pdf" {
+		return fmt.Errorf("unsupported print format %q, use .tiff or .pdf", filepath.Ext(output))
+	}
+
+	opts := prepress.Options{DPI: *dpi, Bleed: *bleed, CropMarks: *marks, CMYK: *cmyk}
+	if *size != "" {
+		w, h, ok := strings.Cut(strings.TrimSuffix(strings.ToLower(*size), "mm"), "x")
+		var err error
+		if ok {
+			if opts.Size[0], err = parseMillimeters(w); err == nil {
+				opts.Size[1], err = parseMillimeters(h)
+			}
+		}
+		if !ok || err != nil {
+			return fmt.Errorf("invalid -size %q, want WxH in millimeters", *size)
+		}
+	}
+
+	img, err := decodeFile(fs.Arg(0), imaging.DefaultAutoOrient, 0)
+	if err != nil {
+		return err
+	}
+	if img, err = prepress.Prepare(img, opts); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := prepress.Encode(&buf, img, format, *dpi); err != nil {
+		return fmt.Errorf("failed to encode %s: %w", output, err)
+	}
+	return writeFile(output, buf.Bytes())
+}
+
+// parseMillimeters parses a positive length in millimeters.
+func parseMillimeters(s string) (float64, error) {
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("invalid length %q", s)
+	}
+	return v, nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunPrint(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.png")
+	f, err := os.Create(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	png.Encode(f, image.NewNRGBA(image.Rect(0, 0, 60, 40)))
+	f.Close()
+
+	out := filepath.Join(dir, "out.pdf")
+	if err := runPrint([]string{"-dpi", "254", "-size", "30x20mm", "-bleed", "2", "-marks", "-cmyk", in, out}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 30x20mm plus (2 + 1.5 + 5)mm margins is 47x37mm: 470x370 pixels at 254 dpi
+	if !bytes.Contains(data, []byte("/Width 470 /Height 370 /ColorSpace /DeviceCMYK")) {
+		t.Error("expected a 470x370 CMYK image in the PDF")
+	}
+
+	if err := runPrint([]string{in, filepath.Join(dir, "out.tiff")}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range [][]string{
+		{in, filepath.Join(dir, "out.png")},
+		{"-size", "30", in, out},
+		{"-size", "axb", in, out},
+		{"-dpi", "0", in, out},
+		{in},
+	} {
+		if err := runPrint(args); err == nil {
+			t.Errorf("runPrint(%q) expected error", args)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunResize(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.png")
+	f, err := os.Create(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	png.Encode(f, image.NewNRGBA(image.Rect(0, 0, 40, 20)))
+	f.Close()
+
+	out := filepath.Join(dir, "out.png")
+	tests := []struct {
+		args  []string
+		width int
+	}{
+		{[]string{"-w", "10"}, 10},
+		{[]string{"-h", "5"}, 10},
+		{[]string{"-w", "7", "-h", "7"}, 7},
+		{[]string{"-g", "50%"}, 20},
+		{[]string{"-g", "400%", "-filter", "pixel"}, 160},
+	}
+	for _, tc := range tests {
+		if err := runResize(append(tc.args, in, out)); err != nil {
+			t.Fatalf("%v: %v", tc.args, err)
+		}
+		if w := pngWidth(t, out); w != tc.width {
+			t.Errorf("%v: expected width %d, got %d", tc.args, tc.width, w)
+		}
+	}
+
+	for _, args := range [][]string{
+		{in, out},
+		{"-w", "10", in},
+		{"-g", "bogus", in, out},
+		{"-w", "10", "-quality", "101", in, out},
+		{"-w", "10", "-filter", "lanczos", in, out},
+		{"-w", "10", in, filepath.Join(dir, "out.bmp")},
+	} {
+		if err := runResize(args); err == nil {
+			t.Errorf("runResize(%q) expected error", args)
+		}
+	}
+}
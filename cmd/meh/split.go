@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"io"
+	"os"
+
+	"image-resizer/imaging"
+)
+
+// runSplit implements `meh split`:
+//
+//	meh split [-trim] [-fuzz percent] [-skip-blank] [-blank-threshold score]
+//	    input left-output right-output
+//
+// It splits a double-page scan at its gutter, optionally trimming each page's
+// borders with imaging.TrimEdges. With -skip-blank, pages imaging.IsBlank
+// reports as blank are not written and are listed on standard error instead.
+func runSplit(args []string) error {
+	fs := flag.NewFlagSet("split", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	trim := fs.Bool("trim", false, "trim each page's borders")
+	fuzz := fs.Float64("fuzz", 10, "trim color tolerance in percent")
+	skipBlank := fs.Bool("skip-blank", false, "don't write blank pages")
+	threshold := fs.Float64("blank-threshold", imaging.DefaultBlankThreshold, "blank score below which a page is blank")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 3 {
+		return fmt.Errorf("expected an input and two output files, got %d file arguments", fs.NArg())
+	}
+
+	img, err := decodeFile(fs.Arg(0), imaging.DefaultAutoOrient, 0)
+	if err != nil {
+		return err
+	}
+	left, right := imaging.SplitPages(img)
+	var skipped int
+	for i, page := range []image.Image{left, right} {
+		output := fs.Arg(i + 1)
+		// Check before trimming, which would shrink a blank page to its noise
+		if *skipBlank && imaging.IsBlank(page, *threshold) {
+			fmt.Fprintf(os.Stderr, "meh: skipped blank page %s (score %.2f)\n", output, imaging.BlankScore(page))
+			skipped++
+			continue
+		}
+		if *trim {
+			page = imaging.TrimEdges(page, imaging.EdgeColors{}, *fuzz)
+		}
+		if err := encodeFile(output, page, 0); err != nil {
+			return err
+		}
+	}
+	if skipped > 0 {
+		fmt.Fprintf(os.Stderr, "meh: wrote %d page(s), skipped %d blank\n", 2-skipped, skipped)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunSplit(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "spread.png")
+
+	// White 60x20 spread with a dark gutter at x=30 and a black mark on each page
+	img := image.NewGray(image.Rect(0, 0, 60, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 60; x++ {
+			v := uint8(255)
+			if x == 30 || x == 31 {
+				v = 40
+			}
+			img.SetGray(x, y, color.Gray{v})
+		}
+	}
+	for _, x := range []int{10, 45} {
+		img.SetGray(x, 10, color.Gray{0})
+	}
+	f, err := os.Create(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	png.Encode(f, img)
+	f.Close()
+
+	left, right := filepath.Join(dir, "left.png"), filepath.Join(dir, "right.png")
+	if err := runSplit([]string{in, left, right}); err != nil {
+		t.Fatal(err)
+	}
+	if w := pngWidth(t, left); w != 30 {
+		t.Errorf("expected 30px left page, got %d", w)
+	}
+
+	if err := runSplit([]string{"-trim", in, left, right}); err != nil {
+		t.Fatal(err)
+	}
+	if w := pngWidth(t, left); w != 1 {
+		t.Errorf("expected the trimmed left page to be the 1px mark, got %d", w)
+	}
+	if w := pngWidth(t, right); w != 1 {
+		t.Errorf("expected the trimmed right page to be the 1px mark, got %d", w)
+	}
+
+	// A spread with no gutter shadow and an empty right page; only the left
+	// page is written
+	for i := range img.Pix {
+		img.Pix[i] = 255
+	}
+	img.SetGray(10, 10, color.Gray{0})
+	f, err = os.Create(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	png.Encode(f, img)
+	f.Close()
+	os.Remove(right)
+	if err := runSplit([]string{"-skip-blank", in, left, right}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(right); !os.IsNotExist(err) {
+		t.Errorf("expected the blank right page to be skipped, got %v", err)
+	}
+	if _, err := os.Stat(left); err != nil {
+		t.Errorf("expected the left page to be written: %v", err)
+	}
+
+	if err := runSplit([]string{in, left}); err == nil {
+		t.Error("expected error for a missing output")
+	}
+}
+
+func pngWidth(t *testing.T, path string) int {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	cfg, err := png.DecodeConfig(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg.Width
+}
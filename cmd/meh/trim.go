@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"image-resizer/imaging"
+)
+
+// trimBox is the content rectangle `meh trim -inspect` reports for a file,
+// in pixels of the upright image.
+type trimBox struct {
+	Path        string `json:"path"`
+	X           int    `json:"x"`
+	Y           int    `json:"y"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	ImageWidth  int    `json:"imageWidth"`
+	ImageHeight int    `json:"imageHeight"`
+}
+
+// runTrim implements `meh trim`:
+//
+//	meh trim [-fuzz percent] [-edges top,bottom] [-margin N] [-o dir] [-inspect] files...
+//
+// Like ImageMagick's mogrify, each file is trimmed and rewritten in place in
+// its own format, unless -o names a directory to write the results into.
+// -edges trims only the named edges, and -margin keeps N pixels of border
+// around the content.
+// With -inspect no files are written; the content rectangles are printed as
+// a JSON array of trimBox instead.
+func runTrim(args []string) error {
+	return trim(args, os.Stdout)
+}
+
+// trim runs `meh trim`, writing -inspect output to w.
+func trim(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("trim", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fuzz := fs.Float64("fuzz", 0, "border color tolerance in percent")
+	outDir := fs.String("o", "", "write results to this directory instead of in place")
+	edges := fs.String("edges", "all", "edges to trim, such as top,bottom")
+	margin := fs.Int("margin", 0, "pixels of border to keep around the content")
+	inspect := fs.Bool("inspect", false, "print the content rectangles as JSON instead of writing files")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("expected at least one file")
+	}
+	if *fuzz < 0 || *fuzz > 100 {
+		return fmt.Errorf("-fuzz must be between 0 and 100")
+	}
+	sides, err := imaging.ParseSides(*edges)
+	if err != nil {
+		return fmt.Errorf("-edges: %w", err)
+	}
+	if *margin < 0 {
+		return fmt.Errorf("-margin must not be negative")
+	}
+
+	if *inspect {
+		boxes := make([]trimBox, 0, fs.NArg())
+		for _, path := range fs.Args() {
+			img, err := decodeFile(path, imaging.DefaultAutoOrient, 0)
+			if err != nil {
+				return err
+			}
+			b := img.Bounds()
+			r := imaging.LimitTrim(b, imaging.TrimRectFuzz(img, *fuzz), sides, *margin).Sub(b.Min)
+			boxes = append(boxes, trimBox{
+				Path: path, X: r.Min.X, Y: r.Min.Y, Width: r.Dx(), Height: r.Dy(),
+				ImageWidth: b.Dx(), ImageHeight: b.Dy(),
+			})
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(boxes)
+	}
+
+	for _, path := range fs.Args() {
+		// Check the output format before doing any work
+		if _, err := imaging.FormatFromPath(path); err != nil {
+			return err
+		}
+		img, err := decodeFile(path, imaging.DefaultAutoOrient, 0)
+		if err != nil {
+			return err
+		}
+		output := path
+		if *outDir != "" {
+			output = filepath.Join(*outDir, filepath.Base(path))
+		}
+		if err := encodeFile(output, imaging.TrimSides(img, *fuzz, sides, *margin), 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
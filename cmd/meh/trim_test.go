@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunTrim(t *testing.T) {
+	dir := t.TempDir()
+	var files []string
+	for _, name := range []string{"a.png", "b.png"} {
+		// White 20x20 with a 4x4 black square
+		img := image.NewGray(image.Rect(0, 0, 20, 20))
+		for i := range img.Pix {
+			img.Pix[i] = 255
+		}
+		for y := 8; y < 12; y++ {
+			for x := 6; x < 10; x++ {
+				img.SetGray(x, y, color.Gray{0})
+			}
+		}
+		path := filepath.Join(dir, name)
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		png.Encode(f, img)
+		f.Close()
+		files = append(files, path)
+	}
+
+	outDir := t.TempDir()
+	if err := runTrim(append([]string{"-o", outDir}, files[0])); err != nil {
+		t.Fatal(err)
+	}
+	if w := pngWidth(t, filepath.Join(outDir, "a.png")); w != 4 {
+		t.Errorf("expected a 4px wide result in the output directory, got %d", w)
+	}
+	if w := pngWidth(t, files[0]); w != 20 {
+		t.Errorf("expected -o to leave the input alone, got width %d", w)
+	}
+
+	if err := runTrim(files); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range files {
+		if w := pngWidth(t, path); w != 4 {
+			t.Errorf("%s: expected trimmed in place to 4px, got %d", path, w)
+		}
+	}
+
+	if err := runTrim(nil); err == nil {
+		t.Error("expected error without files")
+	}
+	if err := runTrim([]string{"-fuzz", "150", files[0]}); err == nil {
+		t.Error("expected error for an out-of-range fuzz")
+	}
+}
+
+func TestTrimInspect(t *testing.T) {
+	// White 20x10 with a 4x3 black box at (6, 2)
+	img := image.NewGray(image.Rect(0, 0, 20, 10))
+	for i := range img.Pix {
+		img.Pix[i] = 255
+	}
+	for y := 2; y < 5; y++ {
+		for x := 6; x < 10; x++ {
+			img.SetGray(x, y, color.Gray{0})
+		}
+	}
+	path := filepath.Join(t.TempDir(), "a.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	png.Encode(f, img)
+	f.Close()
+
+	var out bytes.Buffer
+	if err := trim([]string{"-inspect", path}, &out); err != nil {
+		t.Fatal(err)
+	}
+	var boxes []trimBox
+	if err := json.Unmarshal(out.Bytes(), &boxes); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out.String(), err)
+	}
+	want := trimBox{Path: path, X: 6, Y: 2, Width: 4, Height: 3, ImageWidth: 20, ImageHeight: 10}
+	if len(boxes) != 1 || boxes[0] != want {
+		t.Errorf("expected %+v, got %+v", want, boxes)
+	}
+
+	// Only the left and right edges, with a 1px margin
+	out.Reset()
+	if err := trim([]string{"-inspect", "-edges", "left,right", "-margin", "1", path}, &out); err != nil {
+		t.Fatal(err)
+	}
+	boxes = nil
+	if err := json.Unmarshal(out.Bytes(), &boxes); err != nil {
+		t.Fatal(err)
+	}
+	want = trimBox{Path: path, X: 5, Y: 0, Width: 6, Height: 10, ImageWidth: 20, ImageHeight: 10}
+	if len(boxes) != 1 || boxes[0] != want {
+		t.Errorf("expected %+v, got %+v", want, boxes)
+	}
+	if err := trim([]string{"-edges", "middle", path}, &out); err == nil {
+		t.Error("expected error for an unknown edge")
+	}
+	if w := pngWidth(t, path); w != 20 {
+		t.Errorf("expected -inspect to leave the file alone, got width %d", w)
+	}
+}
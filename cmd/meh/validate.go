@@ -0,0 +1,87 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"image-resizer/imaging"
+	"image-resizer/marketplace"
+)
+
+// runValidate implements `meh validate`:
+//
+//	meh validate -rules amazon|ebay|etsy [-fix dir] files...
+//
+// Each file is checked against the marketplace's image rules and reported as
+// PASS or FAIL with reasons on standard output. With -fix, failing images are
+// run through the rules' fix pipeline, written to dir, and checked again. The
+// command fails if any image (after fixing) still breaks the rules.
+func runValidate(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	rulesName := fs.String("rules", "", "marketplace rules to check against")
+	fixDir := fs.String("fix", "", "write fixed copies of failing images to this directory")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("expected at least one file")
+	}
+	rules, err := marketplace.Lookup(*rulesName)
+	if err != nil {
+		return err
+	}
+
+	var failed int
+	for _, path := range fs.Args() {
+		img, err := decodeFile(path, imaging.DefaultAutoOrient, 0)
+		if err != nil {
+			return err
+		}
+		res := marketplace.Check(img, rules)
+		printValidation(os.Stdout, path, res)
+		if res.Pass {
+			continue
+		}
+		if *fixDir == "" {
+			failed++
+			continue
+		}
+
+		fixed, err := marketplace.Fix(img, rules)
+		if err != nil {
+			return err
+		}
+		output := filepath.Join(*fixDir, filepath.Base(path))
+		if _, err := imaging.FormatFromPath(output); err != nil {
+			output += ".png"
+		}
+		if err := encodeFile(output, fixed, 0); err != nil {
+			return err
+		}
+		res = marketplace.Check(fixed, rules)
+		printValidation(os.Stdout, output, res)
+		if !res.Pass {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d image(s) do not meet the %s rules", failed, fs.NArg(), rules.Name)
+	}
+	return nil
+}
+
+// printValidation writes one validation result.
+func printValidation(w io.Writer, path string, res marketplace.Result) {
+	if res.Pass {
+		fmt.Fprintf(w, "%s: PASS\n", path)
+		return
+	}
+	fmt.Fprintf(w, "%s: FAIL\n", path)
+	for _, reason := range res.Reasons {
+		fmt.Fprintf(w, "  - %s\n", reason)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunValidate(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "product.png")
+
+	// A small product on white that doesn't fill the frame
+	img := image.NewNRGBA(image.Rect(0, 0, 400, 300))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(20, 20, 120, 100), image.NewUniform(color.NRGBA{0, 0, 200, 255}), image.Point{}, draw.Src)
+	f, err := os.Create(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	png.Encode(f, img)
+	f.Close()
+
+	if err := runValidate([]string{"-rules", "amazon", in}); err == nil {
+		t.Error("expected the image to fail the amazon rules")
+	}
+
+	fixDir := t.TempDir()
+	if err := runValidate([]string{"-rules", "amazon", "-fix", fixDir, in}); err != nil {
+		t.Fatalf("expected the fixed image to pass: %v", err)
+	}
+	if w := pngWidth(t, filepath.Join(fixDir, "product.png")); w != 1000 {
+		t.Errorf("expected a 1000px fixed image, got %d", w)
+	}
+
+	if err := runValidate([]string{"-rules", "craigslist", in}); err == nil {
+		t.Error("expected error for unknown rules")
+	}
+	if err := runValidate([]string{"-rules", "amazon"}); err == nil {
+		t.Error("expected error without files")
+	}
+}
@@ -0,0 +1,581 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"sort"
+	"strings"
+	"syscall/js"
+
+	"image-resizer/cache"
+	"image-resizer/imaging"
+)
+
+// processOptions are the settings of one processImage call.
+type processOptions struct {
+	width, height  int
+	geometry       *imaging.Geometry // Takes precedence over width and height
+	crop           image.Rectangle   // Relative to the upright image; empty for none
+	redact         []image.Rectangle // Regions of the upright image to obscure
+	redactMode     imaging.RedactMode
+	trim           bool
+	trimFuzz       float64
+	trimSides      imaging.Sides
+	trimMargin     int
+	format         string
+	quality        int
+	transparentBg  bool
+	replaceBg      color.Color // Replaces the removed background when set
+	feather        float64     // Edge feather radius for background removal
+	keyColor       color.Color // Chroma key color to remove when set
+	pad            imaging.Insets
+	padColor       color.Color
+	radius         float64 // Corner radius in output pixels
+	shape          imaging.Shape
+	mask           []byte // Encoded alpha mask image; nil for none
+	grayscale      bool
+	sepia          bool
+	invert         bool
+	whiteBalance   bool // Automatic, by wbMethod
+	wbMethod       imaging.WhiteBalance
+	temp, tint     float64
+	autoContrast   bool
+	contrastClip   float64 // Percent clipped at each end by autoContrast
+	equalize       bool
+	levels         imaging.LevelsOptions
+	curve          imaging.Curve
+	adjust         imaging.AdjustOptions
+	hsl            imaging.HSLOptions
+	blur           float64 // Gaussian sigma; zero for none
+	autoOrient     bool
+	documentOrient bool
+	maxBytes       int
+	downscale      bool
+	pipeline       *imaging.Pipeline
+	matte          color.NRGBA
+	timings        bool
+	progress       js.Value       // Called with (stage, percent); undefined when not given
+	policy         imaging.Policy // In force when the options were parsed
+}
+
+// positionalOptions names processImage's legacy positional arguments after
+// the image, in order. The list is frozen: options added since are only
+// accepted by name, in objectOptions.
+var positionalOptions = []string{
+	"width", "height", "trim", "format", "quality", "transparentBg", "geometry",
+	"autoOrient", "maxBytes", "downscale", "ops", "preset", "matte", "timings",
+}
+
+// objectOptions names the options that may only be passed in an options object.
+var objectOptions = []string{
+	"progress", "cropX", "cropY", "cropW", "cropH", "feather",
+	"keyColor", "trimEdges", "trimMargin", "pad", "padColor",
+	"radius", "shape", "mask", "grayscale", "sepia", "invert",
+	"brightness", "contrast", "gamma", "hue", "saturation", "vibrance",
+	"blur", "redact", "redactMode", "autoContrast", "equalize",
+	"wb", "temp", "tint", "levels", "curve",
+}
+
+// optionsFromObject collects the properties of a JavaScript options object,
+// rejecting unknown names so that typos aren't silently ignored.
+func optionsFromObject(obj js.Value) (map[string]js.Value, error) {
+	known := map[string]bool{}
+	for _, names := range [][]string{positionalOptions, objectOptions} {
+		for _, name := range names {
+			known[name] = true
+		}
+	}
+	keys := js.Global().Get("Object").Call("keys", obj)
+	values := map[string]js.Value{}
+	var unknown []string
+	for i := 0; i < keys.Length(); i++ {
+		name := keys.Index(i).String()
+		if !known[name] {
+			unknown = append(unknown, name)
+			continue
+		}
+		values[name] = obj.Get(name)
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown option(s) %s", strings.Join(unknown, ", "))
+	}
+	return values, nil
+}
+
+// optionsFromArgs collects the legacy positional arguments by name.
+func optionsFromArgs(args []js.Value) map[string]js.Value {
+	values := map[string]js.Value{}
+	for i, arg := range args {
+		if i < len(positionalOptions) {
+			values[positionalOptions[i]] = arg
+		}
+	}
+	return values
+}
+
+// parseOptions validates the named option values. Missing, null, and
+// undefined values take their defaults.
+func parseOptions(values map[string]js.Value) (processOptions, error) {
+	opts := processOptions{
+		trimSides:  imaging.AllSides,
+		quality:    imaging.DefaultQuality,
+		autoOrient: imaging.DefaultAutoOrient,
+		pipeline:   &imaging.Pipeline{},
+		matte:      color.NRGBAModel.Convert(imaging.DefaultMatte).(color.NRGBA),
+	}
+	get := func(name string) (js.Value, bool) {
+		v, ok := values[name]
+		return v, ok && !v.IsUndefined() && !v.IsNull()
+	}
+	typeErr := func(name, want string, v js.Value) error {
+		return fmt.Errorf("%s must be %s, got %s", name, want, v.Type())
+	}
+	number := func(name string, dst *int) error {
+		v, ok := get(name)
+		if !ok {
+			return nil
+		}
+		if v.Type() != js.TypeNumber {
+			return typeErr(name, "a number", v)
+		}
+		*dst = v.Int()
+		return nil
+	}
+	str := func(name string) (string, error) {
+		v, ok := get(name)
+		if !ok {
+			return "", nil
+		}
+		if v.Type() != js.TypeString {
+			return "", typeErr(name, "a string", v)
+		}
+		return v.String(), nil
+	}
+	boolean := func(name string, dst *bool) error {
+		v, ok := get(name)
+		if !ok {
+			return nil
+		}
+		if v.Type() != js.TypeBoolean {
+			return typeErr(name, "a boolean", v)
+		}
+		*dst = v.Bool()
+		return nil
+	}
+
+	for _, err := range []error{
+		number("width", &opts.width),
+		number("height", &opts.height),
+		number("quality", &opts.quality),
+		number("maxBytes", &opts.maxBytes),
+		boolean("downscale", &opts.downscale),
+		boolean("timings", &opts.timings),
+		boolean("grayscale", &opts.grayscale),
+		boolean("sepia", &opts.sepia),
+		boolean("invert", &opts.invert),
+		boolean("equalize", &opts.equalize),
+	} {
+		if err != nil {
+			return opts, err
+		}
+	}
+	if opts.width < 0 || opts.height < 0 {
+		return opts, fmt.Errorf("width and height must not be negative")
+	}
+
+	// A crop needs all four of cropX, cropY, cropW, and cropH
+	var crop [4]int
+	for i, name := range []string{"cropX", "cropY", "cropW", "cropH"} {
+		if err := number(name, &crop[i]); err != nil {
+			return opts, err
+		}
+		if crop[i] < 0 {
+			return opts, fmt.Errorf("%s must not be negative", name)
+		}
+	}
+	if crop != [4]int{} {
+		if crop[2] == 0 || crop[3] == 0 {
+			return opts, fmt.Errorf("cropW and cropH must be positive")
+		}
+		if err := imaging.CurrentPolicy().CheckOp("crop"); err != nil {
+			return opts, err
+		}
+		opts.crop = image.Rect(crop[0], crop[1], crop[0]+crop[2], crop[1]+crop[3])
+	}
+	_, qualitySet := get("quality")
+	if opts.quality <= 0 || opts.quality > 100 {
+		opts.quality, qualitySet = imaging.DefaultQuality, false
+	}
+
+	var err error
+	if opts.format, err = str("format"); err != nil {
+		return opts, err
+	}
+	formatSet := opts.format != ""
+	if !formatSet {
+		opts.format = "png"
+	}
+
+	// Trim takes a bool, or a number: the border color tolerance in percent
+	if v, ok := get("trim"); ok {
+		switch v.Type() {
+		case js.TypeBoolean:
+			opts.trim = v.Bool()
+		case js.TypeNumber:
+			opts.trim, opts.trimFuzz = true, v.Float()
+			if opts.trimFuzz < 0 || opts.trimFuzz > 100 {
+				return opts, fmt.Errorf("invalid trim fuzz %g", opts.trimFuzz)
+			}
+		default:
+			return opts, typeErr("trim", "a boolean or a number", v)
+		}
+	}
+	if s, err := str("trimEdges"); err != nil {
+		return opts, err
+	} else if s != "" {
+		if opts.trimSides, err = imaging.ParseSides(s); err != nil {
+			return opts, fmt.Errorf("invalid trimEdges: %w", err)
+		}
+	}
+	if err := number("trimMargin", &opts.trimMargin); err != nil {
+		return opts, err
+	}
+	if opts.trimMargin < 0 {
+		return opts, fmt.Errorf("trimMargin must not be negative")
+	}
+
+	// Background removal takes a bool, or a color to replace the background with
+	if v, ok := get("transparentBg"); ok {
+		switch v.Type() {
+		case js.TypeBoolean:
+			opts.transparentBg = v.Bool()
+		case js.TypeString:
+			if v.String() != "" {
+				c, err := imaging.ParseColor(v.String())
+				if err != nil {
+					return opts, fmt.Errorf("invalid background: %w", err)
+				}
+				opts.transparentBg, opts.replaceBg = true, c
+			}
+		default:
+			return opts, typeErr("transparentBg", "a boolean or a color", v)
+		}
+	}
+	if v, ok := get("feather"); ok {
+		if v.Type() != js.TypeNumber {
+			return opts, typeErr("feather", "a number", v)
+		}
+		opts.feather = v.Float()
+		if opts.feather < 0 || opts.feather > imaging.MaxFeather {
+			return opts, fmt.Errorf("feather must be between 0 and %d", imaging.MaxFeather)
+		}
+	}
+
+	if g, err := str("geometry"); err != nil {
+		return opts, err
+	} else if g != "" {
+		geom, err := imaging.ParseGeometry(g)
+		if err != nil {
+			return opts, err
+		}
+		opts.geometry = &geom
+	}
+
+	if v, ok := get("autoOrient"); ok {
+		switch {
+		case v.Type() == js.TypeBoolean:
+			opts.autoOrient = v.Bool()
+		case v.Type() == js.TypeString && v.String() == "document":
+			// Text detection is what the autorotate op runs
+			if err := imaging.CurrentPolicy().CheckOp("autorotate"); err != nil {
+				return opts, err
+			}
+			opts.autoOrient, opts.documentOrient = true, true
+		case v.Type() == js.TypeString && v.String() == "exif":
+			opts.autoOrient = true
+		default:
+			return opts, fmt.Errorf("invalid autoOrient: %s", v.String())
+		}
+	}
+
+	if ops, err := str("ops"); err != nil {
+		return opts, err
+	} else if opts.pipeline, err = imaging.ParsePipeline(ops); err != nil {
+		return opts, fmt.Errorf("invalid ops: %w", err)
+	}
+
+	if name, err := str("preset"); err != nil {
+		return opts, err
+	} else if name != "" {
+		preset, ok := named.Get(name)
+		if !ok {
+			return opts, fmt.Errorf("unknown preset: %s", name)
+		}
+		p, err := preset.Pipeline()
+		if err != nil {
+			return opts, fmt.Errorf("invalid preset: %w", err)
+		}
+		opts.pipeline.Steps = append(p.Steps, opts.pipeline.Steps...)
+		// The preset only fills in what the caller left unset
+		if preset.Format != "" && !formatSet {
+			opts.format = preset.Format
+		}
+		if preset.Quality > 0 && !qualitySet {
+			opts.quality = preset.Quality
+		}
+		if preset.MaxBytes > 0 && opts.maxBytes == 0 {
+			opts.maxBytes = preset.MaxBytes
+		}
+	}
+
+	if v, ok := get("progress"); ok {
+		if v.Type() != js.TypeFunction {
+			return opts, typeErr("progress", "a function", v)
+		}
+		opts.progress = v
+	}
+
+	// The trim and transparentBg options run those operations outside the pipeline
+	opts.policy = imaging.CurrentPolicy()
+	policy := opts.policy
+	if opts.trim {
+		if err := policy.CheckOp("trim"); err != nil {
+			return opts, err
+		}
+	}
+	if opts.transparentBg {
+		if err := policy.CheckOp("removebg"); err != nil {
+			return opts, err
+		}
+	}
+
+	if k, err := str("keyColor"); err != nil {
+		return opts, err
+	} else if k != "" {
+		c, err := imaging.ParseColor(k)
+		if err != nil {
+			return opts, fmt.Errorf("invalid keyColor: %w", err)
+		}
+		if err := policy.CheckOp("chromakey"); err != nil {
+			return opts, err
+		}
+		opts.keyColor = c
+	}
+
+	// Padding takes a number for all sides, or CSS-style margins such as "10,20"
+	if v, ok := get("pad"); ok {
+		switch v.Type() {
+		case js.TypeNumber:
+			n := v.Int()
+			opts.pad = imaging.Insets{Top: n, Right: n, Bottom: n, Left: n}
+			if n < 0 {
+				return opts, fmt.Errorf("pad must not be negative")
+			}
+		case js.TypeString:
+			if opts.pad, err = imaging.ParseInsets(v.String()); err != nil {
+				return opts, fmt.Errorf("invalid pad: %w", err)
+			}
+		default:
+			return opts, typeErr("pad", "a number or a string", v)
+		}
+		if err := policy.CheckOp("pad"); err != nil {
+			return opts, err
+		}
+	}
+	opts.padColor = color.Transparent
+	if s, err := str("padColor"); err != nil {
+		return opts, err
+	} else if s != "" {
+		if opts.padColor, err = imaging.ParseColor(s); err != nil {
+			return opts, fmt.Errorf("invalid padColor: %w", err)
+		}
+	}
+
+	if s, err := str("levels"); err != nil {
+		return opts, err
+	} else if s != "" {
+		if opts.levels, err = imaging.ParseLevels(s); err != nil {
+			return opts, fmt.Errorf("invalid levels: %w", err)
+		}
+		if err := policy.CheckOp("levels"); err != nil {
+			return opts, err
+		}
+	}
+	if s, err := str("curve"); err != nil {
+		return opts, err
+	} else if opts.curve, err = imaging.ParseCurve(s); err != nil {
+		return opts, fmt.Errorf("invalid curve: %w", err)
+	} else if len(opts.curve) > 0 {
+		if err := policy.CheckOp("curves"); err != nil {
+			return opts, err
+		}
+	}
+
+	// Exposure adjustments are plain numbers
+	for _, a := range []struct {
+		name string
+		dst  *float64
+	}{
+		{"brightness", &opts.adjust.Brightness}, {"contrast", &opts.adjust.Contrast}, {"gamma", &opts.adjust.Gamma},
+		{"hue", &opts.hsl.Hue}, {"saturation", &opts.hsl.Saturation}, {"vibrance", &opts.hsl.Vibrance},
+		{"blur", &opts.blur}, {"temp", &opts.temp}, {"tint", &opts.tint},
+	} {
+		if v, ok := get(a.name); ok {
+			if v.Type() != js.TypeNumber {
+				return opts, typeErr(a.name, "a number", v)
+			}
+			*a.dst = v.Float()
+		}
+	}
+	if err := opts.adjust.Validate(); err != nil {
+		return opts, err
+	}
+	if !opts.adjust.IsZero() {
+		if err := policy.CheckOp("adjust"); err != nil {
+			return opts, err
+		}
+	}
+	if opts.blur < 0 || opts.blur > imaging.MaxBlurSigma {
+		return opts, fmt.Errorf("blur must be between 0 and %d", imaging.MaxBlurSigma)
+	}
+	if opts.blur > 0 {
+		if err := policy.CheckOp("blur"); err != nil {
+			return opts, err
+		}
+	}
+	if err := opts.hsl.Validate(); err != nil {
+		return opts, err
+	}
+	if !opts.hsl.IsZero() {
+		if err := policy.CheckOp("hsl"); err != nil {
+			return opts, err
+		}
+	}
+
+	if s, err := str("wb"); err != nil {
+		return opts, err
+	} else if s != "" {
+		if opts.wbMethod, err = imaging.ParseWhiteBalance(s); err != nil {
+			return opts, fmt.Errorf("invalid wb: %w", err)
+		}
+		opts.whiteBalance = true
+	}
+	if opts.temp < -100 || opts.temp > 100 || opts.tint < -100 || opts.tint > 100 {
+		return opts, fmt.Errorf("temp and tint must be between -100 and 100")
+	}
+
+	opts.contrastClip = imaging.DefaultAutoContrastClip
+	if v, ok := get("autoContrast"); ok {
+		switch v.Type() {
+		case js.TypeBoolean:
+			opts.autoContrast = v.Bool()
+		case js.TypeNumber:
+			opts.autoContrast, opts.contrastClip = true, v.Float()
+			if opts.contrastClip < 0 || opts.contrastClip > 50 {
+				return opts, fmt.Errorf("autoContrast clip must be between 0 and 50 percent")
+			}
+		default:
+			return opts, typeErr("autoContrast", "a boolean or a number", v)
+		}
+	}
+
+	for _, f := range []struct {
+		name string
+		on   bool
+	}{
+		{"wb", opts.whiteBalance || opts.temp != 0 || opts.tint != 0},
+		{"autocontrast", opts.autoContrast}, {"equalize", opts.equalize},
+		{"grayscale", opts.grayscale}, {"sepia", opts.sepia}, {"invert", opts.invert},
+	} {
+		if f.on {
+			if err := policy.CheckOp(f.name); err != nil {
+				return opts, err
+			}
+		}
+	}
+
+	if v, ok := get("radius"); ok {
+		if v.Type() != js.TypeNumber {
+			return opts, typeErr("radius", "a number", v)
+		}
+		if opts.radius = v.Float(); opts.radius < 0 {
+			return opts, fmt.Errorf("radius must not be negative")
+		}
+		if err := policy.CheckOp("round"); err != nil {
+			return opts, err
+		}
+	}
+	if s, err := str("redact"); err != nil {
+		return opts, err
+	} else if opts.redact, err = imaging.ParseRegions(s); err != nil {
+		return opts, fmt.Errorf("invalid redact: %w", err)
+	} else if len(opts.redact) > 0 {
+		if err := policy.CheckOp("redact"); err != nil {
+			return opts, err
+		}
+	}
+	if s, err := str("redactMode"); err != nil {
+		return opts, err
+	} else if opts.redactMode, err = imaging.ParseRedactMode(s); err != nil {
+		return opts, err
+	}
+
+	if s, err := str("shape"); err != nil {
+		return opts, err
+	} else if opts.shape, err = imaging.ParseShape(s); err != nil {
+		return opts, err
+	} else if opts.shape != imaging.ShapeNone {
+		if err := policy.CheckOp("shape"); err != nil {
+			return opts, err
+		}
+	}
+
+	if v, ok := get("mask"); ok {
+		if !v.InstanceOf(js.Global().Get("Uint8Array")) {
+			return opts, typeErr("mask", "a Uint8Array", v)
+		}
+		if err := policy.CheckOp("mask"); err != nil {
+			return opts, err
+		}
+		opts.mask = make([]byte, v.Get("length").Int())
+		js.CopyBytesToGo(opts.mask, v)
+	}
+
+	if m, err := str("matte"); err != nil {
+		return opts, err
+	} else if m != "" {
+		c, err := imaging.ParseColor(m)
+		if err != nil {
+			return opts, fmt.Errorf("invalid matte: %w", err)
+		}
+		opts.matte = c
+	}
+	return opts, nil
+}
+
+// report tells the progress callback, if any, that stage is starting with
+// percent of the work done.
+func (o processOptions) report(stage string, percent int) {
+	if o.progress.Type() == js.TypeFunction {
+		o.progress.Invoke(stage, percent)
+	}
+}
+
+// cacheKey describes everything about opts that affects the output.
+func (o processOptions) cacheKey(source string) string {
+	return fmt.Sprintf("src=%s w=%d h=%d crop=%v redact=%v,%d trim=%t fuzz=%g sides=%d margin=%d format=%s q=%d bg=%t replace=%v feather=%g key=%v pad=%+v padColor=%v radius=%g shape=%d mask=%s tone=%t,%t,%t wb=%t,%d,%g,%g auto=%t,%g,%t levels=%+v curve=%v adjust=%+v hsl=%+v blur=%g geom=%+v orient=%t doc=%t max=%d down=%t ops=%s matte=%v policy=%+v",
+		source, o.width, o.height, o.crop, o.redact, o.redactMode, o.trim, o.trimFuzz, o.trimSides, o.trimMargin, o.format, o.quality, o.transparentBg, o.replaceBg, o.feather, o.keyColor, o.pad, o.padColor, o.radius, o.shape, o.maskKey(), o.grayscale, o.sepia, o.invert, o.whiteBalance, o.wbMethod, o.temp, o.tint, o.autoContrast, o.contrastClip, o.equalize, o.levels, o.curve, o.adjust, o.hsl, o.blur, o.geometry,
+		o.autoOrient, o.documentOrient, o.maxBytes, o.downscale, o.pipeline, o.matte, o.policy)
+}
+
+// maskKey identifies the mask image in cache keys without its bytes.
+func (o processOptions) maskKey() string {
+	if o.mask == nil {
+		return ""
+	}
+	return cache.Key(o.mask, "")
+}
@@ -0,0 +1,78 @@
+// Package errcode defines the machine-readable error codes reported to API
+// callers, so the wasm frontend and a JSON API describe failures the same
+// way and clients can branch on the code rather than parse messages.
+package errcode
+
+import (
+	"errors"
+	"image"
+
+	"image-resizer/imaging"
+)
+
+// Code identifies a class of failure.
+type Code string
+
+const (
+	// InvalidArgument means a missing or malformed argument or option.
+	InvalidArgument Code = "INVALID_ARGUMENT"
+	// UnsupportedFormat means the input is not in a format that can be decoded.
+	UnsupportedFormat Code = "UNSUPPORTED_FORMAT"
+	// TooLarge means the image exceeds the configured pixel limit.
+	TooLarge Code = "TOO_LARGE"
+	// DecodeFailed means the input is in a known format but is corrupt.
+	DecodeFailed Code = "DECODE_FAILED"
+	// ProcessingFailed means an operation failed on a decoded image.
+	ProcessingFailed Code = "PROCESSING_FAILED"
+	// OverBudget means the output could not be encoded within maxBytes.
+	OverBudget Code = "OVER_BUDGET"
+	// EncodeFailed means the output could not be encoded.
+	EncodeFailed Code = "ENCODE_FAILED"
+	// Disabled means the deployment's imaging.Policy forbids the operation
+	// or parameter range.
+	Disabled Code = "DISABLED"
+)
+
+// Error is an error with a code. It marshals to JSON as {"code", "message"}.
+type Error struct {
+	Code    Code   `json:"code"`
+	Message string `json:"message"`
+	Err     error  `json:"-"` // The underlying error, if any
+}
+
+func (e *Error) Error() string { return e.Message }
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// New returns an Error with the given code and message.
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Wrap returns err as an Error with the given code, its message prefixed by
+// context when non-empty. Errors with a more specific meaning keep it,
+// whatever code is given: an err that already has a code, an
+// imaging.TooLargeError or DisabledError, image.ErrFormat, and
+// imaging.ErrMaxBytes.
+func Wrap(code Code, context string, err error) *Error {
+	var coded *Error
+	var tooLarge *imaging.TooLargeError
+	var disabled *imaging.DisabledError
+	switch {
+	case errors.As(err, &coded):
+		code = coded.Code
+	case errors.As(err, &tooLarge):
+		code = TooLarge
+	case errors.As(err, &disabled):
+		code = Disabled
+	case errors.Is(err, image.ErrFormat):
+		code = UnsupportedFormat
+	case errors.Is(err, imaging.ErrMaxBytes):
+		code = OverBudget
+	}
+	message := err.Error()
+	if context != "" {
+		message = context + ": " + message
+	}
+	return &Error{Code: code, Message: message, Err: err}
+}
@@ -0,0 +1,48 @@
+package errcode
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"testing"
+
+	"image-resizer/imaging"
+)
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		err  error
+		code Code
+	}{
+		{errors.New("corrupt"), DecodeFailed},
+		{image.ErrFormat, UnsupportedFormat},
+		{&imaging.TooLargeError{Width: 10, Height: 10, MaxPixels: 1}, TooLarge},
+		{fmt.Errorf("fit: %w", imaging.ErrMaxBytes), OverBudget},
+		{fmt.Errorf("invalid ops: %w", &imaging.DisabledError{Op: "removebg"}), Disabled},
+		{New(InvalidArgument, "bad"), InvalidArgument},
+	}
+	for _, tt := range tests {
+		e := Wrap(DecodeFailed, "failed to decode image", tt.err)
+		if e.Code != tt.code {
+			t.Errorf("Wrap(%v): got %s, want %s", tt.err, e.Code, tt.code)
+		}
+		if !errors.Is(e, tt.err) {
+			t.Errorf("Wrap(%v) does not unwrap to the original error", tt.err)
+		}
+	}
+
+	if got := Wrap(InvalidArgument, "", errors.New("bad width")).Message; got != "bad width" {
+		t.Errorf("expected the message unchanged without context, got %q", got)
+	}
+}
+
+func TestError_JSON(t *testing.T) {
+	b, err := json.Marshal(Wrap(DecodeFailed, "failed to decode image", image.ErrFormat))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"code":"UNSUPPORTED_FORMAT","message":"failed to decode image: image: unknown format"}`; string(b) != want {
+		t.Errorf("got %s, want %s", b, want)
+	}
+}
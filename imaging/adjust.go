@@ -0,0 +1,442 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Grayscale converts img to shades of gray by luma, keeping its alpha
+// channel. ToGray is cheaper for opaque images, whose alpha it drops.
+func Grayscale(img image.Image) *image.NRGBA {
+	return mapColors(img, func(c color.NRGBA) color.NRGBA {
+		y := luma8(c)
+		return color.NRGBA{y, y, y, c.A}
+	})
+}
+
+// Sepia tones img in warm browns, like an old photograph, using the common
+// sepia matrix. Alpha is kept.
+func Sepia(img image.Image) *image.NRGBA {
+	return mapColors(img, func(c color.NRGBA) color.NRGBA {
+		r, g, b := float64(c.R), float64(c.G), float64(c.B)
+		return color.NRGBA{
+			clamp8(0.393*r + 0.769*g + 0.189*b),
+			clamp8(0.349*r + 0.686*g + 0.168*b),
+			clamp8(0.272*r + 0.534*g + 0.131*b),
+			c.A,
+		}
+	})
+}
+
+// Invert replaces each color with its negative. Alpha is kept.
+func Invert(img image.Image) *image.NRGBA {
+	return mapColors(img, func(c color.NRGBA) color.NRGBA {
+		return color.NRGBA{255 - c.R, 255 - c.G, 255 - c.B, c.A}
+	})
+}
+
+// AdjustOptions are exposure corrections for Adjust. The zero value changes nothing.
+type AdjustOptions struct {
+	// Brightness shifts every channel by a percentage of full scale, -100 to 100.
+	Brightness float64
+	// Contrast stretches (positive) or flattens (negative) values around
+	// mid-gray, -100 to 100; -100 leaves flat gray.
+	Contrast float64
+	// Gamma brightens midtones above 1 and darkens them below, leaving black
+	// and white alone; zero means 1.
+	Gamma float64
+}
+
+// IsZero reports whether o changes nothing.
+func (o AdjustOptions) IsZero() bool {
+	return o.Brightness == 0 && o.Contrast == 0 && (o.Gamma == 0 || o.Gamma == 1)
+}
+
+// parseAdjust reads AdjustOptions from the named arguments brightness,
+// contrast, and gamma, checking their ranges.
+func parseAdjust(args OpArgs) (AdjustOptions, error) {
+	var opts AdjustOptions
+	var err error
+	if opts.Brightness, err = args.Float("brightness", -1, 0); err != nil {
+		return opts, err
+	}
+	if opts.Contrast, err = args.Float("contrast", -1, 0); err != nil {
+		return opts, err
+	}
+	if opts.Gamma, err = args.Float("gamma", -1, 1); err != nil {
+		return opts, err
+	}
+	return opts, opts.Validate()
+}
+
+// Validate checks that o's values are within their ranges.
+func (o AdjustOptions) Validate() error {
+	if o.Brightness < -100 || o.Brightness > 100 {
+		return fmt.Errorf("brightness must be between -100 and 100")
+	}
+	if o.Contrast < -100 || o.Contrast > 100 {
+		return fmt.Errorf("contrast must be between -100 and 100")
+	}
+	if o.Gamma < 0 || o.Gamma > 10 {
+		return fmt.Errorf("gamma must be between 0 and 10")
+	}
+	return nil
+}
+
+// Adjust applies brightness, then contrast, then gamma to img's color
+// channels in a single pass through a lookup table. Alpha is kept.
+func Adjust(img image.Image, opts AdjustOptions) *image.NRGBA {
+	gamma := opts.Gamma
+	if gamma <= 0 {
+		gamma = 1
+	}
+	var lut [256]uint8
+	for i := range lut {
+		v := float64(i)/255 + opts.Brightness/100
+		v = (v-0.5)*(1+opts.Contrast/100) + 0.5
+		v = math.Pow(max(0, min(v, 1)), 1/gamma)
+		lut[i] = clamp8(v * 255)
+	}
+	return mapColors(img, func(c color.NRGBA) color.NRGBA {
+		return color.NRGBA{lut[c.R], lut[c.G], lut[c.B], c.A}
+	})
+}
+
+// LevelsOptions remap tones as a levels dialog does. The zero value changes nothing.
+type LevelsOptions struct {
+	// InBlack and InWhite are the input levels that become OutBlack and
+	// OutWhite; levels beyond them clip. A nil InWhite means 255.
+	InBlack uint8
+	InWhite *uint8
+	// Gamma brightens midtones between them above 1 and darkens them below;
+	// zero means 1.
+	Gamma float64
+	// A nil OutWhite means 255.
+	OutBlack uint8
+	OutWhite *uint8
+}
+
+// whites returns o's input and output white levels.
+func (o LevelsOptions) whites() (in, out uint8) {
+	in, out = 255, 255
+	if o.InWhite != nil {
+		in = *o.InWhite
+	}
+	if o.OutWhite != nil {
+		out = *o.OutWhite
+	}
+	return in, out
+}
+
+// String describes o by its levels, for logs and cache keys.
+func (o LevelsOptions) String() string {
+	in, out := o.whites()
+	return fmt.Sprintf("in=%d-%d gamma=%g out=%d-%d", o.InBlack, in, o.Gamma, o.OutBlack, out)
+}
+
+// IsZero reports whether o changes nothing.
+func (o LevelsOptions) IsZero() bool {
+	in, out := o.whites()
+	return o.InBlack == 0 && in == 255 && (o.Gamma == 0 || o.Gamma == 1) && o.OutBlack == 0 && out == 255
+}
+
+// ParseLevels parses "black,white" or "black,white,gamma" input levels, as
+// ImageMagick's -level takes them, such as "10,240,1.2".
+func ParseLevels(s string) (LevelsOptions, error) {
+	var opts LevelsOptions
+	fields := strings.Split(s, ",")
+	if len(fields) < 2 || len(fields) > 3 {
+		return opts, fmt.Errorf("levels %q must be black,white or black,white,gamma", s)
+	}
+	var points [2]uint8
+	for i, f := range fields[:2] {
+		v, err := strconv.ParseUint(strings.TrimSpace(f), 10, 8)
+		if err != nil {
+			return opts, fmt.Errorf("levels %q: %q is not a level from 0 to 255", s, f)
+		}
+		points[i] = uint8(v)
+	}
+	opts.InBlack, opts.InWhite = points[0], &points[1]
+	if len(fields) == 3 {
+		g, err := strconv.ParseFloat(strings.TrimSpace(fields[2]), 64)
+		if err != nil {
+			return opts, fmt.Errorf("levels %q: invalid gamma %q", s, fields[2])
+		}
+		opts.Gamma = g
+	}
+	return opts, opts.Validate()
+}
+
+// Validate checks that o's input range is not empty and its gamma is in range.
+func (o LevelsOptions) Validate() error {
+	if in, _ := o.whites(); in <= o.InBlack {
+		return fmt.Errorf("the white level must be above the black level")
+	}
+	if o.Gamma < 0 || o.Gamma > 10 {
+		return fmt.Errorf("gamma must be between 0 and 10")
+	}
+	return nil
+}
+
+// Levels maps the range InBlack-InWhite of img's color channels onto
+// OutBlack-OutWhite, bending it by Gamma, to set black and white points on
+// flat or faded images. Raising OutBlack or lowering OutWhite reduces
+// contrast. Alpha is kept.
+func Levels(img image.Image, opts LevelsOptions) *image.NRGBA {
+	in, out := opts.whites()
+	inWhite, outWhite := float64(in), float64(out)
+	gamma := opts.Gamma
+	if gamma <= 0 {
+		gamma = 1
+	}
+	inBlack, outBlack := float64(opts.InBlack), float64(opts.OutBlack)
+	var lut [256]uint8
+	for i := range lut {
+		v := max(0, min((float64(i)-inBlack)/max(inWhite-inBlack, 1), 1))
+		lut[i] = clamp8(outBlack + math.Pow(v, 1/gamma)*(outWhite-outBlack))
+	}
+	return mapColors(img, func(c color.NRGBA) color.NRGBA {
+		return color.NRGBA{lut[c.R], lut[c.G], lut[c.B], c.A}
+	})
+}
+
+// CurvePoint maps the input level In to the output level Out.
+type CurvePoint struct{ In, Out uint8 }
+
+// Curve is a tone curve through control points in order of In, as in a
+// curves dialog. Between points it follows a monotone cubic spline, which
+// is smooth but never overshoots the points; beyond the first and last it
+// is flat. An empty Curve changes nothing.
+type Curve []CurvePoint
+
+// ParseCurve parses control points as "in:out" pairs separated by
+// semicolons or spaces, such as "0:0;64:48;192:208;255:255".
+func ParseCurve(s string) (Curve, error) {
+	var c Curve
+	for _, f := range strings.FieldsFunc(s, func(r rune) bool { return r == ';' || r == ' ' }) {
+		in, out, ok := strings.Cut(f, ":")
+		x, err1 := strconv.ParseUint(in, 10, 8)
+		y, err2 := strconv.ParseUint(out, 10, 8)
+		if !ok || err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("curve point %q must be in:out, each from 0 to 255", f)
+		}
+		c = append(c, CurvePoint{uint8(x), uint8(y)})
+	}
+	sort.Slice(c, func(i, j int) bool { return c[i].In < c[j].In })
+	for i := 1; i < len(c); i++ {
+		if c[i].In == c[i-1].In {
+			return nil, fmt.Errorf("curve has two points at input %d", c[i].In)
+		}
+	}
+	if len(c) == 1 {
+		return nil, fmt.Errorf("curve needs at least two points")
+	}
+	return c, nil
+}
+
+// lut tabulates the curve for every input level.
+func (c Curve) lut() [256]uint8 {
+	var lut [256]uint8
+	if len(c) < 2 {
+		for i := range lut {
+			lut[i] = uint8(i)
+		}
+		return lut
+	}
+
+	// Fritsch-Carlson tangents: secants averaged, zero at turning points,
+	// then scaled down where they would overshoot
+	n := len(c)
+	secants := make([]float64, n-1)
+	for k := range secants {
+		secants[k] = (float64(c[k+1].Out) - float64(c[k].Out)) / (float64(c[k+1].In) - float64(c[k].In))
+	}
+	tangents := make([]float64, n)
+	tangents[0], tangents[n-1] = secants[0], secants[n-2]
+	for k := 1; k < n-1; k++ {
+		if secants[k-1]*secants[k] > 0 {
+			tangents[k] = (secants[k-1] + secants[k]) / 2
+		}
+	}
+	for k, d := range secants {
+		if d == 0 {
+			tangents[k], tangents[k+1] = 0, 0
+			continue
+		}
+		a, b := tangents[k]/d, tangents[k+1]/d
+		if h := a*a + b*b; h > 9 {
+			t := 3 / math.Sqrt(h)
+			tangents[k], tangents[k+1] = t*a*d, t*b*d
+		}
+	}
+
+	k := 0
+	for i := range lut {
+		switch {
+		case i <= int(c[0].In):
+			lut[i] = c[0].Out
+			continue
+		case i >= int(c[n-1].In):
+			lut[i] = c[n-1].Out
+			continue
+		}
+		for int(c[k+1].In) < i {
+			k++
+		}
+		x0, x1 := float64(c[k].In), float64(c[k+1].In)
+		y0, y1 := float64(c[k].Out), float64(c[k+1].Out)
+		h := x1 - x0
+		t := (float64(i) - x0) / h
+		// Cubic Hermite basis
+		h00 := (1 + 2*t) * (1 - t) * (1 - t)
+		h10 := t * (1 - t) * (1 - t)
+		h01 := t * t * (3 - 2*t)
+		h11 := t * t * (t - 1)
+		lut[i] = clamp8(h00*y0 + h10*h*tangents[k] + h01*y1 + h11*h*tangents[k+1])
+	}
+	return lut
+}
+
+// Curves are tone curves for ApplyCurves. The zero value changes nothing.
+type Curves struct {
+	// All applies to every color channel, after the channel's own curve.
+	All              Curve
+	Red, Green, Blue Curve
+}
+
+// IsZero reports whether c changes nothing.
+func (c Curves) IsZero() bool {
+	return len(c.All) == 0 && len(c.Red) == 0 && len(c.Green) == 0 && len(c.Blue) == 0
+}
+
+// ApplyCurves maps img's color channels through their curves, then through
+// the curve for all of them, so one pass corrects both casts and overall
+// tone. Alpha is kept.
+func ApplyCurves(img image.Image, curves Curves) *image.NRGBA {
+	all := curves.All.lut()
+	var luts [3][256]uint8
+	for i, c := range []Curve{curves.Red, curves.Green, curves.Blue} {
+		channel := c.lut()
+		for v := range luts[i] {
+			luts[i][v] = all[channel[v]]
+		}
+	}
+	return mapColors(img, func(c color.NRGBA) color.NRGBA {
+		return color.NRGBA{luts[0][c.R], luts[1][c.G], luts[2][c.B], c.A}
+	})
+}
+
+// HSLOptions are color adjustments for AdjustHSL. The zero value changes nothing.
+type HSLOptions struct {
+	// Hue rotates every color around the color wheel, in degrees.
+	Hue float64
+	// Saturation scales saturation by a percentage, -100 (gray) to 100 (double).
+	Saturation float64
+	// Vibrance is like Saturation but weighted toward muted colors, so
+	// already vivid colors and skin tones change less, -100 to 100.
+	Vibrance float64
+}
+
+// IsZero reports whether o changes nothing.
+func (o HSLOptions) IsZero() bool {
+	return math.Mod(o.Hue, 360) == 0 && o.Saturation == 0 && o.Vibrance == 0
+}
+
+// Validate checks that o's values are within their ranges.
+func (o HSLOptions) Validate() error {
+	if o.Saturation < -100 || o.Saturation > 100 {
+		return fmt.Errorf("saturation must be between -100 and 100")
+	}
+	if o.Vibrance < -100 || o.Vibrance > 100 {
+		return fmt.Errorf("vibrance must be between -100 and 100")
+	}
+	return nil
+}
+
+// AdjustHSL rotates hue and scales saturation in HSL space, to mute or punch
+// up colors. Lightness and alpha are kept.
+func AdjustHSL(img image.Image, opts HSLOptions) *image.NRGBA {
+	hue := math.Mod(opts.Hue, 360)
+	return mapColors(img, func(c color.NRGBA) color.NRGBA {
+		h, s, l := rgbToHSL(c)
+		h = math.Mod(h+hue+360, 360)
+		s *= 1 + opts.Saturation/100
+		s *= 1 + opts.Vibrance/100*(1-min(s, 1))
+		r, g, b := hslToRGB(h, max(0, min(s, 1)), l)
+		return color.NRGBA{r, g, b, c.A}
+	})
+}
+
+// rgbToHSL returns c's hue in degrees and its saturation and lightness in 0-1.
+func rgbToHSL(c color.NRGBA) (h, s, l float64) {
+	r, g, b := float64(c.R)/255, float64(c.G)/255, float64(c.B)/255
+	hi, lo := max(r, g, b), min(r, g, b)
+	l = (hi + lo) / 2
+	d := hi - lo
+	if d == 0 {
+		return 0, 0, l
+	}
+	s = d / (1 - math.Abs(2*l-1))
+	switch hi {
+	case r:
+		h = math.Mod((g-b)/d+6, 6)
+	case g:
+		h = (b-r)/d + 2
+	default:
+		h = (r-g)/d + 4
+	}
+	return h * 60, s, l
+}
+
+// hslToRGB converts a hue in degrees and saturation and lightness in 0-1 to
+// 8-bit channels.
+func hslToRGB(h, s, l float64) (r, g, b uint8) {
+	c := (1 - math.Abs(2*l-1)) * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	m := l - c/2
+	var rf, gf, bf float64
+	switch {
+	case h < 60:
+		rf, gf = c, x
+	case h < 120:
+		rf, gf = x, c
+	case h < 180:
+		gf, bf = c, x
+	case h < 240:
+		gf, bf = x, c
+	case h < 300:
+		rf, bf = x, c
+	default:
+		rf, bf = c, x
+	}
+	return clamp8((rf + m) * 255), clamp8((gf + m) * 255), clamp8((bf + m) * 255)
+}
+
+// mapColors returns a copy of img starting at (0, 0) with f applied to each
+// pixel's non-premultiplied color.
+func mapColors(img image.Image, f func(color.NRGBA) color.NRGBA) *image.NRGBA {
+	src := ToNRGBA(img)
+	b := src.Rect
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			dst.SetNRGBA(x, y, f(src.NRGBAAt(b.Min.X+x, b.Min.Y+y)))
+		}
+	}
+	return dst
+}
+
+// luma8 returns the Rec. 601 luma of c, as color.GrayModel computes it.
+func luma8(c color.NRGBA) uint8 {
+	return uint8((19595*uint32(c.R) + 38470*uint32(c.G) + 7471*uint32(c.B) + 1<<15) >> 16)
+}
+
+// clamp8 rounds v to the nearest value in 0-255.
+func clamp8(v float64) uint8 {
+	return uint8(math.Round(max(0, min(v, 255))))
+}
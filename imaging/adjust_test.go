@@ -0,0 +1,260 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestTonalFilters(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(3, 3, 5, 4))
+	img.SetNRGBA(3, 3, color.NRGBA{200, 100, 50, 255})
+	img.SetNRGBA(4, 3, color.NRGBA{0, 255, 0, 128})
+
+	tests := []struct {
+		name string
+		f    func(image.Image) *image.NRGBA
+		want [2]color.NRGBA
+	}{
+		{"grayscale", Grayscale, [2]color.NRGBA{{124, 124, 124, 255}, {150, 150, 150, 128}}},
+		{"sepia", Sepia, [2]color.NRGBA{{165, 147, 114, 255}, {196, 175, 136, 128}}},
+		{"invert", Invert, [2]color.NRGBA{{55, 155, 205, 255}, {255, 0, 255, 128}}},
+	}
+	for _, tc := range tests {
+		dst := tc.f(img)
+		if b := dst.Bounds(); b != image.Rect(0, 0, 2, 1) {
+			t.Fatalf("%s: expected bounds from the origin, got %v", tc.name, b)
+		}
+		for x, want := range tc.want {
+			if got := dst.NRGBAAt(x, 0); got != want {
+				t.Errorf("%s: pixel %d expected %v, got %v", tc.name, x, want, got)
+			}
+		}
+	}
+}
+
+func TestGrayscaleOp_KeepsAlpha(t *testing.T) {
+	p, err := ParsePipeline("grayscale|sepia|invert")
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	img.SetNRGBA(0, 0, color.NRGBA{255, 0, 0, 255})
+	result, err := p.Apply(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, a := result.At(1, 1).RGBA(); a != 0 {
+		t.Errorf("expected transparent pixels to stay transparent, got alpha %d", a)
+	}
+
+	// Opaque images still become compact Gray images
+	opaque := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	for i := 3; i < len(opaque.Pix); i += 4 {
+		opaque.Pix[i] = 255
+	}
+	p, _ = ParsePipeline("grayscale")
+	if result, _ := p.Apply(opaque); result.ColorModel() != color.GrayModel {
+		t.Errorf("expected a Gray image, got %T", result)
+	}
+}
+
+func TestAdjust(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 1))
+	img.SetNRGBA(0, 0, color.NRGBA{0, 0, 0, 255})
+	img.SetNRGBA(1, 0, color.NRGBA{64, 128, 192, 200})
+	img.SetNRGBA(2, 0, color.NRGBA{255, 255, 255, 255})
+
+	tests := []struct {
+		opts AdjustOptions
+		want [3]color.NRGBA
+	}{
+		{AdjustOptions{}, [3]color.NRGBA{{0, 0, 0, 255}, {64, 128, 192, 200}, {255, 255, 255, 255}}},
+		{AdjustOptions{Brightness: 20}, [3]color.NRGBA{{51, 51, 51, 255}, {115, 179, 243, 200}, {255, 255, 255, 255}}},
+		{AdjustOptions{Contrast: 100}, [3]color.NRGBA{{0, 0, 0, 255}, {0, 129, 255, 200}, {255, 255, 255, 255}}},
+		{AdjustOptions{Contrast: -100}, [3]color.NRGBA{{128, 128, 128, 255}, {128, 128, 128, 200}, {128, 128, 128, 255}}},
+		{AdjustOptions{Gamma: 2}, [3]color.NRGBA{{0, 0, 0, 255}, {128, 181, 221, 200}, {255, 255, 255, 255}}},
+	}
+	for _, tc := range tests {
+		dst := Adjust(img, tc.opts)
+		for x, want := range tc.want {
+			if got := dst.NRGBAAt(x, 0); got != want {
+				t.Errorf("%+v: pixel %d expected %v, got %v", tc.opts, x, want, got)
+			}
+		}
+	}
+	if !(AdjustOptions{Gamma: 1}).IsZero() || (AdjustOptions{Contrast: 5}).IsZero() {
+		t.Error("IsZero is wrong")
+	}
+
+	if _, err := ParsePipeline("adjust:brightness=10,contrast=-20,gamma=1.2"); err != nil {
+		t.Error(err)
+	}
+	for _, expr := range []string{"adjust:brightness=101", "adjust:contrast=-200", "adjust:gamma=-1", "adjust:gamma=x"} {
+		if _, err := ParsePipeline(expr); err == nil {
+			t.Errorf("ParsePipeline(%q) expected error", expr)
+		}
+	}
+}
+
+func TestAdjustHSL(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 1))
+	img.SetNRGBA(0, 0, color.NRGBA{255, 0, 0, 255})     // vivid red
+	img.SetNRGBA(1, 0, color.NRGBA{140, 115, 115, 255}) // muted red
+	img.SetNRGBA(2, 0, color.NRGBA{128, 128, 128, 100}) // gray
+	img.SetNRGBA(3, 0, color.NRGBA{30, 60, 200, 255})
+
+	// Untouched colors round-trip through HSL
+	if dst := AdjustHSL(img, HSLOptions{}); !equalPix(dst, img) {
+		t.Error("expected zero options to round-trip every color")
+	}
+
+	hue := AdjustHSL(img, HSLOptions{Hue: 120})
+	if got := hue.NRGBAAt(0, 0); got != (color.NRGBA{0, 255, 0, 255}) {
+		t.Errorf("expected red rotated 120 degrees to be green, got %v", got)
+	}
+	if got := hue.NRGBAAt(2, 0); got != (color.NRGBA{128, 128, 128, 100}) {
+		t.Errorf("expected gray to have no hue to rotate, got %v", got)
+	}
+
+	gray := AdjustHSL(img, HSLOptions{Saturation: -100})
+	if got := gray.NRGBAAt(0, 0); got.R != got.G || got.G != got.B {
+		t.Errorf("expected -100 saturation to give gray, got %v", got)
+	}
+
+	// Vibrance lifts muted colors more than vivid ones
+	sat := AdjustHSL(img, HSLOptions{Saturation: 50})
+	vib := AdjustHSL(img, HSLOptions{Vibrance: 50})
+	if got := vib.NRGBAAt(0, 0); got != (color.NRGBA{255, 0, 0, 255}) {
+		t.Errorf("expected vibrance to leave a fully saturated color alone, got %v", got)
+	}
+	if s, v := sat.NRGBAAt(1, 0), vib.NRGBAAt(1, 0); v.R <= 140 || v.R > s.R {
+		t.Errorf("expected vibrance to lift the muted red by at most as much as saturation, got %v vs %v", v, s)
+	}
+
+	if _, err := ParsePipeline("hsl:hue=-30,saturation=20,vibrance=10"); err != nil {
+		t.Error(err)
+	}
+	for _, expr := range []string{"hsl:saturation=150", "hsl:vibrance=-101", "hsl:hue=red"} {
+		if _, err := ParsePipeline(expr); err == nil {
+			t.Errorf("ParsePipeline(%q) expected error", expr)
+		}
+	}
+}
+
+// equalPix reports whether two NRGBA images hold the same pixels.
+func equalPix(a, b *image.NRGBA) bool {
+	if a.Rect.Size() != b.Rect.Size() {
+		return false
+	}
+	for y := 0; y < a.Rect.Dy(); y++ {
+		for x := 0; x < a.Rect.Dx(); x++ {
+			if a.NRGBAAt(a.Rect.Min.X+x, a.Rect.Min.Y+y) != b.NRGBAAt(b.Rect.Min.X+x, b.Rect.Min.Y+y) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// level returns a pointer to v, for LevelsOptions' white levels.
+func level(v uint8) *uint8 { return &v }
+
+func TestLevels(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 1))
+	for x, v := range []uint8{10, 20, 130, 240} {
+		img.SetNRGBA(x, 0, color.NRGBA{v, v, v, 200})
+	}
+
+	dst := Levels(img, LevelsOptions{InBlack: 20, InWhite: level(240)})
+	for x, want := range []uint8{0, 0, 128, 255} {
+		if got := dst.NRGBAAt(x, 0); got.R != want || got.A != 200 {
+			t.Errorf("pixel %d: expected %d, got %v", x, want, got)
+		}
+	}
+	dst = Levels(img, LevelsOptions{OutBlack: 50, OutWhite: level(200)})
+	if lo, hi := dst.NRGBAAt(0, 0).R, dst.NRGBAAt(3, 0).R; lo < 50 || hi > 200 {
+		t.Errorf("expected output within 50-200, got %d-%d", lo, hi)
+	}
+	// An output white of zero maps everything to black
+	dst = Levels(img, LevelsOptions{OutWhite: level(0)})
+	if got := dst.NRGBAAt(3, 0); got != (color.NRGBA{0, 0, 0, 200}) {
+		t.Errorf("expected black, got %v", got)
+	}
+	if !equalPix(Levels(img, LevelsOptions{}), img) {
+		t.Error("expected the zero value to change nothing")
+	}
+
+	opts, err := ParseLevels("10, 240, 1.5")
+	if want := (LevelsOptions{InBlack: 10, InWhite: level(240), Gamma: 1.5}); err != nil || opts.String() != want.String() {
+		t.Errorf("ParseLevels: got %+v, %v", opts, err)
+	}
+	for _, s := range []string{"10", "240,10", "10,0", "0,256", "0,255,x", "0,255,20"} {
+		if _, err := ParseLevels(s); err == nil {
+			t.Errorf("ParseLevels(%q) expected error", s)
+		}
+	}
+}
+
+func TestCurves(t *testing.T) {
+	c, err := ParseCurve("255:255 0:0;128:160")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lut := c.lut()
+	if lut[0] != 0 || lut[128] != 160 || lut[255] != 255 {
+		t.Errorf("expected the curve through its points, got %d %d %d", lut[0], lut[128], lut[255])
+	}
+	for i := 1; i < 256; i++ {
+		if lut[i] < lut[i-1] {
+			t.Fatalf("monotone points gave a falling curve at %d", i)
+		}
+	}
+
+	// Flat beyond the end points
+	c, _ = ParseCurve("50:20;200:220")
+	if lut := c.lut(); lut[10] != 20 || lut[250] != 220 {
+		t.Errorf("expected flat ends, got %d and %d", lut[10], lut[250])
+	}
+
+	img := solid(1, 1, color.NRGBA{100, 100, 100, 255})
+	red, _ := ParseCurve("0:0;100:150;255:255")
+	got := ApplyCurves(img, Curves{Red: red}).NRGBAAt(0, 0)
+	if got != (color.NRGBA{150, 100, 100, 255}) {
+		t.Errorf("expected only red to change, got %v", got)
+	}
+
+	for _, s := range []string{"10:10", "0:0;0:5", "0-0;255:255", "0:0;256:255"} {
+		if _, err := ParseCurve(s); err == nil {
+			t.Errorf("ParseCurve(%q) expected error", s)
+		}
+	}
+	if c, err := ParseCurve(""); err != nil || len(c) != 0 {
+		t.Errorf("empty curve: got %v, %v", c, err)
+	}
+}
+
+func TestPipeline_LevelsCurves(t *testing.T) {
+	for _, expr := range []string{"levels:10,240", "levels:10,240,1.2,outblack=20", "curves:0:0;128:160;255:255", "curves:r=0:0;255:230,b=0:20;255:255"} {
+		if _, err := ParsePipeline(expr); err != nil {
+			t.Errorf("ParsePipeline(%q): %v", expr, err)
+		}
+	}
+	for _, expr := range []string{"levels:200,100", "levels:0,300", "levels:0,0", "curves", "curves:0:0"} {
+		if _, err := ParsePipeline(expr); err == nil {
+			t.Errorf("ParsePipeline(%q) expected error", expr)
+		}
+	}
+
+	p, err := ParsePipeline("levels:0,255,outwhite=0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := p.Apply(solid(1, 1, color.NRGBA{200, 100, 50, 255}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ToNRGBA(out).NRGBAAt(0, 0); got != (color.NRGBA{0, 0, 0, 255}) {
+		t.Errorf("outwhite=0: expected black, got %v", got)
+	}
+}
@@ -0,0 +1,44 @@
+package imaging
+
+import (
+	"image"
+	"math"
+)
+
+// DefaultBlankThreshold is the BlankScore below which IsBlank treats an image
+// as blank. A page with even a few lines of text scores well above it, while
+// scanner noise and paper texture stay below.
+const DefaultBlankThreshold = 2.0
+
+// BlankScore measures how much an image varies: the standard deviation of its
+// luma as a percentage of the full 0-255 range. Uniform images score 0.
+func BlankScore(img image.Image) float64 {
+	gray := ToGray(img)
+	b := gray.Bounds()
+	n := float64(b.Dx() * b.Dy())
+	if n == 0 {
+		return 0
+	}
+
+	var sum, sumSq float64
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row := gray.Pix[gray.PixOffset(b.Min.X, y):]
+		for _, v := range row[:b.Dx()] {
+			f := float64(v)
+			sum += f
+			sumSq += f * f
+		}
+	}
+	mean := sum / n
+	variance := math.Max(0, sumSq/n-mean*mean)
+	return math.Sqrt(variance) / 255 * 100
+}
+
+// IsBlank reports whether img is blank or nearly so, such as an empty scanned
+// page: its BlankScore is below threshold (DefaultBlankThreshold if <= 0).
+func IsBlank(img image.Image, threshold float64) bool {
+	if threshold <= 0 {
+		threshold = DefaultBlankThreshold
+	}
+	return BlankScore(img) < threshold
+}
@@ -0,0 +1,34 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestIsBlank(t *testing.T) {
+	// A white page with light scanner noise
+	page := image.NewGray(image.Rect(0, 0, 200, 300))
+	seed := uint32(1)
+	for i := range page.Pix {
+		seed = seed*1664525 + 1013904223
+		page.Pix[i] = 245 + uint8(seed>>28)%6
+	}
+	if score := BlankScore(page); !IsBlank(page, 0) {
+		t.Errorf("expected a noisy white page to be blank, score %.2f", score)
+	}
+
+	// A few lines of text
+	for _, y := range []int{40, 41, 60, 61, 80, 81} {
+		for x := 20; x < 180; x++ {
+			page.SetGray(x, y, color.Gray{10})
+		}
+	}
+	if score := BlankScore(page); IsBlank(page, 0) {
+		t.Errorf("expected a page with text not to be blank, score %.2f", score)
+	}
+
+	if score := BlankScore(image.NewRGBA(image.Rect(0, 0, 10, 10))); score != 0 {
+		t.Errorf("expected a uniform image to score 0, got %.2f", score)
+	}
+}
@@ -0,0 +1,143 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// MaxBlurSigma bounds the Gaussian blur sigma accepted from users, since the
+// cost grows with it.
+const MaxBlurSigma = 50
+
+// Blur applies a Gaussian blur with standard deviation sigma in pixels, as
+// a horizontal then a vertical pass. Colors are blurred premultiplied by
+// alpha, so transparent pixels don't darken the edges of a cutout, and
+// pixels beyond the edges repeat the edge. The result starts at (0, 0).
+func Blur(img image.Image, sigma float64) *image.NRGBA {
+	if sigma <= 0 {
+		return unblurred(img)
+	}
+	return convolveImage(img, gaussianKernel(sigma))
+}
+
+// BoxBlur replaces each pixel with the mean of the (2*radius+1)² square
+// around it. It is cheaper but blockier than Blur. The result starts at (0, 0).
+func BoxBlur(img image.Image, radius int) *image.NRGBA {
+	if radius <= 0 {
+		return unblurred(img)
+	}
+	kernel := make([]float64, 2*radius+1)
+	for i := range kernel {
+		kernel[i] = 1 / float64(len(kernel))
+	}
+	return convolveImage(img, kernel)
+}
+
+// MaxSharpenAmount bounds the strength accepted by the sharpen op.
+const MaxSharpenAmount = 5
+
+// Sharpen applies an unsharp mask: each color channel moves away from a
+// Gaussian blur of sigma by amount times their difference, so edges gain
+// contrast while flat areas stay as they are. An amount of 1 is a typical
+// strength. Alpha is kept, and the result starts at (0, 0).
+func Sharpen(img image.Image, sigma, amount float64) *image.NRGBA {
+	dst := unblurred(img)
+	if sigma <= 0 || amount <= 0 {
+		return dst
+	}
+	blurred := Blur(dst, sigma)
+	for i := 0; i < len(dst.Pix); i += 4 {
+		for c := i; c < i+3; c++ {
+			v := float64(dst.Pix[c])
+			dst.Pix[c] = clamp8(v + amount*(v-float64(blurred.Pix[c])))
+		}
+	}
+	return dst
+}
+
+// unblurred returns a copy of img starting at (0, 0), as a blur of nothing.
+func unblurred(img image.Image) *image.NRGBA {
+	dst := copyNRGBA(img)
+	dst.Rect = dst.Rect.Sub(dst.Rect.Min)
+	return dst
+}
+
+// gaussianKernel returns normalized weights for a Gaussian of sigma, out to
+// three sigmas on each side.
+func gaussianKernel(sigma float64) []float64 {
+	r := int(math.Ceil(3 * sigma))
+	kernel := make([]float64, 2*r+1)
+	sum := 0.0
+	for i := range kernel {
+		d := float64(i - r)
+		kernel[i] = math.Exp(-d * d / (2 * sigma * sigma))
+		sum += kernel[i]
+	}
+	for i := range kernel {
+		kernel[i] /= sum
+	}
+	return kernel
+}
+
+// convolveImage blurs img's premultiplied channels with kernel along both axes.
+func convolveImage(img image.Image, kernel []float64) *image.NRGBA {
+	src := ToNRGBA(img)
+	b := src.Rect
+	w, h := b.Dx(), b.Dy()
+	var planes [4][]float64
+	for i := range planes {
+		planes[i] = make([]float64, w*h)
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := src.NRGBAAt(b.Min.X+x, b.Min.Y+y)
+			a := float64(c.A) / 255
+			i := y*w + x
+			planes[0][i], planes[1][i], planes[2][i], planes[3][i] = float64(c.R)*a, float64(c.G)*a, float64(c.B)*a, a
+		}
+	}
+	for _, p := range planes {
+		convolvePlane(p, w, h, kernel)
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for i := 0; i < w*h; i++ {
+		a := planes[3][i]
+		if a <= 0 {
+			continue
+		}
+		dst.SetNRGBA(i%w, i/w, color.NRGBA{
+			clamp8(planes[0][i] / a), clamp8(planes[1][i] / a), clamp8(planes[2][i] / a), clamp8(a * 255),
+		})
+	}
+	return dst
+}
+
+// convolvePlane convolves a w x h plane of values in place with kernel,
+// which has odd length and is centered, along rows and then columns.
+// Values beyond the edges repeat the edge.
+func convolvePlane(data []float64, w, h int, kernel []float64) {
+	convolveLines(data, w, h, 1, w, kernel)
+	convolveLines(data, h, w, w, 1, kernel)
+}
+
+// convolveLines convolves lines of n values, at data[i*step + line*stride].
+func convolveLines(data []float64, n, lines, step, stride int, kernel []float64) {
+	r := len(kernel) / 2
+	line := make([]float64, n)
+	for l := 0; l < lines; l++ {
+		base := l * stride
+		for i := range line {
+			line[i] = data[base+i*step]
+		}
+		for i := 0; i < n; i++ {
+			sum := 0.0
+			for k, weight := range kernel {
+				j := max(0, min(i+k-r, n-1))
+				sum += line[j] * weight
+			}
+			data[base+i*step] = sum
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestBlur(t *testing.T) {
+	// A white 9x9 image with one black pixel in the middle
+	img := solid(9, 9, color.NRGBA{255, 255, 255, 255})
+	img.SetNRGBA(4, 4, color.NRGBA{0, 0, 0, 255})
+
+	dst := Blur(img, 1)
+	center, near, far := dst.NRGBAAt(4, 4), dst.NRGBAAt(5, 4), dst.NRGBAAt(0, 0)
+	if !(center.R < near.R && near.R < far.R) {
+		t.Errorf("expected the dark spot to spread and fade, got %v, %v, %v", center, near, far)
+	}
+	if far != (color.NRGBA{255, 255, 255, 255}) {
+		t.Errorf("expected edges to stay white, got %v", far)
+	}
+	if dst.NRGBAAt(3, 4) != near || dst.NRGBAAt(4, 5) != near {
+		t.Error("expected a symmetric blur")
+	}
+
+	box := BoxBlur(img, 1)
+	// The 3x3 mean around the spot: 8 white pixels and 1 black
+	if got := box.NRGBAAt(5, 5).R; got != 227 {
+		t.Errorf("expected the box mean 227, got %d", got)
+	}
+	if got := box.NRGBAAt(7, 7).R; got != 255 {
+		t.Errorf("expected pixels out of reach to be unchanged, got %d", got)
+	}
+}
+
+func TestBlur_PremultipliedEdges(t *testing.T) {
+	// Red on transparent black: blurring must not darken the red
+	img := image.NewNRGBA(image.Rect(0, 0, 10, 1))
+	for x := 0; x < 5; x++ {
+		img.SetNRGBA(x, 0, color.NRGBA{255, 0, 0, 255})
+	}
+	dst := Blur(img, 2)
+	edge := dst.NRGBAAt(5, 0)
+	if edge.R != 255 || edge.A == 0 || edge.A == 255 {
+		t.Errorf("expected pure red with partial alpha past the edge, got %v", edge)
+	}
+}
+
+func TestBlur_Origin(t *testing.T) {
+	img := checker(20, 20).SubImage(image.Rect(5, 5, 15, 15)).(*image.NRGBA)
+	want := image.Rect(0, 0, 10, 10)
+	for name, dst := range map[string]*image.NRGBA{
+		"Blur": Blur(img, 1), "Blur no-op": Blur(img, 0),
+		"BoxBlur": BoxBlur(img, 1), "BoxBlur no-op": BoxBlur(img, 0),
+	} {
+		if dst.Rect != want {
+			t.Errorf("%s: bounds %v, want %v", name, dst.Rect, want)
+		}
+	}
+	if got, src := Blur(img, 0).NRGBAAt(0, 0), img.NRGBAAt(5, 5); got != src {
+		t.Errorf("expected the no-op to copy the pixels, got %v, want %v", got, src)
+	}
+}
+
+func TestSharpen(t *testing.T) {
+	// A step from dark to light gray gains contrast at the edge only
+	img := image.NewNRGBA(image.Rect(0, 0, 20, 1))
+	for x := 0; x < 20; x++ {
+		v := uint8(100)
+		if x >= 10 {
+			v = 150
+		}
+		img.SetNRGBA(x, 0, color.NRGBA{v, v, v, 200})
+	}
+	dst := Sharpen(img, 1, 1)
+	if dark, light := dst.NRGBAAt(9, 0), dst.NRGBAAt(10, 0); dark.R >= 100 || light.R <= 150 {
+		t.Errorf("expected the edge to gain contrast, got %v and %v", dark, light)
+	}
+	if got := dst.NRGBAAt(0, 0); got != (color.NRGBA{100, 100, 100, 200}) {
+		t.Errorf("expected flat areas and alpha to be kept, got %v", got)
+	}
+	if !equalPix(Sharpen(img, 1, 0), img) {
+		t.Error("expected zero amount to change nothing")
+	}
+}
+
+func TestBlurOps(t *testing.T) {
+	for _, expr := range []string{"blur:3", "blur:sigma=0.5", "boxblur:2", "sharpen", "sharpen:0.5", "sharpen:2,amount=1.5",
+		"trim:fuzz=5|resize:w=300|grayscale|sharpen:0.5"} {
+		if _, err := ParsePipeline(expr); err != nil {
+			t.Errorf("ParsePipeline(%q): %v", expr, err)
+		}
+	}
+	for _, expr := range []string{"blur", "blur:0", "blur:100", "boxblur:-1", "boxblur:x", "sharpen:0", "sharpen:1,amount=0", "sharpen:1,amount=9"} {
+		if _, err := ParsePipeline(expr); err == nil {
+			t.Errorf("ParsePipeline(%q) expected error", expr)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+// Gravity is an ImageMagick-style anchor for placing an image on a canvas.
+type Gravity int
+
+const (
+	GravityCenter Gravity = iota
+	GravityNorth
+	GravityNorthEast
+	GravityEast
+	GravitySouthEast
+	GravitySouth
+	GravitySouthWest
+	GravityWest
+	GravityNorthWest
+)
+
+// gravityNames are the ImageMagick names accepted by ParseGravity.
+var gravityNames = map[string]Gravity{
+	"center":    GravityCenter,
+	"north":     GravityNorth,
+	"northeast": GravityNorthEast,
+	"east":      GravityEast,
+	"southeast": GravitySouthEast,
+	"south":     GravitySouth,
+	"southwest": GravitySouthWest,
+	"west":      GravityWest,
+	"northwest": GravityNorthWest,
+}
+
+// ParseGravity parses an ImageMagick gravity name such as "center" or "NorthWest".
+func ParseGravity(s string) (Gravity, error) {
+	if g, ok := gravityNames[strings.ToLower(strings.TrimSpace(s))]; ok {
+		return g, nil
+	}
+	return 0, fmt.Errorf("unknown gravity %q", s)
+}
+
+// Place returns the top-left corner of a box of the given size anchored
+// within outer. As in ImageMagick, the offset moves the box away from the
+// edges it is anchored to, and right and down for centered axes.
+func (g Gravity) Place(outer image.Rectangle, size, offset image.Point) image.Point {
+	pt := outer.Min
+	switch g {
+	case GravityNorthEast, GravityEast, GravitySouthEast:
+		pt.X += outer.Dx() - size.X - offset.X
+	case GravityNorthWest, GravityWest, GravitySouthWest:
+		pt.X += offset.X
+	default:
+		pt.X += (outer.Dx()-size.X)/2 + offset.X
+	}
+	switch g {
+	case GravitySouthWest, GravitySouth, GravitySouthEast:
+		pt.Y += outer.Dy() - size.Y - offset.Y
+	case GravityNorthWest, GravityNorth, GravityNorthEast:
+		pt.Y += offset.Y
+	default:
+		pt.Y += (outer.Dy()-size.Y)/2 + offset.Y
+	}
+	return pt
+}
+
+// Extent places img unscaled on a width x height canvas of color bg, anchored
+// by gravity, like ImageMagick's -extent. Parts falling outside are cropped.
+func Extent(img image.Image, width, height int, gravity Gravity, offset image.Point, bg color.Color) image.Image {
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+
+	b := img.Bounds()
+	pt := gravity.Place(dst.Bounds(), b.Size(), offset)
+	draw.Draw(dst, image.Rectangle{pt, pt.Add(b.Size())}, img, b.Min, draw.Over)
+	return dst
+}
+
+// Extend places img unscaled on a canvas of at least width x height, anchored
+// by gravity, as when making a 1080x1080 tile from a 900x600 photo. Unlike
+// Extent it never crops: a dimension smaller than the image keeps the image's
+// size. Use a transparent bg to letterbox with transparency.
+func Extend(img image.Image, width, height int, gravity Gravity, bg color.Color) image.Image {
+	b := img.Bounds()
+	return Extent(img, max(width, b.Dx()), max(height, b.Dy()), gravity, image.Point{}, bg)
+}
+
+// Canvas scales img to fit within fill percent of a width x height canvas,
+// preserving aspect ratio, and places it with Extent. This is the usual
+// marketplace layout, e.g. a product centered at 85% on 1000x1000 white.
+func Canvas(img image.Image, width, height int, fill float64, gravity Gravity, offset image.Point, bg color.Color) image.Image {
+	box := Geometry{
+		Width:  max(1, int(math.Round(float64(width)*fill/100))),
+		Height: max(1, int(math.Round(float64(height)*fill/100))),
+	}
+	w, h := box.Size(img.Bounds().Dx(), img.Bounds().Dy())
+	return Extent(Resize(img, w, h), width, height, gravity, offset, bg)
+}
+
+// ParseAspect parses an aspect ratio written as "W:H" (e.g. "1:1", "4:3") or
+// as a single number of width per height (e.g. "1.5").
+func ParseAspect(s string) (float64, error) {
+	w, h, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok {
+		h = "1"
+	}
+	fw, err1 := strconv.ParseFloat(strings.TrimSpace(w), 64)
+	fh, err2 := strconv.ParseFloat(strings.TrimSpace(h), 64)
+	if err1 != nil || err2 != nil || fw <= 0 || fh <= 0 || math.IsInf(fw/fh, 0) {
+		return 0, fmt.Errorf("invalid aspect ratio %q", s)
+	}
+	return fw / fh, nil
+}
+
+// PadToAspect pads img out to the aspect ratio (width per height) with
+// margins of color bg, anchored by gravity, without scaling or cropping it.
+// Run after Trim this gives the usual product photo: the subject tight in a
+// square, say, with even margins. An image already at the ratio is returned
+// unchanged.
+func PadToAspect(img image.Image, aspect float64, gravity Gravity, bg color.Color) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w == 0 || h == 0 {
+		return img
+	}
+	if float64(w)/float64(h) < aspect {
+		w = max(w, int(math.Round(float64(h)*aspect)))
+	} else {
+		h = max(h, int(math.Round(float64(w)/aspect)))
+	}
+	if w == b.Dx() && h == b.Dy() {
+		return img
+	}
+	return Extent(img, w, h, gravity, image.Point{}, bg)
+}
+
+// Insets are margins in pixels on each side of an image.
+type Insets struct {
+	Top, Right, Bottom, Left int
+}
+
+// ParseInsets parses margins written as in CSS: one value for all sides
+// ("20"), two for vertical and horizontal ("10,20"), or four for top, right,
+// bottom, and left ("10,20,10,20"). Values may also be separated by spaces.
+func ParseInsets(s string) (Insets, error) {
+	fields := strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' })
+	n := make([]int, len(fields))
+	for i, f := range fields {
+		v, err := strconv.Atoi(f)
+		if err != nil || v < 0 {
+			return Insets{}, fmt.Errorf("invalid margin %q", f)
+		}
+		n[i] = v
+	}
+	switch len(n) {
+	case 1:
+		return Insets{n[0], n[0], n[0], n[0]}, nil
+	case 2:
+		return Insets{n[0], n[1], n[0], n[1]}, nil
+	case 4:
+		return Insets{n[0], n[1], n[2], n[3]}, nil
+	}
+	return Insets{}, fmt.Errorf("margins %q must be 1, 2, or 4 numbers", s)
+}
+
+// AddBorder surrounds img with a uniform border thickness pixels wide of color c.
+func AddBorder(img image.Image, thickness int, c color.Color) image.Image {
+	return Pad(img, Insets{thickness, thickness, thickness, thickness}, c)
+}
+
+// Pad surrounds img with margins of color c, which may differ per side. The
+// result starts at (0, 0). Negative margins are treated as zero.
+func Pad(img image.Image, in Insets, c color.Color) image.Image {
+	in = Insets{max(in.Top, 0), max(in.Right, 0), max(in.Bottom, 0), max(in.Left, 0)}
+	if in == (Insets{}) {
+		return img
+	}
+	b := img.Bounds()
+	return Extent(img, b.Dx()+in.Left+in.Right, b.Dy()+in.Top+in.Bottom, GravityNorthWest, image.Pt(in.Left, in.Top), c)
+}
@@ -0,0 +1,258 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestGravity_Place(t *testing.T) {
+	outer := image.Rect(0, 0, 100, 50)
+	size := image.Pt(20, 10)
+	tests := []struct {
+		gravity Gravity
+		offset  image.Point
+		want    image.Point
+	}{
+		{GravityCenter, image.Point{}, image.Pt(40, 20)},
+		{GravityCenter, image.Pt(5, -5), image.Pt(45, 15)},
+		{GravityNorthWest, image.Pt(5, 5), image.Pt(5, 5)},
+		{GravitySouthEast, image.Pt(5, 5), image.Pt(75, 35)},
+		{GravityNorth, image.Point{}, image.Pt(40, 0)},
+		{GravityWest, image.Point{}, image.Pt(0, 20)},
+	}
+	for _, tc := range tests {
+		if got := tc.gravity.Place(outer, size, tc.offset); got != tc.want {
+			t.Errorf("gravity %d offset %v: expected %v, got %v", tc.gravity, tc.offset, tc.want, got)
+		}
+	}
+
+	if g, err := ParseGravity("NorthEast"); err != nil || g != GravityNorthEast {
+		t.Errorf("expected GravityNorthEast, got %d, %v", g, err)
+	}
+	if _, err := ParseGravity("up"); err == nil {
+		t.Error("expected error for an unknown gravity")
+	}
+}
+
+func TestCanvas(t *testing.T) {
+	// A red 200x100 product on a 1000x1000 white canvas at 85%
+	img := image.NewNRGBA(image.Rect(0, 0, 200, 100))
+	for i := 0; i < len(img.Pix); i += 4 {
+		copy(img.Pix[i:], []uint8{255, 0, 0, 255})
+	}
+
+	p, err := ParsePipeline("canvas:1000x1000,fill=85")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := p.Apply(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := result.Bounds(); b != image.Rect(0, 0, 1000, 1000) {
+		t.Fatalf("expected a 1000x1000 canvas, got %v", b)
+	}
+	// Scaled to 850x425, centered at (75, 288)
+	for _, tc := range []struct {
+		x, y int
+		want color.NRGBA
+	}{
+		{500, 500, color.NRGBA{255, 0, 0, 255}},
+		{80, 300, color.NRGBA{255, 0, 0, 255}},
+		{70, 500, color.NRGBA{255, 255, 255, 255}},
+		{500, 280, color.NRGBA{255, 255, 255, 255}},
+	} {
+		if got := color.NRGBAModel.Convert(result.At(tc.x, tc.y)); got != tc.want {
+			t.Errorf("at (%d,%d): expected %v, got %v", tc.x, tc.y, tc.want, got)
+		}
+	}
+
+	for _, expr := range []string{"canvas", "canvas:50%", "canvas:100x100,fill=0", "canvas:100x100,gravity=up"} {
+		if _, err := ParsePipeline(expr); err == nil {
+			t.Errorf("ParsePipeline(%q) expected error", expr)
+		}
+	}
+}
+
+func TestExtent_Crops(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 30, 30))
+	result := Extent(img, 10, 10, GravityCenter, image.Point{}, color.White)
+	if b := result.Bounds(); b != image.Rect(0, 0, 10, 10) {
+		t.Errorf("expected a 10x10 result, got %v", b)
+	}
+}
+
+func TestExtend(t *testing.T) {
+	// A red 90x60 photo on a 108x108 tile
+	img := image.NewNRGBA(image.Rect(10, 10, 100, 70))
+	for i := 0; i < len(img.Pix); i += 4 {
+		copy(img.Pix[i:], []uint8{255, 0, 0, 255})
+	}
+	p, err := ParsePipeline("extend:108x108,gravity=south")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := p.Apply(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := result.Bounds(); b != image.Rect(0, 0, 108, 108) {
+		t.Fatalf("expected a 108x108 tile, got %v", b)
+	}
+	for _, tc := range []struct {
+		x, y int
+		want color.NRGBA
+	}{{8, 47, color.NRGBA{}}, {9, 48, color.NRGBA{255, 0, 0, 255}}, {98, 107, color.NRGBA{255, 0, 0, 255}}, {99, 107, color.NRGBA{}}} {
+		if got := color.NRGBAModel.Convert(result.At(tc.x, tc.y)); got != tc.want {
+			t.Errorf("(%d, %d): expected %v, got %v", tc.x, tc.y, tc.want, got)
+		}
+	}
+
+	// Sizes smaller than the image never crop it
+	if b := Extend(img, 50, 100, GravityCenter, color.White).Bounds(); b != image.Rect(0, 0, 90, 100) {
+		t.Errorf("expected 90x100, got %v", b)
+	}
+	for _, expr := range []string{"extend", "extend:50%", "extend:100x100,gravity=up"} {
+		if _, err := ParsePipeline(expr); err == nil {
+			t.Errorf("ParsePipeline(%q) expected error", expr)
+		}
+	}
+}
+
+func TestPadToAspect(t *testing.T) {
+	// A white 40x20 image with a red 10x10 product in the middle
+	img := image.NewNRGBA(image.Rect(0, 0, 40, 20))
+	for i := 0; i < len(img.Pix); i += 4 {
+		copy(img.Pix[i:], []uint8{255, 255, 255, 255})
+	}
+	red := color.NRGBA{255, 0, 0, 255}
+	for y := 5; y < 15; y++ {
+		for x := 15; x < 25; x++ {
+			img.SetNRGBA(x, y, red)
+		}
+	}
+
+	p, err := ParsePipeline("trim|aspect:2:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := p.Apply(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Trimmed to 10x10, then padded to 20x10 with transparent margins
+	if b := result.Bounds(); b != image.Rect(0, 0, 20, 10) {
+		t.Fatalf("expected 20x10, got %v", b)
+	}
+	for _, tc := range []struct {
+		x, y int
+		want color.NRGBA
+	}{
+		{0, 5, color.NRGBA{}},
+		{4, 5, color.NRGBA{}},
+		{5, 5, red},
+		{14, 5, red},
+		{15, 5, color.NRGBA{}},
+	} {
+		if got := color.NRGBAModel.Convert(result.At(tc.x, tc.y)); got != tc.want {
+			t.Errorf("(%d, %d): expected %v, got %v", tc.x, tc.y, tc.want, got)
+		}
+	}
+
+	// Taller ratios pad the top and bottom, in the given color and gravity
+	tall := PadToAspect(img, 0.5, GravityNorth, color.Black)
+	if b := tall.Bounds(); b != image.Rect(0, 0, 40, 80) {
+		t.Fatalf("expected 40x80, got %v", b)
+	}
+	if got := color.NRGBAModel.Convert(tall.At(20, 79)); got != (color.NRGBA{0, 0, 0, 255}) {
+		t.Errorf("expected a black bottom margin, got %v", got)
+	}
+	if got := PadToAspect(img, 2, GravityCenter, color.Black); got != image.Image(img) {
+		t.Error("expected an image already at the ratio to be returned unchanged")
+	}
+}
+
+func TestParseAspect(t *testing.T) {
+	for s, want := range map[string]float64{"1:1": 1, "4:3": 4.0 / 3, " 16 : 9 ": 16.0 / 9, "1.5": 1.5} {
+		if got, err := ParseAspect(s); err != nil || got != want {
+			t.Errorf("ParseAspect(%q) = %g, %v; expected %g", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"", "square", "0:1", "1:0", "-1:2"} {
+		if _, err := ParseAspect(s); err == nil {
+			t.Errorf("ParseAspect(%q): expected error", s)
+		}
+	}
+}
+
+func TestPad(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(5, 5, 15, 10))
+	red := color.NRGBA{255, 0, 0, 255}
+	for y := 5; y < 10; y++ {
+		for x := 5; x < 15; x++ {
+			img.SetNRGBA(x, y, red)
+		}
+	}
+
+	bordered := AddBorder(img, 3, color.White)
+	if b := bordered.Bounds(); b != image.Rect(0, 0, 16, 11) {
+		t.Fatalf("expected 16x11, got %v", b)
+	}
+	white := color.NRGBA{255, 255, 255, 255}
+	for _, tc := range []struct {
+		x, y int
+		want color.NRGBA
+	}{{0, 0, white}, {2, 5, white}, {3, 3, red}, {12, 7, red}, {13, 7, white}, {8, 8, white}} {
+		if got := color.NRGBAModel.Convert(bordered.At(tc.x, tc.y)); got != tc.want {
+			t.Errorf("(%d, %d): expected %v, got %v", tc.x, tc.y, tc.want, got)
+		}
+	}
+
+	// Per-side margins from a pipeline, transparent by default
+	p, err := ParsePipeline("pad:top=4,left=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	padded, err := p.Apply(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := padded.Bounds(); b != image.Rect(0, 0, 12, 9) {
+		t.Fatalf("expected 12x9, got %v", b)
+	}
+	if got := color.NRGBAModel.Convert(padded.At(0, 0)); got != (color.NRGBA{}) {
+		t.Errorf("expected a transparent margin, got %v", got)
+	}
+	if got := color.NRGBAModel.Convert(padded.At(2, 4)); got != red {
+		t.Errorf("expected the image at (2, 4), got %v", got)
+	}
+
+	if Pad(img, Insets{}, color.White) != image.Image(img) {
+		t.Error("expected no margins to return the image unchanged")
+	}
+	for _, expr := range []string{"pad", "pad:1,2,3", "pad:-1", "pad:top=-1", "pad:5,color=nope"} {
+		if _, err := ParsePipeline(expr); err == nil {
+			t.Errorf("ParsePipeline(%q) expected error", expr)
+		}
+	}
+}
+
+func TestParseInsets(t *testing.T) {
+	for s, want := range map[string]Insets{
+		"20":         {20, 20, 20, 20},
+		"10,20":      {10, 20, 10, 20},
+		"1 2 3 4":    {1, 2, 3, 4},
+		"1, 2, 3, 4": {1, 2, 3, 4},
+		"0,0,0,5":    {0, 0, 0, 5},
+	} {
+		if got, err := ParseInsets(s); err != nil || got != want {
+			t.Errorf("ParseInsets(%q) = %+v, %v; expected %+v", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"", "a", "1,2,3", "-5"} {
+		if _, err := ParseInsets(s); err == nil {
+			t.Errorf("ParseInsets(%q): expected error", s)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// Default chroma key settings, in percent of the distance from black to white.
+const (
+	DefaultKeyTolerance = 30
+	DefaultKeySoftness  = 10
+)
+
+// ChromaKey makes every pixel close to keyColor transparent, wherever it is in
+// the image, as for green-screen footage. Unlike RemoveBackground the key
+// color need not be connected to the edges, so gaps between a subject's arms
+// are keyed out too.
+//
+// Pixels within tolerance percent of keyColor become fully transparent, and
+// those within a further softness percent fade from transparent to opaque, so
+// the subject's edge stays smooth. Distances are measured in RGB and scaled so
+// that 100 is the distance from black to white.
+//
+// Spill suppression removes the key color's cast from the pixels that remain:
+// the key's strongest channel is limited to the larger of the other two, so a
+// green screen's reflection on skin or hair turns neutral. The result is a
+// copy; img is not modified.
+func ChromaKey(img image.Image, keyColor color.Color, tolerance, softness float64) *image.NRGBA {
+	dst := ToNRGBA(Clone(img))
+	key := color.NRGBAModel.Convert(keyColor).(color.NRGBA)
+	spill := spillChannel(key)
+	inner, outer := tolerance/100, (tolerance+softness)/100
+
+	b := dst.Rect
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := dst.NRGBAAt(x, y)
+			if c.A == 0 {
+				continue
+			}
+			d := rgbDistance(c, key)
+			switch {
+			case d <= inner:
+				dst.SetNRGBA(x, y, color.NRGBA{})
+				continue
+			case d < outer:
+				c.A = uint8(math.Round(float64(c.A) * (d - inner) / (outer - inner)))
+			}
+			if spill >= 0 {
+				ch := []*uint8{&c.R, &c.G, &c.B}
+				limit := max(*ch[(spill+1)%3], *ch[(spill+2)%3])
+				if *ch[spill] > limit {
+					*ch[spill] = limit
+				}
+			}
+			dst.SetNRGBA(x, y, c)
+		}
+	}
+	return dst
+}
+
+// spillChannel returns the index (0 red, 1 green, 2 blue) of the channel
+// that dominates c, or -1 when no channel does, as for gray keys that cast
+// no color.
+func spillChannel(c color.NRGBA) int {
+	ch := [3]uint8{c.R, c.G, c.B}
+	best := 0
+	for i := 1; i < 3; i++ {
+		if ch[i] > ch[best] {
+			best = i
+		}
+	}
+	if ch[best] == ch[(best+1)%3] || ch[best] == ch[(best+2)%3] {
+		return -1
+	}
+	return best
+}
+
+// rgbDistance returns the Euclidean distance between the colors of two
+// pixels, ignoring alpha, normalized so that 1 is the distance from black to white.
+func rgbDistance(c1, c2 color.NRGBA) float64 {
+	dr := float64(c1.R) - float64(c2.R)
+	dg := float64(c1.G) - float64(c2.G)
+	db := float64(c1.B) - float64(c2.B)
+	return math.Sqrt(dr*dr+dg*dg+db*db) / (math.Sqrt(3) * 255)
+}
@@ -0,0 +1,80 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestChromaKey(t *testing.T) {
+	green := color.NRGBA{0, 255, 0, 255}
+	img := image.NewNRGBA(image.Rect(0, 0, 30, 10))
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 30; x++ {
+			img.SetNRGBA(x, y, green)
+		}
+	}
+	// A subject with a green hole not connected to the edges
+	for y := 2; y < 8; y++ {
+		for x := 2; x < 12; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{200, 150, 120, 255})
+		}
+	}
+	img.SetNRGBA(6, 5, green)
+	// Skin with a green cast from the screen, and a pixel halfway to the key
+	img.SetNRGBA(20, 5, color.NRGBA{180, 200, 140, 255})
+	img.SetNRGBA(24, 5, color.NRGBA{40, 220, 40, 255})
+
+	dst := ChromaKey(img, green, DefaultKeyTolerance, DefaultKeySoftness)
+
+	if c := dst.NRGBAAt(0, 0); c.A != 0 {
+		t.Errorf("expected the screen to be keyed out, got %v", c)
+	}
+	if c := dst.NRGBAAt(6, 5); c.A != 0 {
+		t.Errorf("expected the enclosed key color to be keyed out, got %v", c)
+	}
+	if c := dst.NRGBAAt(3, 3); c != (color.NRGBA{200, 150, 120, 255}) {
+		t.Errorf("expected the subject to be unchanged, got %v", c)
+	}
+	if c := dst.NRGBAAt(20, 5); c != (color.NRGBA{180, 180, 140, 255}) {
+		t.Errorf("expected the green spill to be suppressed, got %v", c)
+	}
+	if c := dst.NRGBAAt(24, 5); c.A != 0 {
+		t.Errorf("expected a near-key pixel to be keyed out, got %v", c)
+	}
+	if img.NRGBAAt(0, 0) != green {
+		t.Error("expected the source to be unmodified")
+	}
+}
+
+func TestChromaKeySoftness(t *testing.T) {
+	key := color.NRGBA{0, 0, 255, 255}
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 1))
+	img.SetNRGBA(0, 0, color.NRGBA{0, 0, 200, 255})   // within tolerance
+	img.SetNRGBA(1, 0, color.NRGBA{60, 60, 220, 255}) // within softness
+	img.SetNRGBA(2, 0, color.NRGBA{255, 255, 0, 255}) // far away
+
+	dst := ChromaKey(img, key, 20, 20)
+	if a := dst.NRGBAAt(0, 0).A; a != 0 {
+		t.Errorf("expected alpha 0 within tolerance, got %d", a)
+	}
+	if a := dst.NRGBAAt(1, 0).A; a == 0 || a == 255 {
+		t.Errorf("expected partial alpha within softness, got %d", a)
+	}
+	if a := dst.NRGBAAt(2, 0).A; a != 255 {
+		t.Errorf("expected alpha 255 outside, got %d", a)
+	}
+}
+
+func TestChromaKeyOp(t *testing.T) {
+	for _, expr := range []string{"chromakey", "chromakey:#00ff00,20,5", "chromakey:color=blue,tolerance=10"} {
+		if _, err := ParsePipeline(expr); err != nil {
+			t.Errorf("ParsePipeline(%q): %v", expr, err)
+		}
+	}
+	for _, expr := range []string{"chromakey:nope", "chromakey:tolerance=-1", "chromakey:softness=200"} {
+		if _, err := ParsePipeline(expr); err == nil {
+			t.Errorf("ParsePipeline(%q) expected error", expr)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package imaging
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// namedColors are the color names accepted by ParseColor.
+var namedColors = map[string]color.NRGBA{
+	"transparent": {0, 0, 0, 0},
+	"none":        {0, 0, 0, 0},
+	"white":       {255, 255, 255, 255},
+	"black":       {0, 0, 0, 255},
+	"red":         {255, 0, 0, 255},
+	"green":       {0, 128, 0, 255},
+	"lime":        {0, 255, 0, 255},
+	"blue":        {0, 0, 255, 255},
+	"yellow":      {255, 255, 0, 255},
+	"cyan":        {0, 255, 255, 255},
+	"magenta":     {255, 0, 255, 255},
+	"gray":        {128, 128, 128, 255},
+	"grey":        {128, 128, 128, 255},
+	"orange":      {255, 165, 0, 255},
+}
+
+// ParseColor parses a hex color ("#rgb", "#rrggbb", "#rrggbbaa", with or
+// without the leading '#') or a basic color name such as "white" or "none".
+func ParseColor(s string) (color.NRGBA, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if c, ok := namedColors[s]; ok {
+		return c, nil
+	}
+
+	hex := strings.TrimPrefix(s, "#")
+	switch len(hex) {
+	case 3:
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]}) + "ff"
+	case 6:
+		hex += "ff"
+	case 8:
+	default:
+		return color.NRGBA{}, fmt.Errorf("invalid color %q", s)
+	}
+
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.NRGBA{}, fmt.Errorf("invalid color %q", s)
+	}
+	return color.NRGBA{uint8(v >> 24), uint8(v >> 16), uint8(v >> 8), uint8(v)}, nil
+}
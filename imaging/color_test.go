@@ -0,0 +1,32 @@
+package imaging
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		in   string
+		want color.NRGBA
+	}{
+		{"#ff0000", color.NRGBA{255, 0, 0, 255}},
+		{"00ff00", color.NRGBA{0, 255, 0, 255}},
+		{"#abc", color.NRGBA{0xaa, 0xbb, 0xcc, 255}},
+		{"#11223344", color.NRGBA{0x11, 0x22, 0x33, 0x44}},
+		{"White", color.NRGBA{255, 255, 255, 255}},
+		{"none", color.NRGBA{}},
+	}
+	for _, tt := range tests {
+		got, err := ParseColor(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseColor(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "#12", "#gggggg", "chartreuse-ish"} {
+		if _, err := ParseColor(in); err == nil {
+			t.Errorf("ParseColor(%q) expected error", in)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+)
+
+// BlendMode is how a layer's colors combine with the layers below it.
+type BlendMode int
+
+const (
+	BlendNormal BlendMode = iota
+	BlendMultiply
+	BlendScreen
+	BlendOverlay
+	BlendDarken
+	BlendLighten
+)
+
+// blendModeNames are the names accepted by ParseBlendMode.
+var blendModeNames = map[string]BlendMode{
+	"normal":   BlendNormal,
+	"multiply": BlendMultiply,
+	"screen":   BlendScreen,
+	"overlay":  BlendOverlay,
+	"darken":   BlendDarken,
+	"lighten":  BlendLighten,
+}
+
+// ParseBlendMode parses a blend mode name such as "multiply". The empty
+// string is BlendNormal.
+func ParseBlendMode(s string) (BlendMode, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return BlendNormal, nil
+	}
+	if m, ok := blendModeNames[s]; ok {
+		return m, nil
+	}
+	return 0, fmt.Errorf("unknown blend mode %q", s)
+}
+
+// blend combines a layer channel value s over a backdrop value d, both in
+// 0-1, as in the W3C compositing spec.
+func (m BlendMode) blend(s, d float64) float64 {
+	switch m {
+	case BlendMultiply:
+		return s * d
+	case BlendScreen:
+		return s + d - s*d
+	case BlendOverlay:
+		if d <= 0.5 {
+			return 2 * s * d
+		}
+		return 1 - 2*(1-s)*(1-d)
+	case BlendDarken:
+		return min(s, d)
+	case BlendLighten:
+		return max(s, d)
+	}
+	return s
+}
+
+// Layer is one image in a Composite.
+type Layer struct {
+	Image image.Image
+	// Position is where the layer's top-left corner goes on the canvas.
+	Position image.Point
+	// Scale resizes the layer first; zero means 1.
+	Scale float64
+	// Opacity scales the layer's alpha, from 0 to 1.
+	Opacity float64
+	Blend   BlendMode
+}
+
+// Composite flattens layers, bottom first, onto a width x height canvas of
+// color bg, as for banners and thumbnails built from several images. A zero
+// width or height takes the size of the first layer after scaling. Layers
+// may hang off the canvas; the parts outside are dropped.
+func Composite(width, height int, bg color.Color, layers []Layer) *image.NRGBA {
+	scaled := make([]image.Image, len(layers))
+	for i, l := range layers {
+		scaled[i] = l.Image
+		if l.Scale > 0 && l.Scale != 1 {
+			b := l.Image.Bounds()
+			w := max(1, int(math.Round(float64(b.Dx())*l.Scale)))
+			h := max(1, int(math.Round(float64(b.Dy())*l.Scale)))
+			scaled[i] = Resize(l.Image, w, h)
+		}
+	}
+	if len(layers) > 0 {
+		if width == 0 {
+			width = scaled[0].Bounds().Dx()
+		}
+		if height == 0 {
+			height = scaled[0].Bounds().Dy()
+		}
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	if bg != nil {
+		c := color.NRGBAModel.Convert(bg).(color.NRGBA)
+		for i := 0; i < len(dst.Pix); i += 4 {
+			copy(dst.Pix[i:], []uint8{c.R, c.G, c.B, c.A})
+		}
+	}
+	for i, l := range layers {
+		compositeLayer(dst, scaled[i], l.Position, max(0, min(l.Opacity, 1)), l.Blend)
+	}
+	return dst
+}
+
+// compositeLayer blends src onto dst with its top-left corner at pos, using
+// source-over compositing with the blend mode applied where both have color.
+func compositeLayer(dst *image.NRGBA, src image.Image, pos image.Point, opacity float64, mode BlendMode) {
+	sb := src.Bounds()
+	r := image.Rectangle{pos, pos.Add(sb.Size())}.Intersect(dst.Rect)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			s := color.NRGBAModel.Convert(src.At(sb.Min.X+x-pos.X, sb.Min.Y+y-pos.Y)).(color.NRGBA)
+			sa := float64(s.A) / 255 * opacity
+			if sa == 0 {
+				continue
+			}
+			d := dst.NRGBAAt(x, y)
+			da := float64(d.A) / 255
+			out := sa + da*(1-sa)
+			ch := func(sc, dc uint8) uint8 {
+				cs, cd := float64(sc)/255, float64(dc)/255
+				// The blended color replaces the layer's where the backdrop shows
+				mixed := (1-da)*cs + da*mode.blend(cs, cd)
+				v := (sa*mixed + da*(1-sa)*cd) / out
+				return uint8(math.Round(v * 255))
+			}
+			dst.SetNRGBA(x, y, color.NRGBA{ch(s.R, d.R), ch(s.G, d.G), ch(s.B, d.B), uint8(math.Round(out * 255))})
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestComposite(t *testing.T) {
+	white := solid(40, 20, color.NRGBA{255, 255, 255, 255})
+	gray := solid(10, 10, color.NRGBA{128, 128, 128, 255})
+	red := solid(4, 4, color.NRGBA{255, 0, 0, 255})
+
+	dst := Composite(0, 0, nil, []Layer{
+		{Image: white, Opacity: 1},
+		{Image: gray, Position: image.Pt(2, 2), Opacity: 1, Blend: BlendMultiply},
+		{Image: red, Position: image.Pt(20, 5), Scale: 2, Opacity: 0.5},
+		{Image: red, Position: image.Pt(38, 18), Opacity: 1}, // hangs off the canvas
+	})
+	if b := dst.Bounds(); b != image.Rect(0, 0, 40, 20) {
+		t.Fatalf("expected the first layer's size, got %v", b)
+	}
+	for _, tc := range []struct {
+		x, y int
+		want color.NRGBA
+	}{
+		{0, 0, color.NRGBA{255, 255, 255, 255}},
+		{5, 5, color.NRGBA{128, 128, 128, 255}}, // gray multiplied onto white
+		{27, 12, color.NRGBA{255, 128, 128, 255}},
+		{28, 12, color.NRGBA{255, 255, 255, 255}}, // past the scaled layer
+		{39, 19, color.NRGBA{255, 0, 0, 255}},
+	} {
+		if got := dst.NRGBAAt(tc.x, tc.y); got != tc.want {
+			t.Errorf("(%d, %d): expected %v, got %v", tc.x, tc.y, tc.want, got)
+		}
+	}
+
+	// Blend modes only apply where there is a backdrop
+	onClear := Composite(4, 4, color.Transparent, []Layer{{Image: red, Opacity: 1, Blend: BlendMultiply}})
+	if got := onClear.NRGBAAt(1, 1); got != (color.NRGBA{255, 0, 0, 255}) {
+		t.Errorf("expected the layer's own color on a clear canvas, got %v", got)
+	}
+	onBlack := Composite(4, 4, color.Black, []Layer{{Image: red, Opacity: 1, Blend: BlendScreen}})
+	if got := onBlack.NRGBAAt(1, 1); got != (color.NRGBA{255, 0, 0, 255}) {
+		t.Errorf("expected screen onto black to keep the color, got %v", got)
+	}
+}
+
+func TestParseBlendMode(t *testing.T) {
+	for s, want := range map[string]BlendMode{"": BlendNormal, "Multiply": BlendMultiply, "overlay": BlendOverlay} {
+		if got, err := ParseBlendMode(s); err != nil || got != want {
+			t.Errorf("ParseBlendMode(%q) = %d, %v; expected %d", s, got, err, want)
+		}
+	}
+	if _, err := ParseBlendMode("dissolve"); err == nil {
+		t.Error("expected error for an unknown blend mode")
+	}
+}
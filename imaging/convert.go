@@ -0,0 +1,247 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+
+	"golang.org/x/image/draw"
+)
+
+// subImager is implemented by the standard library image types.
+type subImager interface {
+	SubImage(r image.Rectangle) image.Image
+}
+
+// Crop returns the portion of img inside rect, clipped to img's bounds. When
+// img supports SubImage the result is a zero-copy view sharing pixels with img;
+// otherwise the pixels are copied into a new RGBA image. Either way the result
+// keeps rect's coordinates. Use Clone when an independent buffer is needed.
+func Crop(img image.Image, rect image.Rectangle) image.Image {
+	rect = rect.Intersect(img.Bounds())
+	if si, ok := img.(subImager); ok {
+		return si.SubImage(rect)
+	}
+
+	cropped := image.NewRGBA(rect)
+	draw.Copy(cropped, rect.Min, img, rect, draw.Src, nil)
+	return cropped
+}
+
+// Clone returns a copy of img backed by its own pixel buffer, keeping the
+// same bounds. RGBA, NRGBA, Gray, and Paletted images keep their type; other
+// images are converted to RGBA.
+func Clone(img image.Image) image.Image {
+	bounds := img.Bounds()
+	if bounds.Empty() {
+		return image.NewRGBA(bounds)
+	}
+	switch src := img.(type) {
+	case *image.RGBA:
+		dst := image.NewRGBA(bounds)
+		copyRows(dst.Pix, dst.Stride, src.Pix[src.PixOffset(bounds.Min.X, bounds.Min.Y):], src.Stride, bounds.Dx()*4, bounds.Dy())
+		return dst
+	case *image.NRGBA:
+		dst := image.NewNRGBA(bounds)
+		copyRows(dst.Pix, dst.Stride, src.Pix[src.PixOffset(bounds.Min.X, bounds.Min.Y):], src.Stride, bounds.Dx()*4, bounds.Dy())
+		return dst
+	case *image.Gray:
+		dst := image.NewGray(bounds)
+		copyRows(dst.Pix, dst.Stride, src.Pix[src.PixOffset(bounds.Min.X, bounds.Min.Y):], src.Stride, bounds.Dx(), bounds.Dy())
+		return dst
+	case *image.Paletted:
+		palette := make([]color.Color, len(src.Palette))
+		copy(palette, src.Palette)
+		dst := image.NewPaletted(bounds, palette)
+		copyRows(dst.Pix, dst.Stride, src.Pix[src.PixOffset(bounds.Min.X, bounds.Min.Y):], src.Stride, bounds.Dx(), bounds.Dy())
+		return dst
+	}
+
+	dst := image.NewRGBA(bounds)
+	draw.Copy(dst, bounds.Min, img, bounds, draw.Src, nil)
+	return dst
+}
+
+// copyRows copies rows of rowLen bytes between buffers with different strides.
+func copyRows(dst []byte, dstStride int, src []byte, srcStride int, rowLen, rows int) {
+	for y := 0; y < rows; y++ {
+		copy(dst[y*dstStride:y*dstStride+rowLen], src[y*srcStride:y*srcStride+rowLen])
+	}
+}
+
+// ToNRGBA converts img to non-premultiplied RGBA, keeping its bounds.
+// If img is already *image.NRGBA it is returned unchanged.
+func ToNRGBA(img image.Image) *image.NRGBA {
+	bounds := img.Bounds()
+	switch src := img.(type) {
+	case *image.NRGBA:
+		return src
+	case *image.RGBA:
+		dst := image.NewNRGBA(bounds)
+		for y := 0; y < bounds.Dy(); y++ {
+			si := src.PixOffset(bounds.Min.X, bounds.Min.Y+y)
+			di := y * dst.Stride
+			for x := 0; x < bounds.Dx(); x++ {
+				r, g, b, a := src.Pix[si], src.Pix[si+1], src.Pix[si+2], src.Pix[si+3]
+				switch a {
+				case 0:
+					r, g, b = 0, 0, 0
+				case 0xff:
+				default:
+					r = uint8(uint32(r) * 0xff / uint32(a))
+					g = uint8(uint32(g) * 0xff / uint32(a))
+					b = uint8(uint32(b) * 0xff / uint32(a))
+				}
+				dst.Pix[di], dst.Pix[di+1], dst.Pix[di+2], dst.Pix[di+3] = r, g, b, a
+				si += 4
+				di += 4
+			}
+		}
+		return dst
+	case *image.Gray:
+		dst := image.NewNRGBA(bounds)
+		for y := 0; y < bounds.Dy(); y++ {
+			si := src.PixOffset(bounds.Min.X, bounds.Min.Y+y)
+			di := y * dst.Stride
+			for x := 0; x < bounds.Dx(); x++ {
+				v := src.Pix[si+x]
+				dst.Pix[di], dst.Pix[di+1], dst.Pix[di+2], dst.Pix[di+3] = v, v, v, 0xff
+				di += 4
+			}
+		}
+		return dst
+	}
+
+	dst := image.NewNRGBA(bounds)
+	draw.Draw(dst, bounds, img, bounds.Min, draw.Src)
+	return dst
+}
+
+// copyNRGBA returns img as a new *image.NRGBA that callers may modify. Other
+// image types are converted straight into the copy rather than cloned first.
+func copyNRGBA(img image.Image) *image.NRGBA {
+	src, ok := img.(*image.NRGBA)
+	if !ok {
+		return ToNRGBA(img)
+	}
+	dst := image.NewNRGBA(src.Rect)
+	if !dst.Rect.Empty() {
+		copyRows(dst.Pix, dst.Stride, src.Pix[src.PixOffset(src.Rect.Min.X, src.Rect.Min.Y):], src.Stride, src.Rect.Dx()*4, src.Rect.Dy())
+	}
+	return dst
+}
+
+// ToGray converts img to 8-bit grayscale, keeping its bounds.
+// If img is already *image.Gray it is returned unchanged. YCbCr images use
+// their luma plane directly.
+func ToGray(img image.Image) *image.Gray {
+	bounds := img.Bounds()
+	switch src := img.(type) {
+	case *image.Gray:
+		return src
+	case *image.YCbCr:
+		dst := image.NewGray(bounds)
+		for y := 0; y < bounds.Dy(); y++ {
+			si := src.YOffset(bounds.Min.X, bounds.Min.Y+y)
+			copy(dst.Pix[y*dst.Stride:y*dst.Stride+bounds.Dx()], src.Y[si:si+bounds.Dx()])
+		}
+		return dst
+	case *image.RGBA:
+		return grayFromRGBA8(bounds, src.Pix, src.PixOffset, false)
+	case *image.NRGBA:
+		return grayFromRGBA8(bounds, src.Pix, src.PixOffset, true)
+	}
+
+	dst := image.NewGray(bounds)
+	draw.Draw(dst, bounds, img, bounds.Min, draw.Src)
+	return dst
+}
+
+// grayFromRGBA8 converts 8-bit RGBA pixel data to grayscale using the same
+// luma weights as color.GrayModel. Non-premultiplied data is premultiplied
+// first so the result matches GrayModel for every alpha value.
+func grayFromRGBA8(bounds image.Rectangle, pix []uint8, offset func(x, y int) int, premultiply bool) *image.Gray {
+	dst := image.NewGray(bounds)
+	for y := 0; y < bounds.Dy(); y++ {
+		si := offset(bounds.Min.X, bounds.Min.Y+y)
+		di := y * dst.Stride
+		for x := 0; x < bounds.Dx(); x++ {
+			r := uint32(pix[si]) * 0x101
+			g := uint32(pix[si+1]) * 0x101
+			b := uint32(pix[si+2]) * 0x101
+			if premultiply {
+				a := uint32(pix[si+3]) * 0x101
+				r = r * a / 0xffff
+				g = g * a / 0xffff
+				b = b * a / 0xffff
+			}
+			dst.Pix[di+x] = uint8((19595*r + 38470*g + 7471*b + 1<<15) >> 24)
+			si += 4
+		}
+	}
+	return dst
+}
+
+// ToPaletted converts img to a paletted image using the given palette,
+// mapping each pixel to its nearest palette color. If img is already
+// paletted with an identical palette its indexes are copied directly.
+func ToPaletted(img image.Image, palette color.Palette) *image.Paletted {
+	bounds := img.Bounds()
+	if src, ok := img.(*image.Paletted); ok && palettesEqual(src.Palette, palette) {
+		dst := image.NewPaletted(bounds, palette)
+		if !bounds.Empty() {
+			copyRows(dst.Pix, dst.Stride, src.Pix[src.PixOffset(bounds.Min.X, bounds.Min.Y):], src.Stride, bounds.Dx(), bounds.Dy())
+		}
+		return dst
+	}
+
+	dst := image.NewPaletted(bounds, palette)
+	draw.Draw(dst, bounds, img, bounds.Min, draw.Src)
+	return dst
+}
+
+// palettesEqual reports whether two palettes contain the same colors in order.
+func palettesEqual(a, b color.Palette) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !colorsEqual(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// DefaultMatte is the color transparent pixels are flattened onto when
+// encoding to a format without alpha, such as JPEG.
+var DefaultMatte color.Color = color.White
+
+// Opaque reports whether every pixel of img is fully opaque.
+func Opaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if _, _, _, a := img.At(x, y).RGBA(); a != 0xffff {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Flatten composites img over an opaque matte color, removing transparency.
+// Images that are already opaque are returned unchanged.
+func Flatten(img image.Image, matte color.Color) image.Image {
+	if Opaque(img) {
+		return img
+	}
+	bounds := img.Bounds()
+	dst := image.NewRGBA(bounds)
+	r, g, b, _ := matte.RGBA()
+	draw.Draw(dst, bounds, image.NewUniform(color.RGBA64{uint16(r), uint16(g), uint16(b), 0xffff}), image.Point{}, draw.Src)
+	draw.Draw(dst, bounds, img, bounds.Min, draw.Over)
+	return dst
+}
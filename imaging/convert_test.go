@@ -0,0 +1,189 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestCrop(t *testing.T) {
+	img := createTestImage(20, 10)
+
+	result := Crop(img, image.Rect(5, 2, 15, 8))
+	if want := image.Rect(5, 2, 15, 8); result.Bounds() != want {
+		t.Errorf("expected bounds %v, got %v", want, result.Bounds())
+	}
+	if !colorsEqual(result.At(5, 2), img.At(5, 2)) {
+		t.Error("expected cropped pixels to match the source")
+	}
+
+	// Rectangles extending past the image are clipped
+	result = Crop(img, image.Rect(15, 5, 40, 40))
+	if want := image.Rect(15, 5, 20, 10); result.Bounds() != want {
+		t.Errorf("expected clipped bounds %v, got %v", want, result.Bounds())
+	}
+}
+
+func TestCrop_WithoutSubImage(t *testing.T) {
+	// image.Uniform has no SubImage method, forcing the copy path
+	img := &image.Uniform{C: color.RGBA{1, 2, 3, 255}}
+
+	result := Crop(img, image.Rect(3, 4, 6, 8))
+	if _, ok := result.(*image.RGBA); !ok {
+		t.Fatalf("expected *image.RGBA copy, got %T", result)
+	}
+	if want := image.Rect(3, 4, 6, 8); result.Bounds() != want {
+		t.Errorf("expected bounds %v, got %v", want, result.Bounds())
+	}
+	if !colorsEqual(result.At(3, 4), img.C) {
+		t.Error("expected copied pixels to match the source")
+	}
+}
+
+func TestClone_Types(t *testing.T) {
+	tests := []struct {
+		name string
+		img  image.Image
+	}{
+		{"rgba", image.NewRGBA(image.Rect(2, 3, 7, 9))},
+		{"nrgba", image.NewNRGBA(image.Rect(2, 3, 7, 9))},
+		{"gray", image.NewGray(image.Rect(2, 3, 7, 9))},
+		{"paletted", image.NewPaletted(image.Rect(2, 3, 7, 9), color.Palette{color.Black, color.White})},
+		{"ycbcr", image.NewYCbCr(image.Rect(2, 3, 7, 9), image.YCbCrSubsampleRatio420)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if s, ok := tt.img.(interface{ Set(int, int, color.Color) }); ok {
+				s.Set(4, 5, color.White)
+			}
+			got := Clone(tt.img)
+			if got.Bounds() != tt.img.Bounds() {
+				t.Errorf("expected bounds %v, got %v", tt.img.Bounds(), got.Bounds())
+			}
+			for y := tt.img.Bounds().Min.Y; y < tt.img.Bounds().Max.Y; y++ {
+				for x := tt.img.Bounds().Min.X; x < tt.img.Bounds().Max.X; x++ {
+					if !colorsEqual(color.RGBAModel.Convert(got.At(x, y)), color.RGBAModel.Convert(tt.img.At(x, y))) {
+						t.Fatalf("pixel mismatch at (%d,%d)", x, y)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestToNRGBA(t *testing.T) {
+	rgba := image.NewRGBA(image.Rect(1, 1, 4, 3))
+	rgba.Set(1, 1, color.NRGBA{200, 100, 50, 128})
+	rgba.Set(2, 1, color.NRGBA{10, 20, 30, 255})
+	gray := image.NewGray(image.Rect(0, 0, 2, 2))
+	gray.SetGray(1, 1, color.Gray{77})
+
+	tests := []struct {
+		name string
+		img  image.Image
+	}{
+		{"rgba", rgba},
+		{"gray", gray},
+		{"ycbcr", image.NewYCbCr(image.Rect(0, 0, 3, 3), image.YCbCrSubsampleRatio444)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ToNRGBA(tt.img)
+			if got.Bounds() != tt.img.Bounds() {
+				t.Fatalf("expected bounds %v, got %v", tt.img.Bounds(), got.Bounds())
+			}
+			b := tt.img.Bounds()
+			for y := b.Min.Y; y < b.Max.Y; y++ {
+				for x := b.Min.X; x < b.Max.X; x++ {
+					want := color.NRGBAModel.Convert(tt.img.At(x, y)).(color.NRGBA)
+					if got.NRGBAAt(x, y) != want {
+						t.Fatalf("at (%d,%d): expected %v, got %v", x, y, want, got.NRGBAAt(x, y))
+					}
+				}
+			}
+		})
+	}
+
+	nrgba := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	if ToNRGBA(nrgba) != nrgba {
+		t.Error("expected NRGBA input to be returned unchanged")
+	}
+}
+
+func TestToGray(t *testing.T) {
+	rgba := image.NewRGBA(image.Rect(0, 0, 3, 1))
+	rgba.Set(0, 0, color.RGBA{255, 0, 0, 255})
+	rgba.Set(1, 0, color.RGBA{0, 255, 0, 255})
+	rgba.Set(2, 0, color.RGBA{0, 0, 255, 255})
+	nrgba := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	nrgba.Set(0, 0, color.NRGBA{200, 150, 100, 128})
+	nrgba.Set(1, 0, color.NRGBA{200, 150, 100, 255})
+
+	for name, img := range map[string]image.Image{"rgba": rgba, "nrgba": nrgba, "test": createTestImage(10, 10)} {
+		t.Run(name, func(t *testing.T) {
+			got := ToGray(img)
+			b := img.Bounds()
+			for y := b.Min.Y; y < b.Max.Y; y++ {
+				for x := b.Min.X; x < b.Max.X; x++ {
+					want := color.GrayModel.Convert(img.At(x, y)).(color.Gray)
+					if got.GrayAt(x, y) != want {
+						t.Fatalf("at (%d,%d): expected %v, got %v", x, y, want, got.GrayAt(x, y))
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestToPaletted(t *testing.T) {
+	palette := color.Palette{color.Black, color.White}
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, color.RGBA{20, 20, 20, 255})
+	img.Set(1, 0, color.RGBA{230, 230, 230, 255})
+
+	got := ToPaletted(img, palette)
+	if got.ColorIndexAt(0, 0) != 0 || got.ColorIndexAt(1, 0) != 1 {
+		t.Errorf("expected nearest palette indexes [0 1], got [%d %d]", got.ColorIndexAt(0, 0), got.ColorIndexAt(1, 0))
+	}
+
+	// Same palette copies indexes verbatim, even for duplicate colors
+	dup := color.Palette{color.Black, color.Black}
+	src := image.NewPaletted(image.Rect(0, 0, 1, 1), dup)
+	src.SetColorIndex(0, 0, 1)
+	if idx := ToPaletted(src, dup).ColorIndexAt(0, 0); idx != 1 {
+		t.Errorf("expected index 1 to be preserved, got %d", idx)
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 1))
+	img.Set(0, 0, color.NRGBA{0, 0, 0, 0})
+	img.Set(1, 0, color.NRGBA{255, 0, 0, 128})
+	img.Set(2, 0, color.NRGBA{0, 0, 255, 255})
+
+	got := Flatten(img, color.NRGBA{0, 255, 0, 255})
+	if !Opaque(got) {
+		t.Fatal("expected flattened image to be opaque")
+	}
+	want := []color.RGBA{{0, 255, 0, 255}, {128, 127, 0, 255}, {0, 0, 255, 255}}
+	for x, w := range want {
+		c := color.RGBAModel.Convert(got.At(x, 0)).(color.RGBA)
+		if absDiff(c.R, w.R) > 1 || absDiff(c.G, w.G) > 1 || c.B != w.B || c.A != w.A {
+			t.Errorf("pixel %d: expected %v, got %v", x, w, c)
+		}
+	}
+
+	opaque := image.NewGray(image.Rect(0, 0, 1, 1))
+	if Flatten(opaque, color.White) != image.Image(opaque) {
+		t.Error("expected opaque image to be returned unchanged")
+	}
+}
+
+func absDiff(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
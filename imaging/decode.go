@@ -0,0 +1,42 @@
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+)
+
+// DefaultMaxPixels is the largest image, in pixels, that Decode accepts when
+// no limit is given: 100 megapixels, about 400MB once decoded to RGBA.
+const DefaultMaxPixels = 100_000_000
+
+// TooLargeError is returned by Decode when an image's declared dimensions
+// exceed the pixel limit.
+type TooLargeError struct {
+	Width, Height int
+	MaxPixels     int
+}
+
+func (e *TooLargeError) Error() string {
+	return fmt.Sprintf("image is %dx%d (%d pixels), over the %d pixel limit",
+		e.Width, e.Height, int64(e.Width)*int64(e.Height), e.MaxPixels)
+}
+
+// Decode decodes data like image.Decode, but first reads only the header and
+// rejects images with more than maxPixels pixels (DefaultMaxPixels if
+// maxPixels <= 0). This stops small, highly compressed "decompression bomb"
+// files from allocating gigabytes before anything can check their size.
+// As with image.Decode, format decoders must be registered by the caller.
+func Decode(data []byte, maxPixels int) (image.Image, string, error) {
+	if maxPixels <= 0 {
+		maxPixels = DefaultMaxPixels
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	if cfg.Width < 0 || cfg.Height < 0 || int64(cfg.Width)*int64(cfg.Height) > int64(maxPixels) {
+		return nil, "", &TooLargeError{Width: cfg.Width, Height: cfg.Height, MaxPixels: maxPixels}
+	}
+	return image.Decode(bytes.NewReader(data))
+}
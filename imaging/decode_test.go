@@ -0,0 +1,50 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewGray(image.Rect(0, 0, 100, 50)))
+
+	img, format, err := Decode(buf.Bytes(), 5000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != "png" || img.Bounds().Dx() != 100 {
+		t.Errorf("expected 100px wide png, got %s %v", format, img.Bounds())
+	}
+
+	_, _, err = Decode(buf.Bytes(), 4999)
+	var tooLarge *TooLargeError
+	if !errors.As(err, &tooLarge) {
+		t.Fatalf("expected TooLargeError, got %v", err)
+	}
+	if tooLarge.Width != 100 || tooLarge.Height != 50 || tooLarge.MaxPixels != 4999 {
+		t.Errorf("unexpected error fields: %+v", tooLarge)
+	}
+}
+
+func TestDecode_BombHeader(t *testing.T) {
+	// A PNG header declaring 100000x100000 pixels; only the header is read
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1)))
+	data := buf.Bytes()
+	// IHDR width and height are big-endian at offsets 16 and 20
+	for _, off := range []int{16, 20} {
+		binary.BigEndian.PutUint32(data[off:], 100000)
+	}
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+
+	var tooLarge *TooLargeError
+	if _, _, err := Decode(data, 0); !errors.As(err, &tooLarge) {
+		t.Errorf("expected TooLargeError for a 10-gigapixel header, got %v", err)
+	}
+}
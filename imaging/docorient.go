@@ -0,0 +1,158 @@
+package imaging
+
+import "image"
+
+// minDocumentInk is the fewest ink pixels DocumentOrientation needs before it
+// trusts its measurements; emptier pages are left as they are.
+const minDocumentInk = 200
+
+// DocumentOrientation estimates how a scanned text document must be rotated
+// to read upright, for scans that carry no EXIF orientation. It returns
+// OrientNormal, OrientRotate90, OrientRotate180, or OrientRotate270, suitable
+// for ApplyOrientation.
+//
+// Text lines are found from projection profiles: the ink profile across
+// lines alternates sharply between text and leading, while the profile along
+// them is comparatively flat. Upside-down text is then told apart by Latin
+// script having more ascenders (and capitals) than descenders, so more ink
+// sits above each line's x-height band than below it. Pages with too little
+// text to judge return OrientNormal.
+func DocumentOrientation(img image.Image) int {
+	m := newInkMask(img)
+	if m.count < minDocumentInk {
+		return OrientNormal
+	}
+
+	rows, cols := m.profiles()
+	if peakiness(cols) > peakiness(rows) {
+		// Lines run vertically: turn them horizontal, then check which way up
+		if m.rotate90().upright() {
+			return OrientRotate90
+		}
+		return OrientRotate270
+	}
+	if m.upright() {
+		return OrientNormal
+	}
+	return OrientRotate180
+}
+
+// inkMask marks the pixels of a page darker than its paper.
+type inkMask struct {
+	w, h  int
+	ink   []bool
+	count int
+}
+
+// newInkMask thresholds img at two thirds of its mean luma, which separates
+// dark text from light paper without depending on absolute exposure.
+func newInkMask(img image.Image) *inkMask {
+	gray := ToGray(img)
+	b := gray.Bounds()
+	m := &inkMask{w: b.Dx(), h: b.Dy(), ink: make([]bool, b.Dx()*b.Dy())}
+	if len(m.ink) == 0 {
+		return m
+	}
+
+	var sum int
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row := gray.Pix[gray.PixOffset(b.Min.X, y):]
+		for _, v := range row[:m.w] {
+			sum += int(v)
+		}
+	}
+	threshold := uint8(sum / len(m.ink) * 2 / 3)
+
+	for y := 0; y < m.h; y++ {
+		row := gray.Pix[gray.PixOffset(b.Min.X, b.Min.Y+y):]
+		for x, v := range row[:m.w] {
+			if v < threshold {
+				m.ink[y*m.w+x] = true
+				m.count++
+			}
+		}
+	}
+	return m
+}
+
+// profiles returns the ink count of every row and every column.
+func (m *inkMask) profiles() (rows, cols []int) {
+	rows, cols = make([]int, m.h), make([]int, m.w)
+	for y := 0; y < m.h; y++ {
+		for x := 0; x < m.w; x++ {
+			if m.ink[y*m.w+x] {
+				rows[y]++
+				cols[x]++
+			}
+		}
+	}
+	return rows, cols
+}
+
+// rotate90 returns the mask rotated 90 degrees clockwise, matching
+// ApplyOrientation with OrientRotate90.
+func (m *inkMask) rotate90() *inkMask {
+	r := &inkMask{w: m.h, h: m.w, ink: make([]bool, len(m.ink)), count: m.count}
+	for y := 0; y < m.h; y++ {
+		for x := 0; x < m.w; x++ {
+			r.ink[x*r.w+m.h-1-y] = m.ink[y*m.w+x]
+		}
+	}
+	return r
+}
+
+// upright reports whether the mask's horizontal text lines have more ink
+// above their x-height bands than below. Each line is a run of inked rows;
+// its x-height band is the rows holding at least half the line's peak ink.
+func (m *inkMask) upright() bool {
+	rows, _ := m.profiles()
+	var above, below int
+	for y := 0; y < len(rows); {
+		if rows[y] == 0 {
+			y++
+			continue
+		}
+		start := y
+		peak := 0
+		for ; y < len(rows) && rows[y] > 0; y++ {
+			peak = max(peak, rows[y])
+		}
+		end := y
+
+		top, bottom := -1, -1
+		for i := start; i < end; i++ {
+			if rows[i]*2 >= peak {
+				if top < 0 {
+					top = i
+				}
+				bottom = i
+			}
+		}
+		for i := start; i < top; i++ {
+			above += rows[i]
+		}
+		for i := bottom + 1; i < end; i++ {
+			below += rows[i]
+		}
+	}
+	return above >= below
+}
+
+// peakiness is the variance of a projection profile relative to its squared
+// mean, so profiles of different lengths and ink densities compare fairly.
+func peakiness(profile []int) float64 {
+	if len(profile) == 0 {
+		return 0
+	}
+	var sum, sumSq float64
+	for _, v := range profile {
+		f := float64(v)
+		sum += f
+		sumSq += f * f
+	}
+	mean := sum / float64(len(profile))
+	if mean == 0 {
+		return 0
+	}
+	return (sumSq/float64(len(profile)) - mean*mean) / (mean * mean)
+}
@@ -0,0 +1,67 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+// textPage renders a few lines of prose in black on white.
+func textPage() *image.RGBA {
+	lines := []string{
+		"The quick brown fox jumps over",
+		"the lazy dog. Pack my box with",
+		"five dozen liquor jugs, and then",
+		"sphinx of black quartz, judge my",
+		"vow. How vexingly quick daft",
+		"zebras jump over the fence today.",
+	}
+	page := image.NewRGBA(image.Rect(0, 0, 260, 140))
+	draw.Draw(page, page.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	for i, line := range lines {
+		DrawText(page, image.Pt(12, 24+i*18), line, color.Black)
+	}
+	return page
+}
+
+func TestDocumentOrientation(t *testing.T) {
+	page := textPage()
+	for _, tc := range []struct {
+		name    string
+		rotated int // How the scan was stored
+		want    int // Orientation that makes it upright
+	}{
+		{"upright", OrientNormal, OrientNormal},
+		{"rotated 90", OrientRotate90, OrientRotate270},
+		{"rotated 180", OrientRotate180, OrientRotate180},
+		{"rotated 270", OrientRotate270, OrientRotate90},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			scan := ApplyOrientation(page, tc.rotated)
+			if got := DocumentOrientation(scan); got != tc.want {
+				t.Errorf("expected orientation %d, got %d", tc.want, got)
+			}
+		})
+	}
+
+	blank := image.NewGray(image.Rect(0, 0, 100, 100))
+	if got := DocumentOrientation(blank); got != OrientNormal {
+		t.Errorf("expected a blank page to be left as is, got %d", got)
+	}
+}
+
+func TestPipeline_AutorotateDocument(t *testing.T) {
+	p, err := ParsePipeline("autorotate:document")
+	if err != nil {
+		t.Fatal(err)
+	}
+	scan := ApplyOrientation(textPage(), OrientRotate90)
+	result, err := p.Apply(scan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := result.Bounds(); b.Dx() != 260 || b.Dy() != 140 {
+		t.Errorf("expected the page turned back to 260x140, got %v", b)
+	}
+}
@@ -0,0 +1,167 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultQuality is used when a quality outside 1-100 is requested.
+const DefaultQuality = 90
+
+// OutputFormats lists the formats Encode writes, for callers describing
+// what they support.
+var OutputFormats = []string{"png", "jpeg", "gif", "rgba", "npy", "csv"}
+
+// Encode writes img to w in the given format ("png", "jpeg", or "gif", or one
+// of the pixel dumps "rgba", "npy", and "csv") and returns the MIME type written. Quality (1-100) is the JPEG quality; for PNG
+// it selects the compression level, inverted so that "higher = faster/larger"
+// is consistent with JPEG's "higher = better/larger". Unknown formats fall
+// back to PNG. JPEG output of a transparent image is flattened onto
+// DefaultMatte; call Flatten first to use a different matte color.
+func Encode(w io.Writer, img image.Image, format string, quality int) (string, error) {
+	if quality <= 0 || quality > 100 {
+		quality = DefaultQuality
+	}
+
+	switch format {
+	case "jpeg", "jpg":
+		return "image/jpeg", jpeg.Encode(w, Flatten(img, DefaultMatte), &jpeg.Options{Quality: quality})
+	case "gif":
+		return "image/gif", gif.Encode(w, img, nil)
+	case "rgba", "raw":
+		return "application/octet-stream", encodeRaw(w, img)
+	case "npy":
+		return "application/x-npy", encodeNPY(w, img)
+	case "csv":
+		return "text/csv", encodeCSV(w, img)
+	default:
+		// 1-25 = BestCompression, 26-50 = Default, 51-75 = BestSpeed, 76-100 = NoCompression
+		var compression png.CompressionLevel
+		if quality <= 25 {
+			compression = png.BestCompression
+		} else if quality <= 50 {
+			compression = png.DefaultCompression
+		} else if quality <= 75 {
+			compression = png.BestSpeed
+		} else {
+			compression = png.NoCompression
+		}
+		encoder := &png.Encoder{CompressionLevel: compression}
+		return "image/png", encoder.Encode(w, img)
+	}
+}
+
+// FormatFromPath returns the output format implied by a file name's extension.
+func FormatFromPath(path string) (string, error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".png":
+		return "png", nil
+	case ".jpg", ".jpeg":
+		return "jpeg", nil
+	case ".gif":
+		return "gif", nil
+	case ".rgba", ".raw":
+		return "rgba", nil
+	case ".npy":
+		return "npy", nil
+	case ".csv":
+		return "csv", nil
+	default:
+		return "", fmt.Errorf("unsupported output format %q", ext)
+	}
+}
+
+// ErrMaxBytes is returned by EncodeMaxBytes when the image cannot be encoded
+// within the byte budget.
+var ErrMaxBytes = errors.New("cannot encode image within the byte budget")
+
+// SizedEncoding is the result of EncodeMaxBytes.
+type SizedEncoding struct {
+	Data     []byte
+	MimeType string
+	Quality  int // Quality that produced Data
+	Width    int // Final dimensions, smaller than the input if downscaled
+	Height   int
+}
+
+// minDownscaleSide stops EncodeMaxBytes from shrinking images below this size.
+const minDownscaleSide = 16
+
+// EncodeMaxBytes encodes img so the output is at most maxBytes long. For JPEG
+// it binary-searches for the highest quality that fits; PNG and GIF are
+// lossless, so only their smallest encoding is tried. If nothing fits and
+// downscale is set, the image is repeatedly scaled down and retried.
+func EncodeMaxBytes(img image.Image, format string, maxBytes int, downscale bool) (SizedEncoding, error) {
+	for {
+		enc, err := encodeBestQuality(img, format, maxBytes)
+		if err == nil || !errors.Is(err, ErrMaxBytes) || !downscale {
+			return enc, err
+		}
+
+		// Shrink by the square root of the overshoot, plus a margin, since
+		// encoded size scales roughly with pixel count
+		b := img.Bounds()
+		factor := math.Sqrt(float64(maxBytes)/float64(len(enc.Data))) * 0.9
+		factor = math.Min(factor, 0.9)
+		w := int(float64(b.Dx()) * factor)
+		h := int(float64(b.Dy()) * factor)
+		if w < minDownscaleSide || h < minDownscaleSide {
+			return enc, err
+		}
+		img = Resize(img, w, h)
+	}
+}
+
+// encodeBestQuality finds the highest quality encoding of img that fits in
+// maxBytes, or returns the smallest encoding along with ErrMaxBytes.
+func encodeBestQuality(img image.Image, format string, maxBytes int) (SizedEncoding, error) {
+	b := img.Bounds()
+	encode := func(quality int) (SizedEncoding, error) {
+		var buf bytes.Buffer
+		mimeType, err := Encode(&buf, img, format, quality)
+		return SizedEncoding{Data: buf.Bytes(), MimeType: mimeType, Quality: quality, Width: b.Dx(), Height: b.Dy()}, err
+	}
+
+	if format != "jpeg" && format != "jpg" {
+		// Lossless formats: quality 1 selects the strongest compression
+		enc, err := encode(1)
+		if err == nil && len(enc.Data) > maxBytes {
+			err = ErrMaxBytes
+		}
+		return enc, err
+	}
+
+	smallest, err := encode(1)
+	if err != nil {
+		return smallest, err
+	}
+	if len(smallest.Data) > maxBytes {
+		return smallest, ErrMaxBytes
+	}
+
+	best := smallest
+	lo, hi := 2, 100
+	for lo <= hi {
+		q := (lo + hi) / 2
+		enc, err := encode(q)
+		if err != nil {
+			return enc, err
+		}
+		if len(enc.Data) <= maxBytes {
+			best = enc
+			lo = q + 1
+		} else {
+			hi = q - 1
+		}
+	}
+	return best, nil
+}
@@ -0,0 +1,123 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	img := createTestImage(20, 10)
+
+	tests := []struct {
+		format   string
+		mimeType string
+		decoded  string
+	}{
+		{"png", "image/png", "png"},
+		{"jpeg", "image/jpeg", "jpeg"},
+		{"jpg", "image/jpeg", "jpeg"},
+		{"gif", "image/gif", "gif"},
+		{"unknown", "image/png", "png"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			mimeType, err := Encode(&buf, img, tt.format, 80)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if mimeType != tt.mimeType {
+				t.Errorf("expected %s, got %s", tt.mimeType, mimeType)
+			}
+			_, format, err := image.Decode(&buf)
+			if err != nil || format != tt.decoded {
+				t.Errorf("expected decodable %s, got %q (err=%v)", tt.decoded, format, err)
+			}
+		})
+	}
+}
+
+func TestEncode_JPEGFlattensTransparency(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 8, 8)) // Fully transparent
+
+	var buf bytes.Buffer
+	if _, err := Encode(&buf, img, "jpeg", 90); err != nil {
+		t.Fatal(err)
+	}
+	decoded, _, err := image.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := color.GrayModel.Convert(decoded.At(4, 4)).(color.Gray); c.Y < 250 {
+		t.Errorf("expected transparent pixels to become white, got %v", c)
+	}
+}
+
+func TestFormatFromPath(t *testing.T) {
+	for path, want := range map[string]string{"a.png": "png", "b.JPG": "jpeg", "c.jpeg": "jpeg", "d.gif": "gif", "f.npy": "npy", "g.raw": "rgba"} {
+		if got, err := FormatFromPath(path); err != nil || got != want {
+			t.Errorf("FormatFromPath(%q) = %q, %v; want %q", path, got, err, want)
+		}
+	}
+	if _, err := FormatFromPath("e.bmp"); err == nil {
+		t.Error("expected error for unsupported extension")
+	}
+}
+
+func TestEncodeMaxBytes_JPEGQualitySearch(t *testing.T) {
+	img := createTestImage(200, 200)
+
+	var full bytes.Buffer
+	Encode(&full, img, "jpeg", 100)
+	budget := full.Len() / 3
+
+	enc, err := EncodeMaxBytes(img, "jpeg", budget, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(enc.Data) > budget {
+		t.Errorf("expected at most %d bytes, got %d", budget, len(enc.Data))
+	}
+	if enc.Quality <= 1 || enc.Quality >= 100 {
+		t.Errorf("expected an intermediate quality, got %d", enc.Quality)
+	}
+	if enc.Width != 200 || enc.Height != 200 {
+		t.Errorf("expected no downscale, got %dx%d", enc.Width, enc.Height)
+	}
+
+	// The next quality up must not fit, or the search stopped too early
+	var next bytes.Buffer
+	Encode(&next, img, "jpeg", enc.Quality+1)
+	if next.Len() <= budget {
+		t.Errorf("quality %d also fits in %d bytes", enc.Quality+1, budget)
+	}
+}
+
+func TestEncodeMaxBytes_Downscale(t *testing.T) {
+	// Noise compresses poorly, forcing the downscale path
+	img := image.NewGray(image.Rect(0, 0, 400, 400))
+	seed := uint32(1)
+	for i := range img.Pix {
+		seed = seed*1664525 + 1013904223
+		img.Pix[i] = uint8(seed >> 24)
+	}
+
+	if _, err := EncodeMaxBytes(img, "png", 20000, false); !errors.Is(err, ErrMaxBytes) {
+		t.Errorf("expected ErrMaxBytes without downscaling, got %v", err)
+	}
+
+	enc, err := EncodeMaxBytes(img, "png", 20000, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(enc.Data) > 20000 {
+		t.Errorf("expected at most 20000 bytes, got %d", len(enc.Data))
+	}
+	if enc.Width >= 400 || enc.Width != enc.Height {
+		t.Errorf("expected a proportional downscale, got %dx%d", enc.Width, enc.Height)
+	}
+}
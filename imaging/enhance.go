@@ -0,0 +1,246 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+)
+
+// DefaultAutoContrastClip is the percentage of pixels AutoContrast lets
+// saturate at each end, so a few specks of dust or glare don't stop the stretch.
+const DefaultAutoContrastClip = 0.5
+
+// Defaults for Equalize: the clip limit, as a multiple of the mean histogram
+// bin, and the number of tiles across each side, up to MaxEqualizeTiles.
+const (
+	DefaultEqualizeClip  = 2
+	DefaultEqualizeTiles = 8
+	MaxEqualizeTiles     = 64
+)
+
+// AutoContrast stretches img's levels so its darkest clip percent of pixels
+// become black and its brightest become white, for dull scans and hazy or
+// underexposed photos. The stretch is measured on luma and applied equally
+// to every channel, so colors don't shift. Transparent pixels are ignored,
+// and alpha is kept. An image with a single level is returned unchanged.
+func AutoContrast(img image.Image, clip float64) *image.NRGBA {
+	var hist [256]int
+	total := 0
+	src := ToNRGBA(img)
+	b := src.Rect
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if c := src.NRGBAAt(x, y); c.A > 0 {
+				hist[luma8(c)]++
+				total++
+			}
+		}
+	}
+
+	skip := int(float64(total) * max(0, min(clip, 50)) / 100)
+	lo, hi := 0, 255
+	for n := hist[lo]; n <= skip && lo < 255; n += hist[lo] {
+		lo++
+	}
+	for n := hist[hi]; n <= skip && hi > 0; n += hist[hi] {
+		hi--
+	}
+	if hi <= lo {
+		return mapColors(src, func(c color.NRGBA) color.NRGBA { return c })
+	}
+
+	var lut [256]uint8
+	for i := range lut {
+		lut[i] = clamp8(float64(i-lo) * 255 / float64(hi-lo))
+	}
+	return mapColors(src, func(c color.NRGBA) color.NRGBA {
+		return color.NRGBA{lut[c.R], lut[c.G], lut[c.B], c.A}
+	})
+}
+
+// Equalize spreads img's levels with contrast-limited adaptive histogram
+// equalization (CLAHE): each of tiles x tiles regions gets its own
+// equalization, blended smoothly between tile centers, so shadows and
+// highlights both gain local detail. clip limits each histogram bin to that
+// multiple of the mean bin, keeping flat areas from turning into noise;
+// zero or less means no limit. One tile is plain global equalization.
+// Luma is equalized and the change added to every channel; alpha is kept.
+func Equalize(img image.Image, clip float64, tiles int) *image.NRGBA {
+	src := ToNRGBA(img)
+	b := src.Rect
+	w, h := b.Dx(), b.Dy()
+	if w == 0 || h == 0 {
+		return mapColors(src, func(c color.NRGBA) color.NRGBA { return c })
+	}
+	// Tiles smaller than a few pixels have too little to equalize
+	tx := max(1, min(tiles, w/4))
+	ty := max(1, min(tiles, h/4))
+
+	luma := make([]uint8, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			luma[y*w+x] = luma8(src.NRGBAAt(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+
+	// One lookup table per tile
+	luts := make([][256]uint8, tx*ty)
+	for j := 0; j < ty; j++ {
+		for i := 0; i < tx; i++ {
+			var hist [256]float64
+			x0, x1 := i*w/tx, (i+1)*w/tx
+			y0, y1 := j*h/ty, (j+1)*h/ty
+			for y := y0; y < y1; y++ {
+				for _, v := range luma[y*w+x0 : y*w+x1] {
+					hist[v]++
+				}
+			}
+			n := float64((x1 - x0) * (y1 - y0))
+			if clip > 0 {
+				// Spread what's clipped off evenly over every bin
+				limit := clip * n / 256
+				excess := 0.0
+				for k := range hist {
+					if hist[k] > limit {
+						excess += hist[k] - limit
+						hist[k] = limit
+					}
+				}
+				for k := range hist {
+					hist[k] += excess / 256
+				}
+			}
+			sum := 0.0
+			for k := range hist {
+				sum += hist[k]
+				luts[j*tx+i][k] = clamp8(sum * 255 / n)
+			}
+		}
+	}
+
+	// Interpolate between the tables of the four nearest tile centers
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		fy := max(0, min((float64(y)+0.5)*float64(ty)/float64(h)-0.5, float64(ty-1)))
+		j0 := int(fy)
+		j1, wy := min(j0+1, ty-1), fy-float64(j0)
+		for x := 0; x < w; x++ {
+			fx := max(0, min((float64(x)+0.5)*float64(tx)/float64(w)-0.5, float64(tx-1)))
+			i0 := int(fx)
+			i1, wx := min(i0+1, tx-1), fx-float64(i0)
+			v := luma[y*w+x]
+			top := float64(luts[j0*tx+i0][v])*(1-wx) + float64(luts[j0*tx+i1][v])*wx
+			bottom := float64(luts[j1*tx+i0][v])*(1-wx) + float64(luts[j1*tx+i1][v])*wx
+			d := top*(1-wy) + bottom*wy - float64(v)
+
+			c := src.NRGBAAt(b.Min.X+x, b.Min.Y+y)
+			dst.SetNRGBA(x, y, color.NRGBA{
+				clamp8(float64(c.R) + d), clamp8(float64(c.G) + d), clamp8(float64(c.B) + d), c.A,
+			})
+		}
+	}
+	return dst
+}
+
+// WhiteBalance is how AutoWhiteBalance estimates the color of the light.
+type WhiteBalance int
+
+const (
+	// WhiteBalanceGrayWorld assumes the scene averages to gray.
+	WhiteBalanceGrayWorld WhiteBalance = iota
+	// WhiteBalanceWhitePatch assumes the brightest pixels are white.
+	WhiteBalanceWhitePatch
+)
+
+// ParseWhiteBalance parses "grayworld" or "whitepatch"; "auto" is gray world.
+func ParseWhiteBalance(s string) (WhiteBalance, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "auto", "grayworld":
+		return WhiteBalanceGrayWorld, nil
+	case "whitepatch":
+		return WhiteBalanceWhitePatch, nil
+	}
+	return 0, fmt.Errorf("unknown white balance %q", s)
+}
+
+// maxWhiteBalanceGain bounds the per-channel gains AutoWhiteBalance applies,
+// so an image that really is mostly one color isn't turned gray.
+const maxWhiteBalanceGain = 3
+
+// AutoWhiteBalance removes a color cast by scaling each channel so that,
+// by method's estimate, neutral surfaces come out gray. Transparent pixels
+// are ignored, and alpha is kept.
+func AutoWhiteBalance(img image.Image, method WhiteBalance) *image.NRGBA {
+	src := ToNRGBA(img)
+	b := src.Rect
+
+	// White patch averages the brightest percent of pixels, which is steadier
+	// than the single brightest
+	threshold := uint8(0)
+	if method == WhiteBalanceWhitePatch {
+		var hist [256]int
+		total := 0
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				if c := src.NRGBAAt(x, y); c.A > 0 {
+					hist[luma8(c)]++
+					total++
+				}
+			}
+		}
+		for n, v := 0, 255; v >= 0; v-- {
+			if n += hist[v]; n >= max(1, total/100) {
+				threshold = uint8(v)
+				break
+			}
+		}
+	}
+
+	var sum [3]float64
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := src.NRGBAAt(x, y)
+			if c.A == 0 || luma8(c) < threshold {
+				continue
+			}
+			sum[0] += float64(c.R)
+			sum[1] += float64(c.G)
+			sum[2] += float64(c.B)
+		}
+	}
+	target := (sum[0] + sum[1] + sum[2]) / 3
+	if method == WhiteBalanceWhitePatch {
+		target = max(sum[0], sum[1], sum[2])
+	}
+	var gains [3]float64
+	for i, s := range sum {
+		gains[i] = 1
+		if s > 0 && target > 0 {
+			gains[i] = max(1.0/maxWhiteBalanceGain, min(target/s, maxWhiteBalanceGain))
+		}
+	}
+	return scaleChannels(src, gains)
+}
+
+// AdjustWhiteBalance warms (positive temp) or cools (negative) img by
+// trading red against blue, and shifts it toward magenta (positive tint) or
+// green (negative), each from -100 to 100, as a photo editor's temperature
+// and tint sliders do. Alpha is kept.
+func AdjustWhiteBalance(img image.Image, temp, tint float64) *image.NRGBA {
+	t, g := max(-100, min(temp, 100))/100*0.3, max(-100, min(tint, 100))/100*0.3
+	return scaleChannels(img, [3]float64{1 + t, 1 - g, 1 - t})
+}
+
+// scaleChannels multiplies img's red, green, and blue by gains.
+func scaleChannels(img image.Image, gains [3]float64) *image.NRGBA {
+	var luts [3][256]uint8
+	for i := range luts {
+		for v := range luts[i] {
+			luts[i][v] = clamp8(float64(v) * gains[i])
+		}
+	}
+	return mapColors(img, func(c color.NRGBA) color.NRGBA {
+		return color.NRGBA{luts[0][c.R], luts[1][c.G], luts[2][c.B], c.A}
+	})
+}
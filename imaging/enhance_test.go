@@ -0,0 +1,145 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// dull returns a horizontal ramp of grays from lo to hi.
+func dull(w, h int, lo, hi uint8) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := lo + uint8(int(hi-lo)*x/(w-1))
+			img.SetNRGBA(x, y, color.NRGBA{v, v, v, 255})
+		}
+	}
+	return img
+}
+
+func TestAutoContrast(t *testing.T) {
+	src := dull(100, 4, 80, 160)
+	dst := AutoContrast(src, 0)
+	if got := dst.NRGBAAt(0, 0); got != (color.NRGBA{0, 0, 0, 255}) {
+		t.Errorf("darkest pixel: expected black, got %v", got)
+	}
+	if got := dst.NRGBAAt(99, 0); got != (color.NRGBA{255, 255, 255, 255}) {
+		t.Errorf("brightest pixel: expected white, got %v", got)
+	}
+
+	// A few outliers are clipped rather than stopping the stretch
+	src.SetNRGBA(0, 0, color.NRGBA{0, 0, 0, 255})
+	src.SetNRGBA(99, 3, color.NRGBA{255, 255, 255, 255})
+	if got := AutoContrast(src, 1).NRGBAAt(1, 0); got.R > 10 {
+		t.Errorf("expected the ramp to be stretched past outliers, got %v", got)
+	}
+
+	// Transparent pixels don't count, and alpha is kept
+	src = dull(10, 1, 100, 150)
+	src.SetNRGBA(0, 0, color.NRGBA{0, 0, 0, 0})
+	dst = AutoContrast(src, 0)
+	if got := dst.NRGBAAt(1, 0); got.R != 0 {
+		t.Errorf("expected the darkest opaque pixel to become black, got %v", got)
+	}
+	if dst.NRGBAAt(0, 0).A != 0 {
+		t.Error("expected alpha to be kept")
+	}
+
+	flat := solid(5, 5, color.NRGBA{90, 90, 90, 255})
+	if !equalPix(AutoContrast(flat, 0), flat) {
+		t.Error("expected a single level to be left alone")
+	}
+}
+
+func TestEqualize(t *testing.T) {
+	src := dull(64, 64, 100, 140)
+	dst := Equalize(src, 0, 1)
+	if b := dst.Bounds(); b != src.Bounds() {
+		t.Fatalf("bounds %v, want %v", b, src.Bounds())
+	}
+	// Global equalization of an even ramp spreads it over the full range
+	if lo, hi := dst.NRGBAAt(0, 0).R, dst.NRGBAAt(63, 0).R; lo > 10 || hi != 255 {
+		t.Errorf("expected the range spread out, got %d-%d", lo, hi)
+	}
+
+	// Clipping limits the stretch
+	clipped := Equalize(src, 1.5, 1)
+	if lo, hi := clipped.NRGBAAt(0, 0).R, clipped.NRGBAAt(63, 0).R; hi-lo >= dst.NRGBAAt(63, 0).R-dst.NRGBAAt(0, 0).R {
+		t.Errorf("expected clipping to reduce the stretch, got %d-%d", lo, hi)
+	}
+
+	// Adaptive equalization changes neighboring tiles smoothly, without seams
+	adaptive := Equalize(src, DefaultEqualizeClip, DefaultEqualizeTiles)
+	for x := 1; x < 64; x++ {
+		if d := int(adaptive.NRGBAAt(x, 32).R) - int(adaptive.NRGBAAt(x-1, 32).R); d < -2 {
+			t.Fatalf("levels fall by %d at x=%d", -d, x)
+		}
+	}
+}
+
+func TestPipeline_Enhance(t *testing.T) {
+	for _, expr := range []string{"autocontrast", "autocontrast:2", "equalize", "equalize:clip=3,tiles=4"} {
+		if _, err := ParsePipeline(expr); err != nil {
+			t.Errorf("ParsePipeline(%q): %v", expr, err)
+		}
+	}
+	for _, expr := range []string{"autocontrast:60", "autocontrast:-1", "equalize:tiles=0", "equalize:clip=-1"} {
+		if _, err := ParsePipeline(expr); err == nil {
+			t.Errorf("ParsePipeline(%q) expected error", expr)
+		}
+	}
+}
+
+func TestAutoWhiteBalance(t *testing.T) {
+	// A gray card and a white patch under warm light
+	src := image.NewNRGBA(image.Rect(0, 0, 100, 1))
+	for x := 0; x < 100; x++ {
+		c := color.NRGBA{150, 120, 90, 255}
+		if x == 99 {
+			c = color.NRGBA{250, 220, 180, 255}
+		}
+		src.SetNRGBA(x, 0, c)
+	}
+
+	gray := AutoWhiteBalance(src, WhiteBalanceGrayWorld).NRGBAAt(0, 0)
+	if d := int(gray.R) - int(gray.B); d < -2 || d > 2 {
+		t.Errorf("gray world: expected the card to turn gray, got %v", gray)
+	}
+	white := AutoWhiteBalance(src, WhiteBalanceWhitePatch).NRGBAAt(99, 0)
+	if white.R != 250 || white.G != 250 || white.B != 250 {
+		t.Errorf("white patch: expected the patch to turn white, got %v", white)
+	}
+
+	// Gains are limited, so a pure color isn't forced to gray
+	red := AutoWhiteBalance(solid(4, 4, color.NRGBA{200, 10, 10, 255}), WhiteBalanceGrayWorld).NRGBAAt(0, 0)
+	if red.R <= red.G {
+		t.Errorf("expected a pure color to stay that color, got %v", red)
+	}
+}
+
+func TestAdjustWhiteBalance(t *testing.T) {
+	src := solid(2, 2, color.NRGBA{100, 100, 100, 128})
+	warm := AdjustWhiteBalance(src, 50, 0).NRGBAAt(0, 0)
+	if warm.R <= 100 || warm.B >= 100 || warm.G != 100 || warm.A != 128 {
+		t.Errorf("warm: got %v", warm)
+	}
+	magenta := AdjustWhiteBalance(src, 0, 50).NRGBAAt(0, 0)
+	if magenta.G >= 100 || magenta.R != 100 {
+		t.Errorf("magenta: got %v", magenta)
+	}
+	if !equalPix(AdjustWhiteBalance(src, 0, 0), src) {
+		t.Error("expected zero temp and tint to change nothing")
+	}
+
+	for _, expr := range []string{"wb:auto", "wb:whitepatch,temp=10", "wb:temp=-20,tint=5"} {
+		if _, err := ParsePipeline(expr); err != nil {
+			t.Errorf("ParsePipeline(%q): %v", expr, err)
+		}
+	}
+	for _, expr := range []string{"wb", "wb:sunny", "wb:temp=200"} {
+		if _, err := ParsePipeline(expr); err == nil {
+			t.Errorf("ParsePipeline(%q) expected error", expr)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package imaging
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"image"
+	"io"
+	"strconv"
+)
+
+// MaxCSVPixels is the largest image Encode writes as CSV; at roughly 20 bytes
+// per pixel, CSV output of anything bigger is better served by "npy".
+const MaxCSVPixels = 1 << 20
+
+// RawHeader is the JSON line that precedes the pixel bytes in "rgba" output.
+type RawHeader struct {
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	Channels int    `json:"channels"`
+	Format   string `json:"format"` // Always "rgba8": non-premultiplied, 8 bits per channel
+}
+
+// encodeRaw writes a RawHeader line followed by the non-premultiplied RGBA
+// pixels in row-major order with no padding.
+func encodeRaw(w io.Writer, img image.Image) error {
+	src := ToNRGBA(img)
+	b := src.Bounds()
+	header, err := json.Marshal(RawHeader{Width: b.Dx(), Height: b.Dy(), Channels: 4, Format: "rgba8"})
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(append(header, '\n')); err != nil {
+		return err
+	}
+	return writePixels(w, src)
+}
+
+// encodeNPY writes the pixels as a NumPy version 1.0 .npy array of uint8
+// with shape (height, width, 4), loadable with numpy.load.
+func encodeNPY(w io.Writer, img image.Image) error {
+	src := ToNRGBA(img)
+	b := src.Bounds()
+	dict := fmt.Sprintf("{'descr': '|u1', 'fortran_order': False, 'shape': (%d, %d, 4), }", b.Dy(), b.Dx())
+
+	// The magic, version, length, and dict together are padded with spaces
+	// and a final newline to a multiple of 64 bytes
+	const prefixLen = 10
+	pad := 64 - (prefixLen+len(dict)+1)%64
+	if pad == 64 {
+		pad = 0
+	}
+	header := make([]byte, 0, prefixLen+len(dict)+pad+1)
+	header = append(header, "\x93NUMPY\x01\x00"...)
+	header = binary.LittleEndian.AppendUint16(header, uint16(len(dict)+pad+1))
+	header = append(header, dict...)
+	for i := 0; i < pad; i++ {
+		header = append(header, ' ')
+	}
+	header = append(header, '\n')
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	return writePixels(w, src)
+}
+
+// writePixels writes src's pixel rows without stride padding.
+func writePixels(w io.Writer, src *image.NRGBA) error {
+	b := src.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		i := src.PixOffset(b.Min.X, y)
+		if _, err := w.Write(src.Pix[i : i+b.Dx()*4]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeCSV writes one "x,y,r,g,b,a" line per pixel after a header line.
+// Coordinates are relative to the image's top-left corner.
+func encodeCSV(w io.Writer, img image.Image) error {
+	src := ToNRGBA(img)
+	b := src.Bounds()
+	if b.Dx()*b.Dy() > MaxCSVPixels {
+		return fmt.Errorf("image has %d pixels; CSV output is limited to %d", b.Dx()*b.Dy(), MaxCSVPixels)
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString("x,y,r,g,b,a\n")
+	line := make([]byte, 0, 32)
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			p := src.Pix[src.PixOffset(b.Min.X+x, b.Min.Y+y):]
+			line = strconv.AppendInt(line[:0], int64(x), 10)
+			line = append(line, ',')
+			line = strconv.AppendInt(line, int64(y), 10)
+			for _, v := range p[:4] {
+				line = append(line, ',')
+				line = strconv.AppendUint(line, uint64(v), 10)
+			}
+			line = append(line, '\n')
+			bw.Write(line)
+		}
+	}
+	return bw.Flush()
+}
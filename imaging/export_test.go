@@ -0,0 +1,90 @@
+package imaging
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+// exportTestImage is 3x2 with distinct, partly transparent pixels.
+func exportTestImage() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 3; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{uint8(x * 10), uint8(y * 10), 7, uint8(100 + x)})
+		}
+	}
+	return img
+}
+
+func TestEncode_Raw(t *testing.T) {
+	var buf bytes.Buffer
+	mimeType, err := Encode(&buf, exportTestImage(), "rgba", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mimeType != "application/octet-stream" {
+		t.Errorf("unexpected MIME type %s", mimeType)
+	}
+
+	r := bufio.NewReader(&buf)
+	line, _ := r.ReadBytes('\n')
+	var header RawHeader
+	if err := json.Unmarshal(line, &header); err != nil {
+		t.Fatal(err)
+	}
+	if header != (RawHeader{Width: 3, Height: 2, Channels: 4, Format: "rgba8"}) {
+		t.Errorf("unexpected header %+v", header)
+	}
+	pix := make([]byte, 24)
+	if n, _ := r.Read(pix); n != 24 || r.Buffered() != 0 {
+		t.Fatalf("expected exactly 24 pixel bytes")
+	}
+	// Pixel (2,1) is the last one
+	if !bytes.Equal(pix[20:], []byte{20, 10, 7, 102}) {
+		t.Errorf("unexpected last pixel %v", pix[20:])
+	}
+}
+
+func TestEncode_NPY(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := Encode(&buf, Crop(exportTestImage(), image.Rect(1, 0, 3, 2)), "npy", 0); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	if !bytes.HasPrefix(data, []byte("\x93NUMPY\x01\x00")) {
+		t.Fatalf("missing .npy magic: %q", data[:8])
+	}
+	headerLen := int(binary.LittleEndian.Uint16(data[8:10]))
+	if (10+headerLen)%64 != 0 {
+		t.Errorf("expected header padded to 64 bytes, got %d", 10+headerLen)
+	}
+	header := string(data[10 : 10+headerLen])
+	if !strings.Contains(header, "'shape': (2, 2, 4)") || !strings.HasSuffix(header, "\n") {
+		t.Errorf("unexpected header %q", header)
+	}
+	if pix := data[10+headerLen:]; len(pix) != 16 || pix[0] != 10 {
+		t.Errorf("expected 16 pixel bytes starting at the crop, got %v", pix)
+	}
+}
+
+func TestEncode_CSV(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := Encode(&buf, exportTestImage(), "csv", 0); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 7 || lines[0] != "x,y,r,g,b,a" || lines[6] != "2,1,20,10,7,102" {
+		t.Errorf("unexpected CSV:\n%s", buf.String())
+	}
+
+	big := image.NewGray(image.Rect(0, 0, 1025, 1024))
+	if _, err := Encode(&bytes.Buffer{}, big, "csv", 0); err == nil {
+		t.Error("expected error for CSV output over MaxCSVPixels")
+	}
+}
@@ -0,0 +1,419 @@
+package imaging
+
+import (
+	"bufio"
+	"compress/lzw"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"iter"
+)
+
+// Frame is one frame of a GIF animation. Like gif.GIF's frames, its image may
+// cover only part of the animation's logical screen.
+type Frame struct {
+	Image    *image.Paletted
+	Delay    int  // Hundredths of a second
+	Disposal byte // gif.DisposalNone, gif.DisposalBackground, or gif.DisposalPrevious
+}
+
+// GIF block introducers and extension labels.
+const (
+	gifExtension      = 0x21
+	gifImage          = 0x2c
+	gifTrailer        = 0x3b
+	gifGraphicControl = 0xf9
+	gifApplication    = 0xff
+)
+
+// Frames decodes a GIF animation from r one frame at a time. Unlike
+// gif.DecodeAll, only the current frame is held in memory, so long
+// animations can be processed in bounded memory. As with Decode, a frame of
+// more than maxPixels pixels (DefaultMaxPixels if maxPixels <= 0) is
+// rejected with a *TooLargeError before it is allocated, as is a frame
+// reaching outside the logical screen. Iteration stops after the first
+// error, which is yielded with a zero Frame.
+func Frames(r io.Reader, maxPixels int) iter.Seq2[Frame, error] {
+	if maxPixels <= 0 {
+		maxPixels = DefaultMaxPixels
+	}
+	return func(yield func(Frame, error) bool) {
+		d := &gifDecoder{r: bufio.NewReader(r), maxPixels: maxPixels}
+		if err := d.readHeader(); err != nil {
+			yield(Frame{}, err)
+			return
+		}
+		for {
+			f, err := d.next()
+			if err == io.EOF {
+				return
+			}
+			if !yield(f, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// gifDecoder reads GIF blocks in order.
+type gifDecoder struct {
+	r            *bufio.Reader
+	maxPixels    int
+	width        int
+	height       int
+	global       color.Palette
+	delay        int
+	disposal     byte
+	transparent  int // Index from the last graphic control extension, or -1
+	hasGraphicCE bool
+}
+
+func (d *gifDecoder) readHeader() error {
+	var hdr [13]byte
+	if _, err := io.ReadFull(d.r, hdr[:]); err != nil {
+		return fmt.Errorf("gif: reading header: %w", err)
+	}
+	if v := string(hdr[:6]); v != "GIF87a" && v != "GIF89a" {
+		return errors.New("gif: not a GIF file")
+	}
+	d.width = int(binary.LittleEndian.Uint16(hdr[6:]))
+	d.height = int(binary.LittleEndian.Uint16(hdr[8:]))
+	if hdr[10]&0x80 != 0 {
+		p, err := d.readPalette(hdr[10] & 0x07)
+		if err != nil {
+			return err
+		}
+		d.global = p
+	}
+	return nil
+}
+
+// readPalette reads a color table of 2^(size+1) entries.
+func (d *gifDecoder) readPalette(size byte) (color.Palette, error) {
+	buf := make([]byte, 3<<(size+1))
+	if _, err := io.ReadFull(d.r, buf); err != nil {
+		return nil, fmt.Errorf("gif: reading color table: %w", err)
+	}
+	p := make(color.Palette, len(buf)/3)
+	for i := range p {
+		p[i] = color.RGBA{buf[i*3], buf[i*3+1], buf[i*3+2], 0xff}
+	}
+	return p, nil
+}
+
+// next reads blocks up to and including the next image, returning io.EOF at
+// the trailer.
+func (d *gifDecoder) next() (Frame, error) {
+	for {
+		b, err := d.r.ReadByte()
+		if err != nil {
+			return Frame{}, fmt.Errorf("gif: missing trailer: %w", err)
+		}
+		switch b {
+		case gifExtension:
+			if err := d.readExtension(); err != nil {
+				return Frame{}, err
+			}
+		case gifImage:
+			return d.readImage()
+		case gifTrailer:
+			return Frame{}, io.EOF
+		default:
+			return Frame{}, fmt.Errorf("gif: unknown block type 0x%02x", b)
+		}
+	}
+}
+
+func (d *gifDecoder) readExtension() error {
+	label, err := d.r.ReadByte()
+	if err != nil {
+		return fmt.Errorf("gif: reading extension: %w", err)
+	}
+	if label == gifGraphicControl {
+		var gce [6]byte
+		if _, err := io.ReadFull(d.r, gce[:]); err != nil {
+			return fmt.Errorf("gif: reading graphic control: %w", err)
+		}
+		d.disposal = gce[1] >> 2 & 0x07
+		d.delay = int(binary.LittleEndian.Uint16(gce[2:]))
+		d.transparent = -1
+		if gce[1]&0x01 != 0 {
+			d.transparent = int(gce[4])
+		}
+		d.hasGraphicCE = true
+		return nil
+	}
+	// Other extensions (comments, looping) are skipped
+	return (&blockReader{r: d.r}).drain()
+}
+
+func (d *gifDecoder) readImage() (Frame, error) {
+	var desc [9]byte
+	if _, err := io.ReadFull(d.r, desc[:]); err != nil {
+		return Frame{}, fmt.Errorf("gif: reading image descriptor: %w", err)
+	}
+	left := int(binary.LittleEndian.Uint16(desc[0:]))
+	top := int(binary.LittleEndian.Uint16(desc[2:]))
+	w := int(binary.LittleEndian.Uint16(desc[4:]))
+	h := int(binary.LittleEndian.Uint16(desc[6:]))
+	// Check the frame before allocating it, so a hostile descriptor can't
+	// claim gigabytes
+	if left+w > d.width || top+h > d.height {
+		return Frame{}, errors.New("gif: frame bounds larger than image bounds")
+	}
+	if int64(w)*int64(h) > int64(d.maxPixels) {
+		return Frame{}, &TooLargeError{Width: w, Height: h, MaxPixels: d.maxPixels}
+	}
+	palette := d.global
+	if desc[8]&0x80 != 0 {
+		p, err := d.readPalette(desc[8] & 0x07)
+		if err != nil {
+			return Frame{}, err
+		}
+		palette = p
+	}
+	if palette == nil {
+		return Frame{}, errors.New("gif: image has no color table")
+	}
+	if d.hasGraphicCE && d.transparent >= 0 && d.transparent < len(palette) {
+		palette = append(color.Palette(nil), palette...)
+		palette[d.transparent] = color.RGBA{}
+	}
+
+	litWidth, err := d.r.ReadByte()
+	if err != nil {
+		return Frame{}, fmt.Errorf("gif: reading LZW code size: %w", err)
+	}
+	if litWidth < 2 || litWidth > 8 {
+		return Frame{}, fmt.Errorf("gif: invalid LZW code size %d", litWidth)
+	}
+	img := image.NewPaletted(image.Rect(left, top, left+w, top+h), palette)
+	br := &blockReader{r: d.r}
+	lz := lzw.NewReader(br, lzw.LSB, int(litWidth))
+	if _, err := io.ReadFull(lz, img.Pix); err != nil {
+		lz.Close()
+		return Frame{}, fmt.Errorf("gif: reading image data: %w", err)
+	}
+	lz.Close()
+	if err := br.drain(); err != nil {
+		return Frame{}, err
+	}
+	for _, idx := range img.Pix {
+		if int(idx) >= len(palette) {
+			return Frame{}, errors.New("gif: color index out of range")
+		}
+	}
+	if desc[8]&0x40 != 0 {
+		deinterlace(img)
+	}
+
+	f := Frame{Image: img, Delay: d.delay, Disposal: d.disposal}
+	d.delay, d.disposal, d.transparent, d.hasGraphicCE = 0, 0, -1, false
+	return f, nil
+}
+
+// deinterlace reorders the rows of an interlaced GIF image: every 8th row
+// from 0, every 8th from 4, every 4th from 2, then every 2nd from 1.
+func deinterlace(img *image.Paletted) {
+	h := img.Rect.Dy()
+	src := append([]byte(nil), img.Pix...)
+	row := 0
+	for _, pass := range []struct{ start, step int }{{0, 8}, {4, 8}, {2, 4}, {1, 2}} {
+		for y := pass.start; y < h; y += pass.step {
+			copy(img.Pix[y*img.Stride:(y+1)*img.Stride], src[row*img.Stride:])
+			row++
+		}
+	}
+}
+
+// blockReader reads the data sub-blocks that follow a GIF image or
+// extension: length-prefixed chunks ending with a zero length.
+type blockReader struct {
+	r    *bufio.Reader
+	left int  // Bytes left in the current sub-block
+	done bool // The terminating empty sub-block was read
+}
+
+func (b *blockReader) Read(p []byte) (int, error) {
+	for b.left == 0 {
+		if b.done {
+			return 0, io.EOF
+		}
+		n, err := b.r.ReadByte()
+		if err != nil {
+			return 0, io.ErrUnexpectedEOF
+		}
+		if n == 0 {
+			b.done = true
+			return 0, io.EOF
+		}
+		b.left = int(n)
+	}
+	n, err := b.r.Read(p[:min(len(p), b.left)])
+	b.left -= n
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// drain skips any remaining sub-blocks.
+func (b *blockReader) drain() error {
+	_, err := io.Copy(io.Discard, b)
+	return err
+}
+
+// FrameWriter encodes a GIF animation one frame at a time, the counterpart
+// of Frames: frames are written as they arrive rather than collected first,
+// as gif.EncodeAll requires. Each frame carries its own color table.
+type FrameWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+// NewFrameWriter writes the header of a width x height animation to w.
+// loopCount follows gif.GIF: 0 loops forever, -1 plays once, and n > 0
+// repeats n times after the first.
+func NewFrameWriter(w io.Writer, width, height, loopCount int) (*FrameWriter, error) {
+	if width <= 0 || height <= 0 || width > 0xffff || height > 0xffff {
+		return nil, fmt.Errorf("gif: invalid size %dx%d", width, height)
+	}
+	fw := &FrameWriter{w: bufio.NewWriter(w)}
+	hdr := []byte("GIF89a")
+	hdr = binary.LittleEndian.AppendUint16(hdr, uint16(width))
+	hdr = binary.LittleEndian.AppendUint16(hdr, uint16(height))
+	hdr = append(hdr, 0, 0, 0) // No global color table, background 0, square pixels
+	if loopCount >= 0 {
+		hdr = append(hdr, gifExtension, gifApplication, 11)
+		hdr = append(hdr, "NETSCAPE2.0"...)
+		hdr = append(hdr, 3, 1)
+		hdr = binary.LittleEndian.AppendUint16(hdr, uint16(loopCount))
+		hdr = append(hdr, 0)
+	}
+	fw.write(hdr)
+	return fw, fw.err
+}
+
+func (fw *FrameWriter) write(b []byte) {
+	if fw.err == nil {
+		_, fw.err = fw.w.Write(b)
+	}
+}
+
+// Write encodes one frame. The first palette entry with zero alpha, if any,
+// becomes the frame's transparent color.
+func (fw *FrameWriter) Write(f Frame) error {
+	if fw.err != nil {
+		return fw.err
+	}
+	img := f.Image
+	if img == nil || img.Rect.Empty() {
+		return errors.New("gif: empty frame")
+	}
+	if len(img.Palette) == 0 || len(img.Palette) > 256 {
+		return fmt.Errorf("gif: frame palette has %d colors, want 1-256", len(img.Palette))
+	}
+	if img.Rect.Min.X < 0 || img.Rect.Min.Y < 0 || img.Rect.Max.X > 0xffff || img.Rect.Max.Y > 0xffff {
+		return fmt.Errorf("gif: frame bounds %v out of range", img.Rect)
+	}
+
+	// The color table holds 2^bits entries, bits >= 1
+	bits := 1
+	for 1<<bits < len(img.Palette) {
+		bits++
+	}
+	transparent := -1
+	for i, c := range img.Palette {
+		if _, _, _, a := c.RGBA(); a == 0 {
+			transparent = i
+			break
+		}
+	}
+
+	var flags byte = f.Disposal << 2
+	if transparent >= 0 {
+		flags |= 0x01
+	}
+	block := []byte{gifExtension, gifGraphicControl, 4, flags}
+	block = binary.LittleEndian.AppendUint16(block, uint16(f.Delay))
+	block = append(block, byte(max(transparent, 0)), 0, gifImage)
+	block = binary.LittleEndian.AppendUint16(block, uint16(img.Rect.Min.X))
+	block = binary.LittleEndian.AppendUint16(block, uint16(img.Rect.Min.Y))
+	block = binary.LittleEndian.AppendUint16(block, uint16(img.Rect.Dx()))
+	block = binary.LittleEndian.AppendUint16(block, uint16(img.Rect.Dy()))
+	block = append(block, 0x80|byte(bits-1))
+	for i := 0; i < 1<<bits; i++ {
+		var r, g, b uint32
+		if i < len(img.Palette) {
+			r, g, b, _ = color.NRGBAModel.Convert(img.Palette[i]).RGBA()
+		}
+		block = append(block, byte(r>>8), byte(g>>8), byte(b>>8))
+	}
+	litWidth := max(bits, 2)
+	block = append(block, byte(litWidth))
+	fw.write(block)
+
+	bw := &blockWriter{w: fw}
+	lz := lzw.NewWriter(bw, lzw.LSB, litWidth)
+	for y := 0; y < img.Rect.Dy(); y++ {
+		row := img.Pix[y*img.Stride:][:img.Rect.Dx()]
+		for _, idx := range row {
+			if int(idx) >= len(img.Palette) {
+				lz.Close()
+				return errors.New("gif: color index out of range")
+			}
+		}
+		if _, err := lz.Write(row); err != nil {
+			lz.Close()
+			return err
+		}
+	}
+	if err := lz.Close(); err != nil {
+		return err
+	}
+	bw.flush()
+	fw.write([]byte{0})
+	return fw.err
+}
+
+// Close writes the trailer and flushes. It does not close the underlying
+// writer.
+func (fw *FrameWriter) Close() error {
+	fw.write([]byte{gifTrailer})
+	if fw.err == nil {
+		fw.err = fw.w.Flush()
+	}
+	return fw.err
+}
+
+// blockWriter splits LZW output into sub-blocks of up to 255 bytes.
+type blockWriter struct {
+	w   *FrameWriter
+	buf [256]byte // Length byte followed by data
+	n   int
+}
+
+func (b *blockWriter) Write(p []byte) (int, error) {
+	written := len(p)
+	for len(p) > 0 {
+		c := copy(b.buf[1+b.n:], p)
+		b.n += c
+		p = p[c:]
+		if b.n == 255 {
+			b.flush()
+		}
+	}
+	return written, b.w.err
+}
+
+func (b *blockWriter) flush() {
+	if b.n == 0 {
+		return
+	}
+	b.buf[0] = byte(b.n)
+	b.w.write(b.buf[:1+b.n])
+	b.n = 0
+}
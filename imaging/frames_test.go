@@ -0,0 +1,179 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"runtime"
+	"testing"
+)
+
+func testAnimation() *gif.GIF {
+	palette := color.Palette{color.RGBA{}, color.RGBA{255, 0, 0, 255}, color.RGBA{0, 0, 255, 255}}
+	anim := &gif.GIF{LoopCount: 0}
+	for i := 0; i < 3; i++ {
+		img := image.NewPaletted(image.Rect(i, i, 20+i, 10+i), palette)
+		for j := range img.Pix {
+			img.Pix[j] = uint8((j + i) % 3)
+		}
+		anim.Image = append(anim.Image, img)
+		anim.Delay = append(anim.Delay, 10*(i+1))
+		anim.Disposal = append(anim.Disposal, gif.DisposalBackground)
+	}
+	anim.Config = image.Config{Width: 30, Height: 20}
+	return anim
+}
+
+func TestFrames(t *testing.T) {
+	want := testAnimation()
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, want); err != nil {
+		t.Fatal(err)
+	}
+
+	var n int
+	for f, err := range Frames(&buf, 0) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := want.Image[n]
+		if f.Image.Rect != w.Rect || !bytes.Equal(f.Image.Pix, w.Pix) {
+			t.Errorf("frame %d: got %v, want %v", n, f.Image.Rect, w.Rect)
+		}
+		if f.Delay != want.Delay[n] || f.Disposal != want.Disposal[n] {
+			t.Errorf("frame %d: got delay %d disposal %d", n, f.Delay, f.Disposal)
+		}
+		if _, _, _, a := f.Image.Palette[0].RGBA(); a != 0 {
+			t.Errorf("frame %d: expected a transparent first color", n)
+		}
+		n++
+	}
+	if n != len(want.Image) {
+		t.Errorf("expected %d frames, got %d", len(want.Image), n)
+	}
+}
+
+func TestFrames_Truncated(t *testing.T) {
+	var buf bytes.Buffer
+	gif.EncodeAll(&buf, testAnimation())
+	var failed bool
+	for _, err := range Frames(bytes.NewReader(buf.Bytes()[:buf.Len()/2]), 0) {
+		failed = err != nil
+	}
+	if !failed {
+		t.Error("expected an error for a truncated GIF")
+	}
+
+	for _, err := range Frames(bytes.NewReader([]byte("not a gif at all")), 0) {
+		if err == nil {
+			t.Error("expected an error for a non-GIF")
+		}
+	}
+}
+
+// hostileGIF returns a GIF whose single frame descriptor claims w x h pixels
+// on a screen of sw x sh, with no image data behind it.
+func hostileGIF(sw, sh, w, h uint16) []byte {
+	b := []byte("GIF89a")
+	b = append(b, byte(sw), byte(sw>>8), byte(sh), byte(sh>>8), 0x80, 0, 0)
+	b = append(b, 0, 0, 0, 255, 255, 255) // Two-color global table
+	b = append(b, gifImage, 0, 0, 0, 0, byte(w), byte(w>>8), byte(h), byte(h>>8), 0)
+	return append(b, 2, 0, gifTrailer)
+}
+
+func TestFrames_Hostile(t *testing.T) {
+	// A huge frame on a tiny screen
+	for _, err := range Frames(bytes.NewReader(hostileGIF(1, 1, 65535, 65535)), 0) {
+		if err == nil {
+			t.Error("expected a frame outside the screen to be rejected")
+		}
+	}
+	// A huge frame on a screen that claims to fit it
+	for _, err := range Frames(bytes.NewReader(hostileGIF(65535, 65535, 65535, 65535)), 0) {
+		var tooLarge *TooLargeError
+		if !errors.As(err, &tooLarge) {
+			t.Errorf("expected a TooLargeError, got %v", err)
+		}
+	}
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	before := stats.TotalAlloc
+	for range Frames(bytes.NewReader(hostileGIF(65535, 65535, 65535, 65535)), 100) {
+	}
+	runtime.ReadMemStats(&stats)
+	if n := stats.TotalAlloc - before; n > 1<<20 {
+		t.Errorf("expected the frame to be rejected before allocating, allocated %d bytes", n)
+	}
+}
+
+func TestFrameWriter(t *testing.T) {
+	want := testAnimation()
+	var buf bytes.Buffer
+	fw, err := NewFrameWriter(&buf, 30, 20, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, img := range want.Image {
+		if err := fw.Write(Frame{Image: img, Delay: want.Delay[i], Disposal: want.Disposal[i]}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := fw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Config.Width != 30 || got.Config.Height != 20 || got.LoopCount != 2 {
+		t.Errorf("got %dx%d loop %d", got.Config.Width, got.Config.Height, got.LoopCount)
+	}
+	if len(got.Image) != len(want.Image) {
+		t.Fatalf("expected %d frames, got %d", len(want.Image), len(got.Image))
+	}
+	for i := range got.Image {
+		if got.Image[i].Rect != want.Image[i].Rect || !bytes.Equal(got.Image[i].Pix, want.Image[i].Pix) {
+			t.Errorf("frame %d differs", i)
+		}
+		if got.Delay[i] != want.Delay[i] || got.Disposal[i] != want.Disposal[i] {
+			t.Errorf("frame %d: got delay %d disposal %d", i, got.Delay[i], got.Disposal[i])
+		}
+	}
+}
+
+func TestFrameWriter_RoundTrip(t *testing.T) {
+	// A large frame spans many sub-blocks
+	palette := color.Palette{color.Black, color.White}
+	img := image.NewPaletted(image.Rect(0, 0, 300, 200), palette)
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i * 7 % 13 % 2)
+	}
+	var buf bytes.Buffer
+	fw, _ := NewFrameWriter(&buf, 300, 200, -1)
+	if err := fw.Write(Frame{Image: img}); err != nil {
+		t.Fatal(err)
+	}
+	fw.Close()
+
+	for f, err := range Frames(&buf, 0) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(f.Image.Pix, img.Pix) {
+			t.Error("round trip changed the pixels")
+		}
+	}
+}
+
+func TestFrameWriter_Invalid(t *testing.T) {
+	if _, err := NewFrameWriter(&bytes.Buffer{}, 0, 10, 0); err == nil {
+		t.Error("expected an error for a zero width")
+	}
+	fw, _ := NewFrameWriter(&bytes.Buffer{}, 10, 10, 0)
+	if err := fw.Write(Frame{Image: image.NewPaletted(image.Rect(0, 0, 2, 2), nil)}); err == nil {
+		t.Error("expected an error for an empty palette")
+	}
+}
@@ -0,0 +1,109 @@
+// Package gen generates images from parameters rather than from input files:
+// color swatches and gradients for design tools, and placeholders for tests.
+package gen
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+
+	"image-resizer/imaging"
+)
+
+// Direction is the axis along which colors change.
+type Direction int
+
+const (
+	Horizontal Direction = iota // Colors change from left to right
+	Vertical                    // Colors change from top to bottom
+)
+
+// ParseDirection parses "horizontal" or "vertical" (or "h"/"v").
+func ParseDirection(s string) (Direction, error) {
+	switch strings.ToLower(s) {
+	case "horizontal", "h", "":
+		return Horizontal, nil
+	case "vertical", "v":
+		return Vertical, nil
+	default:
+		return 0, fmt.Errorf("invalid direction %q", s)
+	}
+}
+
+// ParseColors parses a comma-separated color list such as "ff0000,#0f0,white".
+func ParseColors(s string) ([]color.Color, error) {
+	var colors []color.Color
+	for _, part := range strings.Split(s, ",") {
+		c, err := imaging.ParseColor(part)
+		if err != nil {
+			return nil, err
+		}
+		colors = append(colors, c)
+	}
+	return colors, nil
+}
+
+// Swatch draws colors as equal bands along dir.
+func Swatch(colors []color.Color, width, height int, dir Direction) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	if len(colors) == 0 {
+		return img
+	}
+	fill(img, dir, func(pos, length int) color.Color {
+		return colors[pos*len(colors)/length]
+	})
+	return img
+}
+
+// Gradient draws a linear gradient along dir through colors, evenly spaced.
+// Interpolation is in non-premultiplied sRGB, like CSS linear-gradient.
+func Gradient(colors []color.Color, width, height int, dir Direction) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	switch len(colors) {
+	case 0:
+		return img
+	case 1:
+		return Swatch(colors, width, height, dir)
+	}
+	stops := make([]color.NRGBA, len(colors))
+	for i, c := range colors {
+		stops[i] = color.NRGBAModel.Convert(c).(color.NRGBA)
+	}
+	fill(img, dir, func(pos, length int) color.Color {
+		if length == 1 {
+			return stops[0]
+		}
+		t := float64(pos) / float64(length-1) * float64(len(stops)-1)
+		i := min(int(t), len(stops)-2)
+		return lerp(stops[i], stops[i+1], t-float64(i))
+	})
+	return img
+}
+
+// fill sets every pixel of img to colorAt(pos, length), where pos is the
+// pixel's position along dir and length the image's extent along it.
+func fill(img *image.NRGBA, dir Direction, colorAt func(pos, length int) color.Color) {
+	b := img.Bounds()
+	length := b.Dx()
+	if dir == Vertical {
+		length = b.Dy()
+	}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			pos := x - b.Min.X
+			if dir == Vertical {
+				pos = y - b.Min.Y
+			}
+			img.Set(x, y, colorAt(pos, length))
+		}
+	}
+}
+
+// lerp interpolates between a and b; t is in [0, 1].
+func lerp(a, b color.NRGBA, t float64) color.NRGBA {
+	mix := func(x, y uint8) uint8 {
+		return uint8(float64(x) + (float64(y)-float64(x))*t + 0.5)
+	}
+	return color.NRGBA{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), mix(a.A, b.A)}
+}
@@ -0,0 +1,61 @@
+package gen
+
+import (
+	"image/color"
+	"testing"
+)
+
+var (
+	red  = color.NRGBA{255, 0, 0, 255}
+	blue = color.NRGBA{0, 0, 255, 255}
+)
+
+func TestParseColors(t *testing.T) {
+	colors, err := ParseColors("ff0000,#00f")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(colors) != 2 || colors[0] != red || colors[1] != blue {
+		t.Errorf("expected [red blue], got %v", colors)
+	}
+	if _, err := ParseColors("ff0000,nope"); err == nil {
+		t.Error("expected error for invalid color")
+	}
+}
+
+func TestSwatch(t *testing.T) {
+	img := Swatch([]color.Color{red, blue}, 10, 4, Horizontal)
+	if img.NRGBAAt(4, 0) != red || img.NRGBAAt(5, 3) != blue {
+		t.Errorf("expected red then blue bands, got %v and %v", img.NRGBAAt(4, 0), img.NRGBAAt(5, 3))
+	}
+
+	img = Swatch([]color.Color{red, blue}, 4, 10, Vertical)
+	if img.NRGBAAt(3, 4) != red || img.NRGBAAt(0, 5) != blue {
+		t.Errorf("expected red then blue vertical bands, got %v and %v", img.NRGBAAt(3, 4), img.NRGBAAt(0, 5))
+	}
+}
+
+func TestGradient(t *testing.T) {
+	img := Gradient([]color.Color{red, blue}, 11, 1, Horizontal)
+	if img.NRGBAAt(0, 0) != red || img.NRGBAAt(10, 0) != blue {
+		t.Errorf("expected gradient to start red and end blue, got %v and %v", img.NRGBAAt(0, 0), img.NRGBAAt(10, 0))
+	}
+	if mid := img.NRGBAAt(5, 0); mid != (color.NRGBA{128, 0, 128, 255}) {
+		t.Errorf("expected midpoint {128 0 128 255}, got %v", mid)
+	}
+
+	// Three stops pass through the middle color
+	img = Gradient([]color.Color{red, color.White, blue}, 1, 21, Vertical)
+	if mid := img.NRGBAAt(0, 10); mid != (color.NRGBA{255, 255, 255, 255}) {
+		t.Errorf("expected white at the middle stop, got %v", mid)
+	}
+}
+
+func TestParseDirection(t *testing.T) {
+	if d, err := ParseDirection("vertical"); err != nil || d != Vertical {
+		t.Errorf("expected Vertical, got %v (err=%v)", d, err)
+	}
+	if _, err := ParseDirection("diagonal"); err == nil {
+		t.Error("expected error for diagonal")
+	}
+}
@@ -0,0 +1,87 @@
+package gen
+
+import (
+	"image"
+	"math"
+	"math/rand"
+)
+
+// perlin is Ken Perlin's improved gradient noise with a seeded permutation.
+type perlin struct {
+	perm [512]int
+}
+
+func newPerlin(seed int64) *perlin {
+	n := &perlin{}
+	for i, v := range rand.New(rand.NewSource(seed)).Perm(256) {
+		n.perm[i], n.perm[i+256] = v, v
+	}
+	return n
+}
+
+// at returns the noise value at (x, y), roughly in [-1, 1].
+func (n *perlin) at(x, y float64) float64 {
+	fx, fy := math.Floor(x), math.Floor(y)
+	xi, yi := int(fx)&255, int(fy)&255
+	x, y = x-fx, y-fy
+	u, v := fade(x), fade(y)
+
+	p := n.perm
+	aa, ab := p[p[xi]+yi], p[p[xi]+yi+1]
+	ba, bb := p[p[xi+1]+yi], p[p[xi+1]+yi+1]
+	return mix(
+		mix(grad(aa, x, y), grad(ba, x-1, y), u),
+		mix(grad(ab, x, y-1), grad(bb, x-1, y-1), u),
+		v)
+}
+
+// fade is Perlin's 6t^5 - 15t^4 + 10t^3 smoothing curve.
+func fade(t float64) float64 {
+	return t * t * t * (t*(t*6-15) + 10)
+}
+
+func mix(a, b, t float64) float64 {
+	return a + (b-a)*t
+}
+
+// grad returns the dot product of (x, y) with one of four diagonal gradients.
+func grad(hash int, x, y float64) float64 {
+	switch hash & 3 {
+	case 0:
+		return x + y
+	case 1:
+		return -x + y
+	case 2:
+		return x - y
+	default:
+		return -x - y
+	}
+}
+
+// Noise renders fractal Perlin noise as a grayscale image. Scale is the size
+// in pixels of the coarsest noise features; each of the octaves adds detail at
+// twice the frequency and half the amplitude of the previous one. The same
+// seed always produces the same image.
+func Noise(width, height int, scale float64, octaves int, seed int64) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	if scale <= 0 {
+		scale = 1
+	}
+	octaves = max(octaves, 1)
+	n := newPerlin(seed)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var sum, total float64
+			freq, amp := 1/scale, 1.0
+			for o := 0; o < octaves; o++ {
+				sum += n.at(float64(x)*freq, float64(y)*freq) * amp
+				total += amp
+				freq, amp = freq*2, amp/2
+			}
+			v := (sum/total + 1) / 2
+			img.Pix[y*img.Stride+x] = uint8(math.Round(math.Max(0, math.Min(1, v)) * 255))
+		}
+	}
+	return img
+}
@@ -0,0 +1,33 @@
+package gen
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNoise_Deterministic(t *testing.T) {
+	a := Noise(64, 64, 16, 4, 42)
+	b := Noise(64, 64, 16, 4, 42)
+	if !bytes.Equal(a.Pix, b.Pix) {
+		t.Error("expected the same seed to produce the same image")
+	}
+	if c := Noise(64, 64, 16, 4, 43); bytes.Equal(a.Pix, c.Pix) {
+		t.Error("expected different seeds to produce different images")
+	}
+}
+
+func TestNoise_Range(t *testing.T) {
+	img := Noise(128, 128, 32, 1, 1)
+	lo, hi := uint8(255), uint8(0)
+	for _, v := range img.Pix {
+		lo, hi = min(lo, v), max(hi, v)
+	}
+	// Smooth noise should use a wide part of the range without saturating everywhere
+	if hi-lo < 64 {
+		t.Errorf("expected varied values, got range [%d, %d]", lo, hi)
+	}
+	// Perlin noise is zero at lattice points, which map to mid-gray
+	if v := img.GrayAt(32, 32).Y; v < 126 || v > 129 {
+		t.Errorf("expected mid-gray at a lattice point, got %d", v)
+	}
+}
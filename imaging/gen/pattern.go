@@ -0,0 +1,40 @@
+package gen
+
+import (
+	"image"
+	"image/color"
+)
+
+// Checkerboard draws alternating cell x cell squares of a and b, starting
+// with a in the top-left corner.
+func Checkerboard(width, height, cell int, a, b color.Color) *image.Paletted {
+	img := image.NewPaletted(image.Rect(0, 0, width, height), color.Palette{a, b})
+	cell = max(cell, 1)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Pix[y*img.Stride+x] = uint8((x/cell + y/cell) % 2)
+		}
+	}
+	return img
+}
+
+// Stripes draws repeating stripes of the given width, cycling through up to
+// 256 colors. Horizontal stripes change color from left to right, like Swatch.
+func Stripes(colors []color.Color, width, height, stripe int, dir Direction) *image.Paletted {
+	if len(colors) == 0 {
+		colors = []color.Color{color.Transparent}
+	}
+	colors = colors[:min(len(colors), 256)]
+	img := image.NewPaletted(image.Rect(0, 0, width, height), colors)
+	stripe = max(stripe, 1)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			pos := x
+			if dir == Vertical {
+				pos = y
+			}
+			img.Pix[y*img.Stride+x] = uint8(pos / stripe % len(colors))
+		}
+	}
+	return img
+}
@@ -0,0 +1,33 @@
+package gen
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestCheckerboard(t *testing.T) {
+	img := Checkerboard(8, 8, 2, color.Black, color.White)
+	want := [][]uint8{{0, 0, 1, 1}, {0, 0, 1, 1}, {1, 1, 0, 0}}
+	for y, row := range want {
+		for x, idx := range row {
+			if got := img.ColorIndexAt(x, y); got != idx {
+				t.Errorf("(%d,%d): expected index %d, got %d", x, y, idx, got)
+			}
+		}
+	}
+}
+
+func TestStripes(t *testing.T) {
+	colors := []color.Color{red, color.White, blue}
+	img := Stripes(colors, 12, 2, 2, Horizontal)
+	for x, want := range []uint8{0, 0, 1, 1, 2, 2, 0, 0} {
+		if got := img.ColorIndexAt(x, 1); got != want {
+			t.Errorf("x=%d: expected index %d, got %d", x, want, got)
+		}
+	}
+
+	img = Stripes(colors, 2, 6, 3, Vertical)
+	if img.ColorIndexAt(1, 2) != 0 || img.ColorIndexAt(1, 3) != 1 {
+		t.Error("expected vertical stripes to change color every 3 rows")
+	}
+}
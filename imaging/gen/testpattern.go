@@ -0,0 +1,147 @@
+package gen
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"image-resizer/imaging"
+
+	"golang.org/x/image/draw"
+)
+
+// rgb is shorthand for an opaque color in the pattern tables below.
+func rgb(r, g, b uint8) color.NRGBA {
+	return color.NRGBA{r, g, b, 0xff}
+}
+
+// smpteBars are the SMPTE EG 1-1990 color bars at 75% intensity.
+var (
+	smpteTop    = []color.NRGBA{rgb(192, 192, 192), rgb(192, 192, 0), rgb(0, 192, 192), rgb(0, 192, 0), rgb(192, 0, 192), rgb(192, 0, 0), rgb(0, 0, 192)}
+	smpteMiddle = []color.NRGBA{rgb(0, 0, 192), rgb(19, 19, 19), rgb(192, 0, 192), rgb(19, 19, 19), rgb(0, 192, 192), rgb(19, 19, 19), rgb(192, 192, 192)}
+	// The bottom row is -I, white, +Q, and black in 5/4-bar cells, then the
+	// PLUGE (super-black, black, brighter-than-black) in 1/3-bar cells, then black.
+	smpteBottom = []struct {
+		c     color.NRGBA
+		width float64 // In top-row bars
+	}{
+		{rgb(0, 33, 76), 1.25}, {rgb(255, 255, 255), 1.25}, {rgb(50, 0, 106), 1.25}, {rgb(19, 19, 19), 1.25},
+		{rgb(9, 9, 9), 1.0 / 3}, {rgb(19, 19, 19), 1.0 / 3}, {rgb(29, 29, 29), 1.0 / 3}, {rgb(19, 19, 19), 1},
+	}
+)
+
+// SMPTEBars draws the SMPTE color bar test pattern scaled to width x height.
+func SMPTEBars(width, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	top, middle := height*2/3, height*3/4
+	bar := float64(width) / float64(len(smpteTop))
+
+	band := func(y0, y1 int, colors []color.NRGBA) {
+		for i, c := range colors {
+			r := image.Rect(int(float64(i)*bar), y0, int(float64(i+1)*bar), y1)
+			draw.Draw(img, r, image.NewUniform(c), image.Point{}, draw.Src)
+		}
+	}
+	band(0, top, smpteTop)
+	band(top, middle, smpteMiddle)
+
+	x := 0.0
+	for _, cell := range smpteBottom {
+		r := image.Rect(int(x*bar), middle, int((x+cell.width)*bar), height)
+		draw.Draw(img, r, image.NewUniform(cell.c), image.Point{}, draw.Src)
+		x += cell.width
+	}
+	return img
+}
+
+// GrayRamp draws a black-to-white ramp from left to right. With steps > 1 the
+// ramp is quantized into that many equal bands; otherwise it is continuous.
+func GrayRamp(width, height, steps int) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		t := 0.0
+		if steps > 1 {
+			t = float64(x*steps/width) / float64(steps-1)
+		} else if width > 1 {
+			t = float64(x) / float64(width-1)
+		}
+		v := uint8(math.Round(t * 255))
+		for y := 0; y < height; y++ {
+			img.Pix[y*img.Stride+x] = v
+		}
+	}
+	return img
+}
+
+// gammaChartGammas are the display gammas compared by GammaChart.
+var gammaChartGammas = []float64{1.8, 2.2, 2.5}
+
+// GammaChart draws a display gamma check: the left half alternates black and
+// white rows, averaging to 50% luminance, and the right half has one labeled
+// gray patch per common gamma. The patch that blends with the left half when
+// viewed from a distance matches the display's gamma.
+func GammaChart(width, height int) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	half := width / 2
+	for y := 0; y < height; y++ {
+		v := uint8(0)
+		if y%2 == 0 {
+			v = 255
+		}
+		for x := 0; x < half; x++ {
+			img.Pix[y*img.Stride+x] = v
+		}
+	}
+
+	for i, gamma := range gammaChartGammas {
+		r := image.Rect(half, height*i/len(gammaChartGammas), width, height*(i+1)/len(gammaChartGammas))
+		v := uint8(math.Round(255 * math.Pow(0.5, 1/gamma)))
+		draw.Draw(img, r, image.NewUniform(color.Gray{v}), image.Point{}, draw.Src)
+		imaging.DrawText(img, image.Pt(r.Min.X+4, r.Min.Y+13), fmt.Sprintf("%.1f", gamma), color.Black)
+	}
+	return img
+}
+
+// SiemensStar draws a resolution target of alternating black and white
+// wedges radiating from the center. Resolution limits show up as blur or
+// aliasing where the wedges converge.
+func SiemensStar(width, height, spokes int) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	spokes = max(spokes, 2)
+	cx, cy := float64(width)/2, float64(height)/2
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			angle := math.Atan2(float64(y)+0.5-cy, float64(x)+0.5-cx) + math.Pi
+			if int(angle/(2*math.Pi)*float64(2*spokes))%2 == 0 {
+				img.Pix[y*img.Stride+x] = 255
+			}
+		}
+	}
+	return img
+}
+
+// colorChecker holds the sRGB values of the 24 ColorChecker Classic patches,
+// in reading order.
+var colorChecker = [24]color.NRGBA{
+	rgb(115, 82, 68), rgb(194, 150, 130), rgb(98, 122, 157), rgb(87, 108, 67), rgb(133, 128, 177), rgb(103, 189, 170),
+	rgb(214, 126, 44), rgb(80, 91, 166), rgb(193, 90, 99), rgb(94, 60, 108), rgb(157, 188, 64), rgb(224, 163, 46),
+	rgb(56, 61, 150), rgb(70, 148, 73), rgb(175, 54, 60), rgb(231, 199, 31), rgb(187, 86, 149), rgb(8, 133, 161),
+	rgb(243, 243, 242), rgb(200, 200, 200), rgb(160, 160, 160), rgb(122, 122, 121), rgb(85, 85, 85), rgb(52, 52, 52),
+}
+
+// ColorChecker draws the 6x4 ColorChecker Classic layout: 24 reference
+// patches separated by black borders.
+func ColorChecker(width, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.Black), image.Point{}, draw.Src)
+
+	const cols, rows = 6, 4
+	border := max(min(width/cols, height/rows)/10, 1)
+	for i, c := range colorChecker {
+		col, row := i%cols, i/cols
+		r := image.Rect(width*col/cols, height*row/rows, width*(col+1)/cols, height*(row+1)/rows).Inset(border)
+		draw.Draw(img, r, image.NewUniform(c), image.Point{}, draw.Src)
+	}
+	return img
+}
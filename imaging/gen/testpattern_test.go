@@ -0,0 +1,81 @@
+package gen
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestSMPTEBars(t *testing.T) {
+	img := SMPTEBars(700, 120)
+	tests := []struct {
+		x, y int
+		want color.NRGBA
+	}{
+		{50, 10, rgb(192, 192, 192)},   // Top: gray
+		{650, 10, rgb(0, 0, 192)},      // Top: blue
+		{50, 85, rgb(0, 0, 192)},       // Middle: blue
+		{150, 85, rgb(19, 19, 19)},     // Middle: black
+		{50, 110, rgb(0, 33, 76)},      // Bottom: -I
+		{200, 110, rgb(255, 255, 255)}, // Bottom: white
+		{515, 110, rgb(9, 9, 9)},       // Bottom: PLUGE super-black
+	}
+	for _, tt := range tests {
+		if got := img.NRGBAAt(tt.x, tt.y); got != tt.want {
+			t.Errorf("(%d,%d): expected %v, got %v", tt.x, tt.y, tt.want, got)
+		}
+	}
+}
+
+func TestGrayRamp(t *testing.T) {
+	img := GrayRamp(256, 2, 0)
+	if img.GrayAt(0, 1).Y != 0 || img.GrayAt(255, 1).Y != 255 || img.GrayAt(128, 0).Y != 128 {
+		t.Errorf("expected a continuous 0-255 ramp, got %d..%d..%d", img.GrayAt(0, 1).Y, img.GrayAt(128, 0).Y, img.GrayAt(255, 1).Y)
+	}
+
+	img = GrayRamp(100, 1, 5)
+	for x, want := range map[int]uint8{0: 0, 19: 0, 20: 64, 50: 128, 99: 255} {
+		if got := img.GrayAt(x, 0).Y; got != want {
+			t.Errorf("x=%d: expected step %d, got %d", x, want, got)
+		}
+	}
+}
+
+func TestGammaChart(t *testing.T) {
+	img := GammaChart(100, 90)
+	if img.GrayAt(10, 0).Y != 255 || img.GrayAt(10, 1).Y != 0 {
+		t.Error("expected alternating white and black rows on the left")
+	}
+	// The gamma 2.2 patch is 255 * 0.5^(1/2.2)
+	if got := img.GrayAt(95, 45).Y; got != 186 {
+		t.Errorf("expected gamma 2.2 patch value 186, got %d", got)
+	}
+}
+
+func TestSiemensStar(t *testing.T) {
+	img := SiemensStar(64, 64, 8)
+	black, white := 0, 0
+	for _, v := range img.Pix {
+		if v == 0 {
+			black++
+		} else {
+			white++
+		}
+	}
+	// Pixels lying exactly on a wedge edge can fall either way
+	if diff := black - white; diff < -len(img.Pix)/20 || diff > len(img.Pix)/20 {
+		t.Errorf("expected roughly equal black and white areas, got %d black and %d white", black, white)
+	}
+}
+
+func TestColorChecker(t *testing.T) {
+	img := ColorChecker(600, 400)
+	if got := img.NRGBAAt(50, 50); got != colorChecker[0] {
+		t.Errorf("expected dark skin patch, got %v", got)
+	}
+	if got := img.NRGBAAt(550, 350); got != colorChecker[23] {
+		t.Errorf("expected black patch, got %v", got)
+	}
+	if got := img.NRGBAAt(0, 0); got != rgb(0, 0, 0) {
+		t.Errorf("expected black border, got %v", got)
+	}
+}
@@ -0,0 +1,218 @@
+package imaging
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// GeometryFlag is an ImageMagick geometry modifier controlling how the
+// requested size is applied.
+type GeometryFlag int
+
+const (
+	// GeometryFit scales to fit within the size, preserving aspect ratio (default).
+	GeometryFit GeometryFlag = iota
+	// GeometryFill scales to cover the size, preserving aspect ratio (^).
+	GeometryFill
+	// GeometryExact scales to exactly the size, ignoring aspect ratio (!).
+	GeometryExact
+	// GeometryShrink only shrinks images larger than the size (>).
+	GeometryShrink
+	// GeometryEnlarge only enlarges images smaller than the size (<).
+	GeometryEnlarge
+	// GeometryArea scales so the pixel count is at most Width (@).
+	GeometryArea
+)
+
+// Geometry is a parsed ImageMagick-style geometry string such as
+// "300x200^", "50%", "300x200+10+20", or "x200".
+type Geometry struct {
+	Width, Height int     // Requested size in pixels; zero when omitted
+	ScaleX        float64 // Percentage scale factors (1 = 100%); zero unless Percent
+	ScaleY        float64
+	Percent       bool // Size was given as a percentage (%)
+	Flag          GeometryFlag
+	X, Y          int  // Offset, for crop and placement operations
+	HasOffset     bool // Offset was given
+}
+
+// ParseGeometry parses an ImageMagick geometry string:
+//
+//	WxH, W, xH       size (missing side keeps aspect ratio)
+//	P%, PxQ%         percentage scale
+//	suffix ^ ! > <   fill, exact, shrink-only, enlarge-only
+//	A@               maximum area in pixels
+//	+X+Y, -X-Y       offset
+func ParseGeometry(s string) (Geometry, error) {
+	var g Geometry
+	rest := strings.TrimSpace(s)
+	if rest == "" {
+		return g, fmt.Errorf("empty geometry")
+	}
+
+	// Split off the offset, which starts at the first sign character
+	if i := strings.IndexAny(rest, "+-"); i >= 0 {
+		x, y, err := parseOffset(rest[i:])
+		if err != nil {
+			return g, fmt.Errorf("invalid geometry %q: %w", s, err)
+		}
+		g.X, g.Y, g.HasOffset = x, y, true
+		rest = rest[:i]
+	}
+
+	// Modifiers may appear in any order after the size
+modifiers:
+	for len(rest) > 0 {
+		switch rest[len(rest)-1] {
+		case '^':
+			g.Flag = GeometryFill
+		case '!':
+			g.Flag = GeometryExact
+		case '>':
+			g.Flag = GeometryShrink
+		case '<':
+			g.Flag = GeometryEnlarge
+		case '@':
+			g.Flag = GeometryArea
+		case '%':
+			g.Percent = true
+		default:
+			break modifiers
+		}
+		rest = rest[:len(rest)-1]
+	}
+
+	if rest == "" {
+		if g.HasOffset {
+			return g, nil
+		}
+		return g, fmt.Errorf("invalid geometry %q: missing size", s)
+	}
+
+	ws, hs, hasX := strings.Cut(strings.ToLower(rest), "x")
+	if g.Percent {
+		sx, err := parseDimension(ws, true)
+		if err != nil {
+			return g, fmt.Errorf("invalid geometry %q: %w", s, err)
+		}
+		sy := sx
+		if hasX && hs != "" {
+			if sy, err = parseDimension(hs, true); err != nil {
+				return g, fmt.Errorf("invalid geometry %q: %w", s, err)
+			}
+		}
+		if sx == 0 {
+			sx = sy
+		}
+		g.ScaleX, g.ScaleY = sx/100, sy/100
+		return g, nil
+	}
+
+	w, err := parseDimension(ws, false)
+	if err != nil {
+		return g, fmt.Errorf("invalid geometry %q: %w", s, err)
+	}
+	var h float64
+	if hasX {
+		if h, err = parseDimension(hs, false); err != nil {
+			return g, fmt.Errorf("invalid geometry %q: %w", s, err)
+		}
+	}
+	g.Width, g.Height = int(w), int(h)
+	if g.Width == 0 && g.Height == 0 {
+		return g, fmt.Errorf("invalid geometry %q: missing size", s)
+	}
+	return g, nil
+}
+
+// parseDimension parses one side of a size. Empty means "unspecified".
+func parseDimension(s string, allowFraction bool) (float64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if allowFraction {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil || v < 0 {
+			return 0, fmt.Errorf("bad percentage %q", s)
+		}
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("bad size %q", s)
+	}
+	return float64(v), nil
+}
+
+// parseOffset parses "+X+Y" (either sign allowed on each part).
+func parseOffset(s string) (int, int, error) {
+	i := strings.IndexAny(s[1:], "+-")
+	if i < 0 {
+		return 0, 0, fmt.Errorf("bad offset %q", s)
+	}
+	x, err := strconv.Atoi(s[:i+1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("bad offset %q", s)
+	}
+	y, err := strconv.Atoi(s[i+1:])
+	if err != nil {
+		return 0, 0, fmt.Errorf("bad offset %q", s)
+	}
+	return x, y, nil
+}
+
+// Size computes the output dimensions for an image of srcW x srcH.
+// Unspecified sides keep the aspect ratio; the result is at least 1x1.
+func (g Geometry) Size(srcW, srcH int) (int, int) {
+	if srcW <= 0 || srcH <= 0 {
+		return srcW, srcH
+	}
+	fw, fh := float64(srcW), float64(srcH)
+
+	if g.Percent {
+		return atLeastOne(fw * g.ScaleX), atLeastOne(fh * g.ScaleY)
+	}
+	if g.Flag == GeometryArea {
+		if g.Width <= 0 || fw*fh <= float64(g.Width) {
+			return srcW, srcH
+		}
+		s := math.Sqrt(float64(g.Width) / (fw * fh))
+		return atLeastOne(fw * s), atLeastOne(fh * s)
+	}
+	if g.Width == 0 && g.Height == 0 {
+		return srcW, srcH
+	}
+
+	sx, sy := float64(g.Width)/fw, float64(g.Height)/fh
+	if g.Width == 0 {
+		sx = sy
+	} else if g.Height == 0 {
+		sy = sx
+	}
+
+	switch g.Flag {
+	case GeometryExact:
+		if g.Width == 0 || g.Height == 0 {
+			break
+		}
+		return g.Width, g.Height
+	case GeometryFill:
+		s := math.Max(sx, sy)
+		return atLeastOne(fw * s), atLeastOne(fh * s)
+	}
+
+	s := math.Min(sx, sy)
+	if g.Flag == GeometryShrink && s >= 1 || g.Flag == GeometryEnlarge && s <= 1 {
+		return srcW, srcH
+	}
+	return atLeastOne(fw * s), atLeastOne(fh * s)
+}
+
+func atLeastOne(v float64) int {
+	if n := int(math.Round(v)); n > 1 {
+		return n
+	}
+	return 1
+}
@@ -0,0 +1,82 @@
+package imaging
+
+import "testing"
+
+func TestParseGeometry(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Geometry
+	}{
+		{"300x200", Geometry{Width: 300, Height: 200}},
+		{"300", Geometry{Width: 300}},
+		{"x200", Geometry{Height: 200}},
+		{"300x", Geometry{Width: 300}},
+		{"300x200^", Geometry{Width: 300, Height: 200, Flag: GeometryFill}},
+		{"300x200!", Geometry{Width: 300, Height: 200, Flag: GeometryExact}},
+		{"300x200>", Geometry{Width: 300, Height: 200, Flag: GeometryShrink}},
+		{"300x200<", Geometry{Width: 300, Height: 200, Flag: GeometryEnlarge}},
+		{"10000@", Geometry{Width: 10000, Flag: GeometryArea}},
+		{"50%", Geometry{ScaleX: 0.5, ScaleY: 0.5, Percent: true}},
+		{"50x25%", Geometry{ScaleX: 0.5, ScaleY: 0.25, Percent: true}},
+		{"300x200+10+20", Geometry{Width: 300, Height: 200, X: 10, Y: 20, HasOffset: true}},
+		{"300x200-5+7", Geometry{Width: 300, Height: 200, X: -5, Y: 7, HasOffset: true}},
+		{"300x200^+10+20", Geometry{Width: 300, Height: 200, Flag: GeometryFill, X: 10, Y: 20, HasOffset: true}},
+		{"+3+4", Geometry{X: 3, Y: 4, HasOffset: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseGeometry(tt.in)
+			if err != nil {
+				t.Fatalf("ParseGeometry(%q) error: %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseGeometry(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseGeometry_Invalid(t *testing.T) {
+	for _, in := range []string{"", "abc", "x", "300xabc", "-5x10", "^", "300x200+10", "%"} {
+		if _, err := ParseGeometry(in); err == nil {
+			t.Errorf("ParseGeometry(%q) expected error", in)
+		}
+	}
+}
+
+func TestGeometry_Size(t *testing.T) {
+	tests := []struct {
+		geom         string
+		srcW, srcH   int
+		wantW, wantH int
+	}{
+		{"300x200", 600, 600, 200, 200},
+		{"300", 600, 300, 300, 150},
+		{"x200", 600, 300, 400, 200},
+		{"300x200^", 600, 600, 300, 300},
+		{"300x200!", 600, 600, 300, 200},
+		{"300x200>", 100, 100, 100, 100},
+		{"300x200>", 600, 400, 300, 200},
+		{"300x200<", 600, 400, 600, 400},
+		{"300x200<", 30, 20, 300, 200},
+		{"50%", 600, 400, 300, 200},
+		{"50x200%", 600, 400, 300, 800},
+		{"10000@", 200, 200, 100, 100},
+		{"10000@", 50, 50, 50, 50},
+		{"1x1", 1000, 10, 1, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.geom, func(t *testing.T) {
+			g, err := ParseGeometry(tt.geom)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w, h := g.Size(tt.srcW, tt.srcH)
+			if w != tt.wantW || h != tt.wantH {
+				t.Errorf("%q on %dx%d = %dx%d, want %dx%d", tt.geom, tt.srcW, tt.srcH, w, h, tt.wantW, tt.wantH)
+			}
+		})
+	}
+}
@@ -3,15 +3,142 @@
 package imaging
 
 import (
+	"context"
+	"fmt"
 	"image"
 	"image/color"
-
-	"golang.org/x/image/draw"
+	"math"
+	"slices"
+	"strings"
 )
 
 // Trim removes transparent borders (if image has transparency) or solid color borders.
+// The result is a zero-copy view into img that keeps the source coordinates,
+// so its Bounds().Min is generally not (0, 0). Use Clone for an independent copy.
 func Trim(img image.Image) image.Image {
+	return TrimFuzz(img, 0)
+}
+
+// TrimFuzz is like Trim but treats border pixels within fuzz percent (0-100)
+// of the border color as border, like ImageMagick's -fuzz. For transparent
+// borders, pixels with alpha at or below fuzz percent are trimmed.
+func TrimFuzz(img image.Image, fuzz float64) image.Image {
+	trimmed, _ := TrimFuzzContext(context.Background(), img, fuzz)
+	return trimmed
+}
+
+// TrimFuzzContext is like TrimFuzz but stops scanning and returns ctx's error
+// once ctx is done.
+func TrimFuzzContext(ctx context.Context, img image.Image, fuzz float64) (image.Image, error) {
+	r, err := TrimRectContext(ctx, img, fuzz)
+	if err != nil {
+		return nil, err
+	}
+	// If nothing to trim, return original
+	if r == img.Bounds() {
+		return img, nil
+	}
+	return Crop(img, r), nil
+}
+
+// Sides is a set of image edges.
+type Sides uint8
+
+const (
+	SideTop Sides = 1 << iota
+	SideRight
+	SideBottom
+	SideLeft
+
+	AllSides = SideTop | SideRight | SideBottom | SideLeft
+)
+
+// sideNames are the names accepted by ParseSides.
+var sideNames = map[string]Sides{
+	"top":    SideTop,
+	"right":  SideRight,
+	"bottom": SideBottom,
+	"left":   SideLeft,
+	"all":    AllSides,
+}
+
+// ParseSides parses a list of edge names such as "top,bottom". Names may
+// also be separated by "+" or spaces, as in "top+bottom", for contexts such
+// as pipeline arguments where commas are taken.
+func ParseSides(s string) (Sides, error) {
+	var sides Sides
+	for _, name := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '+' || r == ' ' }) {
+		side, ok := sideNames[strings.ToLower(name)]
+		if !ok {
+			return 0, fmt.Errorf("unknown edge %q", name)
+		}
+		sides |= side
+	}
+	if sides == 0 {
+		return 0, fmt.Errorf("no edges in %q", s)
+	}
+	return sides, nil
+}
+
+// TrimSides is like TrimFuzz but trims only the given sides and keeps up to
+// margin pixels of border around the content on each trimmed side, rather
+// than cutting flush. Like Trim, the result is a zero-copy view into img.
+func TrimSides(img image.Image, fuzz float64, sides Sides, margin int) image.Image {
+	b := img.Bounds()
+	r := LimitTrim(b, TrimRectFuzz(img, fuzz), sides, margin)
+	if r == b {
+		return img
+	}
+	return Crop(img, r)
+}
+
+// LimitTrim adjusts r, a trimmed rectangle within b, to undo the trim on the
+// sides not in sides and to keep margin pixels of border on the others.
+func LimitTrim(b, r image.Rectangle, sides Sides, margin int) image.Rectangle {
+	margin = max(margin, 0)
+	if sides&SideTop == 0 {
+		r.Min.Y = b.Min.Y
+	} else {
+		r.Min.Y = max(b.Min.Y, r.Min.Y-margin)
+	}
+	if sides&SideBottom == 0 {
+		r.Max.Y = b.Max.Y
+	} else {
+		r.Max.Y = min(b.Max.Y, r.Max.Y+margin)
+	}
+	if sides&SideLeft == 0 {
+		r.Min.X = b.Min.X
+	} else {
+		r.Min.X = max(b.Min.X, r.Min.X-margin)
+	}
+	if sides&SideRight == 0 {
+		r.Max.X = b.Max.X
+	} else {
+		r.Max.X = min(b.Max.X, r.Max.X+margin)
+	}
+	return r
+}
+
+// TrimRect returns the rectangle Trim would crop img to, in img's
+// coordinates, for callers that store crop coordinates instead of new images.
+// It is img.Bounds() when there is nothing to trim.
+func TrimRect(img image.Image) image.Rectangle {
+	return TrimRectFuzz(img, 0)
+}
+
+// TrimRectFuzz returns the rectangle TrimFuzz would crop img to.
+func TrimRectFuzz(img image.Image, fuzz float64) image.Rectangle {
+	r, _ := TrimRectContext(context.Background(), img, fuzz)
+	return r
+}
+
+// TrimRectContext returns the rectangle TrimFuzz would crop img to. It stops
+// scanning and returns ctx's error once ctx is done.
+func TrimRectContext(ctx context.Context, img image.Image, fuzz float64) (image.Rectangle, error) {
 	bounds := img.Bounds()
+	if bounds.Empty() {
+		return bounds, nil
+	}
 	minX, minY := bounds.Min.X, bounds.Min.Y
 	maxX, maxY := bounds.Max.X, bounds.Max.Y
 
@@ -24,14 +151,20 @@ func Trim(img image.Image) image.Image {
 		c := img.At(x, y)
 		if hasTransparency {
 			_, _, _, alpha := c.RGBA()
-			return alpha == 0
+			return float64(alpha) <= fuzz/100*0xffff
+		}
+		if fuzz == 0 {
+			return colorsEqual(c, topLeft)
 		}
-		return colorsEqual(c, topLeft)
+		return colorDistance(c, topLeft) <= fuzz/100
 	}
 
 	// Find top edge
 	top := minY
 	for y := minY; y < maxY; y++ {
+		if err := ctx.Err(); err != nil {
+			return image.Rectangle{}, err
+		}
 		found := false
 		for x := minX; x < maxX; x++ {
 			if !shouldTrim(x, y) {
@@ -48,6 +181,9 @@ func Trim(img image.Image) image.Image {
 	// Find bottom edge
 	bottom := maxY
 	for y := maxY - 1; y >= top; y-- {
+		if err := ctx.Err(); err != nil {
+			return image.Rectangle{}, err
+		}
 		found := false
 		for x := minX; x < maxX; x++ {
 			if !shouldTrim(x, y) {
@@ -64,6 +200,9 @@ func Trim(img image.Image) image.Image {
 	// Find left edge
 	left := minX
 	for x := minX; x < maxX; x++ {
+		if err := ctx.Err(); err != nil {
+			return image.Rectangle{}, err
+		}
 		found := false
 		for y := top; y < bottom; y++ {
 			if !shouldTrim(x, y) {
@@ -80,6 +219,9 @@ func Trim(img image.Image) image.Image {
 	// Find right edge
 	right := maxX
 	for x := maxX - 1; x >= left; x-- {
+		if err := ctx.Err(); err != nil {
+			return image.Rectangle{}, err
+		}
 		found := false
 		for y := top; y < bottom; y++ {
 			if !shouldTrim(x, y) {
@@ -93,15 +235,108 @@ func Trim(img image.Image) image.Image {
 		}
 	}
 
-	// If nothing to trim, return original
-	if left == minX && right == maxX && top == minY && bottom == maxY {
+	return image.Rect(left, top, right, bottom), nil
+}
+
+// EdgeColors are the border colors TrimEdges removes from each edge. A nil
+// color is detected as the most common color along that edge.
+type EdgeColors struct {
+	Top, Right, Bottom, Left color.Color
+}
+
+// TrimEdges is like TrimFuzz but handles borders of mixed colors, such as book
+// scans with a dark gutter on one side and white margins elsewhere. It works
+// in two passes: the first settles each edge's border color, and the second
+// trims any row or column made up entirely of those colors (within fuzz
+// percent), repeating until no edge moves. The gutter's ends along the top and
+// bottom edges therefore no longer stop those edges from being trimmed. Like
+// Trim, the result is a zero-copy view into img.
+func TrimEdges(img image.Image, edges EdgeColors, fuzz float64) image.Image {
+	b := img.Bounds()
+	if b.Empty() {
 		return img
 	}
 
-	// Create cropped image
-	cropped := image.NewRGBA(image.Rect(0, 0, right-left, bottom-top))
-	draw.Copy(cropped, image.Point{}, img, image.Rect(left, top, right, bottom), draw.Src, nil)
-	return cropped
+	// First pass: find the border colors
+	if edges.Top == nil {
+		edges.Top = dominantColor(img, b.Min.X, b.Min.Y, 1, 0, b.Dx())
+	}
+	if edges.Bottom == nil {
+		edges.Bottom = dominantColor(img, b.Min.X, b.Max.Y-1, 1, 0, b.Dx())
+	}
+	if edges.Left == nil {
+		edges.Left = dominantColor(img, b.Min.X, b.Min.Y, 0, 1, b.Dy())
+	}
+	if edges.Right == nil {
+		edges.Right = dominantColor(img, b.Max.X-1, b.Min.Y, 0, 1, b.Dy())
+	}
+	borders := []color.Color{edges.Top, edges.Right, edges.Bottom, edges.Left}
+	isBorder := func(c color.Color) bool {
+		for _, border := range borders {
+			if fuzz == 0 && colorsEqual(c, border) || fuzz > 0 && colorDistance(c, border) <= fuzz/100 {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Second pass: trim border lines until no edge moves
+	r := b
+	rowIsBorder := func(y int) bool {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if !isBorder(img.At(x, y)) {
+				return false
+			}
+		}
+		return true
+	}
+	colIsBorder := func(x int) bool {
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			if !isBorder(img.At(x, y)) {
+				return false
+			}
+		}
+		return true
+	}
+	for moved := true; moved && !r.Empty(); {
+		moved = false
+		for ; r.Min.Y < r.Max.Y && rowIsBorder(r.Min.Y); r.Min.Y++ {
+			moved = true
+		}
+		for ; r.Max.Y > r.Min.Y && rowIsBorder(r.Max.Y-1); r.Max.Y-- {
+			moved = true
+		}
+		for ; r.Min.X < r.Max.X && colIsBorder(r.Min.X); r.Min.X++ {
+			moved = true
+		}
+		for ; r.Max.X > r.Min.X && colIsBorder(r.Max.X-1); r.Max.X-- {
+			moved = true
+		}
+	}
+
+	// An image that is all border is returned unchanged, as Trim does
+	if r.Empty() || r == b {
+		return img
+	}
+	return Crop(img, r)
+}
+
+// dominantColor returns the most common color among n pixels starting at
+// (x, y) and stepping by (dx, dy). Ties go to the color seen first. The
+// pixel's own color is returned so exact comparisons against it still hold.
+func dominantColor(img image.Image, x, y, dx, dy, n int) color.Color {
+	counts := make(map[color.NRGBA]int)
+	var best color.Color
+	bestCount := 0
+	for i := 0; i < n; i++ {
+		c := img.At(x+i*dx, y+i*dy)
+		key := color.NRGBAModel.Convert(c).(color.NRGBA)
+		counts[key]++
+		if counts[key] > bestCount {
+			best, bestCount = c, counts[key]
+		}
+	}
+	return best
 }
 
 // colorsEqual compares two colors for equality.
@@ -111,11 +346,116 @@ func colorsEqual(c1, c2 color.Color) bool {
 	return r1 == r2 && g1 == g2 && b1 == b2 && a1 == a2
 }
 
+// colorDistance returns the Euclidean distance between two colors in RGBA
+// space, normalized so that 1 is the distance from transparent black to opaque white.
+func colorDistance(c1, c2 color.Color) float64 {
+	r1, g1, b1, a1 := c1.RGBA()
+	r2, g2, b2, a2 := c2.RGBA()
+	dr := float64(r1) - float64(r2)
+	dg := float64(g1) - float64(g2)
+	db := float64(b1) - float64(b2)
+	da := float64(a1) - float64(a2)
+	return math.Sqrt(dr*dr+dg*dg+db*db+da*da) / (2 * 0xffff)
+}
+
 // RemoveBackground replaces background pixels with transparent pixels.
 // Only pixels connected to the image edges are considered background (flood-fill from borders).
+// The background colors are sampled from the corners (see BackgroundColors),
+// so a subject touching one corner doesn't become the background.
 func RemoveBackground(img image.Image) image.Image {
+	result, _ := RemoveBackgroundContext(context.Background(), img)
+	return result
+}
+
+// cancelCheckInterval is how many pixels the flood fill visits between checks
+// of its context.
+const cancelCheckInterval = 4096
+
+// RemoveBackgroundContext is like RemoveBackground but stops and returns
+// ctx's error once ctx is done.
+func RemoveBackgroundContext(ctx context.Context, img image.Image) (image.Image, error) {
+	return RemoveBackgroundWith(ctx, img, BackgroundOptions{})
+}
+
+// MaxFeather bounds BackgroundOptions.Feather as accepted from users, since
+// the cost of feathering grows with the square of the radius.
+const MaxFeather = 20
+
+// BackgroundOptions adjusts RemoveBackgroundWith.
+type BackgroundOptions struct {
+	// Colors are the background colors, such as one picked by the user.
+	// When empty they are sampled with BackgroundColors.
+	Colors []color.Color
+	// Feather is the width in pixels over which the subject's edge fades
+	// to transparent, so cutouts composite without jagged halos. Zero
+	// keeps a hard edge.
+	Feather float64
+}
+
+// BackgroundColors returns the likely background colors of img: those of
+// its four corners that are common along its edges. A corner color is kept
+// if it covers at least half as many edge pixels as the most common one,
+// which keeps both halves of a two-tone backdrop but drops the color of a
+// subject that only touches a corner.
+func BackgroundColors(img image.Image) []color.Color {
+	b := img.Bounds()
+	if b.Empty() {
+		return nil
+	}
+	var corners []color.Color
+	for _, pt := range []image.Point{b.Min, {b.Max.X - 1, b.Min.Y}, {b.Min.X, b.Max.Y - 1}, b.Max.Sub(image.Pt(1, 1))} {
+		c := img.At(pt.X, pt.Y)
+		if !slices.ContainsFunc(corners, func(o color.Color) bool { return colorsEqual(o, c) }) {
+			corners = append(corners, c)
+		}
+	}
+	if len(corners) == 1 {
+		return corners
+	}
+
+	counts := make([]int, len(corners))
+	count := func(x, y int) {
+		c := img.At(x, y)
+		for i, corner := range corners {
+			if colorsEqual(c, corner) {
+				counts[i]++
+				return
+			}
+		}
+	}
+	for x := b.Min.X; x < b.Max.X; x++ {
+		count(x, b.Min.Y)
+		count(x, b.Max.Y-1)
+	}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		count(b.Min.X, y)
+		count(b.Max.X-1, y)
+	}
+	most := slices.Max(counts)
+	var colors []color.Color
+	for i, c := range corners {
+		if 2*counts[i] >= most {
+			colors = append(colors, c)
+		}
+	}
+	return colors
+}
+
+// RemoveBackgroundWith is RemoveBackgroundContext with options.
+// Edge-connected pixels matching any of the background colors become
+// transparent.
+func RemoveBackgroundWith(ctx context.Context, img image.Image, opts BackgroundOptions) (image.Image, error) {
 	bounds := img.Bounds()
-	bgColor := img.At(bounds.Min.X, bounds.Min.Y)
+	if bounds.Empty() {
+		return image.NewRGBA(bounds), nil
+	}
+	colors := opts.Colors
+	if len(colors) == 0 {
+		colors = BackgroundColors(img)
+	}
+	isBgColor := func(c color.Color) bool {
+		return slices.ContainsFunc(colors, func(bg color.Color) bool { return colorsEqual(c, bg) })
+	}
 	width := bounds.Dx()
 	height := bounds.Dy()
 
@@ -125,31 +465,30 @@ func RemoveBackground(img image.Image) image.Image {
 		isBackground[i] = make([]bool, width)
 	}
 
-	// Flood-fill from all edge pixels that match the background color
-	type point struct{ x, y int }
+	// Flood-fill from all edge pixels that match a background color
 	queue := make([]point, 0)
 
-	// Add all edge pixels matching background color to the queue
+	// Add all edge pixels matching a background color to the queue
 	for x := bounds.Min.X; x < bounds.Max.X; x++ {
 		// Top edge
-		if colorsEqual(img.At(x, bounds.Min.Y), bgColor) {
+		if isBgColor(img.At(x, bounds.Min.Y)) {
 			queue = append(queue, point{x - bounds.Min.X, 0})
 			isBackground[0][x-bounds.Min.X] = true
 		}
 		// Bottom edge
-		if colorsEqual(img.At(x, bounds.Max.Y-1), bgColor) {
+		if isBgColor(img.At(x, bounds.Max.Y-1)) {
 			queue = append(queue, point{x - bounds.Min.X, height - 1})
 			isBackground[height-1][x-bounds.Min.X] = true
 		}
 	}
 	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
 		// Left edge
-		if colorsEqual(img.At(bounds.Min.X, y), bgColor) {
+		if isBgColor(img.At(bounds.Min.X, y)) {
 			queue = append(queue, point{0, y - bounds.Min.Y})
 			isBackground[y-bounds.Min.Y][0] = true
 		}
 		// Right edge
-		if colorsEqual(img.At(bounds.Max.X-1, y), bgColor) {
+		if isBgColor(img.At(bounds.Max.X-1, y)) {
 			queue = append(queue, point{width - 1, y - bounds.Min.Y})
 			isBackground[y-bounds.Min.Y][width-1] = true
 		}
@@ -157,14 +496,19 @@ func RemoveBackground(img image.Image) image.Image {
 
 	// BFS flood-fill
 	dirs := []point{{0, 1}, {0, -1}, {1, 0}, {-1, 0}}
-	for len(queue) > 0 {
+	for visited := 0; len(queue) > 0; visited++ {
+		if visited%cancelCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
 		p := queue[0]
 		queue = queue[1:]
 
 		for _, d := range dirs {
 			nx, ny := p.x+d.x, p.y+d.y
 			if nx >= 0 && nx < width && ny >= 0 && ny < height && !isBackground[ny][nx] {
-				if colorsEqual(img.At(nx+bounds.Min.X, ny+bounds.Min.Y), bgColor) {
+				if isBgColor(img.At(nx+bounds.Min.X, ny+bounds.Min.Y)) {
 					isBackground[ny][nx] = true
 					queue = append(queue, point{nx, ny})
 				}
@@ -172,17 +516,93 @@ func RemoveBackground(img image.Image) image.Image {
 		}
 	}
 
+	var feather map[point]float64
+	if opts.Feather > 0 {
+		feather = featherAlpha(isBackground, opts.Feather)
+	}
+
 	// Create result image
 	result := image.NewRGBA(bounds)
 	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		for x := bounds.Min.X; x < bounds.Max.X; x++ {
 			if isBackground[y-bounds.Min.Y][x-bounds.Min.X] {
 				result.Set(x, y, color.Transparent)
+			} else if f, ok := feather[point{x - bounds.Min.X, y - bounds.Min.Y}]; ok {
+				c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+				c.A = uint8(math.Round(float64(c.A) * f))
+				result.Set(x, y, c)
 			} else {
 				result.Set(x, y, img.At(x, y))
 			}
 		}
 	}
 
-	return result
+	return result, nil
+}
+
+// point is a pixel position relative to the image origin.
+type point struct{ x, y int }
+
+// featherAlpha returns the factor to scale the alpha of each foreground
+// pixel within radius of the background by: its Euclidean distance to the
+// nearest background pixel over radius+1. Only the band along the edge is
+// searched, found by stepping out from the background radius times.
+func featherAlpha(isBackground [][]bool, radius float64) map[point]float64 {
+	height, width := len(isBackground), len(isBackground[0])
+	r := int(math.Ceil(radius))
+	bg := func(x, y int) bool {
+		return x >= 0 && x < width && y >= 0 && y < height && isBackground[y][x]
+	}
+
+	// Collect the foreground pixels within r steps of the background
+	band := map[point]bool{}
+	var frontier []point
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if isBackground[y][x] {
+				continue
+			}
+			for dy := -1; dy <= 1; dy++ {
+				if bg(x-1, y+dy) || bg(x, y+dy) || bg(x+1, y+dy) {
+					band[point{x, y}] = true
+					frontier = append(frontier, point{x, y})
+					break
+				}
+			}
+		}
+	}
+	for step := 1; step < r; step++ {
+		var next []point
+		for _, p := range frontier {
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					q := point{p.x + dx, p.y + dy}
+					if q.x >= 0 && q.x < width && q.y >= 0 && q.y < height && !isBackground[q.y][q.x] && !band[q] {
+						band[q] = true
+						next = append(next, q)
+					}
+				}
+			}
+		}
+		frontier = next
+	}
+
+	alpha := make(map[point]float64, len(band))
+	for p := range band {
+		best := math.Inf(1)
+		for dy := -r; dy <= r; dy++ {
+			for dx := -r; dx <= r; dx++ {
+				if bg(p.x+dx, p.y+dy) {
+					best = min(best, math.Hypot(float64(dx), float64(dy)))
+				}
+			}
+		}
+		if best <= radius {
+			alpha[p] = best / (radius + 1)
+		}
+	}
+	return alpha
 }
@@ -1,6 +1,8 @@
 package imaging
 
 import (
+	"context"
+	"errors"
 	"image"
 	"image/color"
 	"testing"
@@ -58,9 +60,9 @@ func TestTrim_SolidBorder(t *testing.T) {
 	}
 
 	// Check that the result contains red pixels
-	r, g, b, _ := result.At(0, 0).RGBA()
+	r, g, b, _ := result.At(bounds.Min.X, bounds.Min.Y).RGBA()
 	if r>>8 != 255 || g != 0 || b != 0 {
-		t.Errorf("expected red pixel at (0,0), got r=%d g=%d b=%d", r>>8, g>>8, b>>8)
+		t.Errorf("expected red pixel at %v, got r=%d g=%d b=%d", bounds.Min, r>>8, g>>8, b>>8)
 	}
 }
 
@@ -181,6 +183,183 @@ func TestTrim_SinglePixelContent(t *testing.T) {
 	}
 }
 
+func TestTrimFuzz(t *testing.T) {
+	// Off-white noisy border around a dark center
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			v := uint8(250 + (x+y)%4)
+			img.Set(x, y, color.RGBA{v, v, v, 255})
+		}
+	}
+	for y := 3; y < 7; y++ {
+		for x := 3; x < 7; x++ {
+			img.Set(x, y, color.RGBA{20, 20, 20, 255})
+		}
+	}
+
+	if b := Trim(img).Bounds(); b.Dx() != 10 {
+		t.Errorf("expected exact trim to keep the noisy border, got %dx%d", b.Dx(), b.Dy())
+	}
+	if b := TrimFuzz(img, 5).Bounds(); b != image.Rect(3, 3, 7, 7) {
+		t.Errorf("expected fuzzy trim to find the center, got %v", b)
+	}
+}
+
+func TestTrim_NonZeroOrigin(t *testing.T) {
+	// 10x10 white image whose bounds start at (100, 50)
+	img := image.NewRGBA(image.Rect(100, 50, 110, 60))
+	for y := 50; y < 60; y++ {
+		for x := 100; x < 110; x++ {
+			img.Set(x, y, color.White)
+		}
+	}
+	red := color.RGBA{255, 0, 0, 255}
+	for y := 52; y < 55; y++ {
+		for x := 104; x < 108; x++ {
+			img.Set(x, y, red)
+		}
+	}
+
+	result := Trim(img)
+	if want := image.Rect(104, 52, 108, 55); result.Bounds() != want {
+		t.Errorf("expected bounds %v, got %v", want, result.Bounds())
+	}
+}
+
+func TestTrimRect(t *testing.T) {
+	// 10x10 white image whose bounds start at (100, 50)
+	img := image.NewRGBA(image.Rect(100, 50, 110, 60))
+	for y := 50; y < 60; y++ {
+		for x := 100; x < 110; x++ {
+			img.Set(x, y, color.White)
+		}
+	}
+	if r := TrimRect(img); r != img.Bounds() {
+		t.Errorf("expected the full bounds with nothing to trim, got %v", r)
+	}
+
+	for y := 52; y < 55; y++ {
+		for x := 104; x < 108; x++ {
+			img.Set(x, y, color.RGBA{255, 0, 0, 255})
+		}
+	}
+	if want := image.Rect(104, 52, 108, 55); TrimRect(img) != want {
+		t.Errorf("expected %v, got %v", want, TrimRect(img))
+	}
+	if TrimRect(img) != Trim(img).Bounds() {
+		t.Errorf("expected TrimRect to match Trim's bounds %v", Trim(img).Bounds())
+	}
+}
+
+func TestTrimSides(t *testing.T) {
+	// White 20x20 with a red 4x4 square at (8, 6)
+	img := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 20; x++ {
+			img.Set(x, y, color.White)
+		}
+	}
+	for y := 6; y < 10; y++ {
+		for x := 8; x < 12; x++ {
+			img.Set(x, y, color.RGBA{255, 0, 0, 255})
+		}
+	}
+
+	tests := []struct {
+		sides  string
+		margin int
+		want   image.Rectangle
+	}{
+		{"all", 0, image.Rect(8, 6, 12, 10)},
+		{"top,bottom", 0, image.Rect(0, 6, 20, 10)},
+		{"left+right", 0, image.Rect(8, 0, 12, 20)},
+		{"top", 0, image.Rect(0, 6, 20, 20)},
+		{"all", 2, image.Rect(6, 4, 14, 12)},
+		// Margins stop at the image edge
+		{"all", 7, image.Rect(1, 0, 19, 17)},
+		{"bottom", 5, image.Rect(0, 0, 20, 15)},
+	}
+	for _, tc := range tests {
+		sides, err := ParseSides(tc.sides)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := TrimSides(img, 0, sides, tc.margin).Bounds(); got != tc.want {
+			t.Errorf("sides %q margin %d: expected %v, got %v", tc.sides, tc.margin, tc.want, got)
+		}
+	}
+
+	if got := TrimSides(img, 0, AllSides, 20); got != image.Image(img) {
+		t.Error("expected the image back unchanged when the margin covers the whole border")
+	}
+	for _, s := range []string{"", ",", "middle"} {
+		if _, err := ParseSides(s); err == nil {
+			t.Errorf("ParseSides(%q): expected error", s)
+		}
+	}
+}
+
+func TestTrim_ZeroCopy(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			img.Set(x, y, color.White)
+		}
+	}
+	img.Set(5, 5, color.RGBA{255, 0, 0, 255})
+
+	result := Trim(img)
+	view, ok := result.(*image.RGBA)
+	if !ok {
+		t.Fatalf("expected *image.RGBA view, got %T", result)
+	}
+
+	// Writes through the view are visible in the source
+	view.Set(5, 5, color.RGBA{0, 0, 255, 255})
+	if _, _, b, _ := img.At(5, 5).RGBA(); b>>8 != 255 {
+		t.Error("expected trimmed view to share pixels with the source")
+	}
+
+	// Clone detaches the buffer
+	clone := Clone(result).(*image.RGBA)
+	clone.Set(5, 5, color.Black)
+	if _, _, b, _ := img.At(5, 5).RGBA(); b>>8 != 255 {
+		t.Error("expected clone not to share pixels with the source")
+	}
+	if clone.Bounds() != result.Bounds() {
+		t.Errorf("expected clone bounds %v, got %v", result.Bounds(), clone.Bounds())
+	}
+}
+
+func TestRemoveBackground_NonZeroOrigin(t *testing.T) {
+	img := image.NewRGBA(image.Rect(-5, 20, 5, 30))
+	for y := 20; y < 30; y++ {
+		for x := -5; x < 5; x++ {
+			img.Set(x, y, color.White)
+		}
+	}
+	img.Set(0, 25, color.RGBA{255, 0, 0, 255})
+
+	result := RemoveBackground(img)
+	if result.Bounds() != img.Bounds() {
+		t.Errorf("expected bounds %v, got %v", img.Bounds(), result.Bounds())
+	}
+	if _, _, _, a := result.At(-5, 20).RGBA(); a != 0 {
+		t.Error("expected corner to be transparent")
+	}
+	if _, _, _, a := result.At(0, 25).RGBA(); a == 0 {
+		t.Error("expected subject pixel to stay opaque")
+	}
+}
+
+func TestRemoveBackground_Empty(t *testing.T) {
+	result := RemoveBackground(image.NewRGBA(image.Rect(0, 0, 0, 0)))
+	if !result.Bounds().Empty() {
+		t.Errorf("expected empty result, got %v", result.Bounds())
+	}
+}
+
 func TestRemoveBackground_SolidBackground(t *testing.T) {
 	// Create 10x10 image with white background and red center
 	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
@@ -381,3 +560,203 @@ func createTestImage(width, height int) *image.RGBA {
 	}
 	return img
 }
+
+func TestRemoveBackground_SubjectInCorner(t *testing.T) {
+	// The subject covers the top-left corner, which used to be taken as the background
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	red := color.RGBA{255, 0, 0, 255}
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			if x < 4 && y < 4 {
+				img.Set(x, y, red)
+			} else {
+				img.Set(x, y, color.White)
+			}
+		}
+	}
+
+	result := RemoveBackground(img)
+	if _, _, _, a := result.At(1, 1).RGBA(); a == 0 {
+		t.Error("expected the subject in the corner to stay opaque")
+	}
+	if _, _, _, a := result.At(9, 9).RGBA(); a != 0 {
+		t.Error("expected the white background to be removed")
+	}
+}
+
+func TestRemoveBackground_TwoToneBackdrop(t *testing.T) {
+	// White wall above a gray floor, with a subject in the middle
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	gray := color.RGBA{128, 128, 128, 255}
+	red := color.RGBA{255, 0, 0, 255}
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			switch {
+			case x >= 4 && x < 6 && y >= 3 && y < 7:
+				img.Set(x, y, red)
+			case y < 5:
+				img.Set(x, y, color.White)
+			default:
+				img.Set(x, y, gray)
+			}
+		}
+	}
+
+	if got := len(BackgroundColors(img)); got != 2 {
+		t.Errorf("expected 2 background colors, got %d", got)
+	}
+	result := RemoveBackground(img)
+	for _, pt := range []image.Point{{0, 0}, {9, 9}} {
+		if _, _, _, a := result.At(pt.X, pt.Y).RGBA(); a != 0 {
+			t.Errorf("expected %v to be transparent", pt)
+		}
+	}
+	if _, _, _, a := result.At(5, 5).RGBA(); a == 0 {
+		t.Error("expected the subject to stay opaque")
+	}
+}
+
+func TestRemoveBackgroundWith(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	green := color.RGBA{0, 255, 0, 255}
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			if x >= 5 {
+				img.Set(x, y, green)
+			} else {
+				img.Set(x, y, color.White)
+			}
+		}
+	}
+
+	for _, expr := range []string{"removebg:color=#00ff00", "removebg:x=9,y=0"} {
+		p, err := ParsePipeline(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := p.Apply(img)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, _, a := result.At(9, 9).RGBA(); a != 0 {
+			t.Errorf("%s: expected green to be removed", expr)
+		}
+		if _, _, _, a := result.At(0, 0).RGBA(); a == 0 {
+			t.Errorf("%s: expected white to stay", expr)
+		}
+	}
+
+	for _, expr := range []string{"removebg:x=1", "removebg:color=nope", "removebg:feather=-1", "removebg:feather=100"} {
+		if _, err := ParsePipeline(expr); err == nil {
+			t.Errorf("ParsePipeline(%q) expected error", expr)
+		}
+	}
+}
+
+func TestRemoveBackgroundFeather(t *testing.T) {
+	// White square on a black backdrop
+	img := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 20; x++ {
+			if x >= 5 && x < 15 && y >= 5 && y < 15 {
+				img.Set(x, y, color.White)
+			} else {
+				img.Set(x, y, color.Black)
+			}
+		}
+	}
+
+	hard, err := RemoveBackgroundWith(context.Background(), img, BackgroundOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, a := hard.At(5, 10).RGBA(); a != 0xffff {
+		t.Errorf("expected a hard edge without feather, got alpha %d", a)
+	}
+
+	soft, err := RemoveBackgroundWith(context.Background(), img, BackgroundOptions{Feather: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	alpha := func(x, y int) uint32 {
+		_, _, _, a := soft.At(x, y).RGBA()
+		return a
+	}
+	if a := alpha(0, 0); a != 0 {
+		t.Errorf("expected the background to stay transparent, got alpha %d", a)
+	}
+	// Alpha ramps up across the feather radius from the edge inward
+	if !(0 < alpha(5, 10) && alpha(5, 10) < alpha(6, 10) && alpha(6, 10) < alpha(7, 10)) {
+		t.Errorf("expected alpha to ramp up from the edge, got %d, %d, %d", alpha(5, 10), alpha(6, 10), alpha(7, 10))
+	}
+	if a := alpha(10, 10); a != 0xffff {
+		t.Errorf("expected the subject's middle to stay opaque, got alpha %d", a)
+	}
+	// Feathering fades alpha only; the color is kept
+	if c := color.NRGBAModel.Convert(soft.At(5, 10)).(color.NRGBA); c.R != 255 || c.G != 255 || c.B != 255 {
+		t.Errorf("expected the feathered edge to stay white, got %v", c)
+	}
+}
+
+func TestContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	img := createTestImage(20, 10)
+
+	if _, err := TrimFuzzContext(ctx, img, 0); !errors.Is(err, context.Canceled) {
+		t.Errorf("TrimFuzzContext: expected context.Canceled, got %v", err)
+	}
+	if _, err := RemoveBackgroundContext(ctx, img); !errors.Is(err, context.Canceled) {
+		t.Errorf("RemoveBackgroundContext: expected context.Canceled, got %v", err)
+	}
+	if _, err := ResizeContext(ctx, img, 5, 5); !errors.Is(err, context.Canceled) {
+		t.Errorf("ResizeContext: expected context.Canceled, got %v", err)
+	}
+
+	// A live context behaves like the plain functions
+	trimmed, err := TrimFuzzContext(context.Background(), img, 0)
+	if err != nil || trimmed.Bounds() != Trim(img).Bounds() {
+		t.Errorf("expected TrimFuzzContext to match Trim, got %v (err=%v)", trimmed.Bounds(), err)
+	}
+}
+
+func TestTrimEdges_Gutter(t *testing.T) {
+	// 20x10 white page with a 3px dark gutter down the left side
+	// and a 4x2 red block of content at (10,4)
+	img := image.NewRGBA(image.Rect(0, 0, 20, 10))
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 20; x++ {
+			c := color.RGBA{255, 255, 255, 255}
+			if x < 3 {
+				c = color.RGBA{30, 30, 30, 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	for y := 4; y < 6; y++ {
+		for x := 10; x < 14; x++ {
+			img.Set(x, y, color.RGBA{255, 0, 0, 255})
+		}
+	}
+
+	// Plain Trim keys on the top-left pixel and stops at the white page
+	if b := Trim(img).Bounds(); b != image.Rect(3, 0, 20, 10) {
+		t.Errorf("expected Trim to remove only the gutter, got %v", b)
+	}
+
+	if b := TrimEdges(img, EdgeColors{}, 0).Bounds(); b != image.Rect(10, 4, 14, 6) {
+		t.Errorf("expected TrimEdges to isolate the content, got %v", b)
+	}
+
+	// An explicit edge color that doesn't match leaves that edge alone
+	if b := TrimEdges(img, EdgeColors{Left: color.Black}, 0).Bounds(); b.Min.X != 0 {
+		t.Errorf("expected left edge to be kept, got %v", b)
+	}
+}
+
+func TestTrimEdges_AllBorder(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 5, 5))
+	if got := TrimEdges(img, EdgeColors{}, 0); got != image.Image(img) {
+		t.Error("expected a uniform image to be returned unchanged")
+	}
+}
@@ -0,0 +1,106 @@
+// Package imagingtest provides fixtures shared by the tests of every
+// frontend (library, CLI, wasm) so they are held to the same expectations.
+package imagingtest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+)
+
+// Corner identifies a corner of an image.
+type Corner string
+
+// Corners of an image.
+const (
+	TopLeft     Corner = "top-left"
+	TopRight    Corner = "top-right"
+	BottomLeft  Corner = "bottom-left"
+	BottomRight Corner = "bottom-right"
+)
+
+// OrientationCase is the expected upright result for one EXIF orientation
+// applied to MarkerImage.
+type OrientationCase struct {
+	Orientation   int
+	Width, Height int
+	Marker        Corner
+}
+
+// OrientationCases is the conformance table for EXIF auto-orientation.
+var OrientationCases = []OrientationCase{
+	{1, 40, 20, TopLeft},
+	{2, 40, 20, TopRight},
+	{3, 40, 20, BottomRight},
+	{4, 40, 20, BottomLeft},
+	{5, 20, 40, TopLeft},
+	{6, 20, 40, TopRight},
+	{7, 20, 40, BottomRight},
+	{8, 20, 40, BottomLeft},
+}
+
+// MarkerImage returns a 40x20 white image with a red 10x10 block in the
+// top-left corner, large enough to survive JPEG compression.
+func MarkerImage() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 40; x++ {
+			if x < 10 && y < 10 {
+				img.Set(x, y, color.RGBA{255, 0, 0, 255})
+			} else {
+				img.Set(x, y, color.White)
+			}
+		}
+	}
+	return img
+}
+
+// MarkerCorner reports which corner of img holds the red marker block.
+func MarkerCorner(img image.Image) Corner {
+	b := img.Bounds()
+	corners := map[Corner]image.Point{
+		TopLeft:     {b.Min.X + 2, b.Min.Y + 2},
+		TopRight:    {b.Max.X - 3, b.Min.Y + 2},
+		BottomLeft:  {b.Min.X + 2, b.Max.Y - 3},
+		BottomRight: {b.Max.X - 3, b.Max.Y - 3},
+	}
+	for corner, p := range corners {
+		r, g, _, _ := img.At(p.X, p.Y).RGBA()
+		if r>>8 > 200 && g>>8 < 60 {
+			return corner
+		}
+	}
+	return ""
+}
+
+// OrientedJPEG encodes MarkerImage as a JPEG tagged with the given EXIF orientation.
+func OrientedJPEG(orientation int) []byte {
+	var buf bytes.Buffer
+	jpeg.Encode(&buf, MarkerImage(), &jpeg.Options{Quality: 95})
+	return WithOrientation(buf.Bytes(), orientation)
+}
+
+// WithOrientation inserts an Exif APP1 segment carrying the given orientation
+// directly after the SOI marker of JPEG data.
+func WithOrientation(data []byte, orientation int) []byte {
+	// Big-endian TIFF header with a single IFD entry: Orientation (SHORT)
+	tiff := []byte{'M', 'M', 0, 42, 0, 0, 0, 8, 0, 1}
+	entry := make([]byte, 12)
+	binary.BigEndian.PutUint16(entry[0:], 0x0112)
+	binary.BigEndian.PutUint16(entry[2:], 3)
+	binary.BigEndian.PutUint32(entry[4:], 1)
+	binary.BigEndian.PutUint16(entry[8:], uint16(orientation))
+	tiff = append(tiff, entry...)
+	tiff = append(tiff, 0, 0, 0, 0) // no next IFD
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xff, 0xe1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	out := append([]byte{}, data[:2]...)
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
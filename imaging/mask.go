@@ -0,0 +1,122 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+)
+
+// Shape is an outline MaskShape cuts an image to.
+type Shape int
+
+const (
+	ShapeNone Shape = iota
+	// ShapeCircle crops to the centered square first, as for avatars.
+	ShapeCircle
+	// ShapeEllipse fits the whole image.
+	ShapeEllipse
+)
+
+// ParseShape parses "circle", "ellipse", or "none".
+func ParseShape(s string) (Shape, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "none":
+		return ShapeNone, nil
+	case "circle":
+		return ShapeCircle, nil
+	case "ellipse":
+		return ShapeEllipse, nil
+	}
+	return 0, fmt.Errorf("unknown shape %q", s)
+}
+
+// RoundCorners makes the corners of img transparent outside quarter circles
+// of the given radius, with anti-aliased edges. The radius is limited to half
+// the shorter side, where the corners meet. The result is a copy starting at
+// (0, 0); img is not modified.
+func RoundCorners(img image.Image, radius float64) *image.NRGBA {
+	b := img.Bounds()
+	w, h := float64(b.Dx()), float64(b.Dy())
+	r := max(0, min(radius, w/2, h/2))
+	return applyCoverage(img, func(x, y float64) float64 {
+		// Distance past the nearest corner's circle; zero along the straight edges
+		cx := max(r-x, x-(w-r), 0)
+		cy := max(r-y, y-(h-r), 0)
+		if cx == 0 || cy == 0 {
+			return 1
+		}
+		return coverage(math.Hypot(cx, cy) - r)
+	})
+}
+
+// MaskShape cuts img to shape, making everything outside it transparent with
+// anti-aliased edges. ShapeCircle crops to the centered square first, so the
+// result is square. The result is a copy starting at (0, 0); img is not modified.
+func MaskShape(img image.Image, shape Shape) *image.NRGBA {
+	b := img.Bounds()
+	if shape == ShapeCircle {
+		side := min(b.Dx(), b.Dy())
+		pt := GravityCenter.Place(b, image.Pt(side, side), image.Point{})
+		img = Crop(img, image.Rectangle{pt, pt.Add(image.Pt(side, side))})
+		b = img.Bounds()
+	}
+	if shape == ShapeNone {
+		return ToNRGBA(Clone(img))
+	}
+	a, c := float64(b.Dx())/2, float64(b.Dy())/2
+	return applyCoverage(img, func(x, y float64) float64 {
+		// Approximate distance to the ellipse from its implicit function
+		// and gradient, exact for circles
+		px, py := (x-a)/a, (y-c)/c
+		k0 := math.Hypot(px, py)
+		k1 := math.Hypot(px/a, py/c)
+		if k1 == 0 {
+			return 1
+		}
+		return coverage(k0 * (k0 - 1) / k1)
+	})
+}
+
+// ApplyMask uses mask as an alpha mask for img, for custom-shaped cutouts: a
+// pixel's alpha is scaled by the brightness of the mask at the same place,
+// so white keeps it, black makes it transparent, and grays fade it. A
+// transparent part of the mask counts as black. The mask is scaled to img's
+// size, distorting it if the aspect ratios differ. The result is a copy
+// starting at (0, 0); img is not modified.
+func ApplyMask(img, mask image.Image) *image.NRGBA {
+	b := img.Bounds()
+	if mb := mask.Bounds(); mb.Dx() != b.Dx() || mb.Dy() != b.Dy() {
+		mask = Resize(mask, b.Dx(), b.Dy())
+	}
+	mb := mask.Bounds()
+	return applyCoverage(img, func(x, y float64) float64 {
+		// Premultiplied gray, so transparency darkens the mask
+		g := color.Gray16Model.Convert(mask.At(mb.Min.X+int(x), mb.Min.Y+int(y))).(color.Gray16)
+		return float64(g.Y) / 0xffff
+	})
+}
+
+// coverage returns the fraction of a pixel covered by a shape whose edge is
+// d pixels from the pixel's center, positive outside.
+func coverage(d float64) float64 {
+	return max(0, min(1, 0.5-d))
+}
+
+// applyCoverage copies img to a new image starting at (0, 0), scaling each
+// pixel's alpha by cover of its center relative to the image origin.
+func applyCoverage(img image.Image, cover func(x, y float64) float64) *image.NRGBA {
+	b := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			c := color.NRGBAModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.NRGBA)
+			if f := cover(float64(x)+0.5, float64(y)+0.5); f < 1 {
+				c.A = uint8(math.Round(float64(c.A) * f))
+			}
+			dst.SetNRGBA(x, y, c)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,128 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// solid returns a w x h image of color c.
+func solid(w, h int, c color.NRGBA) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for i := 0; i < len(img.Pix); i += 4 {
+		copy(img.Pix[i:], []uint8{c.R, c.G, c.B, c.A})
+	}
+	return img
+}
+
+func TestRoundCorners(t *testing.T) {
+	img := solid(40, 20, color.NRGBA{255, 0, 0, 255})
+	dst := RoundCorners(img, 8)
+
+	for _, tc := range []struct {
+		x, y int
+		want uint8
+	}{
+		{0, 0, 0}, {39, 0, 0}, {0, 19, 0}, {39, 19, 0}, // corners cut
+		{8, 0, 255}, {0, 8, 255}, {20, 10, 255}, // straight edges and middle kept
+	} {
+		if a := dst.NRGBAAt(tc.x, tc.y).A; a != tc.want {
+			t.Errorf("(%d, %d): expected alpha %d, got %d", tc.x, tc.y, tc.want, a)
+		}
+	}
+	// The curve is anti-aliased
+	partial := false
+	for i := 0; i < 8; i++ {
+		if a := dst.NRGBAAt(i, i).A; a > 0 && a < 255 {
+			partial = true
+		}
+	}
+	if !partial {
+		t.Error("expected partial alpha along the curve")
+	}
+
+	// An oversized radius gives a pill shape rather than overlapping corners
+	pill := RoundCorners(img, 100)
+	if a := pill.NRGBAAt(20, 0).A; a != 255 {
+		t.Errorf("expected the top middle of a pill to be opaque, got %d", a)
+	}
+	if a := pill.NRGBAAt(1, 1).A; a != 0 {
+		t.Errorf("expected the pill's corner to be cut, got %d", a)
+	}
+}
+
+func TestMaskShape(t *testing.T) {
+	img := solid(60, 40, color.NRGBA{0, 0, 255, 255})
+
+	circle := MaskShape(img, ShapeCircle)
+	if b := circle.Bounds(); b != image.Rect(0, 0, 40, 40) {
+		t.Fatalf("expected a 40x40 circle, got %v", b)
+	}
+	for _, tc := range []struct {
+		x, y int
+		want uint8
+	}{{0, 0, 0}, {39, 39, 0}, {20, 20, 255}, {20, 1, 255}, {1, 20, 255}, {5, 5, 0}} {
+		if a := circle.NRGBAAt(tc.x, tc.y).A; a != tc.want {
+			t.Errorf("circle (%d, %d): expected alpha %d, got %d", tc.x, tc.y, tc.want, a)
+		}
+	}
+
+	ellipse := MaskShape(img, ShapeEllipse)
+	if b := ellipse.Bounds(); b != image.Rect(0, 0, 60, 40) {
+		t.Fatalf("expected the ellipse to keep the size, got %v", b)
+	}
+	for _, tc := range []struct {
+		x, y int
+		want uint8
+	}{{0, 0, 0}, {30, 20, 255}, {30, 1, 255}, {1, 20, 255}, {4, 4, 0}} {
+		if a := ellipse.NRGBAAt(tc.x, tc.y).A; a != tc.want {
+			t.Errorf("ellipse (%d, %d): expected alpha %d, got %d", tc.x, tc.y, tc.want, a)
+		}
+	}
+}
+
+func TestMaskOps(t *testing.T) {
+	for _, expr := range []string{"round:10", "round:radius=4.5", "shape:circle", "shape:ellipse"} {
+		if _, err := ParsePipeline(expr); err != nil {
+			t.Errorf("ParsePipeline(%q): %v", expr, err)
+		}
+	}
+	for _, expr := range []string{"round", "round:-1", "shape", "shape:star"} {
+		if _, err := ParsePipeline(expr); err == nil {
+			t.Errorf("ParsePipeline(%q) expected error", expr)
+		}
+	}
+}
+
+func TestApplyMask(t *testing.T) {
+	img := solid(40, 20, color.NRGBA{0, 128, 0, 255})
+
+	// A 4x2 mask, scaled up 10x: white, gray, black, and transparent columns
+	mask := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	for y := 0; y < 2; y++ {
+		mask.SetNRGBA(0, y, color.NRGBA{255, 255, 255, 255})
+		mask.SetNRGBA(1, y, color.NRGBA{128, 128, 128, 255})
+		mask.SetNRGBA(2, y, color.NRGBA{0, 0, 0, 255})
+		mask.SetNRGBA(3, y, color.NRGBA{255, 255, 255, 0})
+	}
+
+	dst := ApplyMask(img, mask)
+	if b := dst.Bounds(); b != img.Bounds() {
+		t.Fatalf("expected the image's bounds, got %v", b)
+	}
+	for _, tc := range []struct {
+		x    int
+		want uint8
+	}{{0, 255}, {25, 0}, {35, 0}} {
+		if a := dst.NRGBAAt(tc.x, 10).A; a != tc.want {
+			t.Errorf("x=%d: expected alpha %d, got %d", tc.x, tc.want, a)
+		}
+	}
+	// Scaling blends the gray column with its neighbors a little
+	if a := dst.NRGBAAt(15, 10).A; a < 100 || a > 156 {
+		t.Errorf("expected about half alpha under gray, got %d", a)
+	}
+	if c := dst.NRGBAAt(0, 0); c.G != 128 {
+		t.Errorf("expected the color to be kept, got %v", c)
+	}
+}
@@ -0,0 +1,102 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+)
+
+// EXIF orientation values, named for the transform that makes the stored
+// pixels upright.
+const (
+	OrientNormal     = 1
+	OrientFlipH      = 2
+	OrientRotate180  = 3
+	OrientFlipV      = 4
+	OrientTranspose  = 5
+	OrientRotate90   = 6
+	OrientTransverse = 7
+	OrientRotate270  = 8
+)
+
+// DefaultAutoOrient is the default for the autoOrient option in every
+// frontend: images are rotated upright according to their EXIF orientation.
+const DefaultAutoOrient = true
+
+// Orientation returns the EXIF orientation (1-8) stored in encoded JPEG data,
+// or OrientNormal when the data has no valid orientation tag.
+func Orientation(data []byte) int {
+	tiff := jpegExif(data)
+	if len(tiff) < 8 {
+		return OrientNormal
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return OrientNormal
+	}
+	if order.Uint16(tiff[2:]) != 42 {
+		return OrientNormal
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return OrientNormal
+	}
+	n := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < n; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			break
+		}
+		// Tag 0x0112 is Orientation, stored as a SHORT in the value field
+		if order.Uint16(tiff[entry:]) == 0x0112 && order.Uint16(tiff[entry+2:]) == 3 {
+			if o := int(order.Uint16(tiff[entry+8:])); o >= OrientNormal && o <= OrientRotate270 {
+				return o
+			}
+			break
+		}
+	}
+	return OrientNormal
+}
+
+// jpegExif returns the TIFF-structured payload of a JPEG's Exif APP1 segment.
+func jpegExif(data []byte) []byte {
+	if len(data) < 4 || data[0] != 0xff || data[1] != 0xd8 {
+		return nil
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xff {
+			return nil
+		}
+		marker := data[i+1]
+		// Start of scan or end of image: metadata segments come before these
+		if marker == 0xda || marker == 0xd9 {
+			return nil
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return nil
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xe1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return segment[6:]
+		}
+		i += 2 + length
+	}
+	return nil
+}
+
+// ApplyOrientation transforms img so that an image stored with the given
+// EXIF orientation is upright. OrientNormal and invalid values return img.
+func ApplyOrientation(img image.Image, orientation int) image.Image {
+	if orientation <= OrientNormal || orientation > OrientRotate270 {
+		return img
+	}
+	return transform(img, orientation)
+}
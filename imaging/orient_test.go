@@ -0,0 +1,47 @@
+package imaging_test
+
+import (
+	"bytes"
+	"image"
+	"testing"
+
+	"image-resizer/imaging"
+	"image-resizer/imaging/imagingtest"
+)
+
+func TestOrientation(t *testing.T) {
+	for _, tc := range imagingtest.OrientationCases {
+		if got := imaging.Orientation(imagingtest.OrientedJPEG(tc.Orientation)); got != tc.Orientation {
+			t.Errorf("expected orientation %d, got %d", tc.Orientation, got)
+		}
+	}
+
+	for name, data := range map[string][]byte{
+		"empty":     nil,
+		"not jpeg":  []byte("\x89PNG\r\n\x1a\n"),
+		"truncated": imagingtest.OrientedJPEG(6)[:12],
+	} {
+		if got := imaging.Orientation(data); got != imaging.OrientNormal {
+			t.Errorf("%s: expected OrientNormal, got %d", name, got)
+		}
+	}
+}
+
+func TestApplyOrientation_Conformance(t *testing.T) {
+	for _, tc := range imagingtest.OrientationCases {
+		data := imagingtest.OrientedJPEG(tc.Orientation)
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result := imaging.ApplyOrientation(img, imaging.Orientation(data))
+		b := result.Bounds()
+		if b.Dx() != tc.Width || b.Dy() != tc.Height {
+			t.Errorf("orientation %d: expected %dx%d, got %dx%d", tc.Orientation, tc.Width, tc.Height, b.Dx(), b.Dy())
+		}
+		if got := imagingtest.MarkerCorner(result); got != tc.Marker {
+			t.Errorf("orientation %d: expected marker at %s, got %q", tc.Orientation, tc.Marker, got)
+		}
+	}
+}
@@ -0,0 +1,852 @@
+package imaging
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Op is a single image operation in a Pipeline.
+type Op func(img image.Image) (image.Image, error)
+
+// OpArgs holds the arguments of one pipeline step. In "resize:w=300,h=200"
+// the arguments are named; in "rotate:90" they are positional.
+type OpArgs struct {
+	Named      map[string]string
+	Positional []string
+}
+
+// lookup returns the named argument, falling back to the positional one at pos.
+func (a OpArgs) lookup(name string, pos int) (string, bool) {
+	if v, ok := a.Named[name]; ok {
+		return v, true
+	}
+	if pos >= 0 && pos < len(a.Positional) {
+		return a.Positional[pos], true
+	}
+	return "", false
+}
+
+// String returns the argument called name (or at position pos), or def.
+func (a OpArgs) String(name string, pos int, def string) string {
+	if v, ok := a.lookup(name, pos); ok {
+		return v
+	}
+	return def
+}
+
+// Int returns the argument called name (or at position pos) as an int, or def.
+func (a OpArgs) Int(name string, pos int, def int) (int, error) {
+	v, ok := a.lookup(name, pos)
+	if !ok {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s: invalid integer %q", name, v)
+	}
+	return n, nil
+}
+
+// Float returns the argument called name (or at position pos) as a float64, or def.
+func (a OpArgs) Float(name string, pos int, def float64) (float64, error) {
+	v, ok := a.lookup(name, pos)
+	if !ok {
+		return def, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s: invalid number %q", name, v)
+	}
+	return f, nil
+}
+
+// Color returns the argument called name (or at position pos) as a color, or def.
+func (a OpArgs) Color(name string, pos int, def color.Color) (color.Color, error) {
+	v, ok := a.lookup(name, pos)
+	if !ok {
+		return def, nil
+	}
+	c, err := ParseColor(v)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return c, nil
+}
+
+// OpBuilder validates the arguments of a pipeline step and returns the operation.
+type OpBuilder func(args OpArgs) (Op, error)
+
+var (
+	opsMu sync.RWMutex
+	ops   = map[string]OpBuilder{}
+)
+
+// RegisterOp makes an operation available to ParsePipeline under name.
+// Registering a name twice replaces the earlier builder.
+func RegisterOp(name string, build OpBuilder) {
+	opsMu.Lock()
+	defer opsMu.Unlock()
+	ops[name] = build
+}
+
+// OpNames returns the names of all registered operations, sorted.
+func OpNames() []string {
+	opsMu.RLock()
+	defer opsMu.RUnlock()
+	names := make([]string, 0, len(ops))
+	for name := range ops {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Step is one parsed operation of a Pipeline.
+type Step struct {
+	Name string
+	Args OpArgs
+	op   Op
+}
+
+// Pipeline is an ordered list of operations parsed from a DSL such as
+//
+//	trim:fuzz=5|resize:w=300|grayscale|rotate:90
+//
+// Steps are separated by '|'. A step is an operation name optionally
+// followed by ':' and comma-separated arguments, each either key=value
+// or a bare positional value.
+type Pipeline struct {
+	Steps []Step
+}
+
+// ParsePipeline parses and validates a pipeline expression.
+// An empty expression yields an empty pipeline.
+func ParsePipeline(s string) (*Pipeline, error) {
+	p := &Pipeline{}
+	if strings.TrimSpace(s) == "" {
+		return p, nil
+	}
+
+	for _, part := range strings.Split(s, "|") {
+		name, rawArgs, _ := strings.Cut(strings.TrimSpace(part), ":")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			return nil, fmt.Errorf("pipeline %q: empty operation", s)
+		}
+
+		args := OpArgs{Named: map[string]string{}}
+		if rawArgs != "" {
+			for _, arg := range strings.Split(rawArgs, ",") {
+				arg = strings.TrimSpace(arg)
+				if k, v, ok := strings.Cut(arg, "="); ok {
+					args.Named[strings.ToLower(strings.TrimSpace(k))] = strings.TrimSpace(v)
+				} else if arg != "" {
+					args.Positional = append(args.Positional, arg)
+				}
+			}
+		}
+
+		opsMu.RLock()
+		build, ok := ops[name]
+		opsMu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("unknown operation %q", name)
+		}
+		if err := CurrentPolicy().CheckOp(name); err != nil {
+			return nil, err
+		}
+		op, err := build(args)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		p.Steps = append(p.Steps, Step{Name: name, Args: args, op: op})
+	}
+	return p, nil
+}
+
+// Apply runs every step in order.
+func (p *Pipeline) Apply(img image.Image) (image.Image, error) {
+	return p.ApplyTimed(img, nil)
+}
+
+// ApplyTimed is like Apply but, if record is non-nil, reports the time spent
+// in each step as it completes. Steps that enlarge the image beyond the
+// current Policy's MaxUpscale fail with a *DisabledError.
+func (p *Pipeline) ApplyTimed(img image.Image, record func(step Step, d time.Duration)) (image.Image, error) {
+	policy := CurrentPolicy()
+	for _, step := range p.Steps {
+		start := time.Now()
+		from := img.Bounds().Size()
+		var err error
+		if img, err = step.op(img); err != nil {
+			return nil, fmt.Errorf("%s: %w", step.Name, err)
+		}
+		if err := policy.CheckScale(step.Name, from, img.Bounds().Size()); err != nil {
+			return nil, err
+		}
+		if record != nil {
+			record(step, time.Since(start))
+		}
+	}
+	return img, nil
+}
+
+// String returns the pipeline in canonical form, with named arguments sorted,
+// suitable for use in cache keys.
+func (p *Pipeline) String() string {
+	parts := make([]string, len(p.Steps))
+	for i, step := range p.Steps {
+		var args []string
+		args = append(args, step.Args.Positional...)
+		keys := make([]string, 0, len(step.Args.Named))
+		for k := range step.Args.Named {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			args = append(args, k+"="+step.Args.Named[k])
+		}
+		parts[i] = step.Name
+		if len(args) > 0 {
+			parts[i] += ":" + strings.Join(args, ",")
+		}
+	}
+	return strings.Join(parts, "|")
+}
+
+func init() {
+	RegisterOp("trim", func(args OpArgs) (Op, error) {
+		fuzz, err := args.Float("fuzz", 0, 0)
+		if err != nil {
+			return nil, err
+		}
+		if fuzz < 0 || fuzz > 100 {
+			return nil, fmt.Errorf("fuzz must be between 0 and 100")
+		}
+
+		// Which sides to trim (e.g. sides=top+bottom), keeping margin pixels of border
+		sides := AllSides
+		if s, ok := args.lookup("sides", -1); ok {
+			if sides, err = ParseSides(s); err != nil {
+				return nil, err
+			}
+		}
+		margin, err := args.Int("margin", -1, 0)
+		if err != nil {
+			return nil, err
+		}
+		if margin < 0 {
+			return nil, fmt.Errorf("margin must not be negative")
+		}
+
+		// Per-edge colors ("auto" to detect) or edges=auto switch to TrimEdges
+		var edges EdgeColors
+		perEdge := args.String("edges", -1, "") == "auto"
+		for _, e := range []struct {
+			name string
+			c    *color.Color
+		}{{"top", &edges.Top}, {"right", &edges.Right}, {"bottom", &edges.Bottom}, {"left", &edges.Left}} {
+			v, ok := args.lookup(e.name, -1)
+			if !ok {
+				continue
+			}
+			perEdge = true
+			if v == "auto" {
+				continue
+			}
+			c, err := ParseColor(v)
+			if err != nil {
+				return nil, err
+			}
+			*e.c = c
+		}
+		if perEdge {
+			return func(img image.Image) (image.Image, error) {
+				b := img.Bounds()
+				r := LimitTrim(b, TrimEdges(img, edges, fuzz).Bounds(), sides, margin)
+				if r == b {
+					return img, nil
+				}
+				return Crop(img, r), nil
+			}, nil
+		}
+		return func(img image.Image) (image.Image, error) { return TrimSides(img, fuzz, sides, margin), nil }, nil
+	})
+
+	RegisterOp("resize", func(args OpArgs) (Op, error) {
+		filter, err := ParseFilter(args.String("filter", -1, ""))
+		if err != nil {
+			return nil, err
+		}
+		if g, ok := args.lookup("g", 0); ok {
+			geom, err := ParseGeometry(g)
+			if err != nil {
+				return nil, err
+			}
+			return func(img image.Image) (image.Image, error) {
+				w, h := geom.Size(img.Bounds().Dx(), img.Bounds().Dy())
+				return ResizeFilter(img, w, h, filter), nil
+			}, nil
+		}
+
+		w, err := args.Int("w", -1, 0)
+		if err != nil {
+			return nil, err
+		}
+		h, err := args.Int("h", -1, 0)
+		if err != nil {
+			return nil, err
+		}
+		if w < 0 || h < 0 || w == 0 && h == 0 {
+			return nil, fmt.Errorf("requires w and/or h, or a geometry")
+		}
+		geom := Geometry{Width: w, Height: h}
+		if w > 0 && h > 0 {
+			geom.Flag = GeometryExact
+		}
+		return func(img image.Image) (image.Image, error) {
+			w, h := geom.Size(img.Bounds().Dx(), img.Bounds().Dy())
+			return ResizeFilter(img, w, h, filter), nil
+		}, nil
+	})
+
+	RegisterOp("canvas", func(args OpArgs) (Op, error) {
+		g, ok := args.lookup("g", 0)
+		if !ok {
+			return nil, fmt.Errorf("requires a canvas size such as 1000x1000")
+		}
+		geom, err := ParseGeometry(g)
+		if err != nil {
+			return nil, err
+		}
+		if geom.Width <= 0 || geom.Height <= 0 || geom.Percent {
+			return nil, fmt.Errorf("canvas size %q must be WxH in pixels", g)
+		}
+		fill, err := args.Float("fill", -1, 100)
+		if err != nil {
+			return nil, err
+		}
+		if fill <= 0 || fill > 100 {
+			return nil, fmt.Errorf("fill must be between 0 and 100")
+		}
+		gravity, err := ParseGravity(args.String("gravity", -1, "center"))
+		if err != nil {
+			return nil, err
+		}
+		bg, err := args.Color("bg", -1, color.White)
+		if err != nil {
+			return nil, err
+		}
+		offset := image.Pt(geom.X, geom.Y)
+		return func(img image.Image) (image.Image, error) {
+			return Canvas(img, geom.Width, geom.Height, fill, gravity, offset, bg), nil
+		}, nil
+	})
+
+	RegisterOp("extend", func(args OpArgs) (Op, error) {
+		g, ok := args.lookup("g", 0)
+		if !ok {
+			return nil, fmt.Errorf("requires a canvas size such as 1080x1080")
+		}
+		geom, err := ParseGeometry(g)
+		if err != nil {
+			return nil, err
+		}
+		if geom.Width < 0 || geom.Height < 0 || geom.Width+geom.Height == 0 || geom.Percent {
+			return nil, fmt.Errorf("canvas size %q must be WxH in pixels", g)
+		}
+		gravity, err := ParseGravity(args.String("gravity", -1, "center"))
+		if err != nil {
+			return nil, err
+		}
+		bg, err := args.Color("bg", -1, color.Transparent)
+		if err != nil {
+			return nil, err
+		}
+		return func(img image.Image) (image.Image, error) {
+			return Extend(img, geom.Width, geom.Height, gravity, bg), nil
+		}, nil
+	})
+
+	RegisterOp("aspect", func(args OpArgs) (Op, error) {
+		a, ok := args.lookup("ratio", 0)
+		if !ok {
+			return nil, fmt.Errorf("requires an aspect ratio such as 1:1")
+		}
+		aspect, err := ParseAspect(a)
+		if err != nil {
+			return nil, err
+		}
+		gravity, err := ParseGravity(args.String("gravity", -1, "center"))
+		if err != nil {
+			return nil, err
+		}
+		bg, err := args.Color("bg", -1, color.Transparent)
+		if err != nil {
+			return nil, err
+		}
+		return func(img image.Image) (image.Image, error) {
+			return PadToAspect(img, aspect, gravity, bg), nil
+		}, nil
+	})
+
+	RegisterOp("pad", func(args OpArgs) (Op, error) {
+		// Positional margins as in CSS (pad:20 or pad:10,20,10,20), or
+		// named sides; unnamed sides default to zero
+		in, err := ParseInsets(strings.Join(args.Positional, ","))
+		if len(args.Positional) == 0 {
+			in, err = Insets{}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, side := range []struct {
+			name string
+			n    *int
+		}{{"top", &in.Top}, {"right", &in.Right}, {"bottom", &in.Bottom}, {"left", &in.Left}} {
+			if *side.n, err = args.Int(side.name, -1, *side.n); err != nil {
+				return nil, err
+			}
+			if *side.n < 0 {
+				return nil, fmt.Errorf("%s must not be negative", side.name)
+			}
+		}
+		if in == (Insets{}) {
+			return nil, fmt.Errorf("requires margins such as 20 or top=10")
+		}
+		c, err := args.Color("color", -1, color.Transparent)
+		if err != nil {
+			return nil, err
+		}
+		return func(img image.Image) (image.Image, error) { return Pad(img, in, c), nil }, nil
+	})
+
+	RegisterOp("round", func(args OpArgs) (Op, error) {
+		radius, err := args.Float("radius", 0, 0)
+		if err != nil {
+			return nil, err
+		}
+		if radius <= 0 {
+			return nil, fmt.Errorf("requires a positive radius such as 20")
+		}
+		return func(img image.Image) (image.Image, error) { return RoundCorners(img, radius), nil }, nil
+	})
+
+	RegisterOp("shape", func(args OpArgs) (Op, error) {
+		shape, err := ParseShape(args.String("shape", 0, ""))
+		if err != nil {
+			return nil, err
+		}
+		if shape == ShapeNone {
+			return nil, fmt.Errorf("requires a shape: circle or ellipse")
+		}
+		return func(img image.Image) (image.Image, error) { return MaskShape(img, shape), nil }, nil
+	})
+
+	RegisterOp("crop", func(args OpArgs) (Op, error) {
+		g, ok := args.lookup("g", 0)
+		if !ok {
+			return nil, fmt.Errorf("requires a region such as 400x300+20+10")
+		}
+		geom, err := ParseGeometry(g)
+		if err != nil {
+			return nil, err
+		}
+		if geom.Width <= 0 || geom.Height <= 0 || geom.Percent {
+			return nil, fmt.Errorf("region %q must be WxH+X+Y in pixels", g)
+		}
+		return func(img image.Image) (image.Image, error) {
+			b := img.Bounds()
+			pt := b.Min.Add(image.Pt(geom.X, geom.Y))
+			r := image.Rectangle{pt, pt.Add(image.Pt(geom.Width, geom.Height))}.Intersect(b)
+			if r.Empty() {
+				return nil, fmt.Errorf("region %s is outside the %dx%d image", g, b.Dx(), b.Dy())
+			}
+			return Crop(img, r), nil
+		}, nil
+	})
+
+	RegisterOp("redact", func(args OpArgs) (Op, error) {
+		// Each positional argument is a region, so one step can cover several
+		specs := args.Positional
+		if g, ok := args.Named["g"]; ok {
+			specs = append([]string{g}, specs...)
+		}
+		if len(specs) == 0 {
+			return nil, fmt.Errorf("requires a region such as 120x160+40+30")
+		}
+		var regions []image.Rectangle
+		for _, g := range specs {
+			geom, err := ParseGeometry(g)
+			if err != nil {
+				return nil, err
+			}
+			if geom.Width <= 0 || geom.Height <= 0 || geom.Percent {
+				return nil, fmt.Errorf("region %q must be WxH+X+Y in pixels", g)
+			}
+			regions = append(regions, image.Rect(geom.X, geom.Y, geom.X+geom.Width, geom.Y+geom.Height))
+		}
+		mode, err := ParseRedactMode(args.String("mode", -1, ""))
+		if err != nil {
+			return nil, err
+		}
+		cells, err := args.Int("cells", -1, DefaultRedactCells)
+		if err != nil {
+			return nil, err
+		}
+		if cells <= 0 {
+			return nil, fmt.Errorf("cells must be positive")
+		}
+		sigma, err := args.Float("sigma", -1, 0)
+		if err != nil {
+			return nil, err
+		}
+		if sigma < 0 || sigma > MaxBlurSigma {
+			return nil, fmt.Errorf("sigma must be between 0 and %d", MaxBlurSigma)
+		}
+		id := args.String("id", -1, "")
+		opts := RedactOptions{Mode: mode, Cells: cells, Identity: id, Sigma: sigma}
+		return func(img image.Image) (image.Image, error) {
+			origin := img.Bounds().Min
+			shifted := make([]image.Rectangle, len(regions))
+			for i, r := range regions {
+				shifted[i] = r.Add(origin)
+			}
+			return RedactRegions(img, shifted, opts), nil
+		}, nil
+	})
+
+	RegisterOp("rotate", func(args OpArgs) (Op, error) {
+		degrees, err := args.Float("deg", 0, 0)
+		if err != nil {
+			return nil, err
+		}
+		bg, err := args.Color("bg", 1, color.Transparent)
+		if err != nil {
+			return nil, err
+		}
+		return func(img image.Image) (image.Image, error) { return Rotate(img, degrees, bg), nil }, nil
+	})
+
+	RegisterOp("autorotate", func(args OpArgs) (Op, error) {
+		// EXIF orientation is applied at decode time; only text detection runs here
+		if mode := args.String("mode", 0, "document"); mode != "document" {
+			return nil, fmt.Errorf("unsupported mode %q", mode)
+		}
+		return func(img image.Image) (image.Image, error) {
+			return ApplyOrientation(img, DocumentOrientation(img)), nil
+		}, nil
+	})
+
+	RegisterOp("flip", func(OpArgs) (Op, error) {
+		return func(img image.Image) (image.Image, error) { return FlipV(img), nil }, nil
+	})
+
+	RegisterOp("flop", func(OpArgs) (Op, error) {
+		return func(img image.Image) (image.Image, error) { return FlipH(img), nil }, nil
+	})
+
+	RegisterOp("removebg", func(args OpArgs) (Op, error) {
+		var opts BackgroundOptions
+		var err error
+		if opts.Feather, err = args.Float("feather", -1, 0); err != nil {
+			return nil, err
+		}
+		if opts.Feather < 0 || opts.Feather > MaxFeather {
+			return nil, fmt.Errorf("feather must be between 0 and %d", MaxFeather)
+		}
+
+		// The background is sampled from the corners unless given as a
+		// color, or as the pixel at x,y
+		bg, err := args.Color("color", -1, nil)
+		if err != nil {
+			return nil, err
+		}
+		if bg != nil {
+			opts.Colors = []color.Color{bg}
+			return func(img image.Image) (image.Image, error) {
+				return RemoveBackgroundWith(context.Background(), img, opts)
+			}, nil
+		}
+		_, hasX := args.lookup("x", -1)
+		_, hasY := args.lookup("y", -1)
+		if hasX != hasY {
+			return nil, fmt.Errorf("x and y must be given together")
+		}
+		if hasX {
+			x, err := args.Int("x", -1, 0)
+			if err != nil {
+				return nil, err
+			}
+			y, err := args.Int("y", -1, 0)
+			if err != nil {
+				return nil, err
+			}
+			return func(img image.Image) (image.Image, error) {
+				pt := img.Bounds().Min.Add(image.Pt(x, y))
+				if !pt.In(img.Bounds()) {
+					return nil, fmt.Errorf("point %d,%d is outside the image", x, y)
+				}
+				opts := opts
+				opts.Colors = []color.Color{img.At(pt.X, pt.Y)}
+				return RemoveBackgroundWith(context.Background(), img, opts)
+			}, nil
+		}
+		return func(img image.Image) (image.Image, error) {
+			return RemoveBackgroundWith(context.Background(), img, opts)
+		}, nil
+	})
+
+	RegisterOp("chromakey", func(args OpArgs) (Op, error) {
+		key, err := args.Color("color", 0, color.NRGBA{0, 255, 0, 255})
+		if err != nil {
+			return nil, err
+		}
+		tolerance, err := args.Float("tolerance", 1, DefaultKeyTolerance)
+		if err != nil {
+			return nil, err
+		}
+		softness, err := args.Float("softness", 2, DefaultKeySoftness)
+		if err != nil {
+			return nil, err
+		}
+		if tolerance < 0 || tolerance > 100 || softness < 0 || softness > 100 {
+			return nil, fmt.Errorf("tolerance and softness must be between 0 and 100")
+		}
+		return func(img image.Image) (image.Image, error) {
+			return ChromaKey(img, key, tolerance, softness), nil
+		}, nil
+	})
+
+	RegisterOp("shadow", func(args OpArgs) (Op, error) {
+		opts := DefaultShadow
+		var err error
+		if opts.Offset.X, err = args.Int("x", 0, opts.Offset.X); err != nil {
+			return nil, err
+		}
+		if opts.Offset.Y, err = args.Int("y", 1, opts.Offset.Y); err != nil {
+			return nil, err
+		}
+		if opts.Blur, err = args.Float("blur", 2, opts.Blur); err != nil {
+			return nil, err
+		}
+		opacity, err := args.Float("opacity", -1, opts.Opacity*100)
+		if err != nil {
+			return nil, err
+		}
+		if opts.Color, err = args.Color("color", -1, color.Black); err != nil {
+			return nil, err
+		}
+		if opts.Blur < 0 || opts.Blur > MaxShadowBlur {
+			return nil, fmt.Errorf("blur must be between 0 and %d", MaxShadowBlur)
+		}
+		if opacity < 0 || opacity > 100 {
+			return nil, fmt.Errorf("opacity must be between 0 and 100")
+		}
+		opts.Opacity = opacity / 100
+		return func(img image.Image) (image.Image, error) { return DropShadow(img, opts), nil }, nil
+	})
+
+	RegisterOp("grayscale", func(OpArgs) (Op, error) {
+		return func(img image.Image) (image.Image, error) {
+			// A Gray image encodes smaller but has no alpha to keep
+			if Opaque(img) {
+				return ToGray(img), nil
+			}
+			return Grayscale(img), nil
+		}, nil
+	})
+
+	RegisterOp("adjust", func(args OpArgs) (Op, error) {
+		opts, err := parseAdjust(args)
+		if err != nil {
+			return nil, err
+		}
+		return func(img image.Image) (image.Image, error) { return Adjust(img, opts), nil }, nil
+	})
+
+	RegisterOp("levels", func(args OpArgs) (Op, error) {
+		var levels [4]int
+		for i, a := range []struct {
+			name string
+			pos  int
+			def  int
+		}{{"black", 0, 0}, {"white", 1, 255}, {"outblack", -1, 0}, {"outwhite", -1, 255}} {
+			v, err := args.Int(a.name, a.pos, a.def)
+			if err != nil {
+				return nil, err
+			}
+			if v < 0 || v > 255 {
+				return nil, fmt.Errorf("%s must be between 0 and 255", a.name)
+			}
+			levels[i] = v
+		}
+		gamma, err := args.Float("gamma", 2, 1)
+		if err != nil {
+			return nil, err
+		}
+		inWhite, outWhite := uint8(levels[1]), uint8(levels[3])
+		opts := LevelsOptions{
+			InBlack: uint8(levels[0]), InWhite: &inWhite, Gamma: gamma,
+			OutBlack: uint8(levels[2]), OutWhite: &outWhite,
+		}
+		if err := opts.Validate(); err != nil {
+			return nil, err
+		}
+		return func(img image.Image) (image.Image, error) { return Levels(img, opts), nil }, nil
+	})
+
+	RegisterOp("curves", func(args OpArgs) (Op, error) {
+		var curves Curves
+		for _, c := range []struct {
+			name string
+			pos  int
+			dst  *Curve
+		}{{"all", 0, &curves.All}, {"r", -1, &curves.Red}, {"g", -1, &curves.Green}, {"b", -1, &curves.Blue}} {
+			var err error
+			if *c.dst, err = ParseCurve(args.String(c.name, c.pos, "")); err != nil {
+				return nil, err
+			}
+		}
+		if curves.IsZero() {
+			return nil, fmt.Errorf("requires control points such as 0:0;128:160;255:255")
+		}
+		return func(img image.Image) (image.Image, error) { return ApplyCurves(img, curves), nil }, nil
+	})
+
+	RegisterOp("hsl", func(args OpArgs) (Op, error) {
+		var opts HSLOptions
+		var err error
+		if opts.Hue, err = args.Float("hue", -1, 0); err != nil {
+			return nil, err
+		}
+		if opts.Saturation, err = args.Float("saturation", -1, 0); err != nil {
+			return nil, err
+		}
+		if opts.Vibrance, err = args.Float("vibrance", -1, 0); err != nil {
+			return nil, err
+		}
+		if err := opts.Validate(); err != nil {
+			return nil, err
+		}
+		return func(img image.Image) (image.Image, error) { return AdjustHSL(img, opts), nil }, nil
+	})
+
+	RegisterOp("blur", func(args OpArgs) (Op, error) {
+		sigma, err := args.Float("sigma", 0, 0)
+		if err != nil {
+			return nil, err
+		}
+		if sigma <= 0 || sigma > MaxBlurSigma {
+			return nil, fmt.Errorf("requires a sigma between 0 and %d, such as 3", MaxBlurSigma)
+		}
+		return func(img image.Image) (image.Image, error) { return Blur(img, sigma), nil }, nil
+	})
+
+	RegisterOp("boxblur", func(args OpArgs) (Op, error) {
+		radius, err := args.Int("radius", 0, 0)
+		if err != nil {
+			return nil, err
+		}
+		if radius <= 0 || radius > 3*MaxBlurSigma {
+			return nil, fmt.Errorf("requires a radius between 1 and %d", 3*MaxBlurSigma)
+		}
+		return func(img image.Image) (image.Image, error) { return BoxBlur(img, radius), nil }, nil
+	})
+
+	RegisterOp("sharpen", func(args OpArgs) (Op, error) {
+		sigma, err := args.Float("sigma", 0, 1)
+		if err != nil {
+			return nil, err
+		}
+		if sigma <= 0 || sigma > MaxBlurSigma {
+			return nil, fmt.Errorf("sigma must be between 0 and %d", MaxBlurSigma)
+		}
+		amount, err := args.Float("amount", 1, 1)
+		if err != nil {
+			return nil, err
+		}
+		if amount <= 0 || amount > MaxSharpenAmount {
+			return nil, fmt.Errorf("amount must be between 0 and %d", MaxSharpenAmount)
+		}
+		return func(img image.Image) (image.Image, error) { return Sharpen(img, sigma, amount), nil }, nil
+	})
+
+	RegisterOp("wb", func(args OpArgs) (Op, error) {
+		var method WhiteBalance
+		mode, auto := args.lookup("mode", 0)
+		if auto {
+			var err error
+			if method, err = ParseWhiteBalance(mode); err != nil {
+				return nil, err
+			}
+		}
+		temp, err := args.Float("temp", -1, 0)
+		if err != nil {
+			return nil, err
+		}
+		tint, err := args.Float("tint", -1, 0)
+		if err != nil {
+			return nil, err
+		}
+		if temp < -100 || temp > 100 || tint < -100 || tint > 100 {
+			return nil, fmt.Errorf("temp and tint must be between -100 and 100")
+		}
+		if !auto && temp == 0 && tint == 0 {
+			return nil, fmt.Errorf("requires auto, grayworld, whitepatch, or temp= and tint=")
+		}
+		return func(img image.Image) (image.Image, error) {
+			if auto {
+				img = AutoWhiteBalance(img, method)
+			}
+			if temp != 0 || tint != 0 {
+				img = AdjustWhiteBalance(img, temp, tint)
+			}
+			return img, nil
+		}, nil
+	})
+
+	RegisterOp("autocontrast", func(args OpArgs) (Op, error) {
+		clip, err := args.Float("clip", 0, DefaultAutoContrastClip)
+		if err != nil {
+			return nil, err
+		}
+		if clip < 0 || clip > 50 {
+			return nil, fmt.Errorf("clip must be between 0 and 50 percent")
+		}
+		return func(img image.Image) (image.Image, error) { return AutoContrast(img, clip), nil }, nil
+	})
+
+	RegisterOp("equalize", func(args OpArgs) (Op, error) {
+		clip, err := args.Float("clip", 0, DefaultEqualizeClip)
+		if err != nil {
+			return nil, err
+		}
+		if clip < 0 {
+			return nil, fmt.Errorf("clip must not be negative")
+		}
+		tiles, err := args.Int("tiles", 1, DefaultEqualizeTiles)
+		if err != nil {
+			return nil, err
+		}
+		if tiles < 1 || tiles > MaxEqualizeTiles {
+			return nil, fmt.Errorf("tiles must be between 1 and %d", MaxEqualizeTiles)
+		}
+		return func(img image.Image) (image.Image, error) { return Equalize(img, clip, tiles), nil }, nil
+	})
+
+	RegisterOp("sepia", func(OpArgs) (Op, error) {
+		return func(img image.Image) (image.Image, error) { return Sepia(img), nil }, nil
+	})
+
+	RegisterOp("invert", func(OpArgs) (Op, error) {
+		return func(img image.Image) (image.Image, error) { return Invert(img), nil }, nil
+	})
+}
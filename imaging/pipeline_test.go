@@ -0,0 +1,211 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePipeline(t *testing.T) {
+	p, err := ParsePipeline("trim:fuzz=5 | resize:w=300|grayscale|rotate:90,#fff")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, s := range p.Steps {
+		names = append(names, s.Name)
+	}
+	if got := strings.Join(names, ","); got != "trim,resize,grayscale,rotate" {
+		t.Errorf("unexpected steps %s", got)
+	}
+	if p.Steps[0].Args.Named["fuzz"] != "5" {
+		t.Errorf("expected named fuzz=5, got %+v", p.Steps[0].Args)
+	}
+	if got := p.Steps[3].Args.Positional; len(got) != 2 || got[0] != "90" {
+		t.Errorf("expected positional rotate args, got %q", got)
+	}
+	if got := p.String(); got != "trim:fuzz=5|resize:w=300|grayscale|rotate:90,#fff" {
+		t.Errorf("unexpected canonical form %q", got)
+	}
+
+	empty, err := ParsePipeline("  ")
+	if err != nil || len(empty.Steps) != 0 {
+		t.Errorf("expected empty pipeline, got %+v (err=%v)", empty, err)
+	}
+}
+
+func TestParsePipeline_Errors(t *testing.T) {
+	tests := []string{
+		"sharpen-everything",
+		"trim||resize:w=10",
+		"trim:fuzz=abc",
+		"trim:fuzz=150",
+		"trim:sides=middle",
+		"trim:margin=-1",
+		"resize",
+		"resize:w=-3",
+		"resize:g=bogus",
+		"rotate:ninety",
+		"rotate:90,notacolor",
+		"autorotate:exif",
+	}
+	for _, in := range tests {
+		if _, err := ParsePipeline(in); err == nil {
+			t.Errorf("ParsePipeline(%q) expected error", in)
+		}
+	}
+}
+
+func TestPipeline_Apply(t *testing.T) {
+	// 20x10 white image with a 10x4 blue block
+	img := image.NewRGBA(image.Rect(0, 0, 20, 10))
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 20; x++ {
+			img.Set(x, y, color.White)
+		}
+	}
+	for y := 3; y < 7; y++ {
+		for x := 5; x < 15; x++ {
+			img.Set(x, y, color.RGBA{0, 0, 255, 255})
+		}
+	}
+
+	p, err := ParsePipeline("trim|resize:g=200%|rotate:90|grayscale")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := p.Apply(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := result.(*image.Gray); !ok {
+		t.Errorf("expected grayscale output, got %T", result)
+	}
+	if b := result.Bounds(); b.Dx() != 8 || b.Dy() != 20 {
+		t.Errorf("expected 8x20, got %dx%d", b.Dx(), b.Dy())
+	}
+}
+
+func TestPipeline_Crop(t *testing.T) {
+	img := image.NewRGBA(image.Rect(10, 10, 50, 40))
+	p, err := ParsePipeline("crop:20x15+5+5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := p.Apply(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := result.Bounds(); b != image.Rect(15, 15, 35, 30) {
+		t.Errorf("expected the region offset from the image origin, got %v", b)
+	}
+
+	// Regions are clipped to the image, and fail when entirely outside it
+	p, _ = ParsePipeline("crop:100x100+30+20")
+	if result, _ := p.Apply(img); result.Bounds() != image.Rect(40, 30, 50, 40) {
+		t.Errorf("expected a clipped region, got %v", result.Bounds())
+	}
+	p, _ = ParsePipeline("crop:10x10+40+0")
+	if _, err := p.Apply(img); err == nil {
+		t.Error("expected an error for a region outside the image")
+	}
+	for _, expr := range []string{"crop", "crop:50%", "crop:0x10+1+1"} {
+		if _, err := ParsePipeline(expr); err == nil {
+			t.Errorf("ParsePipeline(%q) expected error", expr)
+		}
+	}
+}
+
+func TestPipeline_TrimEdges(t *testing.T) {
+	// White image with a dark left gutter and a gray block of content
+	img := image.NewRGBA(image.Rect(0, 0, 20, 10))
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 20; x++ {
+			img.Set(x, y, color.White)
+			if x < 2 {
+				img.Set(x, y, color.Black)
+			}
+		}
+	}
+	for y := 3; y < 5; y++ {
+		for x := 8; x < 12; x++ {
+			img.Set(x, y, color.Gray{128})
+		}
+	}
+
+	for _, expr := range []string{"trim:edges=auto", "trim:left=#000", "trim:left=black,top=auto"} {
+		p, err := ParsePipeline(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := p.Apply(img)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b := result.Bounds(); b != image.Rect(8, 3, 12, 5) {
+			t.Errorf("%s: expected (8,3)-(12,5), got %v", expr, b)
+		}
+	}
+
+	// Sides and margin apply to the per-edge trim too
+	p, err := ParsePipeline("trim:edges=auto,sides=left+right,margin=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := p.Apply(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := result.Bounds(); b != image.Rect(7, 0, 13, 10) {
+		t.Errorf("expected (7,0)-(13,10), got %v", b)
+	}
+
+	if _, err := ParsePipeline("trim:left=notacolor"); err == nil {
+		t.Error("expected error for an invalid edge color")
+	}
+}
+
+func TestPipeline_ApplyTimed(t *testing.T) {
+	p, err := ParsePipeline("flip|grayscale")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var steps []string
+	_, err = p.ApplyTimed(createTestImage(4, 4), func(step Step, d time.Duration) {
+		if d < 0 {
+			t.Errorf("%s: negative duration %v", step.Name, d)
+		}
+		steps = append(steps, step.Name)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(steps, ",") != "flip,grayscale" {
+		t.Errorf("expected timings for flip,grayscale, got %v", steps)
+	}
+}
+
+func TestRegisterOp(t *testing.T) {
+	RegisterOp("test-noop", func(OpArgs) (Op, error) {
+		return func(img image.Image) (image.Image, error) { return img, nil }, nil
+	})
+	defer func() {
+		opsMu.Lock()
+		delete(ops, "test-noop")
+		opsMu.Unlock()
+	}()
+
+	if _, err := ParsePipeline("test-noop"); err != nil {
+		t.Errorf("expected registered op to parse, got %v", err)
+	}
+	found := false
+	for _, name := range OpNames() {
+		found = found || name == "test-noop"
+	}
+	if !found {
+		t.Error("expected OpNames to include the registered op")
+	}
+}
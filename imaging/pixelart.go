@@ -0,0 +1,142 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+// Filter is the resampling algorithm used by ResizeFilter.
+type Filter int
+
+const (
+	// FilterDefault is Resize's behavior: Catmull-Rom, or nearest-neighbor
+	// for paletted images with transparent entries.
+	FilterDefault Filter = iota
+	// FilterNearest samples the nearest source pixel, so every output pixel
+	// is a source color. Integer scale factors give uniform blocks.
+	FilterNearest
+	// FilterPixel is for pixel art: upscales are doubled with Scale2x while
+	// the result still fits, smoothing diagonal edges without adding colors,
+	// and the rest of the way is nearest-neighbor.
+	FilterPixel
+)
+
+// filterNames are the names accepted by ParseFilter. "point" is
+// ImageMagick's name for nearest-neighbor.
+var filterNames = map[string]Filter{
+	"":           FilterDefault,
+	"catmullrom": FilterDefault,
+	"nearest":    FilterNearest,
+	"point":      FilterNearest,
+	"pixel":      FilterPixel,
+}
+
+// ParseFilter parses a filter name: "catmullrom" (the default), "nearest"
+// (or "point"), or "pixel".
+func ParseFilter(s string) (Filter, error) {
+	if f, ok := filterNames[strings.ToLower(strings.TrimSpace(s))]; ok {
+		return f, nil
+	}
+	return 0, fmt.Errorf("unknown filter %q (want catmullrom, nearest, or pixel)", s)
+}
+
+// ResizeFilter is like Resize but resamples with filter. Paletted images stay
+// paletted with every filter.
+func ResizeFilter(img image.Image, width, height int, filter Filter) image.Image {
+	switch filter {
+	case FilterNearest:
+		return resizeNearest(img, width, height)
+	case FilterPixel:
+		b := img.Bounds()
+		for b.Dx()*2 <= width && b.Dy()*2 <= height && !b.Empty() {
+			img = Scale2x(img)
+			b = img.Bounds()
+		}
+		if b.Dx() == width && b.Dy() == height {
+			return img
+		}
+		return resizeNearest(img, width, height)
+	default:
+		return Resize(img, width, height)
+	}
+}
+
+// resizeNearest scales img with nearest-neighbor sampling, keeping paletted
+// images paletted.
+func resizeNearest(img image.Image, width, height int) image.Image {
+	if p, ok := img.(*image.Paletted); ok {
+		return resizePalettedNearest(p, width, height)
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.NearestNeighbor.Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Src, nil)
+	return dst
+}
+
+// Scale2x doubles img with the Scale2x (EPX) pixel-art algorithm: each pixel
+// becomes a 2x2 block whose corners take a neighbor's color where two
+// neighbors agree, rounding off staircase diagonals. No new colors are
+// introduced, so paletted images keep their palette.
+func Scale2x(img image.Image) image.Image {
+	b := img.Bounds()
+	if p, ok := img.(*image.Paletted); ok {
+		src := make([]uint8, 0, b.Dx()*b.Dy())
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			src = append(src, p.Pix[p.PixOffset(b.Min.X, y):][:b.Dx()]...)
+		}
+		dst := image.NewPaletted(image.Rect(0, 0, b.Dx()*2, b.Dy()*2), p.Palette)
+		dst.Pix = scale2x(src, b.Dx(), b.Dy())
+		return dst
+	}
+
+	n := ToNRGBA(img)
+	src := make([]uint32, 0, b.Dx()*b.Dy())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row := n.Pix[n.PixOffset(b.Min.X, y):][:b.Dx()*4]
+		for i := 0; i < len(row); i += 4 {
+			src = append(src, uint32(row[i])<<24|uint32(row[i+1])<<16|uint32(row[i+2])<<8|uint32(row[i+3]))
+		}
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx()*2, b.Dy()*2))
+	for i, c := range scale2x(src, b.Dx(), b.Dy()) {
+		dst.Pix[i*4], dst.Pix[i*4+1], dst.Pix[i*4+2], dst.Pix[i*4+3] = uint8(c>>24), uint8(c>>16), uint8(c>>8), uint8(c)
+	}
+	return dst
+}
+
+// scale2x applies Scale2x to a w x h grid of pixel values, returning the
+// 2w x 2h grid. Neighbors past the edges repeat the edge pixel.
+func scale2x[T comparable](src []T, w, h int) []T {
+	dst := make([]T, 4*w*h)
+	at := func(x, y int) T {
+		return src[min(max(y, 0), h-1)*w+min(max(x, 0), w-1)]
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			//   a
+			// c p b
+			//   d
+			p := at(x, y)
+			a, b, c, d := at(x, y-1), at(x+1, y), at(x-1, y), at(x, y+1)
+			e0, e1, e2, e3 := p, p, p, p
+			if c == a && c != d && a != b {
+				e0 = a
+			}
+			if a == b && a != c && b != d {
+				e1 = b
+			}
+			if d == c && d != b && c != a {
+				e2 = c
+			}
+			if b == d && b != a && d != c {
+				e3 = d
+			}
+			i := 2*y*2*w + 2*x
+			dst[i], dst[i+1] = e0, e1
+			dst[i+2*w], dst[i+2*w+1] = e2, e3
+		}
+	}
+	return dst
+}
@@ -0,0 +1,96 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestScale2x(t *testing.T) {
+	// A black diagonal staircase on white
+	src := image.NewPaletted(image.Rect(0, 0, 3, 3), color.Palette{color.White, color.Black})
+	src.SetColorIndex(0, 0, 1)
+	src.SetColorIndex(1, 1, 1)
+	src.SetColorIndex(2, 2, 1)
+
+	dst, ok := Scale2x(src).(*image.Paletted)
+	if !ok {
+		t.Fatal("expected a paletted result")
+	}
+	if dst.Bounds() != image.Rect(0, 0, 6, 6) {
+		t.Fatalf("expected 6x6, got %v", dst.Bounds())
+	}
+	// Corners between diagonal neighbors are filled in, so the line is
+	// smoother than nearest-neighbor's 2x2 blocks
+	if dst.ColorIndexAt(1, 2) != 1 || dst.ColorIndexAt(2, 1) != 1 {
+		t.Error("expected the staircase corners to be filled")
+	}
+	if dst.ColorIndexAt(5, 0) != 0 || dst.ColorIndexAt(0, 5) != 0 {
+		t.Error("expected the background corners to stay white")
+	}
+
+	// Non-paletted images use their exact colors
+	rgba := Scale2x(ToNRGBA(src)).(*image.NRGBA)
+	if c := rgba.NRGBAAt(1, 2); c != (color.NRGBA{0, 0, 0, 255}) {
+		t.Errorf("expected black, got %v", c)
+	}
+}
+
+func TestResizeFilter(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	for i := range src.Pix {
+		src.Pix[i] = 255
+	}
+	src.SetNRGBA(1, 1, color.NRGBA{255, 0, 0, 255})
+
+	for _, tt := range []struct {
+		filter Filter
+		w, h   int
+	}{
+		{FilterNearest, 12, 12},
+		{FilterPixel, 12, 12},
+		{FilterPixel, 16, 16},
+		{FilterPixel, 2, 2},
+	} {
+		dst := ResizeFilter(src, tt.w, tt.h, tt.filter)
+		if dst.Bounds().Dx() != tt.w || dst.Bounds().Dy() != tt.h {
+			t.Errorf("filter %d to %dx%d: got %v", tt.filter, tt.w, tt.h, dst.Bounds())
+		}
+		// No blended colors: every pixel is red or white
+		b := dst.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				c := color.NRGBAModel.Convert(dst.At(x, y)).(color.NRGBA)
+				if c != (color.NRGBA{255, 255, 255, 255}) && c != (color.NRGBA{255, 0, 0, 255}) {
+					t.Fatalf("filter %d to %dx%d: blended color %v at %d,%d", tt.filter, tt.w, tt.h, c, x, y)
+				}
+			}
+		}
+	}
+
+	for _, name := range []string{"", "CatmullRom", "point", "nearest", "pixel"} {
+		if _, err := ParseFilter(name); err != nil {
+			t.Errorf("ParseFilter(%q): %v", name, err)
+		}
+	}
+	if _, err := ParseFilter("lanczos"); err == nil {
+		t.Error("expected error for an unknown filter")
+	}
+}
+
+func TestPipeline_ResizeFilter(t *testing.T) {
+	p, err := ParsePipeline("resize:g=400%,filter=pixel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := p.Apply(image.NewGray(image.Rect(0, 0, 8, 4)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Bounds().Dx() != 32 || out.Bounds().Dy() != 16 {
+		t.Errorf("expected 32x16, got %v", out.Bounds())
+	}
+	if _, err := ParsePipeline("resize:w=10,filter=bogus"); err == nil {
+		t.Error("expected error for an unknown filter")
+	}
+}
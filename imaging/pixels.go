@@ -0,0 +1,62 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"strings"
+)
+
+// PixelFormat is the layout of a raw pixel buffer passed to FromPixels.
+type PixelFormat int
+
+const (
+	// PixelRGBA is 8-bit non-premultiplied RGBA, as returned by canvas getImageData.
+	PixelRGBA PixelFormat = iota
+	// PixelGray is 8-bit grayscale.
+	PixelGray
+)
+
+// bytesPerPixel returns the size of one pixel in f.
+func (f PixelFormat) bytesPerPixel() int {
+	if f == PixelGray {
+		return 1
+	}
+	return 4
+}
+
+// ParsePixelFormat parses "rgba" or "gray".
+func ParsePixelFormat(s string) (PixelFormat, error) {
+	switch strings.ToLower(s) {
+	case "rgba", "":
+		return PixelRGBA, nil
+	case "gray", "grey":
+		return PixelGray, nil
+	default:
+		return 0, fmt.Errorf("unsupported pixel format %q", s)
+	}
+}
+
+// FromPixels wraps a raw pixel buffer as an image without copying, so the
+// result shares pix. Rows are stride bytes apart; a stride of 0 means rows
+// are packed with no padding.
+func FromPixels(pix []byte, width, height, stride int, format PixelFormat) (image.Image, error) {
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("invalid dimensions %dx%d", width, height)
+	}
+	rowLen := width * format.bytesPerPixel()
+	if stride == 0 {
+		stride = rowLen
+	}
+	if stride < rowLen {
+		return nil, fmt.Errorf("stride %d is shorter than a %d-byte row", stride, rowLen)
+	}
+	if need := stride*(height-1) + rowLen; len(pix) < need {
+		return nil, fmt.Errorf("pixel buffer has %d bytes, need %d for %dx%d", len(pix), need, width, height)
+	}
+
+	rect := image.Rect(0, 0, width, height)
+	if format == PixelGray {
+		return &image.Gray{Pix: pix, Stride: stride, Rect: rect}, nil
+	}
+	return &image.NRGBA{Pix: pix, Stride: stride, Rect: rect}, nil
+}
@@ -0,0 +1,56 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestFromPixels(t *testing.T) {
+	// 2x2 RGBA with a 2-byte row pad
+	pix := []byte{
+		1, 2, 3, 4, 5, 6, 7, 8, 0, 0,
+		9, 10, 11, 12, 13, 14, 15, 16,
+	}
+	img, err := FromPixels(pix, 2, 2, 10, PixelRGBA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := img.(*image.NRGBA).NRGBAAt(0, 1); got != (color.NRGBA{9, 10, 11, 12}) {
+		t.Errorf("expected second row to start after the padding, got %v", got)
+	}
+
+	gray, err := FromPixels([]byte{10, 20, 30, 40, 50, 60}, 3, 2, 0, PixelGray)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := gray.(*image.Gray).GrayAt(2, 1).Y; got != 60 {
+		t.Errorf("expected packed gray rows, got %d", got)
+	}
+}
+
+func TestFromPixels_Errors(t *testing.T) {
+	tests := []struct {
+		pix                   []byte
+		width, height, stride int
+	}{
+		{make([]byte, 16), 0, 2, 0},
+		{make([]byte, 15), 2, 2, 0},
+		{make([]byte, 16), 2, 2, 4},
+		{make([]byte, 17), 2, 2, 10},
+	}
+	for _, tt := range tests {
+		if _, err := FromPixels(tt.pix, tt.width, tt.height, tt.stride, PixelRGBA); err == nil {
+			t.Errorf("FromPixels(%d bytes, %dx%d, stride %d) expected error", len(tt.pix), tt.width, tt.height, tt.stride)
+		}
+	}
+}
+
+func TestParsePixelFormat(t *testing.T) {
+	if f, err := ParsePixelFormat("gray"); err != nil || f != PixelGray {
+		t.Errorf("expected PixelGray, got %v (err=%v)", f, err)
+	}
+	if _, err := ParsePixelFormat("cmyk"); err == nil {
+		t.Error("expected error for cmyk")
+	}
+}
@@ -0,0 +1,71 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"slices"
+	"sync"
+)
+
+// Policy restricts what pipelines may do, so a deployment can expose only
+// the operations it needs. It applies to every pipeline in the process.
+type Policy struct {
+	// Disabled lists operation names that ParsePipeline rejects.
+	Disabled []string
+	// MaxUpscale is the largest factor by which one step may enlarge an
+	// image's width or height; zero or less allows any.
+	MaxUpscale float64
+}
+
+// DisabledError is returned when a pipeline uses an operation, or a
+// parameter range, that the current Policy forbids.
+type DisabledError struct {
+	Op     string
+	Reason string
+}
+
+func (e *DisabledError) Error() string {
+	return fmt.Sprintf("%s is disabled: %s", e.Op, e.Reason)
+}
+
+var (
+	policyMu sync.RWMutex
+	policy   Policy
+)
+
+// SetPolicy replaces the current policy. The zero Policy allows everything.
+func SetPolicy(p Policy) {
+	p.Disabled = slices.Clone(p.Disabled)
+	policyMu.Lock()
+	defer policyMu.Unlock()
+	policy = p
+}
+
+// CurrentPolicy returns the policy set by SetPolicy.
+func CurrentPolicy() Policy {
+	policyMu.RLock()
+	defer policyMu.RUnlock()
+	return policy
+}
+
+// CheckOp returns a *DisabledError if the operation name is disabled.
+// Frontends that run operations outside a pipeline (a trim or removebg
+// option, say) check them under their pipeline names.
+func (p Policy) CheckOp(name string) error {
+	if slices.Contains(p.Disabled, name) {
+		return &DisabledError{Op: name, Reason: "turned off by the deployment"}
+	}
+	return nil
+}
+
+// CheckScale returns a *DisabledError if op enlarged an image from size
+// from to size to by more than MaxUpscale in either dimension.
+func (p Policy) CheckScale(op string, from, to image.Point) error {
+	if p.MaxUpscale <= 0 || from.X <= 0 || from.Y <= 0 {
+		return nil
+	}
+	if float64(to.X) > float64(from.X)*p.MaxUpscale || float64(to.Y) > float64(from.Y)*p.MaxUpscale {
+		return &DisabledError{Op: op, Reason: fmt.Sprintf("%v to %v upscales by more than %gx", from, to, p.MaxUpscale)}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package imaging
+
+import (
+	"errors"
+	"image"
+	"testing"
+)
+
+func TestPolicy_DisabledOp(t *testing.T) {
+	SetPolicy(Policy{Disabled: []string{"removebg"}})
+	t.Cleanup(func() { SetPolicy(Policy{}) })
+
+	_, err := ParsePipeline("trim|removebg")
+	var disabled *DisabledError
+	if !errors.As(err, &disabled) || disabled.Op != "removebg" {
+		t.Fatalf("expected removebg to be disabled, got %v", err)
+	}
+	if _, err := ParsePipeline("trim|grayscale"); err != nil {
+		t.Errorf("expected other operations to parse, got %v", err)
+	}
+}
+
+func TestPolicy_MaxUpscale(t *testing.T) {
+	SetPolicy(Policy{MaxUpscale: 2})
+	t.Cleanup(func() { SetPolicy(Policy{}) })
+
+	img := image.NewNRGBA(image.Rect(0, 0, 100, 50))
+	p, err := ParsePipeline("resize:w=200")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Apply(img); err != nil {
+		t.Errorf("expected 2x to be allowed, got %v", err)
+	}
+
+	p, _ = ParsePipeline("resize:w=201")
+	var disabled *DisabledError
+	if _, err := p.Apply(img); !errors.As(err, &disabled) || disabled.Op != "resize" {
+		t.Errorf("expected upscaling past 2x to be disabled, got %v", err)
+	}
+}
+
+func TestPolicy_Zero(t *testing.T) {
+	var p Policy
+	if err := p.CheckOp("resize"); err != nil {
+		t.Error(err)
+	}
+	if err := p.CheckScale("resize", image.Pt(1, 1), image.Pt(1000, 1000)); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,206 @@
+package imaging
+
+import (
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+// DefaultRedactCells is the number of pixelation cells across the longer side
+// of a redacted region when Redact is given zero.
+const DefaultRedactCells = 8
+
+// redactNoise is the largest brightness offset, out of 255, that an identity
+// adds to a cell.
+const redactNoise = 48
+
+// Redact pixelates rect in img into a grid of cells, cells across its longer
+// side. Cells are sized relative to the region rather than in pixels, so a
+// face redacted in frames of a series looks alike however large it appears.
+//
+// A non-empty identity adds a brightness pattern derived only from the
+// identity: the same person redacted with the same token is obscured the same
+// way in every frame, and different people get visibly different patterns,
+// without anything recoverable about the original pixels. The result is a
+// copy; img is not modified.
+func Redact(img image.Image, rect image.Rectangle, cells int, identity string) *image.NRGBA {
+	return RedactRegions(img, []image.Rectangle{rect}, RedactOptions{Cells: cells, Identity: identity})
+}
+
+// pixelate is Redact on dst in place.
+func pixelate(dst *image.NRGBA, rect image.Rectangle, cells int, identity string) {
+	rect = rect.Intersect(dst.Rect)
+	if rect.Empty() {
+		return
+	}
+	if cells <= 0 {
+		cells = DefaultRedactCells
+	}
+	cols, rows := cells, cells
+	if rect.Dx() > rect.Dy() {
+		rows = max(1, cells*rect.Dy()/rect.Dx())
+	} else {
+		cols = max(1, cells*rect.Dx()/rect.Dy())
+	}
+	cols, rows = min(cols, rect.Dx()), min(rows, rect.Dy())
+
+	noise := identityNoise(identity, cols*rows)
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			cell := image.Rect(
+				rect.Min.X+col*rect.Dx()/cols, rect.Min.Y+row*rect.Dy()/rows,
+				rect.Min.X+(col+1)*rect.Dx()/cols, rect.Min.Y+(row+1)*rect.Dy()/rows,
+			)
+			c := meanColor(dst, cell)
+			if noise != nil {
+				d := int(noise[row*cols+col])
+				c.R, c.G, c.B = clampByte(int(c.R)+d), clampByte(int(c.G)+d), clampByte(int(c.B)+d)
+			}
+			for y := cell.Min.Y; y < cell.Max.Y; y++ {
+				for x := cell.Min.X; x < cell.Max.X; x++ {
+					dst.SetNRGBA(x, y, c)
+				}
+			}
+		}
+	}
+}
+
+// RedactMode is how a redacted region is obscured.
+type RedactMode int
+
+const (
+	// RedactPixelate replaces the region with a grid of flat cells (Redact).
+	RedactPixelate RedactMode = iota
+	// RedactBlur smears the region with a Gaussian blur (BlurRegion).
+	RedactBlur
+)
+
+// ParseRedactMode parses "pixelate" or "blur". The empty string is RedactPixelate.
+func ParseRedactMode(s string) (RedactMode, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "pixelate":
+		return RedactPixelate, nil
+	case "blur":
+		return RedactBlur, nil
+	}
+	return 0, fmt.Errorf("unknown redact mode %q", s)
+}
+
+// BlurRegion blurs rect in img with a Gaussian of sigma. Only pixels inside
+// the region are sampled, so nothing bleeds in from around it and its edges
+// stay sharp. A sigma of zero or less is chosen relative to the region, as
+// Redact sizes its cells, so small and large faces are obscured alike. The
+// result is a copy; img is not modified.
+func BlurRegion(img image.Image, rect image.Rectangle, sigma float64) *image.NRGBA {
+	return RedactRegions(img, []image.Rectangle{rect}, RedactOptions{Mode: RedactBlur, Sigma: sigma})
+}
+
+// blurRegion is BlurRegion on dst in place.
+func blurRegion(dst *image.NRGBA, rect image.Rectangle, sigma float64) {
+	rect = rect.Intersect(dst.Rect)
+	if rect.Empty() {
+		return
+	}
+	if sigma <= 0 {
+		sigma = float64(max(rect.Dx(), rect.Dy())) / DefaultRedactCells / 2
+	}
+	blurred := Blur(dst.SubImage(rect), min(sigma, MaxBlurSigma))
+	draw.Draw(dst, rect, blurred, image.Point{}, draw.Src)
+}
+
+// RedactOptions say how RedactRegions obscures each region.
+type RedactOptions struct {
+	Mode RedactMode
+	// Cells and Identity are as for Redact, when pixelating.
+	Cells    int
+	Identity string
+	// Sigma is as for BlurRegion, when blurring.
+	Sigma float64
+}
+
+// RedactRegions obscures every region of img as Redact or BlurRegion would,
+// by opts.Mode. The image is copied once and each region applied to that
+// copy, so many regions cost little more than one. img is not modified.
+func RedactRegions(img image.Image, regions []image.Rectangle, opts RedactOptions) *image.NRGBA {
+	dst := copyNRGBA(img)
+	for _, r := range regions {
+		if opts.Mode == RedactBlur {
+			blurRegion(dst, r, opts.Sigma)
+		} else {
+			pixelate(dst, r, opts.Cells, opts.Identity)
+		}
+	}
+	return dst
+}
+
+// ParseRegions parses rectangles given as "x,y,w,h", separated by
+// semicolons, as in "10,10,80,40;200,50,60,60".
+func ParseRegions(s string) ([]image.Rectangle, error) {
+	var regions []image.Rectangle
+	for _, part := range strings.Split(s, ";") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		fields := strings.Split(part, ",")
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("region %q must be x,y,w,h", part)
+		}
+		var n [4]int
+		for i, f := range fields {
+			v, err := strconv.Atoi(strings.TrimSpace(f))
+			if err != nil || v < 0 {
+				return nil, fmt.Errorf("region %q must be x,y,w,h in non-negative pixels", part)
+			}
+			n[i] = v
+		}
+		if n[2] == 0 || n[3] == 0 {
+			return nil, fmt.Errorf("region %q must have a positive width and height", part)
+		}
+		regions = append(regions, image.Rect(n[0], n[1], n[0]+n[2], n[1]+n[3]))
+	}
+	return regions, nil
+}
+
+// identityNoise returns n brightness offsets in [-redactNoise, redactNoise]
+// seeded by identity, or nil for an empty identity.
+func identityNoise(identity string, n int) []int8 {
+	if identity == "" {
+		return nil
+	}
+	h := fnv.New64a()
+	h.Write([]byte(identity))
+	state := h.Sum64()
+	noise := make([]int8, n)
+	for i := range noise {
+		// xorshift64*: small, fast, and the same on every platform
+		state ^= state >> 12
+		state ^= state << 25
+		state ^= state >> 27
+		noise[i] = int8(int((state*2685821657736338717)>>32%(2*redactNoise+1)) - redactNoise)
+	}
+	return noise
+}
+
+// meanColor returns the average color of r in img.
+func meanColor(img *image.NRGBA, r image.Rectangle) color.NRGBA {
+	var sum [4]int
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		row := img.Pix[img.PixOffset(r.Min.X, y):][:r.Dx()*4]
+		for i := 0; i < len(row); i += 4 {
+			for c := range sum {
+				sum[c] += int(row[i+c])
+			}
+		}
+	}
+	n := max(1, r.Dx()*r.Dy())
+	return color.NRGBA{uint8(sum[0] / n), uint8(sum[1] / n), uint8(sum[2] / n), uint8(sum[3] / n)}
+}
+
+func clampByte(v int) uint8 {
+	return uint8(min(max(v, 0), 255))
+}
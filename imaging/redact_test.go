@@ -0,0 +1,190 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"runtime"
+	"testing"
+)
+
+// gradient returns an image whose pixels all differ, standing in for a face.
+func gradient(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{uint8(x * 255 / w), uint8(y * 255 / h), 128, 255})
+		}
+	}
+	return img
+}
+
+func TestRedact(t *testing.T) {
+	src := gradient(100, 100)
+	rect := image.Rect(20, 20, 60, 60)
+	dst := Redact(src, rect, 4, "")
+
+	// Inside, each 10x10 cell is one color; outside, nothing changes
+	if dst.NRGBAAt(20, 20) != dst.NRGBAAt(29, 29) {
+		t.Error("expected a uniform cell")
+	}
+	if dst.NRGBAAt(20, 20) == dst.NRGBAAt(30, 20) {
+		t.Error("expected neighboring cells to differ")
+	}
+	if dst.NRGBAAt(10, 10) != src.NRGBAAt(10, 10) || dst.NRGBAAt(60, 60) != src.NRGBAAt(60, 60) {
+		t.Error("expected pixels outside the region to be unchanged")
+	}
+	if src.NRGBAAt(20, 20) == src.NRGBAAt(29, 29) {
+		t.Error("expected the source to be left unmodified")
+	}
+}
+
+func TestRedact_IdentityIsConsistent(t *testing.T) {
+	// The same identity over the same content gives the same pattern, even
+	// when the region is larger in another frame
+	flat := image.NewNRGBA(image.Rect(0, 0, 200, 200))
+	for i := range flat.Pix {
+		flat.Pix[i] = 128
+	}
+	small := Redact(flat, image.Rect(0, 0, 40, 40), 4, "alice")
+	large := Redact(flat, image.Rect(100, 100, 180, 180), 4, "alice")
+	for row := 0; row < 4; row++ {
+		for col := 0; col < 4; col++ {
+			a := small.NRGBAAt(col*10, row*10)
+			b := large.NRGBAAt(100+col*20, 100+row*20)
+			if a != b {
+				t.Fatalf("cell %d,%d: %v != %v", col, row, a, b)
+			}
+		}
+	}
+
+	other := Redact(flat, image.Rect(0, 0, 40, 40), 4, "bob")
+	same := true
+	for row := 0; row < 4; row++ {
+		for col := 0; col < 4; col++ {
+			if other.NRGBAAt(col*10, row*10) != small.NRGBAAt(col*10, row*10) {
+				same = false
+			}
+		}
+	}
+	if same {
+		t.Error("expected different identities to differ")
+	}
+}
+
+func TestPipeline_Redact(t *testing.T) {
+	p, err := ParsePipeline("redact:40x40+20+20,id=alice,cells=4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := p.Apply(gradient(100, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.At(20, 20) != out.At(29, 29) {
+		t.Error("expected the region to be pixelated")
+	}
+	p, err = ParsePipeline("redact:10x10+0+0,10x10+80+80,mode=blur,sigma=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := checker(100, 100)
+	out, err = p.Apply(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.At(0, 0) == src.At(0, 0) || out.At(85, 85) == src.At(85, 85) {
+		t.Error("expected both regions to be blurred")
+	}
+	if out.At(50, 50) != src.At(50, 50) {
+		t.Error("expected pixels between the regions to be unchanged")
+	}
+
+	for _, expr := range []string{"redact", "redact:50%", "redact:40x40,cells=0", "redact:40x40,mode=smudge", "redact:40x40,sigma=-1"} {
+		if _, err := ParsePipeline(expr); err == nil {
+			t.Errorf("ParsePipeline(%q) expected error", expr)
+		}
+	}
+}
+
+// checker returns black and white 1-pixel squares, which any blur changes.
+func checker(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := uint8((x + y) % 2 * 255)
+			img.SetNRGBA(x, y, color.NRGBA{v, v, v, 255})
+		}
+	}
+	return img
+}
+
+func TestBlurRegion(t *testing.T) {
+	src := checker(100, 100)
+	rect := image.Rect(20, 20, 60, 60)
+	dst := BlurRegion(src, rect, 0)
+
+	if dst.NRGBAAt(30, 30) == src.NRGBAAt(30, 30) {
+		t.Error("expected the region to be blurred")
+	}
+	if dst.NRGBAAt(19, 19) != src.NRGBAAt(19, 19) || dst.NRGBAAt(60, 60) != src.NRGBAAt(60, 60) {
+		t.Error("expected pixels outside the region to be unchanged")
+	}
+	// Colors outside the region don't leak into it
+	edge := BlurRegion(src, rect, 0)
+	src.SetNRGBA(19, 20, color.NRGBA{255, 0, 0, 255})
+	if BlurRegion(src, rect, 0).NRGBAAt(20, 20) != edge.NRGBAAt(20, 20) {
+		t.Error("expected pixels outside the region not to be sampled")
+	}
+}
+
+func TestParseRegions(t *testing.T) {
+	got, err := ParseRegions("10,20,30,40; 0,0,5,5;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []image.Rectangle{image.Rect(10, 20, 40, 60), image.Rect(0, 0, 5, 5)}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, err := ParseRegions(""); err != nil || got != nil {
+		t.Errorf("empty: got %v, %v", got, err)
+	}
+	for _, s := range []string{"1,2,3", "1,2,3,x", "-1,0,5,5", "0,0,0,5"} {
+		if _, err := ParseRegions(s); err == nil {
+			t.Errorf("ParseRegions(%q) expected error", s)
+		}
+	}
+}
+
+func TestRedactRegions(t *testing.T) {
+	src := gradient(100, 100)
+	regions := []image.Rectangle{image.Rect(0, 0, 40, 40), image.Rect(30, 30, 90, 70)}
+
+	// The same as redacting each region in turn, without touching img
+	want := Redact(Redact(src, regions[0], 4, "alice"), regions[1], 4, "alice")
+	got := RedactRegions(src, regions, RedactOptions{Cells: 4, Identity: "alice"})
+	if !equalPix(got, want) {
+		t.Error("expected the same result as redacting each region in turn")
+	}
+	blurred := RedactRegions(src, regions, RedactOptions{Mode: RedactBlur, Sigma: 2})
+	if !equalPix(blurred, BlurRegion(BlurRegion(src, regions[0], 2), regions[1], 2)) {
+		t.Error("expected the same result as blurring each region in turn")
+	}
+	if !equalPix(src, gradient(100, 100)) {
+		t.Error("expected the source to be left unmodified")
+	}
+}
+
+func TestRedactRegions_OneCopy(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 200, 200))
+	regions := []image.Rectangle{image.Rect(0, 0, 20, 20), image.Rect(100, 100, 140, 140)}
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	before := stats.TotalAlloc
+	RedactRegions(src, regions, RedactOptions{})
+	runtime.ReadMemStats(&stats)
+	// An RGBA source is converted straight into the copy, not cloned first
+	if n, size := stats.TotalAlloc-before, uint64(len(src.Pix)); n >= 2*size {
+		t.Errorf("allocated %d bytes for a %d byte image", n, size)
+	}
+}
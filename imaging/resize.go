@@ -0,0 +1,60 @@
+package imaging
+
+import (
+	"context"
+	"image"
+	"image/color"
+
+	"golang.org/x/image/draw"
+)
+
+// Resize scales img to width x height.
+// Paletted images stay paletted with the source palette, so the result can
+// be re-encoded with a palette. They are resampled with Catmull-Rom and each
+// pixel mapped back to its nearest palette color, unless the palette has
+// transparent entries: blending those would give partial alpha the palette
+// can't hold, so such images are scaled with nearest-neighbor sampling and
+// their transparency survives exactly. For pixel art, use ResizeFilter with
+// FilterNearest or FilterPixel. All other images are resampled with
+// Catmull-Rom into an RGBA image.
+func Resize(img image.Image, width, height int) image.Image {
+	dst, _ := ResizeContext(context.Background(), img, width, height)
+	return dst
+}
+
+// ResizeContext is like Resize but returns ctx's error instead of resampling
+// once ctx is done. The resampling itself runs to completion once started.
+func ResizeContext(ctx context.Context, img image.Image, width, height int) (image.Image, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	p, paletted := img.(*image.Paletted)
+	if paletted && paletteHasAlpha(p.Palette) {
+		return resizePalettedNearest(p, width, height), nil
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Over, nil)
+	if paletted {
+		return ToPaletted(dst, p.Palette), nil
+	}
+	return dst, nil
+}
+
+// resizePalettedNearest scales p with nearest-neighbor sampling into a new
+// paletted image sharing its palette.
+func resizePalettedNearest(p *image.Paletted, width, height int) *image.Paletted {
+	dst := image.NewPaletted(image.Rect(0, 0, width, height), p.Palette)
+	draw.NearestNeighbor.Scale(dst, dst.Bounds(), p, p.Bounds(), draw.Src, nil)
+	return dst
+}
+
+// paletteHasAlpha reports whether any palette entry is not fully opaque.
+func paletteHasAlpha(palette color.Palette) bool {
+	for _, c := range palette {
+		if _, _, _, a := c.RGBA(); a != 0xffff {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func createPalettedImage(width, height int) *image.Paletted {
+	palette := color.Palette{
+		color.RGBA{0, 0, 0, 0},
+		color.RGBA{255, 0, 0, 255},
+	}
+	img := image.NewPaletted(image.Rect(0, 0, width, height), palette)
+	// Opaque red block surrounded by transparent index 0
+	for y := 2; y < height-2; y++ {
+		for x := 2; x < width-2; x++ {
+			img.SetColorIndex(x, y, 1)
+		}
+	}
+	return img
+}
+
+func TestResize_RGBA(t *testing.T) {
+	img := createTestImage(100, 50)
+
+	result := Resize(img, 40, 20)
+	if _, ok := result.(*image.RGBA); !ok {
+		t.Errorf("expected *image.RGBA, got %T", result)
+	}
+	if b := result.Bounds(); b.Dx() != 40 || b.Dy() != 20 {
+		t.Errorf("expected 40x20, got %dx%d", b.Dx(), b.Dy())
+	}
+}
+
+func TestResize_NonZeroOrigin(t *testing.T) {
+	img := image.NewRGBA(image.Rect(10, 10, 30, 20))
+	for y := 10; y < 20; y++ {
+		for x := 10; x < 30; x++ {
+			img.Set(x, y, color.RGBA{0, 0, 255, 255})
+		}
+	}
+
+	result := Resize(img, 4, 2)
+	if want := image.Rect(0, 0, 4, 2); result.Bounds() != want {
+		t.Errorf("expected bounds %v, got %v", want, result.Bounds())
+	}
+	if _, _, b, _ := result.At(3, 1).RGBA(); b>>8 != 255 {
+		t.Error("expected resized pixels to come from the source bounds")
+	}
+}
+
+func TestResize_PalettedPreserved(t *testing.T) {
+	img := createPalettedImage(10, 10)
+
+	result := Resize(img, 20, 20)
+	p, ok := result.(*image.Paletted)
+	if !ok {
+		t.Fatalf("expected *image.Paletted, got %T", result)
+	}
+	if len(p.Palette) != len(img.Palette) {
+		t.Errorf("expected palette of %d colors, got %d", len(img.Palette), len(p.Palette))
+	}
+	if idx := p.ColorIndexAt(0, 0); idx != 0 {
+		t.Errorf("expected transparent index 0 at corner, got %d", idx)
+	}
+	if idx := p.ColorIndexAt(10, 10); idx != 1 {
+		t.Errorf("expected red index 1 at center, got %d", idx)
+	}
+}
+
+func TestResize_OpaquePalettedSmoothed(t *testing.T) {
+	// A 16-step ramp in a 256-gray palette, as a GIF or PNG-8 photo would be
+	palette := make(color.Palette, 256)
+	for i := range palette {
+		palette[i] = color.Gray{uint8(i)}
+	}
+	img := image.NewPaletted(image.Rect(0, 0, 16, 4), palette)
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 16; x++ {
+			img.SetColorIndex(x, y, uint8(x*17))
+		}
+	}
+
+	result := Resize(img, 64, 4)
+	p, ok := result.(*image.Paletted)
+	if !ok {
+		t.Fatalf("expected *image.Paletted, got %T", result)
+	}
+	// Nearest-neighbor would only repeat the 16 source levels in runs of 4
+	levels := map[uint8]bool{}
+	for x := 0; x < 64; x++ {
+		levels[p.ColorIndexAt(x, 2)] = true
+	}
+	if len(levels) <= 16 {
+		t.Errorf("expected levels between the source steps, got %d distinct", len(levels))
+	}
+}
+
+func TestTrim_PalettedPreserved(t *testing.T) {
+	img := createPalettedImage(10, 10)
+
+	result := Trim(img)
+	p, ok := result.(*image.Paletted)
+	if !ok {
+		t.Fatalf("expected *image.Paletted, got %T", result)
+	}
+	if b := p.Bounds(); b != image.Rect(2, 2, 8, 8) {
+		t.Errorf("expected bounds (2,2)-(8,8), got %v", b)
+	}
+
+	// PNG encoding of a paletted image keeps the palette and transparency
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, Resize(p, 3, 3)); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	decoded, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if _, ok := decoded.(*image.Paletted); !ok {
+		t.Errorf("expected paletted PNG round trip, got %T", decoded)
+	}
+}
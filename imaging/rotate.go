@@ -0,0 +1,108 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+)
+
+// Rotate rotates img clockwise by degrees. Multiples of 90 are exact pixel
+// remaps; other angles are resampled bilinearly onto a canvas enlarged to
+// hold the whole rotated image, with uncovered areas filled with bg.
+func Rotate(img image.Image, degrees float64, bg color.Color) image.Image {
+	degrees = math.Mod(degrees, 360)
+	if degrees < 0 {
+		degrees += 360
+	}
+
+	switch degrees {
+	case 0:
+		return img
+	case 90, 180, 270:
+		return rotateRightAngle(img, int(degrees))
+	}
+
+	bounds := img.Bounds()
+	w, h := float64(bounds.Dx()), float64(bounds.Dy())
+	rad := degrees * math.Pi / 180
+	sin, cos := math.Sin(rad), math.Cos(rad)
+
+	dw := int(math.Ceil(math.Abs(w*cos) + math.Abs(h*sin)))
+	dh := int(math.Ceil(math.Abs(w*sin) + math.Abs(h*cos)))
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+
+	// Map source coordinates to destination: rotate around the source center,
+	// then move that center to the destination center.
+	cx := float64(bounds.Min.X) + w/2
+	cy := float64(bounds.Min.Y) + h/2
+	dcx, dcy := float64(dw)/2, float64(dh)/2
+	m := f64.Aff3{
+		cos, -sin, dcx - cos*cx + sin*cy,
+		sin, cos, dcy - sin*cx - cos*cy,
+	}
+	draw.BiLinear.Transform(dst, m, img, bounds, draw.Over, nil)
+	return dst
+}
+
+// FlipH mirrors img left to right.
+func FlipH(img image.Image) image.Image { return transform(img, OrientFlipH) }
+
+// FlipV mirrors img top to bottom.
+func FlipV(img image.Image) image.Image { return transform(img, OrientFlipV) }
+
+// rotateRightAngle rotates img clockwise by 90, 180, or 270 degrees.
+func rotateRightAngle(img image.Image, degrees int) image.Image {
+	switch degrees {
+	case 90:
+		return transform(img, OrientRotate90)
+	case 180:
+		return transform(img, OrientRotate180)
+	default:
+		return transform(img, OrientRotate270)
+	}
+}
+
+// transform remaps img's pixels according to an EXIF orientation value,
+// producing the upright image a viewer would display.
+func transform(img image.Image, orientation int) image.Image {
+	src := ToNRGBA(img)
+	sb := src.Bounds()
+	w, h := sb.Dx(), sb.Dy()
+
+	dw, dh := w, h
+	if orientation >= OrientTranspose {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < h; y++ {
+		si := src.PixOffset(sb.Min.X, sb.Min.Y+y)
+		for x := 0; x < w; x++ {
+			dx, dy := x, y
+			switch orientation {
+			case OrientFlipH:
+				dx = w - 1 - x
+			case OrientRotate180:
+				dx, dy = w-1-x, h-1-y
+			case OrientFlipV:
+				dy = h - 1 - y
+			case OrientTranspose:
+				dx, dy = y, x
+			case OrientRotate90:
+				dx, dy = h-1-y, x
+			case OrientTransverse:
+				dx, dy = h-1-y, w-1-x
+			case OrientRotate270:
+				dx, dy = y, w-1-x
+			}
+			di := dst.PixOffset(dx, dy)
+			copy(dst.Pix[di:di+4], src.Pix[si:si+4])
+			si += 4
+		}
+	}
+	return dst
+}
@@ -0,0 +1,65 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestRotate_RightAngles(t *testing.T) {
+	// 3x2 image with a red marker in the top-left corner
+	img := image.NewRGBA(image.Rect(10, 10, 13, 12))
+	red := color.RGBA{255, 0, 0, 255}
+	img.Set(10, 10, red)
+
+	tests := []struct {
+		degrees      float64
+		wantW, wantH int
+		markerX      int
+		markerY      int
+	}{
+		{90, 2, 3, 1, 0},
+		{180, 3, 2, 2, 1},
+		{270, 2, 3, 0, 2},
+		{-90, 2, 3, 0, 2},
+		{450, 2, 3, 1, 0},
+	}
+
+	for _, tt := range tests {
+		result := Rotate(img, tt.degrees, color.Transparent)
+		b := result.Bounds()
+		if b.Dx() != tt.wantW || b.Dy() != tt.wantH {
+			t.Errorf("Rotate(%v): expected %dx%d, got %dx%d", tt.degrees, tt.wantW, tt.wantH, b.Dx(), b.Dy())
+			continue
+		}
+		if !colorsEqual(result.At(b.Min.X+tt.markerX, b.Min.Y+tt.markerY), red) {
+			t.Errorf("Rotate(%v): expected marker at (%d,%d)", tt.degrees, tt.markerX, tt.markerY)
+		}
+	}
+
+	if Rotate(img, 360, color.Transparent) != image.Image(img) {
+		t.Error("expected a full turn to return the image unchanged")
+	}
+}
+
+func TestRotate_ArbitraryAngle(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 100, 50))
+	for y := 0; y < 50; y++ {
+		for x := 0; x < 100; x++ {
+			img.Set(x, y, color.RGBA{0, 0, 255, 255})
+		}
+	}
+
+	result := Rotate(img, 45, color.White)
+	b := result.Bounds()
+	// Bounding box of a rotated 100x50 rectangle is ~106x106
+	if b.Dx() < 105 || b.Dx() > 108 || b.Dy() < 105 || b.Dy() > 108 {
+		t.Errorf("expected ~106x106 canvas, got %dx%d", b.Dx(), b.Dy())
+	}
+	if !colorsEqual(result.At(0, 0), color.White) {
+		t.Error("expected uncovered corner to be filled with the background")
+	}
+	if _, _, bl, _ := result.At(b.Dx()/2, b.Dy()/2).RGBA(); bl>>8 != 255 {
+		t.Error("expected the center to come from the source image")
+	}
+}
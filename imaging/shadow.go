@@ -0,0 +1,75 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"golang.org/x/image/draw"
+)
+
+// ShadowOptions describe the shadow DropShadow casts.
+type ShadowOptions struct {
+	// Offset moves the shadow right and down from the subject.
+	Offset image.Point
+	// Blur is the distance in pixels over which the shadow's edge fades out.
+	Blur float64
+	// Opacity scales the shadow's alpha, from 0 (invisible) to 1.
+	Opacity float64
+	// Color is the shadow color; nil means black.
+	Color color.Color
+}
+
+// MaxShadowBlur bounds ShadowOptions.Blur as accepted from users, since the
+// canvas grows by the blur on each side.
+const MaxShadowBlur = 100
+
+// DefaultShadow is a soft shadow below and to the right of the subject.
+var DefaultShadow = ShadowOptions{Offset: image.Pt(5, 5), Blur: 8, Opacity: 0.5}
+
+// DropShadow casts a shadow of img's alpha channel behind it, so it pairs
+// with RemoveBackground: a cutout gets a shadow shaped like the subject, not
+// like its rectangle. The canvas grows to fit the offset and blurred shadow,
+// and the result starts at (0, 0).
+func DropShadow(img image.Image, opts ShadowOptions) *image.NRGBA {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	margin := int(math.Ceil(max(opts.Blur, 0)))
+	if opts.Color == nil {
+		opts.Color = color.Black
+	}
+	opacity := max(0, min(opts.Opacity, 1))
+
+	// The canvas covers the image and the shadow's blurred extent
+	subject := image.Rect(0, 0, w, h)
+	shadow := subject.Add(opts.Offset).Inset(-margin)
+	canvas := subject.Union(shadow)
+	subject = subject.Sub(canvas.Min)
+	shadow = shadow.Sub(canvas.Min)
+	cw, ch := canvas.Dx(), canvas.Dy()
+
+	// Copy the alpha channel to the shadow's place, then blur it
+	alpha := make([]float64, cw*ch)
+	ox, oy := shadow.Min.X+margin, shadow.Min.Y+margin
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			_, _, _, a := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			alpha[(oy+y)*cw+ox+x] = float64(a) / 0xffff
+		}
+	}
+	if opts.Blur > 0 {
+		// Three sigmas reach out to the margin
+		convolvePlane(alpha, cw, ch, gaussianKernel(opts.Blur/3))
+	}
+
+	sc := color.NRGBAModel.Convert(opts.Color).(color.NRGBA)
+	dst := image.NewNRGBA(image.Rect(0, 0, cw, ch))
+	for y := 0; y < ch; y++ {
+		for x := 0; x < cw; x++ {
+			a := alpha[y*cw+x] * opacity * float64(sc.A)
+			dst.SetNRGBA(x, y, color.NRGBA{sc.R, sc.G, sc.B, uint8(math.Round(min(a, 255)))})
+		}
+	}
+	draw.Draw(dst, subject, img, b.Min, draw.Over)
+	return dst
+}
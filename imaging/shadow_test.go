@@ -0,0 +1,71 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestDropShadow(t *testing.T) {
+	// A red 10x10 square in the middle of a transparent 20x20 cutout
+	img := image.NewNRGBA(image.Rect(0, 0, 20, 20))
+	red := color.NRGBA{255, 0, 0, 255}
+	for y := 5; y < 15; y++ {
+		for x := 5; x < 15; x++ {
+			img.SetNRGBA(x, y, red)
+		}
+	}
+
+	// Without blur the shadow is the square's alpha, offset
+	hard := DropShadow(img, ShadowOptions{Offset: image.Pt(3, 4), Opacity: 1})
+	if b := hard.Bounds(); b != image.Rect(0, 0, 23, 24) {
+		t.Fatalf("expected the canvas to grow by the offset, got %v", b)
+	}
+	for _, tc := range []struct {
+		x, y int
+		want color.NRGBA
+	}{
+		{10, 10, red},
+		{16, 17, color.NRGBA{0, 0, 0, 255}}, // shadow only
+		{2, 2, color.NRGBA{0, 0, 0, 0}},     // outside both
+		{7, 18, color.NRGBA{0, 0, 0, 0}},    // below the square, left of the shadow
+		{8, 18, color.NRGBA{0, 0, 0, 255}},
+	} {
+		if got := hard.NRGBAAt(tc.x, tc.y); got != tc.want {
+			t.Errorf("(%d, %d): expected %v, got %v", tc.x, tc.y, tc.want, got)
+		}
+	}
+
+	// Blur grows the canvas on every side and softens the edge
+	soft := DropShadow(img, ShadowOptions{Offset: image.Pt(-2, 0), Blur: 6, Opacity: 0.5, Color: color.NRGBA{0, 0, 255, 255}})
+	if b := soft.Bounds(); b != image.Rect(0, 0, 32, 32) {
+		t.Fatalf("expected a 32x32 canvas, got %v", b)
+	}
+	// The subject is drawn at (8, 6) on the canvas, the shadow's square at (11, 11)
+	if got := soft.NRGBAAt(8+10, 6+10); got != red {
+		t.Errorf("expected the subject on top, got %v", got)
+	}
+	core, edge := soft.NRGBAAt(11, 16), soft.NRGBAAt(7, 16)
+	if core.B != 255 || core.A == 0 || core.A > 128 {
+		t.Errorf("expected a blue shadow fading at the shadow's edge, got %v", core)
+	}
+	if edge.A == 0 || edge.A >= core.A {
+		t.Errorf("expected the blurred shadow to fade outward, got %v then %v", core, edge)
+	}
+	if got := soft.NRGBAAt(0, 0).A; got != 0 {
+		t.Errorf("expected the far corner to stay clear, got alpha %d", got)
+	}
+}
+
+func TestShadowOp(t *testing.T) {
+	for _, expr := range []string{"shadow", "shadow:4,4,10", "shadow:x=-3,blur=0,opacity=80,color=#333"} {
+		if _, err := ParsePipeline(expr); err != nil {
+			t.Errorf("ParsePipeline(%q): %v", expr, err)
+		}
+	}
+	for _, expr := range []string{"shadow:x=a", "shadow:blur=-1", "shadow:blur=500", "shadow:opacity=150", "shadow:color=nope"} {
+		if _, err := ParsePipeline(expr); err == nil {
+			t.Errorf("ParsePipeline(%q) expected error", expr)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"sort"
+)
+
+// FormatChoice is the output format picked by ChooseFormat.
+type FormatChoice struct {
+	Format  string
+	Quality int
+	// Palette is set when the image has few enough colors to be stored
+	// losslessly as a paletted PNG; convert with ToPaletted before encoding.
+	Palette color.Palette
+	// Reason describes why the format was chosen.
+	Reason string
+}
+
+// Thresholds used by ChooseFormat.
+const (
+	smartPhotoQuality = 85
+	smartMaxPalette   = 256
+	// smartFlatRatio is the fraction of pixels matching their left neighbor
+	// above which an image is treated as flat graphics (screenshots, diagrams)
+	smartFlatRatio = 0.5
+)
+
+// ChooseFormat analyzes img and picks the output format and encoder settings
+// best suited to its content:
+//
+//   - transparency requires PNG
+//   - at most 256 colors (icons, logos) becomes a paletted PNG
+//   - large flat regions (screenshots, diagrams) stay lossless PNG
+//   - everything else is treated as a photo and encoded as JPEG
+func ChooseFormat(img image.Image) FormatChoice {
+	bounds := img.Bounds()
+	colors := make(map[color.NRGBA]struct{}, smartMaxPalette+1)
+	transparent := false
+	flat, total := 0, 0
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		var prev color.NRGBA
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			if c.A != 0xff {
+				transparent = true
+			}
+			if len(colors) <= smartMaxPalette {
+				colors[c] = struct{}{}
+			}
+			if x > bounds.Min.X && c == prev {
+				flat++
+			}
+			prev = c
+			total++
+		}
+	}
+
+	var palette color.Palette
+	if len(colors) <= smartMaxPalette {
+		palette = make(color.Palette, 0, len(colors))
+		keys := make([]uint32, 0, len(colors))
+		for c := range colors {
+			keys = append(keys, uint32(c.R)<<24|uint32(c.G)<<16|uint32(c.B)<<8|uint32(c.A))
+		}
+		// Sorted so the same image always encodes to the same bytes
+		sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+		for _, k := range keys {
+			palette = append(palette, color.NRGBA{uint8(k >> 24), uint8(k >> 16), uint8(k >> 8), uint8(k)})
+		}
+	}
+
+	switch {
+	case palette != nil:
+		return FormatChoice{Format: "png", Palette: palette,
+			Reason: fmt.Sprintf("flat graphics with %d colors; using paletted PNG", len(palette))}
+	case transparent:
+		return FormatChoice{Format: "png", Quality: 1, Reason: "image has transparency; using PNG"}
+	case total > 0 && float64(flat)/float64(total) > smartFlatRatio:
+		return FormatChoice{Format: "png", Quality: 1, Reason: "large flat color regions; using lossless PNG"}
+	default:
+		return FormatChoice{Format: "jpeg", Quality: smartPhotoQuality, Reason: "photographic content; using JPEG"}
+	}
+}
@@ -0,0 +1,79 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestChooseFormat(t *testing.T) {
+	// Few colors, like a logo
+	logo := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 20; x++ {
+			if x < 10 {
+				logo.Set(x, y, color.White)
+			} else {
+				logo.Set(x, y, color.RGBA{0, 0, 255, 255})
+			}
+		}
+	}
+
+	// Smooth gradient with an alpha ramp: many colors and transparency
+	transparent := image.NewNRGBA(image.Rect(0, 0, 40, 40))
+	for y := 0; y < 40; y++ {
+		for x := 0; x < 40; x++ {
+			transparent.Set(x, y, color.NRGBA{uint8(x * 6), uint8(y * 6), 100, uint8(x*6 + 10)})
+		}
+	}
+
+	// Many colors in long horizontal runs, like a screenshot
+	screenshot := image.NewRGBA(image.Rect(0, 0, 100, 300))
+	for y := 0; y < 300; y++ {
+		for x := 0; x < 100; x++ {
+			screenshot.Set(x, y, color.RGBA{uint8(y), uint8(y / 2), uint8(x / 50), 255})
+		}
+	}
+
+	// Noisy photographic content
+	photo := image.NewRGBA(image.Rect(0, 0, 60, 60))
+	seed := uint32(7)
+	for i := range photo.Pix {
+		seed = seed*1664525 + 1013904223
+		photo.Pix[i] = uint8(seed >> 24)
+		if i%4 == 3 {
+			photo.Pix[i] = 255
+		}
+	}
+
+	tests := []struct {
+		name     string
+		img      image.Image
+		format   string
+		paletted bool
+	}{
+		{"logo", logo, "png", true},
+		{"transparent", transparent, "png", false},
+		{"screenshot", screenshot, "png", false},
+		{"photo", photo, "jpeg", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ChooseFormat(tt.img)
+			if got.Format != tt.format {
+				t.Errorf("expected %s, got %s (%s)", tt.format, got.Format, got.Reason)
+			}
+			if (got.Palette != nil) != tt.paletted {
+				t.Errorf("expected paletted=%v, got palette of %d colors", tt.paletted, len(got.Palette))
+			}
+			if got.Reason == "" {
+				t.Error("expected a reason")
+			}
+		})
+	}
+
+	if got := ChooseFormat(logo); len(got.Palette) != 2 {
+		t.Errorf("expected a 2-color palette, got %d", len(got.Palette))
+	}
+}
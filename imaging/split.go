@@ -0,0 +1,76 @@
+package imaging
+
+import (
+	"image"
+	"math"
+	"sort"
+)
+
+// Gutter search parameters for FindGutter.
+const (
+	// gutterSearch is the fraction of the width, centered, searched for the gutter
+	gutterSearch = 0.3
+	// gutterMinContrast is how far, in 8-bit luma, the gutter column's mean
+	// must stand out from the typical column before it is trusted
+	gutterMinContrast = 6
+)
+
+// FindGutter returns the x coordinate of the gutter between the two pages of
+// a double-page scan. It looks in the middle of the image for the column whose
+// mean brightness stands out most from its neighbors, which catches both the
+// dark shadow of a bound spine and a bright gap between separate pages. If no
+// column stands out, the center is returned.
+func FindGutter(img image.Image) int {
+	gray := ToGray(img)
+	b := gray.Bounds()
+	center := b.Min.X + b.Dx()/2
+	half := int(float64(b.Dx()) * gutterSearch / 2)
+	x0, x1 := center-half, center+half
+	if x1-x0 < 3 || b.Dy() == 0 {
+		return center
+	}
+
+	means := make([]float64, x1-x0)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row := gray.Pix[gray.PixOffset(x0, y):]
+		for i := range means {
+			means[i] += float64(row[i])
+		}
+	}
+	for i := range means {
+		means[i] /= float64(b.Dy())
+	}
+
+	sorted := append([]float64(nil), means...)
+	sort.Float64s(sorted)
+	median := sorted[len(sorted)/2]
+
+	best, bestDev := center, 0.0
+	for i, m := range means {
+		dev := math.Abs(m - median)
+		// Prefer the column nearest the center among equally strong candidates
+		if dev > bestDev || dev == bestDev && abs(x0+i-center) < abs(best-center) {
+			best, bestDev = x0+i, dev
+		}
+	}
+	if bestDev < gutterMinContrast {
+		return center
+	}
+	return best
+}
+
+// SplitPages splits a double-page scan at its gutter (see FindGutter) into
+// left and right page images. Both are zero-copy views into img; the gutter
+// column itself starts the right page.
+func SplitPages(img image.Image) (left, right image.Image) {
+	b := img.Bounds()
+	x := FindGutter(img)
+	return Crop(img, image.Rect(b.Min.X, b.Min.Y, x, b.Max.Y)), Crop(img, image.Rect(x, b.Min.Y, b.Max.X, b.Max.Y))
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
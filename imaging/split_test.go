@@ -0,0 +1,49 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// scanImage returns a 100x40 white spread with a gutter band at gx of the given shade.
+func scanImage(gx int, shade uint8) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, 100, 40))
+	for y := 0; y < 40; y++ {
+		for x := 0; x < 100; x++ {
+			v := uint8(235)
+			if x >= gx && x < gx+2 {
+				v = shade
+			}
+			// Some "text" on each page
+			if y%6 == 0 && (x > 10 && x < 35 || x > 65 && x < 90) {
+				v = 20
+			}
+			img.SetGray(x, y, color.Gray{v})
+		}
+	}
+	return img
+}
+
+func TestFindGutter(t *testing.T) {
+	if x := FindGutter(scanImage(46, 60)); x != 46 && x != 47 {
+		t.Errorf("expected dark gutter at 46-47, got %d", x)
+	}
+	bright := scanImage(54, 255)
+	if x := FindGutter(bright); x != 54 && x != 55 {
+		t.Errorf("expected bright gutter at 54-55, got %d", x)
+	}
+	if x := FindGutter(image.NewGray(image.Rect(0, 0, 100, 10))); x != 50 {
+		t.Errorf("expected center for a blank image, got %d", x)
+	}
+}
+
+func TestSplitPages(t *testing.T) {
+	left, right := SplitPages(scanImage(46, 60))
+	if left.Bounds().Min.X != 0 || left.Bounds().Max.X != right.Bounds().Min.X || right.Bounds().Max.X != 100 {
+		t.Errorf("expected adjacent halves covering the image, got %v and %v", left.Bounds(), right.Bounds())
+	}
+	if x := right.Bounds().Min.X; x < 46 || x > 47 {
+		t.Errorf("expected split at the gutter, got %d", x)
+	}
+}
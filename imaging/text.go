@@ -0,0 +1,31 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// TextFace is the fixed 7x13 bitmap face used by DrawText. It needs no font
+// files, so it works the same in the wasm build and the CLI.
+var TextFace font.Face = basicfont.Face7x13
+
+// TextSize returns the width and line height in pixels of s drawn with TextFace.
+func TextSize(s string) (width, height int) {
+	return font.MeasureString(TextFace, s).Ceil(), TextFace.Metrics().Height.Ceil()
+}
+
+// DrawText draws s onto dst in color c, with the left end of its baseline at pt.
+func DrawText(dst draw.Image, pt image.Point, s string, c color.Color) {
+	d := &font.Drawer{
+		Dst:  dst,
+		Src:  image.NewUniform(c),
+		Face: TextFace,
+		Dot:  fixed.P(pt.X, pt.Y),
+	}
+	d.DrawString(s)
+}
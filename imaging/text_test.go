@@ -0,0 +1,34 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestTextSize(t *testing.T) {
+	w, h := TextSize("abc")
+	if w != 21 || h != 13 {
+		t.Errorf("expected 21x13, got %dx%d", w, h)
+	}
+}
+
+func TestDrawText(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 20, 16))
+	DrawText(img, image.Pt(2, 12), "H", color.White)
+
+	lit := 0
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 20; x++ {
+			if img.RGBAAt(x, y).A != 0 {
+				if x < 2 || x >= 9 {
+					t.Fatalf("pixel (%d,%d) drawn outside the glyph cell", x, y)
+				}
+				lit++
+			}
+		}
+	}
+	if lit == 0 {
+		t.Error("expected glyph pixels to be drawn")
+	}
+}
@@ -0,0 +1,213 @@
+// Package marketplace checks product images against the image rules of
+// online marketplaces (minimum size, white background, how much of the frame
+// the product fills, no borders) and fixes failing images with an imaging
+// pipeline.
+//
+// The built-in rules follow each marketplace's published main-image
+// requirements at the time of writing; they are not exhaustive. Watermarks
+// and text overlays in particular are not detected.
+package marketplace
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"sort"
+	"strings"
+
+	"image-resizer/imaging"
+)
+
+// whiteFuzz is the tolerance, in percent, within which a pixel counts as
+// white. JPEG compression keeps "pure white" backgrounds only near 255.
+const whiteFuzz = 3
+
+// Rules are one marketplace's image requirements. Zero fields are not checked.
+type Rules struct {
+	Name        string
+	MinWidth    int
+	MinHeight   int
+	MinLongSide int     // Minimum size of the longer side, in pixels
+	MinWhite    float64 // Minimum percentage of white pixels along the image edges
+	MinFill     float64 // Minimum percentage of the width or height the product spans
+	NoBorder    bool    // Reject solid non-white frames around the image
+	Fix         string  // imaging.Pipeline expression that makes an image comply
+}
+
+// Built-in rules for the main product image.
+var (
+	Amazon = Rules{
+		Name:        "amazon",
+		MinLongSide: 1000,
+		MinWhite:    95,
+		MinFill:     85,
+		NoBorder:    true,
+		Fix:         "trim:fuzz=5|canvas:1000x1000,fill=85,bg=white",
+	}
+	EBay = Rules{
+		Name:        "ebay",
+		MinLongSide: 500,
+		NoBorder:    true,
+		Fix:         "trim:fuzz=5|resize:g=500x500<",
+	}
+	Etsy = Rules{
+		Name:     "etsy",
+		MinWidth: 635,
+		NoBorder: true,
+		Fix:      "trim:fuzz=5|resize:g=635<",
+	}
+)
+
+var builtin = map[string]Rules{
+	Amazon.Name: Amazon,
+	EBay.Name:   EBay,
+	Etsy.Name:   Etsy,
+}
+
+// Lookup returns the built-in rules called name, ignoring case.
+func Lookup(name string) (Rules, error) {
+	if r, ok := builtin[strings.ToLower(strings.TrimSpace(name))]; ok {
+		return r, nil
+	}
+	return Rules{}, fmt.Errorf("unknown marketplace %q (have %s)", name, strings.Join(Names(), ", "))
+}
+
+// Names returns the names of the built-in rules, sorted.
+func Names() []string {
+	names := make([]string, 0, len(builtin))
+	for name := range builtin {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Result is the outcome of Check.
+type Result struct {
+	Rules   string
+	Pass    bool
+	Reasons []string // Why the image failed, one per broken rule
+}
+
+// Check reports whether img meets r.
+func Check(img image.Image, r Rules) Result {
+	res := Result{Rules: r.Name}
+	fail := func(format string, args ...interface{}) {
+		res.Reasons = append(res.Reasons, fmt.Sprintf(format, args...))
+	}
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if r.MinWidth > 0 && w < r.MinWidth {
+		fail("width %dpx is below the %dpx minimum", w, r.MinWidth)
+	}
+	if r.MinHeight > 0 && h < r.MinHeight {
+		fail("height %dpx is below the %dpx minimum", h, r.MinHeight)
+	}
+	if long := max(w, h); r.MinLongSide > 0 && long < r.MinLongSide {
+		fail("longest side %dpx is below the %dpx minimum", long, r.MinLongSide)
+	}
+	if r.MinWhite > 0 {
+		if white := EdgeWhiteness(img); white < r.MinWhite {
+			fail("background is %.0f%% white at the edges, below %.0f%%", white, r.MinWhite)
+		}
+	}
+	if r.MinFill > 0 {
+		if fill := Fill(img); fill < r.MinFill {
+			fail("product fills %.0f%% of the frame, below %.0f%%", fill, r.MinFill)
+		}
+	}
+	if r.NoBorder {
+		if c, ok := Border(img); ok {
+			fail("image has a solid %s border", hex(c))
+		}
+	}
+	res.Pass = len(res.Reasons) == 0
+	return res
+}
+
+// Fix runs r.Fix on img. The result should be checked again: a fix cannot
+// invent resolution or remove a busy background.
+func Fix(img image.Image, r Rules) (image.Image, error) {
+	p, err := imaging.ParsePipeline(r.Fix)
+	if err != nil {
+		return nil, fmt.Errorf("%s fix: %w", r.Name, err)
+	}
+	return p.Apply(img)
+}
+
+// EdgeWhiteness returns the percentage of pixels along the four edges of img
+// that are opaque and white. Transparent pixels do not count as white.
+func EdgeWhiteness(img image.Image) float64 {
+	src := imaging.ToNRGBA(img)
+	b := src.Bounds()
+	if b.Empty() {
+		return 0
+	}
+	var white, total int
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			// Interior rows only contribute their first and last pixels
+			if y > b.Min.Y && y < b.Max.Y-1 && x > b.Min.X && x < b.Max.X-1 {
+				x = b.Max.X - 2
+				continue
+			}
+			total++
+			if isWhite(src.NRGBAAt(x, y)) {
+				white++
+			}
+		}
+	}
+	return float64(white) / float64(total) * 100
+}
+
+// Fill returns how much of the frame the product spans: the larger of its
+// bounding box's width and height as a percentage of the image's, where the
+// product is everything that is not white or transparent.
+func Fill(img image.Image) float64 {
+	src := imaging.ToNRGBA(img)
+	b := src.Bounds()
+	subject := image.Rectangle{}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if c := src.NRGBAAt(x, y); c.A != 0 && !isWhite(c) {
+				subject = subject.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	if subject.Empty() {
+		return 0
+	}
+	return max(float64(subject.Dx())/float64(b.Dx()), float64(subject.Dy())/float64(b.Dy())) * 100
+}
+
+// Border reports whether img is framed by a solid non-white border, returning
+// its color. A uniform dark background reaching every edge is
+// indistinguishable from a border and is reported as one.
+func Border(img image.Image) (color.Color, bool) {
+	b := img.Bounds()
+	if b.Dx() < 3 || b.Dy() < 3 {
+		return nil, false
+	}
+	c := color.NRGBAModel.Convert(img.At(b.Min.X, b.Min.Y)).(color.NRGBA)
+	if c.A == 0 || isWhite(c) {
+		return nil, false
+	}
+	inner := imaging.TrimFuzz(img, whiteFuzz).Bounds()
+	if inner.Min.X > b.Min.X && inner.Min.Y > b.Min.Y && inner.Max.X < b.Max.X && inner.Max.Y < b.Max.Y {
+		return c, true
+	}
+	return nil, false
+}
+
+// isWhite reports whether c is opaque and within whiteFuzz of white.
+func isWhite(c color.NRGBA) bool {
+	const lo = 255 * (100 - whiteFuzz) / 100
+	return c.A == 255 && c.R >= lo && c.G >= lo && c.B >= lo
+}
+
+// hex formats c as #rrggbb for messages.
+func hex(c color.Color) string {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return fmt.Sprintf("#%02x%02x%02x", n.R, n.G, n.B)
+}
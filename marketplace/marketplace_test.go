@@ -0,0 +1,92 @@
+package marketplace
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+	"testing"
+)
+
+// product draws a w x h image of bg with a red box covering rect.
+func product(w, h int, bg color.Color, rect image.Rectangle) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+	draw.Draw(img, rect, image.NewUniform(color.NRGBA{200, 0, 0, 255}), image.Point{}, draw.Src)
+	return img
+}
+
+func TestCheck_Amazon(t *testing.T) {
+	good := product(1000, 1000, color.White, image.Rect(70, 200, 930, 800))
+	if res := Check(good, Amazon); !res.Pass {
+		t.Errorf("expected a compliant image to pass, got %v", res.Reasons)
+	}
+
+	tests := []struct {
+		name string
+		img  image.Image
+		want string
+	}{
+		{"small", product(600, 600, color.White, image.Rect(30, 30, 570, 570)), "longest side"},
+		{"gray background", product(1000, 1000, color.NRGBA{200, 200, 200, 255}, image.Rect(70, 70, 930, 930)), "white"},
+		{"product too small", product(1000, 1000, color.White, image.Rect(400, 400, 600, 600)), "fills"},
+	}
+	for _, tc := range tests {
+		res := Check(tc.img, Amazon)
+		if res.Pass || !containsReason(res.Reasons, tc.want) {
+			t.Errorf("%s: expected a failure mentioning %q, got %+v", tc.name, tc.want, res)
+		}
+	}
+}
+
+func TestCheck_Border(t *testing.T) {
+	framed := product(700, 700, color.Black, image.Rect(10, 10, 690, 690))
+	res := Check(framed, EBay)
+	if res.Pass || !containsReason(res.Reasons, "#000000 border") {
+		t.Errorf("expected a black border to fail, got %+v", res)
+	}
+
+	plain := product(700, 700, color.White, image.Rect(100, 100, 600, 600))
+	if res := Check(plain, EBay); !res.Pass {
+		t.Errorf("expected a white background not to count as a border, got %v", res.Reasons)
+	}
+}
+
+func TestFix(t *testing.T) {
+	// Too small, off-center, and not filling the frame
+	img := product(400, 300, color.White, image.Rect(20, 20, 120, 100))
+	if Check(img, Amazon).Pass {
+		t.Fatal("expected the input to fail")
+	}
+	fixed, err := Fix(img, Amazon)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res := Check(fixed, Amazon); !res.Pass {
+		t.Errorf("expected the fixed image to pass, got %v", res.Reasons)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	if r, err := Lookup("Etsy"); err != nil || r.Name != "etsy" {
+		t.Errorf("expected the etsy rules, got %+v, %v", r, err)
+	}
+	if _, err := Lookup("craigslist"); err == nil {
+		t.Error("expected error for unknown rules")
+	}
+	for _, name := range Names() {
+		r, _ := Lookup(name)
+		if _, err := Fix(image.NewNRGBA(image.Rect(0, 0, 10, 10)), r); err != nil {
+			t.Errorf("%s: invalid fix pipeline: %v", name, err)
+		}
+	}
+}
+
+func containsReason(reasons []string, s string) bool {
+	for _, r := range reasons {
+		if strings.Contains(r, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+// Package passport crops photos to country passport and ID photo specs and
+// lays them out on print sheets.
+//
+// Face detection is not included: callers supply the crown and chin positions
+// (for example from the browser's FaceDetector API or a manual click), and the
+// photo is scaled and placed so the head meets the spec.
+package passport
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"sort"
+	"strings"
+
+	"image-resizer/imaging"
+	"image-resizer/prepress"
+
+	"golang.org/x/image/draw"
+)
+
+// Spec is a passport photo specification. Lengths are in millimeters.
+type Spec struct {
+	Name        string
+	Width       float64
+	Height      float64
+	HeadHeight  float64 // Target chin-to-crown height
+	CrownMargin float64 // Space between the top edge and the crown
+}
+
+// Specs are the built-in specifications, keyed by lowercase name. Head sizes
+// are the middle of each country's allowed range.
+var Specs = map[string]Spec{
+	"us":     {Name: "us", Width: 51, Height: 51, HeadHeight: 30, CrownMargin: 6.5},
+	"uk":     {Name: "uk", Width: 35, Height: 45, HeadHeight: 32, CrownMargin: 5},
+	"eu":     {Name: "eu", Width: 35, Height: 45, HeadHeight: 34, CrownMargin: 4},
+	"canada": {Name: "canada", Width: 50, Height: 70, HeadHeight: 33.5, CrownMargin: 12},
+}
+
+// Lookup returns the spec called name, ignoring case.
+func Lookup(name string) (Spec, error) {
+	if s, ok := Specs[strings.ToLower(strings.TrimSpace(name))]; ok {
+		return s, nil
+	}
+	names := make([]string, 0, len(Specs))
+	for n := range Specs {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return Spec{}, fmt.Errorf("unknown passport spec %q (have %s)", name, strings.Join(names, ", "))
+}
+
+// Face locates the head in a photo: the top of the head and the bottom of the
+// chin. The head is centered horizontally on their midpoint.
+type Face struct {
+	Crown, Chin image.Point
+}
+
+// Crop scales and crops img so the face matches spec at dpi. Areas the photo
+// doesn't cover are filled with white.
+func Crop(img image.Image, face Face, spec Spec, dpi float64) (image.Image, error) {
+	head := face.Chin.Y - face.Crown.Y
+	if head <= 0 {
+		return nil, fmt.Errorf("chin must be below the crown")
+	}
+	w, h := prepress.Pixels(spec.Width, dpi), prepress.Pixels(spec.Height, dpi)
+	scale := float64(prepress.Pixels(spec.HeadHeight, dpi)) / float64(head)
+
+	b := img.Bounds()
+	scaled := imaging.Resize(img, max(1, int(math.Round(float64(b.Dx())*scale))), max(1, int(math.Round(float64(b.Dy())*scale))))
+
+	// Position the scaled photo so the crown lands CrownMargin below the top
+	// and the face is centered
+	cx := float64(face.Crown.X+face.Chin.X)/2 - float64(b.Min.X)
+	crown := float64(face.Crown.Y - b.Min.Y)
+	offset := image.Pt(
+		int(math.Round(float64(w)/2-cx*scale)),
+		prepress.Pixels(spec.CrownMargin, dpi)-int(math.Round(crown*scale)),
+	)
+	return imaging.Extent(scaled, w, h, imaging.GravityNorthWest, offset, color.White), nil
+}
+
+// maxBackgroundScore is the imaging.BlankScore above which the background
+// beside the head is too busy or uneven.
+const maxBackgroundScore = 4
+
+// CheckBackground reports why the background of a cropped photo fails the
+// usual plain, light background rule, or nil if it passes. It samples the top
+// corners, beside the head, where only background should show.
+func CheckBackground(photo image.Image) []string {
+	b := photo.Bounds()
+	w, h := b.Dx()/5, b.Dy()/5
+	var reasons []string
+	for _, corner := range []struct {
+		name string
+		rect image.Rectangle
+	}{
+		{"top left", image.Rect(b.Min.X, b.Min.Y, b.Min.X+w, b.Min.Y+h)},
+		{"top right", image.Rect(b.Max.X-w, b.Min.Y, b.Max.X, b.Min.Y+h)},
+	} {
+		region := imaging.Crop(photo, corner.rect)
+		if score := imaging.BlankScore(region); score > maxBackgroundScore {
+			reasons = append(reasons, fmt.Sprintf("background at the %s is not uniform (score %.1f)", corner.name, score))
+		}
+		if mean := meanLuma(region); mean < 180 {
+			reasons = append(reasons, fmt.Sprintf("background at the %s is too dark (%.0f/255)", corner.name, mean))
+		}
+	}
+	return reasons
+}
+
+// meanLuma returns the average gray level of img.
+func meanLuma(img image.Image) float64 {
+	gray := imaging.ToGray(img)
+	b := gray.Bounds()
+	var sum int
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for _, v := range gray.Pix[gray.PixOffset(b.Min.X, y):][:b.Dx()] {
+			sum += int(v)
+		}
+	}
+	return float64(sum) / float64(max(1, b.Dx()*b.Dy()))
+}
+
+// Sheet tiles copies of photo onto white paper of the given size in
+// millimeters (e.g. 152x102 for 6x4 inch prints) at dpi, separated by a 2mm
+// gap with a light gray cutting guide around each copy.
+func Sheet(photo image.Image, paperWidth, paperHeight, dpi float64) (image.Image, error) {
+	gap := prepress.Pixels(2, dpi)
+	pw, ph := prepress.Pixels(paperWidth, dpi), prepress.Pixels(paperHeight, dpi)
+	size := photo.Bounds().Size()
+	cols, rows := (pw-gap)/(size.X+gap), (ph-gap)/(size.Y+gap)
+	if cols < 1 || rows < 1 {
+		return nil, fmt.Errorf("a %dx%d photo does not fit on %gx%gmm paper", size.X, size.Y, paperWidth, paperHeight)
+	}
+
+	sheet := image.NewNRGBA(image.Rect(0, 0, pw, ph))
+	draw.Draw(sheet, sheet.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	guide := image.NewUniform(color.NRGBA{200, 200, 200, 255})
+	// Center the grid on the paper
+	x0 := (pw - cols*size.X - (cols-1)*gap) / 2
+	y0 := (ph - rows*size.Y - (rows-1)*gap) / 2
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			pt := image.Pt(x0+col*(size.X+gap), y0+row*(size.Y+gap))
+			r := image.Rectangle{pt, pt.Add(size)}
+			draw.Draw(sheet, r.Inset(-1), guide, image.Point{}, draw.Src)
+			draw.Draw(sheet, r, photo, photo.Bounds().Min, draw.Src)
+		}
+	}
+	return sheet, nil
+}
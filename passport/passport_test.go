@@ -0,0 +1,97 @@
+package passport
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"image-resizer/prepress"
+)
+
+// portrait returns a 400x500 white photo with a dark "head" from y=100 to
+// y=300 centered at x=220, and the Face locating it.
+func portrait(bg color.Color) (*image.NRGBA, Face) {
+	img := image.NewNRGBA(image.Rect(0, 0, 400, 500))
+	draw.Draw(img, img.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(160, 100, 280, 300), image.NewUniform(color.NRGBA{60, 40, 30, 255}), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(100, 300, 340, 500), image.NewUniform(color.NRGBA{20, 20, 80, 255}), image.Point{}, draw.Src)
+	return img, Face{Crown: image.Pt(220, 100), Chin: image.Pt(220, 300)}
+}
+
+func TestCrop(t *testing.T) {
+	img, face := portrait(color.White)
+	spec, err := Lookup("US")
+	if err != nil {
+		t.Fatal(err)
+	}
+	photo, err := Crop(img, face, spec, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := prepress.Pixels(51, 300)
+	if b := photo.Bounds(); b.Dx() != size || b.Dy() != size {
+		t.Fatalf("expected %dx%d, got %v", size, size, b)
+	}
+
+	// The head starts CrownMargin below the top and spans HeadHeight
+	crown, head := prepress.Pixels(6.5, 300), prepress.Pixels(30, 300)
+	isHead := func(y int) bool {
+		r, _, _, _ := photo.At(size/2, y).RGBA()
+		return r>>8 == 60
+	}
+	for _, tc := range []struct {
+		y    int
+		want bool
+	}{{crown - 3, false}, {crown + 3, true}, {crown + head - 3, true}, {crown + head + 3, false}} {
+		if got := isHead(tc.y); got != tc.want {
+			t.Errorf("at y=%d: expected head=%t", tc.y, tc.want)
+		}
+	}
+	if reasons := CheckBackground(photo); len(reasons) != 0 {
+		t.Errorf("expected a white background to pass, got %v", reasons)
+	}
+
+	if _, err := Crop(img, Face{Crown: face.Chin, Chin: face.Crown}, spec, 300); err == nil {
+		t.Error("expected error for a chin above the crown")
+	}
+	if _, err := Lookup("atlantis"); err == nil {
+		t.Error("expected error for an unknown spec")
+	}
+}
+
+func TestCheckBackground(t *testing.T) {
+	img, face := portrait(color.NRGBA{90, 90, 90, 255})
+	photo, err := Crop(img, face, Specs["eu"], 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reasons := CheckBackground(photo); len(reasons) == 0 {
+		t.Error("expected a dark background to fail")
+	}
+}
+
+func TestSheet(t *testing.T) {
+	img, face := portrait(color.White)
+	photo, err := Crop(img, face, Specs["us"], 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sheet, err := Sheet(photo, 152, 102, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := sheet.Bounds(); b.Dx() != prepress.Pixels(152, 300) || b.Dy() != prepress.Pixels(102, 300) {
+		t.Errorf("expected a 6x4in sheet, got %v", b)
+	}
+
+	// Two 2-inch photos side by side: the center column is gap, not photo
+	b := sheet.Bounds()
+	if c := color.NRGBAModel.Convert(sheet.At(b.Dx()/2, b.Dy()/2)); c != (color.NRGBA{255, 255, 255, 255}) {
+		t.Errorf("expected the gap between copies at the center, got %v", c)
+	}
+
+	if _, err := Sheet(photo, 40, 40, 300); err == nil {
+		t.Error("expected error when the photo doesn't fit")
+	}
+}
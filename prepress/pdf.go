@@ -0,0 +1,59 @@
+package prepress
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"io"
+)
+
+// EncodePDF writes img as a single-page PDF whose page is the image's
+// physical size at dpi. Pixels are stored losslessly (Flate) as DeviceCMYK
+// for *image.CMYK images and DeviceRGB otherwise.
+func EncodePDF(w io.Writer, img image.Image, dpi float64) error {
+	pix, channels := samples(img)
+	b := img.Bounds()
+	colorSpace := "/DeviceRGB"
+	if channels == 4 {
+		colorSpace = "/DeviceCMYK"
+	}
+
+	var stream bytes.Buffer
+	zw := zlib.NewWriter(&stream)
+	if _, err := zw.Write(pix); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	// PDF units are points (1/72 inch)
+	pw, ph := float64(b.Dx())/dpi*72, float64(b.Dy())/dpi*72
+	content := fmt.Sprintf("q %.4f 0 0 %.4f 0 0 cm /Im0 Do Q", pw, ph)
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.4f %.4f] /Resources << /XObject << /Im0 4 0 R >> >> /Contents 5 0 R >>", pw, ph),
+		fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace %s /BitsPerComponent 8 /Filter /FlateDecode /Length %d >>\nstream\n%s\nendstream",
+			b.Dx(), b.Dy(), colorSpace, stream.Len(), stream.Bytes()),
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	_, err := buf.WriteTo(w)
+	return err
+}
@@ -0,0 +1,188 @@
+// Package prepress prepares images for commercial printing: it adds bleed
+// and crop marks at a target resolution, converts to CMYK, and writes TIFF or
+// PDF files that carry that resolution.
+//
+// CMYK conversion is the naive device conversion of color.CMYKModel; no ICC
+// profile is applied, so a print shop's RIP should still convert with its own
+// profile for accurate color.
+package prepress
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+
+	"image-resizer/imaging"
+
+	"golang.org/x/image/draw"
+)
+
+// DefaultDPI is the resolution used when Options.DPI is zero.
+const DefaultDPI = 300
+
+// Crop mark geometry in millimeters: marks start markGap beyond the bleed
+// so they never print inside it, and are markLength long.
+const (
+	markGap    = 1.5
+	markLength = 5
+)
+
+// Options control Prepare.
+type Options struct {
+	DPI       float64    // Output resolution; DefaultDPI if zero
+	Size      [2]float64 // Trim size in millimeters; zero keeps the image's size at DPI
+	Bleed     float64    // Bleed on each side in millimeters
+	CropMarks bool       // Draw trim marks in a margin outside the bleed
+	CMYK      bool       // Convert the result to *image.CMYK
+}
+
+// dpi returns the effective resolution.
+func (o Options) dpi() float64 {
+	if o.DPI <= 0 {
+		return DefaultDPI
+	}
+	return o.DPI
+}
+
+// Pixels converts a length in millimeters to pixels at dpi.
+func Pixels(mm, dpi float64) int {
+	return int(math.Round(mm / 25.4 * dpi))
+}
+
+// Prepare lays img out for print. With a Size the image is scaled to cover
+// the trim size and center-cropped to it. Bleed extends the image past the
+// trim edge by mirroring its border, so trimming slightly off still leaves no
+// white edge. Crop marks are drawn in black on a white margin. Transparent
+// areas are flattened onto white.
+func Prepare(img image.Image, opts Options) (image.Image, error) {
+	dpi := opts.dpi()
+	if opts.Bleed < 0 {
+		return nil, fmt.Errorf("bleed must not be negative")
+	}
+	if opts.Size[0] < 0 || opts.Size[1] < 0 || (opts.Size[0] == 0) != (opts.Size[1] == 0) {
+		return nil, fmt.Errorf("trim size needs both a width and a height")
+	}
+
+	if opts.Size[0] > 0 {
+		w, h := Pixels(opts.Size[0], dpi), Pixels(opts.Size[1], dpi)
+		g := imaging.Geometry{Width: w, Height: h, Flag: imaging.GeometryFill}
+		sw, sh := g.Size(img.Bounds().Dx(), img.Bounds().Dy())
+		img = imaging.Extent(imaging.Resize(img, sw, sh), w, h, imaging.GravityCenter, image.Point{}, color.White)
+	}
+	img = imaging.Flatten(img, color.White)
+
+	bleed := Pixels(opts.Bleed, dpi)
+	trim := img.Bounds().Size()
+	margin := bleed
+	if opts.CropMarks {
+		margin = Pixels(opts.Bleed+markGap+markLength, dpi)
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, trim.X+2*margin, trim.Y+2*margin))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	mirror(dst, img, image.Pt(margin, margin), bleed)
+	if opts.CropMarks {
+		drawCropMarks(dst, image.Rect(margin, margin, margin+trim.X, margin+trim.Y), Pixels(markLength, dpi), max(1, int(math.Round(dpi/300))))
+	}
+
+	if opts.CMYK {
+		return ToCMYK(dst), nil
+	}
+	return dst, nil
+}
+
+// mirror draws src onto dst with its top-left at pt, extended by bleed pixels
+// on every side with a reflection of its border.
+func mirror(dst *image.NRGBA, img image.Image, pt image.Point, bleed int) {
+	src := imaging.ToNRGBA(img)
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	reflect := func(v, n int) int {
+		// Reflect out-of-range coordinates back in, clamping bleeds wider than the image
+		if v < 0 {
+			v = -v - 1
+		} else if v >= n {
+			v = 2*n - v - 1
+		}
+		return min(max(v, 0), n-1)
+	}
+	for y := -bleed; y < h+bleed; y++ {
+		sy := b.Min.Y + reflect(y, h)
+		for x := -bleed; x < w+bleed; x++ {
+			si := src.PixOffset(b.Min.X+reflect(x, w), sy)
+			di := dst.PixOffset(pt.X+x, pt.Y+y)
+			copy(dst.Pix[di:di+4], src.Pix[si:si+4])
+		}
+	}
+}
+
+// drawCropMarks draws marks of the given length and line width along the
+// outer edges of dst, in line with the edges of the trim rectangle.
+func drawCropMarks(dst *image.NRGBA, trim image.Rectangle, length, width int) {
+	black := image.NewUniform(color.Black)
+	bounds := dst.Bounds()
+	for _, x := range []int{trim.Min.X, trim.Max.X - width} {
+		draw.Draw(dst, image.Rect(x, bounds.Min.Y, x+width, bounds.Min.Y+length), black, image.Point{}, draw.Src)
+		draw.Draw(dst, image.Rect(x, bounds.Max.Y-length, x+width, bounds.Max.Y), black, image.Point{}, draw.Src)
+	}
+	for _, y := range []int{trim.Min.Y, trim.Max.Y - width} {
+		draw.Draw(dst, image.Rect(bounds.Min.X, y, bounds.Min.X+length, y+width), black, image.Point{}, draw.Src)
+		draw.Draw(dst, image.Rect(bounds.Max.X-length, y, bounds.Max.X, y+width), black, image.Point{}, draw.Src)
+	}
+}
+
+// ToCMYK converts img to CMYK with color.CMYKModel, flattening transparency
+// onto white.
+func ToCMYK(img image.Image) *image.CMYK {
+	src := imaging.ToNRGBA(imaging.Flatten(img, color.White))
+	b := src.Bounds()
+	dst := image.NewCMYK(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := src.NRGBAAt(x, y)
+			cc, m, yy, k := color.RGBToCMYK(c.R, c.G, c.B)
+			dst.SetCMYK(x, y, color.CMYK{C: cc, M: m, Y: yy, K: k})
+		}
+	}
+	return dst
+}
+
+// Encode writes img as "tiff" or "pdf" tagged with dpi.
+func Encode(w io.Writer, img image.Image, format string, dpi float64) error {
+	if dpi <= 0 {
+		dpi = DefaultDPI
+	}
+	switch format {
+	case "tiff", "tif":
+		return EncodeTIFF(w, img, dpi)
+	case "pdf":
+		return EncodePDF(w, img, dpi)
+	default:
+		return fmt.Errorf("unsupported print format %q (want tiff or pdf)", format)
+	}
+}
+
+// samples returns img's pixels as packed 8-bit samples: CMYK for *image.CMYK,
+// otherwise RGB flattened onto white.
+func samples(img image.Image) (pix []byte, channels int) {
+	b := img.Bounds()
+	if c, ok := img.(*image.CMYK); ok {
+		pix = make([]byte, 0, b.Dx()*b.Dy()*4)
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			i := c.PixOffset(b.Min.X, y)
+			pix = append(pix, c.Pix[i:i+b.Dx()*4]...)
+		}
+		return pix, 4
+	}
+	src := imaging.ToNRGBA(imaging.Flatten(img, color.White))
+	pix = make([]byte, 0, b.Dx()*b.Dy()*3)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row := src.Pix[src.PixOffset(b.Min.X, y):]
+		for x := 0; x < b.Dx(); x++ {
+			pix = append(pix, row[x*4:x*4+3]...)
+		}
+	}
+	return pix, 3
+}
@@ -0,0 +1,166 @@
+package prepress
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/draw"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"golang.org/x/image/tiff"
+)
+
+// halves returns a w x h image, red on the left half and blue on the right.
+func halves(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, image.Rect(0, 0, w/2, h), image.NewUniform(color.NRGBA{255, 0, 0, 255}), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(w/2, 0, w, h), image.NewUniform(color.NRGBA{0, 0, 255, 255}), image.Point{}, draw.Src)
+	return img
+}
+
+func TestPrepare(t *testing.T) {
+	// 100x50mm at 254 dpi is 1000x500 pixels; 3mm bleed is 30 pixels
+	opts := Options{DPI: 254, Size: [2]float64{100, 50}, Bleed: 3}
+	out, err := Prepare(halves(200, 100), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := out.Bounds(); b.Dx() != 1060 || b.Dy() != 560 {
+		t.Fatalf("expected 1060x560 with bleed, got %v", b)
+	}
+	// The bleed mirrors the border colors
+	if c := color.NRGBAModel.Convert(out.At(0, 0)); c != (color.NRGBA{255, 0, 0, 255}) {
+		t.Errorf("expected red bleed at the left, got %v", c)
+	}
+	if c := color.NRGBAModel.Convert(out.At(1059, 559)); c != (color.NRGBA{0, 0, 255, 255}) {
+		t.Errorf("expected blue bleed at the right, got %v", c)
+	}
+
+	opts.CropMarks = true
+	out, err = Prepare(halves(200, 100), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Margin is 3 + 1.5 + 5 = 9.5mm = 95 pixels; marks are 50 pixels long
+	if b := out.Bounds(); b.Dx() != 1190 || b.Dy() != 690 {
+		t.Fatalf("expected 1190x690 with crop marks, got %v", b)
+	}
+	for _, tc := range []struct {
+		x, y int
+		want color.NRGBA
+	}{
+		{95, 10, color.NRGBA{0, 0, 0, 255}},       // Top mark at the left trim line
+		{10, 95, color.NRGBA{0, 0, 0, 255}},       // Left mark at the top trim line
+		{10, 10, color.NRGBA{255, 255, 255, 255}}, // Corner of the margin
+		{95, 60, color.NRGBA{255, 255, 255, 255}}, // Gap between mark and bleed
+		{66, 66, color.NRGBA{255, 0, 0, 255}},     // Bleed
+	} {
+		if c := color.NRGBAModel.Convert(out.At(tc.x, tc.y)); c != tc.want {
+			t.Errorf("at (%d,%d): expected %v, got %v", tc.x, tc.y, tc.want, c)
+		}
+	}
+
+	opts.CMYK = true
+	out, err = Prepare(halves(200, 100), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := out.(*image.CMYK); !ok {
+		t.Errorf("expected *image.CMYK, got %T", out)
+	}
+
+	for _, bad := range []Options{{Bleed: -1}, {Size: [2]float64{100, 0}}} {
+		if _, err := Prepare(halves(10, 10), bad); err == nil {
+			t.Errorf("Prepare(%+v) expected error", bad)
+		}
+	}
+}
+
+func TestEncodeTIFF(t *testing.T) {
+	img := halves(20, 10)
+	var buf bytes.Buffer
+	if err := EncodeTIFF(&buf, img, 300); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := tiff.Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := color.NRGBAModel.Convert(decoded.At(15, 5)); c != (color.NRGBA{0, 0, 255, 255}) {
+		t.Errorf("expected blue, got %v", c)
+	}
+	if tags := tiffTags(t, buf.Bytes()); tags[tagXResolution] != 300 || tags[tagPhotometricInterpretation] != photometricRGB {
+		t.Errorf("expected RGB at 300 dpi, got %v", tags)
+	}
+
+	buf.Reset()
+	if err := EncodeTIFF(&buf, ToCMYK(img), 600); err != nil {
+		t.Fatal(err)
+	}
+	tags := tiffTags(t, buf.Bytes())
+	if tags[tagPhotometricInterpretation] != photometricSeparated || tags[tagSamplesPerPixel] != 4 || tags[tagXResolution] != 600 {
+		t.Errorf("expected 4-channel CMYK at 600 dpi, got %v", tags)
+	}
+}
+
+// tiffTags reads the first value of each tag in a little-endian TIFF's first
+// IFD; rationals are returned divided out.
+func tiffTags(t *testing.T, data []byte) map[uint16]uint32 {
+	t.Helper()
+	le := binary.LittleEndian
+	if string(data[:4]) != "II*\x00" {
+		t.Fatalf("bad TIFF header %q", data[:4])
+	}
+	ifd := int(le.Uint32(data[4:]))
+	tags := map[uint16]uint32{}
+	for i := 0; i < int(le.Uint16(data[ifd:])); i++ {
+		e := data[ifd+2+i*12:]
+		tag, typ := le.Uint16(e), le.Uint16(e[2:])
+		switch typ {
+		case tiffShort:
+			tags[tag] = uint32(le.Uint16(e[8:]))
+		case tiffLong:
+			tags[tag] = le.Uint32(e[8:])
+		case tiffRational:
+			off := le.Uint32(e[8:])
+			tags[tag] = le.Uint32(data[off:]) / le.Uint32(data[off+4:])
+		}
+	}
+	return tags
+}
+
+func TestEncodePDF(t *testing.T) {
+	// 300x150 pixels at 150 dpi is 2x1 inches, 144x72 points
+	var buf bytes.Buffer
+	if err := Encode(&buf, ToCMYK(halves(300, 150)), "pdf", 150); err != nil {
+		t.Fatal(err)
+	}
+	pdf := buf.Bytes()
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.4")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+		t.Fatal("expected a complete PDF")
+	}
+	if !bytes.Contains(pdf, []byte("/MediaBox [0 0 144.0000 72.0000]")) {
+		t.Error("expected a 144x72pt page")
+	}
+	if !bytes.Contains(pdf, []byte("/ColorSpace /DeviceCMYK")) {
+		t.Error("expected a CMYK image")
+	}
+
+	// Every xref entry points at its object
+	m := regexp.MustCompile(`startxref\n(\d+)`).FindSubmatch(pdf)
+	xref, _ := strconv.Atoi(string(m[1]))
+	entries := regexp.MustCompile(`(\d{10}) 00000 n`).FindAllSubmatch(pdf[xref:], -1)
+	for i, e := range entries {
+		off, _ := strconv.Atoi(string(e[1]))
+		if want := strconv.Itoa(i+1) + " 0 obj"; !bytes.HasPrefix(pdf[off:], []byte(want)) {
+			t.Errorf("xref entry %d points at %q", i+1, pdf[off:off+10])
+		}
+	}
+
+	if err := Encode(&buf, halves(2, 2), "eps", 300); err == nil {
+		t.Error("expected error for an unsupported format")
+	}
+}
@@ -0,0 +1,123 @@
+package prepress
+
+import (
+	"encoding/binary"
+	"image"
+	"io"
+	"math"
+)
+
+// TIFF tags and types used by EncodeTIFF.
+const (
+	tiffShort    = 3
+	tiffLong     = 4
+	tiffRational = 5
+
+	tagImageWidth                = 256
+	tagImageLength               = 257
+	tagBitsPerSample             = 258
+	tagCompression               = 259
+	tagPhotometricInterpretation = 262
+	tagStripOffsets              = 273
+	tagSamplesPerPixel           = 277
+	tagRowsPerStrip              = 278
+	tagStripByteCounts           = 279
+	tagXResolution               = 282
+	tagYResolution               = 283
+	tagPlanarConfiguration       = 284
+	tagResolutionUnit            = 296
+	tagInkSet                    = 332
+
+	photometricRGB       = 2
+	photometricSeparated = 5 // CMYK
+)
+
+// tiffEntry is one IFD entry. Values that don't fit in the entry's four bytes
+// are written after the IFD.
+type tiffEntry struct {
+	tag, typ uint16
+	values   []uint32
+}
+
+// EncodeTIFF writes img as an uncompressed baseline TIFF with its resolution
+// set to dpi. *image.CMYK images are written as separated (CMYK) TIFFs,
+// anything else as RGB. Unlike golang.org/x/image/tiff, which always records
+// 72 dpi and has no CMYK support, this keeps what print shops check first.
+func EncodeTIFF(w io.Writer, img image.Image, dpi float64) error {
+	pix, channels := samples(img)
+	b := img.Bounds()
+
+	photometric := uint32(photometricRGB)
+	bits := []uint32{8, 8, 8}
+	if channels == 4 {
+		photometric = photometricSeparated
+		bits = []uint32{8, 8, 8, 8}
+	}
+	// Resolution as a rational with two decimal places
+	res := []uint32{uint32(math.Round(dpi * 100)), 100}
+
+	// Layout: header, pixel data, IFD, then out-of-line values
+	const headerLen = 8
+	entries := []tiffEntry{
+		{tagImageWidth, tiffLong, []uint32{uint32(b.Dx())}},
+		{tagImageLength, tiffLong, []uint32{uint32(b.Dy())}},
+		{tagBitsPerSample, tiffShort, bits},
+		{tagCompression, tiffShort, []uint32{1}},
+		{tagPhotometricInterpretation, tiffShort, []uint32{photometric}},
+		{tagStripOffsets, tiffLong, []uint32{headerLen}},
+		{tagSamplesPerPixel, tiffShort, []uint32{uint32(channels)}},
+		{tagRowsPerStrip, tiffLong, []uint32{uint32(b.Dy())}},
+		{tagStripByteCounts, tiffLong, []uint32{uint32(len(pix))}},
+		{tagXResolution, tiffRational, res},
+		{tagYResolution, tiffRational, res},
+		{tagPlanarConfiguration, tiffShort, []uint32{1}},
+		{tagResolutionUnit, tiffShort, []uint32{2}}, // Inches
+	}
+	if channels == 4 {
+		entries = append(entries, tiffEntry{tagInkSet, tiffShort, []uint32{1}}) // CMYK
+	}
+
+	// IFDs must start on a word boundary
+	pad := len(pix) % 2
+	ifdOffset := headerLen + len(pix) + pad
+	extraOffset := ifdOffset + 2 + len(entries)*12 + 4
+
+	var ifd, extra []byte
+	le := binary.LittleEndian
+	ifd = le.AppendUint16(ifd, uint16(len(entries)))
+	for _, e := range entries {
+		ifd = le.AppendUint16(ifd, e.tag)
+		ifd = le.AppendUint16(ifd, e.typ)
+		count := len(e.values)
+		if e.typ == tiffRational {
+			count /= 2
+		}
+		ifd = le.AppendUint32(ifd, uint32(count))
+
+		var data []byte
+		for _, v := range e.values {
+			if e.typ == tiffShort {
+				data = le.AppendUint16(data, uint16(v))
+			} else {
+				data = le.AppendUint32(data, v)
+			}
+		}
+		if len(data) <= 4 {
+			ifd = append(ifd, data...)
+			ifd = append(ifd, make([]byte, 4-len(data))...)
+			continue
+		}
+		ifd = le.AppendUint32(ifd, uint32(extraOffset+len(extra)))
+		extra = append(extra, data...)
+	}
+	ifd = le.AppendUint32(ifd, 0) // No further IFDs
+
+	header := []byte{'I', 'I', 42, 0}
+	header = le.AppendUint32(header, uint32(ifdOffset))
+	for _, part := range [][]byte{header, pix, make([]byte, pad), ifd, extra} {
+		if _, err := w.Write(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+// Package presets parses named option sets ("thumbnail", "hero", ...) from
+// JSON so renditions can be changed without code changes.
+//
+// A presets document maps names to options:
+//
+//	{
+//	  "thumbnail": {"geometry": "150x150^", "ops": "trim", "format": "jpeg", "quality": 80},
+//	  "hero":      {"geometry": "1600x>", "format": "jpeg", "maxBytes": 300000}
+//	}
+package presets
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+
+	"image-resizer/imaging"
+)
+
+// Preset is a named set of processing options.
+type Preset struct {
+	// Geometry is an ImageMagick-style size applied after Ops.
+	Geometry string `json:"geometry,omitempty"`
+	// Ops is an imaging.Pipeline expression.
+	Ops      string `json:"ops,omitempty"`
+	Format   string `json:"format,omitempty"`
+	Quality  int    `json:"quality,omitempty"`
+	MaxBytes int    `json:"maxBytes,omitempty"`
+}
+
+// Pipeline returns the preset's operations followed by its resize.
+func (p Preset) Pipeline() (*imaging.Pipeline, error) {
+	expr := p.Ops
+	if p.Geometry != "" {
+		if expr != "" {
+			expr += "|"
+		}
+		expr += "resize:g=" + p.Geometry
+	}
+	return imaging.ParsePipeline(expr)
+}
+
+// Validate checks that every field of p is usable.
+func (p Preset) Validate() error {
+	if _, err := p.Pipeline(); err != nil {
+		return err
+	}
+	switch p.Format {
+	case "", "jpg", "smart":
+	default:
+		if !slices.Contains(imaging.OutputFormats, p.Format) {
+			return fmt.Errorf("unsupported format %q", p.Format)
+		}
+	}
+	if p.Quality < 0 || p.Quality > 100 {
+		return fmt.Errorf("quality must be between 1 and 100")
+	}
+	if p.MaxBytes < 0 {
+		return fmt.Errorf("maxBytes must not be negative")
+	}
+	return nil
+}
+
+// Parse decodes and validates a JSON presets document.
+func Parse(data []byte) (map[string]Preset, error) {
+	var presets map[string]Preset
+	if err := json.Unmarshal(data, &presets); err != nil {
+		return nil, fmt.Errorf("invalid presets: %w", err)
+	}
+	for name, p := range presets {
+		if err := p.Validate(); err != nil {
+			return nil, fmt.Errorf("preset %q: %w", name, err)
+		}
+	}
+	return presets, nil
+}
+
+// Store holds the current presets. It is safe for concurrent use.
+type Store struct {
+	mu      sync.RWMutex
+	presets map[string]Preset
+}
+
+// NewStore returns a Store holding the given presets.
+func NewStore(presets map[string]Preset) *Store {
+	return &Store{presets: presets}
+}
+
+// Replace swaps in a new set of presets.
+func (s *Store) Replace(presets map[string]Preset) {
+	s.mu.Lock()
+	s.presets = presets
+	s.mu.Unlock()
+}
+
+// Get returns the preset called name.
+func (s *Store) Get(name string) (Preset, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.presets[name]
+	return p, ok
+}
+
+// Names returns the preset names, sorted.
+func (s *Store) Names() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]string, 0, len(s.presets))
+	for name := range s.presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
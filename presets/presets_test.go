@@ -0,0 +1,74 @@
+package presets
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testPresets = `{
+	"thumbnail": {"geometry": "150x150^", "ops": "trim", "format": "jpeg", "quality": 80},
+	"hero": {"geometry": "1600x>", "maxBytes": 300000}
+}`
+
+func TestParse(t *testing.T) {
+	presets, err := Parse([]byte(testPresets))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Preset{Geometry: "150x150^", Ops: "trim", Format: "jpeg", Quality: 80}
+	if presets["thumbnail"] != want {
+		t.Errorf("expected %+v, got %+v", want, presets["thumbnail"])
+	}
+
+	p, err := presets["thumbnail"].Pipeline()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := p.String(); got != "trim|resize:g=150x150^" {
+		t.Errorf("unexpected pipeline %q", got)
+	}
+}
+
+func TestParse_Formats(t *testing.T) {
+	// Every format the encoder writes, plus jpg and smart
+	for _, format := range []string{"png", "jpeg", "jpg", "gif", "rgba", "npy", "csv", "smart"} {
+		if _, err := Parse([]byte(`{"a": {"format": "` + format + `"}}`)); err != nil {
+			t.Errorf("format %q: %v", format, err)
+		}
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := []string{
+		`not json`,
+		`{"a": {"geometry": "huge"}}`,
+		`{"a": {"ops": "explode"}}`,
+		`{"a": {"format": "bmp"}}`,
+		`{"a": {"quality": 101}}`,
+		`{"a": {"maxBytes": -1}}`,
+	}
+	for _, in := range tests {
+		if _, err := Parse([]byte(in)); err == nil {
+			t.Errorf("Parse(%s) expected error", in)
+		}
+	}
+}
+
+func TestStore(t *testing.T) {
+	presets, err := Parse([]byte(testPresets))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewStore(presets)
+	if got := s.Names(); !reflect.DeepEqual(got, []string{"hero", "thumbnail"}) {
+		t.Errorf("unexpected names %v", got)
+	}
+
+	s.Replace(map[string]Preset{"tiny": {Geometry: "16x16"}})
+	if _, ok := s.Get("tiny"); !ok {
+		t.Error("expected the replaced presets")
+	}
+	if _, ok := s.Get("hero"); ok {
+		t.Error("expected the previous presets to be gone")
+	}
+}
@@ -0,0 +1,55 @@
+// meh-worker.js runs main.wasm inside a Web Worker so that processing large
+// images doesn't freeze the page. Load it through meh.js rather than directly.
+//
+// Messages in:  {id, fn, args}
+// Messages out: {ready: [names]} once loaded, or {loadError} if loading failed;
+//               {id, result} or {id, error} per call;
+//               {id, progress: [stage, percent]} for calls whose options had progress: true.
+importScripts('wasm_exec.js');
+
+const go = new Go();
+const loaded = WebAssembly.instantiateStreaming(fetch('main.wasm'), go.importObject)
+    .then(({ instance }) => {
+        // main registers its functions on self before blocking
+        go.run(instance);
+        self.postMessage({ ready: self.mehExports });
+    })
+    .catch((err) => {
+        self.postMessage({ loadError: err.message });
+        throw err;
+    });
+
+// transferables lists the whole-buffer byte arrays in a result so they can be
+// moved to the page instead of copied.
+function transferables(value, out = []) {
+    if (ArrayBuffer.isView(value)) {
+        if (value.byteOffset === 0 && value.byteLength === value.buffer.byteLength && !out.includes(value.buffer)) {
+            out.push(value.buffer);
+        }
+    } else if (Array.isArray(value)) {
+        value.forEach((v) => transferables(v, out));
+    } else if (value && typeof value === 'object') {
+        Object.values(value).forEach((v) => transferables(v, out));
+    }
+    return out;
+}
+
+self.onmessage = async (e) => {
+    const { id, fn, args } = e.data;
+    try {
+        await loaded;
+        if (!self.mehExports.includes(fn)) {
+            throw new Error('unknown function ' + fn);
+        }
+        // Functions can't cross to the worker; meh.js sends progress: true instead
+        for (const arg of args) {
+            if (arg && arg.progress === true) {
+                arg.progress = (stage, percent) => self.postMessage({ id, progress: [stage, percent] });
+            }
+        }
+        const result = await self[fn](...args);
+        self.postMessage({ id, result }, transferables(result));
+    } catch (err) {
+        self.postMessage({ id, error: err.message });
+    }
+};
@@ -0,0 +1,81 @@
+// meh.js loads main.wasm in a Web Worker (meh-worker.js) and exposes each
+// function main.go registers as a method returning a Promise:
+//
+//     const meh = await loadMeh();
+//     const result = await meh.processImage(bytes, { width: 300, progress: (stage, pct) => ... });
+//
+// The methods are generated from the worker's list of exports, so new Go
+// functions need no changes here. Image buffers passed in are transferred
+// to the worker rather than copied and can't be used afterwards; pass
+// bytes.slice() to keep a copy. Result buffers are transferred back the same way.
+//
+// base is the URL of the directory holding meh-worker.js, wasm_exec.js, and main.wasm.
+function loadMeh(base = '') {
+    const worker = new Worker(new URL('meh-worker.js', new URL(base, document.baseURI)));
+    const pending = new Map();
+    let nextID = 0;
+
+    // Whole-buffer byte arrays in the arguments are transferred; views into
+    // a larger buffer are copied so the rest of the buffer stays usable.
+    function transferables(value, out = []) {
+        if (ArrayBuffer.isView(value)) {
+            if (value.byteOffset === 0 && value.byteLength === value.buffer.byteLength && !out.includes(value.buffer)) {
+                out.push(value.buffer);
+            }
+        } else if (Array.isArray(value)) {
+            value.forEach((v) => transferables(v, out));
+        } else if (value && typeof value === 'object') {
+            Object.values(value).forEach((v) => transferables(v, out));
+        }
+        return out;
+    }
+
+    function call(fn, args) {
+        const id = nextID++;
+        let progress;
+        args = args.map((arg) => {
+            if (arg && typeof arg.progress === 'function') {
+                progress = arg.progress;
+                return { ...arg, progress: true };
+            }
+            return arg;
+        });
+        return new Promise((resolve, reject) => {
+            pending.set(id, { resolve, reject, progress });
+            worker.postMessage({ id, fn, args }, transferables(args));
+        });
+    }
+
+    return new Promise((resolve, reject) => {
+        worker.onerror = (e) => reject(new Error(e.message || 'failed to start worker'));
+        worker.onmessage = (e) => {
+            const msg = e.data;
+            if (msg.loadError !== undefined) {
+                reject(new Error('failed to load main.wasm: ' + msg.loadError));
+                return;
+            }
+            if (msg.ready) {
+                const meh = { terminate: () => worker.terminate() };
+                for (const name of msg.ready) {
+                    meh[name] = (...args) => call(name, args);
+                }
+                resolve(meh);
+                return;
+            }
+            const p = pending.get(msg.id);
+            if (!p) {
+                return;
+            }
+            if (msg.progress) {
+                if (p.progress) p.progress(...msg.progress);
+                return;
+            }
+            pending.delete(msg.id);
+            if (msg.error !== undefined) {
+                p.reject(new Error(msg.error));
+            } else {
+                p.resolve(msg.result);
+            }
+        };
+    });
+}